@@ -0,0 +1,72 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	apiKeyNameFlag  = "name"
+	apiKeyAdminFlag = "admin"
+	// apiKeysDir is the store of API keys in the data directory
+	apiKeysDir = "apikeys"
+)
+
+// apiKeyCmd represents the apikey command
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manages the API keys of the Email backend service",
+}
+
+// apiKeyCreateCmd creates the first admin key, the next keys can be created
+// with APIKeyService. The store can not be shared with the running service.
+var apiKeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates the API key and prints it, the service must be stopped",
+	Run: func(cmd *cobra.Command, args []string) {
+		// The flag is bound only here, so it does not replace the one of serve
+		if err := viper.BindPFlag(dataDirFlag, cmd.Flags().Lookup(dataDirFlag)); err != nil {
+			zap.L().Fatal("Unable to bind flags", zap.Error(err))
+		}
+		store, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), apiKeysDir))
+		if err != nil {
+			zap.L().Fatal("Can not open api keys", zap.Error(err))
+		}
+		defer store.Close()
+		name, _ := cmd.Flags().GetString(apiKeyNameFlag)
+		admin, _ := cmd.Flags().GetBool(apiKeyAdminFlag)
+		key, secret, err := auth.NewKeys(auth.NewStorageKeyStore(store)).Create(name, admin)
+		if err != nil {
+			zap.L().Fatal("Can not create api key", zap.Error(err))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "id: %s\nkey: %s\n", key.ID, secret)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(apiKeyCmd)
+	apiKeyCmd.AddCommand(apiKeyCreateCmd)
+
+	apiKeyCreateCmd.Flags().String(apiKeyNameFlag, "", "who uses the key e.g. the name of the client application")
+	apiKeyCreateCmd.Flags().Bool(apiKeyAdminFlag, false, "whether the key can manage the service and the API keys")
+	apiKeyCreateCmd.Flags().String(dataDirFlag, "data", "the directory where the API keys are stored")
+}
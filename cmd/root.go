@@ -58,7 +58,12 @@ After that you can test it with the client subcommand:
 
 Or over HTTP 1.1 with curl:
 
-	curl -X POST -k https://localhost:9091/v1alpha1/emial -d '{"message":"abc"}''
+	curl -X POST -k https://localhost:9091/v1alpha1/email -d '{
+		"from": {"email": "jane@example.com", "name": "Jane"},
+		"to": [{"email": "john@example.com"}],
+		"subject": "Hello",
+		"text_body": "Hello John"
+	}'
 `,
 }
 
@@ -14,13 +14,24 @@
 package cmd
 
 import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"net"
 
 	"fmt"
 
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
 	"github.com/RafalKorepta/coding-challenge/pkg/backend"
+	"github.com/RafalKorepta/coding-challenge/pkg/dkim"
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"github.com/RafalKorepta/coding-challenge/pkg/services"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -32,6 +43,51 @@ const (
 	certFileNameFlag = "cert_file_name"
 	keyFileNameFlag  = "key_file_name"
 	secureFlag       = "secure"
+
+	sendGridAPIKeyFlag   = "sendgrid_api_key"
+	sendGridEndpointFlag = "sendgrid_endpoint"
+
+	sesRegionFlag          = "ses_region"
+	sesAccessKeyIDFlag     = "ses_access_key_id"
+	sesSecretAccessKeyFlag = "ses_secret_access_key"
+	sesEndpointFlag        = "ses_endpoint"
+
+	smtpHostFlag     = "smtp_host"
+	smtpPortFlag     = "smtp_port"
+	smtpTLSFlag      = "smtp_tls"
+	smtpUsernameFlag = "smtp_username"
+	smtpPasswordFlag = "smtp_password"
+	smtpHeloNameFlag = "smtp_helo_name"
+
+	balancerStrategyFlag = "balancer_strategy"
+
+	dataDirFlag           = "data_dir"
+	workersFlag           = "workers"
+	idempotencyWindowFlag = "idempotency_window"
+	messageRetentionFlag  = "message_retention"
+	shutdownTimeoutFlag   = "shutdown_timeout"
+	// providersKey is the list of providers which can be set only in the config file
+	providersKey = "providers"
+	// retryKey is the default retry policy which can be set only in the config file
+	retryKey = "retry"
+	// attachmentsKey is the attachment policy which can be set only in the config file
+	attachmentsKey = "attachments"
+	// addressesKey configures the checks of recipient addresses and can be set only in the config file
+	addressesKey = "addresses"
+	// rateLimitKey configures the limits of calls of clients and can be set only in the config file
+	rateLimitKey = "rate_limit"
+	// dkimKey is the list of DKIM keys of sender domains which can be set only in the config file
+	dkimKey = "dkim"
+	// webhooksKey configures the endpoints of provider events and can be set only in the config file
+	webhooksKey = "webhooks"
+	// callbacksKey configures posting the events to the callbacks and can be set only in the config file
+	callbacksKey = "callbacks"
+	// authKey configures the authentication of callers and can be set only in the config file
+	authKey = "auth"
+	// mtlsKey configures the client certificates and can be set only in the config file
+	mtlsKey = "mtls"
+	// jwtKey configures the validation of OIDC tokens and can be set only in the config file
+	jwtKey = "jwt"
 )
 
 // serveCmd represents the serve command
@@ -47,17 +103,183 @@ var serveCmd = &cobra.Command{
 		if err != nil {
 			zap.L().Fatal(fmt.Sprintf("Can not listen on localhost:%d", viper.GetInt(portNumberFlag)), zap.Error(err))
 		}
+		var dkimKeys []dkim.KeyConfig
+		if err := viper.UnmarshalKey(dkimKey, &dkimKeys); err != nil {
+			zap.L().Fatal("Can not configure DKIM", zap.Error(err))
+		}
+		keyring, err := dkim.NewKeyring(dkimKeys)
+		if err != nil {
+			zap.L().Fatal("Can not configure DKIM", zap.Error(err))
+		}
+		providers, err := configuredProviders(keyring)
+		if err != nil {
+			zap.L().Fatal("Can not create email provider", zap.Error(err))
+		}
+		provider, err := newProvider(providers)
+		if err != nil {
+			zap.L().Fatal("Can not create email provider", zap.Error(err))
+		}
+		queueStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), "queue"))
+		if err != nil {
+			zap.L().Fatal("Can not open email queue", zap.Error(err))
+		}
+		deadLetterStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), "deadletters"))
+		if err != nil {
+			zap.L().Fatal("Can not open dead letters", zap.Error(err))
+		}
+		queue, err := services.NewQueue(queueStore, services.NewDeadLetters(deadLetterStore))
+		if err != nil {
+			zap.L().Fatal("Can not load email queue", zap.Error(err))
+		}
+		if retention := viper.GetDuration(messageRetentionFlag); retention > 0 {
+			queue.StartRetention(retention)
+		}
+		idempotencyStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), "idempotency"))
+		if err != nil {
+			zap.L().Fatal("Can not open idempotency keys", zap.Error(err))
+		}
+		templateStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), "templates"))
+		if err != nil {
+			zap.L().Fatal("Can not open email templates", zap.Error(err))
+		}
+		suppressionStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), "suppressions"))
+		if err != nil {
+			zap.L().Fatal("Can not open suppressions", zap.Error(err))
+		}
+		callbackStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), "callbacks"))
+		if err != nil {
+			zap.L().Fatal("Can not open callbacks", zap.Error(err))
+		}
+		callbacks := services.NewCallbacks(callbackStore)
+		var notifierCfg services.NotifierConfig
+		if err := viper.UnmarshalKey(callbacksKey, &notifierCfg); err != nil {
+			zap.L().Fatal("Can not configure callbacks", zap.Error(err))
+		}
+		notifier := services.NewNotifier(callbacks, queue.Events(), notifierCfg)
+		notifier.Start()
+		var dispatcher *services.Dispatcher
+		if provider != nil {
+			cfg, err := dispatcherConfig(providers)
+			if err != nil {
+				zap.L().Fatal("Can not configure email dispatcher", zap.Error(err))
+			}
+			dispatcher = services.NewDispatcher(queue, provider, cfg)
+			dispatcher.Start()
+		} else {
+			zap.L().Warn("Emails will wait in the queue until the provider is configured")
+		}
+		var attachmentPolicy services.AttachmentPolicy
+		if err := viper.UnmarshalKey(attachmentsKey, &attachmentPolicy); err != nil {
+			zap.L().Fatal("Can not configure attachments", zap.Error(err))
+		}
+		var addressValidation services.AddressValidation
+		if err := viper.UnmarshalKey(addressesKey, &addressValidation); err != nil {
+			zap.L().Fatal("Can not configure address validation", zap.Error(err))
+		}
+		addressValidator, err := services.NewAddressValidator(addressValidation, nil)
+		if err != nil {
+			zap.L().Fatal("Can not configure address validation", zap.Error(err))
+		}
+		var rateLimit ratelimit.Config
+		if err := viper.UnmarshalKey(rateLimitKey, &rateLimit); err != nil {
+			zap.L().Fatal("Can not configure rate limit", zap.Error(err))
+		}
+		apiKeyStore, err := storage.Open(filepath.Join(viper.GetString(dataDirFlag), apiKeysDir))
+		if err != nil {
+			zap.L().Fatal("Can not open api keys", zap.Error(err))
+		}
+		var authCfg auth.Config
+		if err := viper.UnmarshalKey(authKey, &authCfg); err != nil {
+			zap.L().Fatal("Can not configure authentication", zap.Error(err))
+		}
+		var mtls auth.MTLSConfig
+		if err := viper.UnmarshalKey(mtlsKey, &mtls); err != nil {
+			zap.L().Fatal("Can not configure client certificates", zap.Error(err))
+		}
+		var jwtCfg auth.JWTConfig
+		if err := viper.UnmarshalKey(jwtKey, &jwtCfg); err != nil {
+			zap.L().Fatal("Can not configure tokens", zap.Error(err))
+		}
+		var jwt *auth.JWT
+		if jwtCfg.Enabled() {
+			if jwt, err = auth.NewJWT(jwtCfg); err != nil {
+				zap.L().Fatal("Can not configure tokens", zap.Error(err))
+			}
+			jwt.Start()
+		}
+		if !authCfg.Enabled && jwt == nil && !mtls.Enabled() {
+			zap.L().Warn("Authentication is disabled, every client can send emails")
+		}
+		idempotency := services.NewIdempotency(idempotencyStore, viper.GetDuration(idempotencyWindowFlag))
+		idempotency.Start()
+		var webhooks services.WebhookConfig
+		if err := viper.UnmarshalKey(webhooksKey, &webhooks); err != nil {
+			zap.L().Fatal("Can not configure webhooks", zap.Error(err))
+		}
 		srv := backend.NewServer(listener,
 			backend.WithSecure(viper.GetBool(secureFlag)),
 			backend.WithCertFile(filepath.Join(viper.GetString(certPathFlag), viper.GetString(certFileNameFlag))),
-			backend.WithKeyFile(filepath.Join(viper.GetString(certPathFlag), viper.GetString(keyFileNameFlag))))
-		err = srv.Serve()
-		if err != nil {
+			backend.WithKeyFile(filepath.Join(viper.GetString(certPathFlag), viper.GetString(keyFileNameFlag))),
+			backend.WithProvider(provider),
+			backend.WithQueue(queue),
+			backend.WithIdempotency(idempotency),
+			backend.WithTemplates(services.NewTemplates(templateStore)),
+			backend.WithSuppressions(services.NewSuppressions(suppressionStore)),
+			backend.WithCallbacks(callbacks),
+			backend.WithWebhooks(webhooks),
+			backend.WithAttachmentPolicy(attachmentPolicy),
+			backend.WithAddressValidator(addressValidator),
+			backend.WithRateLimit(rateLimit),
+			backend.WithAPIKeys(auth.NewKeys(auth.NewStorageKeyStore(apiKeyStore))),
+			backend.WithAuth(authCfg),
+			backend.WithMTLS(mtls),
+			backend.WithJWT(jwt))
+
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve() }()
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		select {
+		case err = <-served:
+		case sig := <-signals:
+			zap.L().Info("Shutting down", zap.String("signal", sig.String()))
+			ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration(shutdownTimeoutFlag))
+			if err := srv.Shutdown(ctx); err != nil {
+				zap.L().Warn("Calls were interrupted by the shutdown", zap.Error(err))
+			}
+			cancel()
+			err = <-served
+		}
+		signal.Stop(signals)
+
+		// The dispatcher finishes the emails it is sending before the queue
+		// is closed, and the notifier stores the events of the last changes
+		if dispatcher != nil {
+			dispatcher.Stop()
+		}
+		queue.Close()
+		notifier.Stop()
+		idempotency.Stop()
+		if jwt != nil {
+			jwt.Stop()
+		}
+		closeStores(queueStore, deadLetterStore, idempotencyStore, templateStore, suppressionStore,
+			callbackStore, apiKeyStore)
+		if err != nil && err != http.ErrServerClosed {
 			zap.L().Fatal("Server failed", zap.Error(err))
 		}
 	},
 }
 
+// closeStores syncs the stores to the disk and closes them
+func closeStores(stores ...*storage.Store) {
+	for _, s := range stores {
+		if err := s.Close(); err != nil {
+			zap.L().Error("Can not close store", zap.Error(err))
+		}
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(serveCmd)
 
@@ -67,7 +289,113 @@ func init() {
 	serveCmd.Flags().String(certFileNameFlag, "server.pem", "the path where key and certificate are located")
 	serveCmd.Flags().String(keyFileNameFlag, "server.key", "the path where key and certificate are located")
 	serveCmd.Flags().BoolP(secureFlag, "s", false, "flag which change if email service will be serving tls connection or not")
+	serveCmd.Flags().String(sendGridAPIKeyFlag, "", "the SendGrid API key used to deliver emails")
+	serveCmd.Flags().String(sendGridEndpointFlag, "", "the SendGrid API base URL (default https://api.sendgrid.com)")
+	serveCmd.Flags().String(sesRegionFlag, "", "the AWS region of Amazon SES used to deliver emails")
+	serveCmd.Flags().String(sesAccessKeyIDFlag, "", "the AWS access key id allowed to call ses:SendRawEmail")
+	serveCmd.Flags().String(sesSecretAccessKeyFlag, "", "the AWS secret access key")
+	serveCmd.Flags().String(sesEndpointFlag, "", "the Amazon SES endpoint (default https://email.<region>.amazonaws.com)")
+	serveCmd.Flags().String(smtpHostFlag, "", "the SMTP relay host used to deliver emails")
+	serveCmd.Flags().Int(smtpPortFlag, 0, "the SMTP relay port (default 587 or 465 for implicit TLS)")
+	serveCmd.Flags().String(smtpTLSFlag, services.SMTPStartTLS, "the SMTP relay TLS mode: none, starttls or tls")
+	serveCmd.Flags().String(smtpUsernameFlag, "", "the SMTP relay user, without it AUTH is not used")
+	serveCmd.Flags().String(smtpPasswordFlag, "", "the SMTP relay password")
+	serveCmd.Flags().String(smtpHeloNameFlag, "", "the name sent in EHLO command (default localhost)")
+	serveCmd.Flags().String(balancerStrategyFlag, services.WeightedRoundRobin,
+		"the strategy of spreading emails between providers: weighted_round_robin, least_outstanding or weighted_random")
+	serveCmd.Flags().String(dataDirFlag, "data", "the directory where the queue of emails is stored")
+	serveCmd.Flags().Int(workersFlag, 4, "the number of workers delivering emails from the queue")
+	serveCmd.Flags().Duration(idempotencyWindowFlag, 24*time.Hour, "how long the idempotency keys of SendMail are remembered")
+	serveCmd.Flags().Duration(messageRetentionFlag, 30*24*time.Hour,
+		"how long the sent, failed and canceled messages are kept, zero keeps them forever")
+	serveCmd.Flags().Duration(shutdownTimeoutFlag, 30*time.Second,
+		"how long the calls in progress are waited for when the server is stopped")
 	if err := viper.BindPFlags(serveCmd.Flags()); err != nil {
 		zap.L().Error("Unable to bind flags")
 	}
 }
+
+// newProvider creates the balancer of configured providers.
+// Without any provider configured the emails are not delivered.
+func newProvider(providers []services.WeightedProvider) (services.Provider, error) {
+	if len(providers) == 0 {
+		zap.L().Warn("No email provider is configured")
+		return nil, nil
+	}
+	return services.NewBalancer(viper.GetString(balancerStrategyFlag), providers...)
+}
+
+// configuredProviders creates the providers from the configuration. The providers
+// list from the config file takes precedence over the single provider flags.
+// The keyring signs the messages of providers which accept raw emails.
+func configuredProviders(keyring *dkim.Keyring) ([]services.WeightedProvider, error) {
+	if viper.IsSet(providersKey) {
+		var entries []map[string]interface{}
+		if err := viper.UnmarshalKey(providersKey, &entries); err != nil {
+			return nil, err
+		}
+		providers := make([]services.WeightedProvider, 0, len(entries))
+		for _, entry := range entries {
+			p, err := services.NewWeightedProvider(entry, services.WithDKIM(keyring))
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, p)
+		}
+		return providers, nil
+	}
+
+	var (
+		provider services.Provider
+		err      error
+	)
+	switch {
+	case viper.GetString(sendGridAPIKeyFlag) != "":
+		provider, err = services.NewSendGrid(services.SendGridConfig{
+			APIKey:   viper.GetString(sendGridAPIKeyFlag),
+			Endpoint: viper.GetString(sendGridEndpointFlag),
+		})
+	case viper.GetString(sesRegionFlag) != "":
+		provider, err = services.NewSES(services.SESConfig{
+			Region:          viper.GetString(sesRegionFlag),
+			AccessKeyID:     viper.GetString(sesAccessKeyIDFlag),
+			SecretAccessKey: viper.GetString(sesSecretAccessKeyFlag),
+			Endpoint:        viper.GetString(sesEndpointFlag),
+			DKIM:            keyring,
+		})
+	case viper.GetString(smtpHostFlag) != "":
+		provider, err = services.NewSMTP(services.SMTPConfig{
+			Host:     viper.GetString(smtpHostFlag),
+			Port:     viper.GetInt(smtpPortFlag),
+			TLS:      viper.GetString(smtpTLSFlag),
+			Username: viper.GetString(smtpUsernameFlag),
+			Password: viper.GetString(smtpPasswordFlag),
+			HeloName: viper.GetString(smtpHeloNameFlag),
+			DKIM:     keyring,
+		})
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []services.WeightedProvider{{Provider: provider, Weight: 1}}, nil
+}
+
+// dispatcherConfig reads the default retry policy from the config file
+// and takes the policies set in the providers list
+func dispatcherConfig(providers []services.WeightedProvider) (services.DispatcherConfig, error) {
+	cfg := services.DispatcherConfig{
+		Workers:       viper.GetInt(workersFlag),
+		ProviderRetry: make(map[string]services.RetryPolicy),
+	}
+	if err := viper.UnmarshalKey(retryKey, &cfg.Retry); err != nil {
+		return cfg, err
+	}
+	for _, p := range providers {
+		if p.Retry != (services.RetryPolicy{}) {
+			cfg.ProviderRetry[p.Provider.Name()] = p.Retry
+		}
+	}
+	return cfg, nil
+}
@@ -6,6 +6,8 @@ package email
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import duration "github.com/golang/protobuf/ptypes/duration"
+import timestamp "github.com/golang/protobuf/ptypes/timestamp"
 import _ "google.golang.org/genproto/googleapis/api/annotations"
 
 import (
@@ -24,18 +26,346 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
-type EmailRequest struct {
-	Message              string   `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+// Disposition tells how the attachment is presented
+type Attachment_Disposition int32
+
+const (
+	// The same as ATTACHMENT
+	Attachment_DISPOSITION_UNSPECIFIED Attachment_Disposition = 0
+	// Shown as the downloadable file
+	Attachment_ATTACHMENT Attachment_Disposition = 1
+	// Embedded in the HTML body and referenced with cid:content_id
+	Attachment_INLINE Attachment_Disposition = 2
+)
+
+var Attachment_Disposition_name = map[int32]string{
+	0: "DISPOSITION_UNSPECIFIED",
+	1: "ATTACHMENT",
+	2: "INLINE",
+}
+var Attachment_Disposition_value = map[string]int32{
+	"DISPOSITION_UNSPECIFIED": 0,
+	"ATTACHMENT":              1,
+	"INLINE":                  2,
+}
+
+func (x Attachment_Disposition) String() string {
+	return proto.EnumName(Attachment_Disposition_name, int32(x))
+}
+func (Attachment_Disposition) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{2, 0}
+}
+
+type MessageEvent_Type int32
+
+const (
+	MessageEvent_TYPE_UNSPECIFIED MessageEvent_Type = 0
+	// Accepted and waits in the queue
+	MessageEvent_QUEUED MessageEvent_Type = 1
+	// Taken by the worker for the delivery attempt
+	MessageEvent_SENDING MessageEvent_Type = 2
+	// Accepted by the provider
+	MessageEvent_SENT MessageEvent_Type = 3
+	// Failed temporarily and waits for the next attempt
+	MessageEvent_DEFERRED MessageEvent_Type = 4
+	// Accepted by the recipient mail server
+	MessageEvent_DELIVERED MessageEvent_Type = 5
+	// Rejected by the recipient mail server
+	MessageEvent_BOUNCED MessageEvent_Type = 6
+	// Opened by the recipient
+	MessageEvent_OPENED MessageEvent_Type = 7
+	// Failed permanently or ran out of retries
+	MessageEvent_FAILED MessageEvent_Type = 8
+	// Canceled before the delivery
+	MessageEvent_CANCELED MessageEvent_Type = 9
+)
+
+var MessageEvent_Type_name = map[int32]string{
+	0: "TYPE_UNSPECIFIED",
+	1: "QUEUED",
+	2: "SENDING",
+	3: "SENT",
+	4: "DEFERRED",
+	5: "DELIVERED",
+	6: "BOUNCED",
+	7: "OPENED",
+	8: "FAILED",
+	9: "CANCELED",
+}
+var MessageEvent_Type_value = map[string]int32{
+	"TYPE_UNSPECIFIED": 0,
+	"QUEUED":           1,
+	"SENDING":          2,
+	"SENT":             3,
+	"DEFERRED":         4,
+	"DELIVERED":        5,
+	"BOUNCED":          6,
+	"OPENED":           7,
+	"FAILED":           8,
+	"CANCELED":         9,
+}
+
+func (x MessageEvent_Type) String() string {
+	return proto.EnumName(MessageEvent_Type_name, int32(x))
+}
+func (MessageEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{5, 0}
+}
+
+// State is the step of the message lifecycle
+type Message_State int32
+
+const (
+	Message_STATE_UNSPECIFIED Message_State = 0
+	// Waits in the queue for the delivery
+	Message_QUEUED Message_State = 1
+	// Is being delivered to the provider
+	Message_SENDING Message_State = 2
+	// Was accepted by the provider
+	Message_SENT Message_State = 3
+	// Failed temporarily and waits for the next attempt
+	Message_DEFERRED Message_State = 4
+	// Was rejected by the recipient mail server
+	Message_BOUNCED Message_State = 5
+	// Could not be delivered
+	Message_FAILED Message_State = 6
+	// Was canceled before the delivery
+	Message_CANCELED Message_State = 7
+	// Was accepted by the recipient mail server
+	Message_DELIVERED Message_State = 8
+	// Was opened by the recipient
+	Message_OPENED Message_State = 9
+)
+
+var Message_State_name = map[int32]string{
+	0: "STATE_UNSPECIFIED",
+	1: "QUEUED",
+	2: "SENDING",
+	3: "SENT",
+	4: "DEFERRED",
+	5: "BOUNCED",
+	6: "FAILED",
+	7: "CANCELED",
+	8: "DELIVERED",
+	9: "OPENED",
+}
+var Message_State_value = map[string]int32{
+	"STATE_UNSPECIFIED": 0,
+	"QUEUED":            1,
+	"SENDING":           2,
+	"SENT":              3,
+	"DEFERRED":          4,
+	"BOUNCED":           5,
+	"FAILED":            6,
+	"CANCELED":          7,
+	"DELIVERED":         8,
+	"OPENED":            9,
+}
+
+func (x Message_State) String() string {
+	return proto.EnumName(Message_State_name, int32(x))
+}
+func (Message_State) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{13, 0}
+}
+
+// Why the address is suppressed
+type Suppression_Reason int32
+
+const (
+	Suppression_REASON_UNSPECIFIED Suppression_Reason = 0
+	// The mail server of the recipient rejected the mail permanently
+	Suppression_BOUNCE Suppression_Reason = 1
+	// The recipient marked the mail as spam
+	Suppression_COMPLAINT Suppression_Reason = 2
+	// The recipient does not want to receive mails
+	Suppression_UNSUBSCRIBE Suppression_Reason = 3
+	// Added by the operator
+	Suppression_MANUAL Suppression_Reason = 4
+)
+
+var Suppression_Reason_name = map[int32]string{
+	0: "REASON_UNSPECIFIED",
+	1: "BOUNCE",
+	2: "COMPLAINT",
+	3: "UNSUBSCRIBE",
+	4: "MANUAL",
+}
+var Suppression_Reason_value = map[string]int32{
+	"REASON_UNSPECIFIED": 0,
+	"BOUNCE":             1,
+	"COMPLAINT":          2,
+	"UNSUBSCRIBE":        3,
+	"MANUAL":             4,
+}
+
+func (x Suppression_Reason) String() string {
+	return proto.EnumName(Suppression_Reason_name, int32(x))
+}
+func (Suppression_Reason) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{29, 0}
+}
+
+type ValidateAddressResponse_Result int32
+
+const (
+	ValidateAddressResponse_RESULT_UNSPECIFIED ValidateAddressResponse_Result = 0
+	// The address can receive emails as far as it can be checked
+	ValidateAddressResponse_VALID ValidateAddressResponse_Result = 1
+	// The address is not the RFC 5322 addr-spec
+	ValidateAddressResponse_INVALID_SYNTAX ValidateAddressResponse_Result = 2
+	// The domain is not the valid host name, e.g. has the unknown character
+	ValidateAddressResponse_INVALID_DOMAIN ValidateAddressResponse_Result = 3
+	// The domain does not exist or does not accept emails
+	ValidateAddressResponse_NO_MAIL_SERVER ValidateAddressResponse_Result = 4
+	// The domain gives out temporary addresses and they are rejected
+	ValidateAddressResponse_DISPOSABLE ValidateAddressResponse_Result = 5
+)
+
+var ValidateAddressResponse_Result_name = map[int32]string{
+	0: "RESULT_UNSPECIFIED",
+	1: "VALID",
+	2: "INVALID_SYNTAX",
+	3: "INVALID_DOMAIN",
+	4: "NO_MAIL_SERVER",
+	5: "DISPOSABLE",
+}
+var ValidateAddressResponse_Result_value = map[string]int32{
+	"RESULT_UNSPECIFIED": 0,
+	"VALID":              1,
+	"INVALID_SYNTAX":     2,
+	"INVALID_DOMAIN":     3,
+	"NO_MAIL_SERVER":     4,
+	"DISPOSABLE":         5,
+}
+
+func (x ValidateAddressResponse_Result) String() string {
+	return proto.EnumName(ValidateAddressResponse_Result_name, int32(x))
+}
+func (ValidateAddressResponse_Result) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{48, 0}
+}
+
+type ValidateAddressResponse_MailServer int32
+
+const (
+	// The mail server was not looked up
+	ValidateAddressResponse_MAIL_SERVER_UNSPECIFIED ValidateAddressResponse_MailServer = 0
+	// The domain has MX record or, without it, the address record
+	ValidateAddressResponse_FOUND ValidateAddressResponse_MailServer = 1
+	// The domain does not exist, has no records or has the null MX record
+	ValidateAddressResponse_NOT_FOUND ValidateAddressResponse_MailServer = 2
+	// The lookup failed temporarily
+	ValidateAddressResponse_UNKNOWN ValidateAddressResponse_MailServer = 3
+)
+
+var ValidateAddressResponse_MailServer_name = map[int32]string{
+	0: "MAIL_SERVER_UNSPECIFIED",
+	1: "FOUND",
+	2: "NOT_FOUND",
+	3: "UNKNOWN",
+}
+var ValidateAddressResponse_MailServer_value = map[string]int32{
+	"MAIL_SERVER_UNSPECIFIED": 0,
+	"FOUND":                   1,
+	"NOT_FOUND":               2,
+	"UNKNOWN":                 3,
+}
+
+func (x ValidateAddressResponse_MailServer) String() string {
+	return proto.EnumName(ValidateAddressResponse_MailServer_name, int32(x))
+}
+func (ValidateAddressResponse_MailServer) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{48, 1}
+}
+
+// Address is a single mailbox, optionally with a display name
+type Address struct {
+	// The mailbox e.g. jane.doe@example.com
+	Email string `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	// The display name e.g. Jane Doe
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *Address) Reset()         { *m = Address{} }
+func (m *Address) String() string { return proto.CompactTextString(m) }
+func (*Address) ProtoMessage()    {}
+func (*Address) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{0}
+}
+func (m *Address) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Address.Unmarshal(m, b)
+}
+func (m *Address) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Address.Marshal(b, m, deterministic)
+}
+func (dst *Address) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Address.Merge(dst, src)
+}
+func (m *Address) XXX_Size() int {
+	return xxx_messageInfo_Address.Size(m)
+}
+func (m *Address) XXX_DiscardUnknown() {
+	xxx_messageInfo_Address.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Address proto.InternalMessageInfo
+
+func (m *Address) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *Address) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// EmailRequest describes the envelope and the content of a single email
+//
+// At least one recipient in to, cc or bcc is required together with
+// the sender, the subject and a text or HTML body.
+type EmailRequest struct {
+	From     *Address   `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	ReplyTo  *Address   `protobuf:"bytes,3,opt,name=reply_to,json=replyTo,proto3" json:"reply_to,omitempty"`
+	To       []*Address `protobuf:"bytes,4,rep,name=to,proto3" json:"to,omitempty"`
+	Cc       []*Address `protobuf:"bytes,5,rep,name=cc,proto3" json:"cc,omitempty"`
+	Bcc      []*Address `protobuf:"bytes,6,rep,name=bcc,proto3" json:"bcc,omitempty"`
+	Subject  string     `protobuf:"bytes,7,opt,name=subject,proto3" json:"subject,omitempty"`
+	TextBody string     `protobuf:"bytes,8,opt,name=text_body,json=textBody,proto3" json:"text_body,omitempty"`
+	HtmlBody string     `protobuf:"bytes,9,opt,name=html_body,json=htmlBody,proto3" json:"html_body,omitempty"`
+	// Custom headers e.g. X-Campaign-Id. Headers that are derived from
+	// the envelope like From or Subject can not be overridden.
+	Headers map[string]string `protobuf:"bytes,10,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Identifier of the stored template used instead of the subject and bodies
+	TemplateId string `protobuf:"bytes,11,opt,name=template_id,json=templateId,proto3" json:"template_id,omitempty"`
+	// Variables available in the template e.g. {{.name}}
+	Variables map[string]string `protobuf:"bytes,12,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Files attached to the email or embedded in the HTML body
+	Attachments []*Attachment `protobuf:"bytes,13,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	// Tags label the message e.g. newsletter, at most 10. They are passed to
+	// the providers which support them and can be used to filter the events.
+	Tags []string `protobuf:"bytes,14,rep,name=tags,proto3" json:"tags,omitempty"`
+	// When the email should be sent, at most 30 days ahead. The email is sent
+	// immediately when it is not set or already passed. Requires the queue.
+	SendAt               *timestamp.Timestamp `protobuf:"bytes,15,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
 func (m *EmailRequest) Reset()         { *m = EmailRequest{} }
 func (m *EmailRequest) String() string { return proto.CompactTextString(m) }
 func (*EmailRequest) ProtoMessage()    {}
 func (*EmailRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_email_facc9f55703e9d70, []int{0}
+	return fileDescriptor_email_2793442e12fb03ac, []int{1}
 }
 func (m *EmailRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EmailRequest.Unmarshal(m, b)
@@ -55,25 +385,196 @@ func (m *EmailRequest) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_EmailRequest proto.InternalMessageInfo
 
-func (m *EmailRequest) GetMessage() string {
+func (m *EmailRequest) GetFrom() *Address {
 	if m != nil {
-		return m.Message
+		return m.From
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetReplyTo() *Address {
+	if m != nil {
+		return m.ReplyTo
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetTo() []*Address {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetCc() []*Address {
+	if m != nil {
+		return m.Cc
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetBcc() []*Address {
+	if m != nil {
+		return m.Bcc
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetSubject() string {
+	if m != nil {
+		return m.Subject
 	}
 	return ""
 }
 
-type EmailResponse struct {
-	Error                string   `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+func (m *EmailRequest) GetTextBody() string {
+	if m != nil {
+		return m.TextBody
+	}
+	return ""
+}
+
+func (m *EmailRequest) GetHtmlBody() string {
+	if m != nil {
+		return m.HtmlBody
+	}
+	return ""
+}
+
+func (m *EmailRequest) GetHeaders() map[string]string {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetTemplateId() string {
+	if m != nil {
+		return m.TemplateId
+	}
+	return ""
+}
+
+func (m *EmailRequest) GetVariables() map[string]string {
+	if m != nil {
+		return m.Variables
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetAttachments() []*Attachment {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *EmailRequest) GetSendAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.SendAt
+	}
+	return nil
+}
+
+// Attachment is the file sent together with the email
+type Attachment struct {
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Type of the content e.g. application/pdf, it is detected from the content when empty
+	ContentType string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content     []byte                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Disposition Attachment_Disposition `protobuf:"varint,4,opt,name=disposition,proto3,enum=korepta.rafal.email.v1alpha1.Attachment_Disposition" json:"disposition,omitempty"`
+	// Identifier used by the HTML body e.g. <img src="cid:logo">, required for INLINE
+	ContentId            string   `protobuf:"bytes,5,opt,name=content_id,json=contentId,proto3" json:"content_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *Attachment) Reset()         { *m = Attachment{} }
+func (m *Attachment) String() string { return proto.CompactTextString(m) }
+func (*Attachment) ProtoMessage()    {}
+func (*Attachment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{2}
+}
+func (m *Attachment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Attachment.Unmarshal(m, b)
+}
+func (m *Attachment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Attachment.Marshal(b, m, deterministic)
+}
+func (dst *Attachment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Attachment.Merge(dst, src)
+}
+func (m *Attachment) XXX_Size() int {
+	return xxx_messageInfo_Attachment.Size(m)
+}
+func (m *Attachment) XXX_DiscardUnknown() {
+	xxx_messageInfo_Attachment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Attachment proto.InternalMessageInfo
+
+func (m *Attachment) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *Attachment) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Attachment) GetContent() []byte {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+func (m *Attachment) GetDisposition() Attachment_Disposition {
+	if m != nil {
+		return m.Disposition
+	}
+	return Attachment_DISPOSITION_UNSPECIFIED
+}
+
+func (m *Attachment) GetContentId() string {
+	if m != nil {
+		return m.ContentId
+	}
+	return ""
+}
+
+type EmailResponse struct {
+	Error string `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	// Identifier of the accepted message
+	MessageId string `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	// Name of the provider which delivered the message, empty when the message was queued
+	Provider string `protobuf:"bytes,3,opt,name=provider,proto3" json:"provider,omitempty"`
+	// Recipients which were dropped because they are suppressed. The message is
+	// not sent at all when every recipient is suppressed.
+	Suppressed           []*Suppression `protobuf:"bytes,4,rep,name=suppressed,proto3" json:"suppressed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
 func (m *EmailResponse) Reset()         { *m = EmailResponse{} }
 func (m *EmailResponse) String() string { return proto.CompactTextString(m) }
 func (*EmailResponse) ProtoMessage()    {}
 func (*EmailResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_email_facc9f55703e9d70, []int{1}
+	return fileDescriptor_email_2793442e12fb03ac, []int{3}
 }
 func (m *EmailResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EmailResponse.Unmarshal(m, b)
@@ -100,102 +601,4286 @@ func (m *EmailResponse) GetError() string {
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*EmailRequest)(nil), "korepta.rafal.email.v1alpha1.EmailRequest")
-	proto.RegisterType((*EmailResponse)(nil), "korepta.rafal.email.v1alpha1.EmailResponse")
+func (m *EmailResponse) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *EmailResponse) GetProvider() string {
+	if m != nil {
+		return m.Provider
+	}
+	return ""
+}
 
-// EmailServiceClient is the client API for EmailService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type EmailServiceClient interface {
-	// SendMail
-	SendMail(ctx context.Context, in *EmailRequest, opts ...grpc.CallOption) (*EmailResponse, error)
+func (m *EmailResponse) GetSuppressed() []*Suppression {
+	if m != nil {
+		return m.Suppressed
+	}
+	return nil
 }
 
-type emailServiceClient struct {
-	cc *grpc.ClientConn
+// WatchMessagesRequest selects the events, the empty request selects all of them.
+// The event must match every given filter and any value of the filter.
+type WatchMessagesRequest struct {
+	MessageIds []string `protobuf:"bytes,1,rep,name=message_ids,json=messageIds,proto3" json:"message_ids,omitempty"`
+	Tags       []string `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Email of the sender
+	From                 string              `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	Types                []MessageEvent_Type `protobuf:"varint,4,rep,packed,name=types,proto3,enum=korepta.rafal.email.v1alpha1.MessageEvent_Type" json:"types,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
-func NewEmailServiceClient(cc *grpc.ClientConn) EmailServiceClient {
-	return &emailServiceClient{cc}
+func (m *WatchMessagesRequest) Reset()         { *m = WatchMessagesRequest{} }
+func (m *WatchMessagesRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchMessagesRequest) ProtoMessage()    {}
+func (*WatchMessagesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{4}
+}
+func (m *WatchMessagesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchMessagesRequest.Unmarshal(m, b)
+}
+func (m *WatchMessagesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchMessagesRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchMessagesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchMessagesRequest.Merge(dst, src)
+}
+func (m *WatchMessagesRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchMessagesRequest.Size(m)
+}
+func (m *WatchMessagesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchMessagesRequest.DiscardUnknown(m)
 }
 
-func (c *emailServiceClient) SendMail(ctx context.Context, in *EmailRequest, opts ...grpc.CallOption) (*EmailResponse, error) {
-	out := new(EmailResponse)
-	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/SendMail", in, out, opts...)
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_WatchMessagesRequest proto.InternalMessageInfo
+
+func (m *WatchMessagesRequest) GetMessageIds() []string {
+	if m != nil {
+		return m.MessageIds
 	}
-	return out, nil
+	return nil
 }
 
-// EmailServiceServer is the server API for EmailService service.
-type EmailServiceServer interface {
-	// SendMail
-	SendMail(context.Context, *EmailRequest) (*EmailResponse, error)
+func (m *WatchMessagesRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
 }
 
-func RegisterEmailServiceServer(s *grpc.Server, srv EmailServiceServer) {
-	s.RegisterService(&_EmailService_serviceDesc, srv)
+func (m *WatchMessagesRequest) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
 }
 
-func _EmailService_SendMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(EmailRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(EmailServiceServer).SendMail(ctx, in)
+func (m *WatchMessagesRequest) GetTypes() []MessageEvent_Type {
+	if m != nil {
+		return m.Types
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/SendMail",
+	return nil
+}
+
+// MessageEvent is the change in the lifecycle of the message
+type MessageEvent struct {
+	MessageId string               `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Type      MessageEvent_Type    `protobuf:"varint,2,opt,name=type,proto3,enum=korepta.rafal.email.v1alpha1.MessageEvent_Type" json:"type,omitempty"`
+	Time      *timestamp.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	// Email of the sender
+	From     string   `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	Tags     []string `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	Provider string   `protobuf:"bytes,6,opt,name=provider,proto3" json:"provider,omitempty"`
+	Attempts int32    `protobuf:"varint,7,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	// The reason of DEFERRED, BOUNCED and FAILED events
+	Error                string   `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MessageEvent) Reset()         { *m = MessageEvent{} }
+func (m *MessageEvent) String() string { return proto.CompactTextString(m) }
+func (*MessageEvent) ProtoMessage()    {}
+func (*MessageEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{5}
+}
+func (m *MessageEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MessageEvent.Unmarshal(m, b)
+}
+func (m *MessageEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MessageEvent.Marshal(b, m, deterministic)
+}
+func (dst *MessageEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MessageEvent.Merge(dst, src)
+}
+func (m *MessageEvent) XXX_Size() int {
+	return xxx_messageInfo_MessageEvent.Size(m)
+}
+func (m *MessageEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_MessageEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MessageEvent proto.InternalMessageInfo
+
+func (m *MessageEvent) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(EmailServiceServer).SendMail(ctx, req.(*EmailRequest))
+	return ""
+}
+
+func (m *MessageEvent) GetType() MessageEvent_Type {
+	if m != nil {
+		return m.Type
 	}
-	return interceptor(ctx, in, info, handler)
+	return MessageEvent_TYPE_UNSPECIFIED
 }
 
-var _EmailService_serviceDesc = grpc.ServiceDesc{
+func (m *MessageEvent) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *MessageEvent) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *MessageEvent) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *MessageEvent) GetProvider() string {
+	if m != nil {
+		return m.Provider
+	}
+	return ""
+}
+
+func (m *MessageEvent) GetAttempts() int32 {
+	if m != nil {
+		return m.Attempts
+	}
+	return 0
+}
+
+func (m *MessageEvent) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// SendMailBatchRequest is the email sent to many recipients
+type SendMailBatchRequest struct {
+	// The email shared by the recipients, its to, cc and bcc must be empty.
+	// The variables are the defaults of the recipient variables.
+	Email *EmailRequest `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	// The recipients, at most 1000
+	Recipients           []*BatchRecipient `protobuf:"bytes,2,rep,name=recipients,proto3" json:"recipients,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SendMailBatchRequest) Reset()         { *m = SendMailBatchRequest{} }
+func (m *SendMailBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*SendMailBatchRequest) ProtoMessage()    {}
+func (*SendMailBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{6}
+}
+func (m *SendMailBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendMailBatchRequest.Unmarshal(m, b)
+}
+func (m *SendMailBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendMailBatchRequest.Marshal(b, m, deterministic)
+}
+func (dst *SendMailBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendMailBatchRequest.Merge(dst, src)
+}
+func (m *SendMailBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_SendMailBatchRequest.Size(m)
+}
+func (m *SendMailBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendMailBatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendMailBatchRequest proto.InternalMessageInfo
+
+func (m *SendMailBatchRequest) GetEmail() *EmailRequest {
+	if m != nil {
+		return m.Email
+	}
+	return nil
+}
+
+func (m *SendMailBatchRequest) GetRecipients() []*BatchRecipient {
+	if m != nil {
+		return m.Recipients
+	}
+	return nil
+}
+
+// BatchRecipient receives its own copy of the batch email
+type BatchRecipient struct {
+	Address *Address `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// Variables of the template which override the shared ones
+	Variables            map[string]string `protobuf:"bytes,2,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *BatchRecipient) Reset()         { *m = BatchRecipient{} }
+func (m *BatchRecipient) String() string { return proto.CompactTextString(m) }
+func (*BatchRecipient) ProtoMessage()    {}
+func (*BatchRecipient) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{7}
+}
+func (m *BatchRecipient) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchRecipient.Unmarshal(m, b)
+}
+func (m *BatchRecipient) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchRecipient.Marshal(b, m, deterministic)
+}
+func (dst *BatchRecipient) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchRecipient.Merge(dst, src)
+}
+func (m *BatchRecipient) XXX_Size() int {
+	return xxx_messageInfo_BatchRecipient.Size(m)
+}
+func (m *BatchRecipient) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchRecipient.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchRecipient proto.InternalMessageInfo
+
+func (m *BatchRecipient) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *BatchRecipient) GetVariables() map[string]string {
+	if m != nil {
+		return m.Variables
+	}
+	return nil
+}
+
+type SendMailBatchResponse struct {
+	// The results in the order of recipients
+	Results              []*BatchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *SendMailBatchResponse) Reset()         { *m = SendMailBatchResponse{} }
+func (m *SendMailBatchResponse) String() string { return proto.CompactTextString(m) }
+func (*SendMailBatchResponse) ProtoMessage()    {}
+func (*SendMailBatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{8}
+}
+func (m *SendMailBatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendMailBatchResponse.Unmarshal(m, b)
+}
+func (m *SendMailBatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendMailBatchResponse.Marshal(b, m, deterministic)
+}
+func (dst *SendMailBatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendMailBatchResponse.Merge(dst, src)
+}
+func (m *SendMailBatchResponse) XXX_Size() int {
+	return xxx_messageInfo_SendMailBatchResponse.Size(m)
+}
+func (m *SendMailBatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendMailBatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendMailBatchResponse proto.InternalMessageInfo
+
+func (m *SendMailBatchResponse) GetResults() []*BatchResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+// BatchResult is the outcome of sending the email to the single recipient
+type BatchResult struct {
+	// Identifier of the accepted message, empty on error
+	MessageId string `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	// Name of the provider which delivered the message, empty when the message was queued
+	Provider string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	// The gRPC status code, 0 when the message was accepted
+	Code  int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// The recipient was not sent the message because it is suppressed
+	Suppressed           *Suppression `protobuf:"bytes,5,opt,name=suppressed,proto3" json:"suppressed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *BatchResult) Reset()         { *m = BatchResult{} }
+func (m *BatchResult) String() string { return proto.CompactTextString(m) }
+func (*BatchResult) ProtoMessage()    {}
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{9}
+}
+func (m *BatchResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchResult.Unmarshal(m, b)
+}
+func (m *BatchResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchResult.Marshal(b, m, deterministic)
+}
+func (dst *BatchResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchResult.Merge(dst, src)
+}
+func (m *BatchResult) XXX_Size() int {
+	return xxx_messageInfo_BatchResult.Size(m)
+}
+func (m *BatchResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchResult proto.InternalMessageInfo
+
+func (m *BatchResult) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *BatchResult) GetProvider() string {
+	if m != nil {
+		return m.Provider
+	}
+	return ""
+}
+
+func (m *BatchResult) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *BatchResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *BatchResult) GetSuppressed() *Suppression {
+	if m != nil {
+		return m.Suppressed
+	}
+	return nil
+}
+
+// GetMessageRequest identifies the message returned by GetMessage
+type GetMessageRequest struct {
+	// The message_id returned by SendMail
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMessageRequest) Reset()         { *m = GetMessageRequest{} }
+func (m *GetMessageRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMessageRequest) ProtoMessage()    {}
+func (*GetMessageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{10}
+}
+func (m *GetMessageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMessageRequest.Unmarshal(m, b)
+}
+func (m *GetMessageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMessageRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetMessageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMessageRequest.Merge(dst, src)
+}
+func (m *GetMessageRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMessageRequest.Size(m)
+}
+func (m *GetMessageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMessageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMessageRequest proto.InternalMessageInfo
+
+func (m *GetMessageRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// CancelMessageRequest selects the message to cancel
+type CancelMessageRequest struct {
+	// The message_id returned by SendMail
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelMessageRequest) Reset()         { *m = CancelMessageRequest{} }
+func (m *CancelMessageRequest) String() string { return proto.CompactTextString(m) }
+func (*CancelMessageRequest) ProtoMessage()    {}
+func (*CancelMessageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{11}
+}
+func (m *CancelMessageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelMessageRequest.Unmarshal(m, b)
+}
+func (m *CancelMessageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelMessageRequest.Marshal(b, m, deterministic)
+}
+func (dst *CancelMessageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelMessageRequest.Merge(dst, src)
+}
+func (m *CancelMessageRequest) XXX_Size() int {
+	return xxx_messageInfo_CancelMessageRequest.Size(m)
+}
+func (m *CancelMessageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelMessageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelMessageRequest proto.InternalMessageInfo
+
+func (m *CancelMessageRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// RescheduleMessageRequest changes the time of the delivery of the message
+type RescheduleMessageRequest struct {
+	// The message_id returned by SendMail
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// When the email should be sent, at most 30 days ahead
+	SendAt               *timestamp.Timestamp `protobuf:"bytes,2,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *RescheduleMessageRequest) Reset()         { *m = RescheduleMessageRequest{} }
+func (m *RescheduleMessageRequest) String() string { return proto.CompactTextString(m) }
+func (*RescheduleMessageRequest) ProtoMessage()    {}
+func (*RescheduleMessageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{12}
+}
+func (m *RescheduleMessageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RescheduleMessageRequest.Unmarshal(m, b)
+}
+func (m *RescheduleMessageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RescheduleMessageRequest.Marshal(b, m, deterministic)
+}
+func (dst *RescheduleMessageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RescheduleMessageRequest.Merge(dst, src)
+}
+func (m *RescheduleMessageRequest) XXX_Size() int {
+	return xxx_messageInfo_RescheduleMessageRequest.Size(m)
+}
+func (m *RescheduleMessageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RescheduleMessageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RescheduleMessageRequest proto.InternalMessageInfo
+
+func (m *RescheduleMessageRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RescheduleMessageRequest) GetSendAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.SendAt
+	}
+	return nil
+}
+
+// Message is the email accepted by the service together with its delivery state
+type Message struct {
+	// Identifier of the message
+	Id    string        `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State Message_State `protobuf:"varint,2,opt,name=state,proto3,enum=korepta.rafal.email.v1alpha1.Message_State" json:"state,omitempty"`
+	// When the message was accepted
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// When the state of the message changed for the last time
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// When the next delivery attempt is due, set only for queued and deferred messages
+	NextAttemptAt *timestamp.Timestamp `protobuf:"bytes,5,opt,name=next_attempt_at,json=nextAttemptAt,proto3" json:"next_attempt_at,omitempty"`
+	// Name of the provider which delivered the message
+	Provider string `protobuf:"bytes,6,opt,name=provider,proto3" json:"provider,omitempty"`
+	// Identifier of the message assigned by the provider
+	ProviderMessageId string `protobuf:"bytes,7,opt,name=provider_message_id,json=providerMessageId,proto3" json:"provider_message_id,omitempty"`
+	// Number of delivery attempts
+	Attempts int32 `protobuf:"varint,8,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	// Error of the last failed attempt
+	LastError            string   `protobuf:"bytes,9,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+func (*Message) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{13}
+}
+func (m *Message) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Message.Unmarshal(m, b)
+}
+func (m *Message) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Message.Marshal(b, m, deterministic)
+}
+func (dst *Message) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message.Merge(dst, src)
+}
+func (m *Message) XXX_Size() int {
+	return xxx_messageInfo_Message.Size(m)
+}
+func (m *Message) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message proto.InternalMessageInfo
+
+func (m *Message) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Message) GetState() Message_State {
+	if m != nil {
+		return m.State
+	}
+	return Message_STATE_UNSPECIFIED
+}
+
+func (m *Message) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *Message) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+func (m *Message) GetNextAttemptAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.NextAttemptAt
+	}
+	return nil
+}
+
+func (m *Message) GetProvider() string {
+	if m != nil {
+		return m.Provider
+	}
+	return ""
+}
+
+func (m *Message) GetProviderMessageId() string {
+	if m != nil {
+		return m.ProviderMessageId
+	}
+	return ""
+}
+
+func (m *Message) GetAttempts() int32 {
+	if m != nil {
+		return m.Attempts
+	}
+	return 0
+}
+
+func (m *Message) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+// ListDeadLettersRequest selects the page of dead-lettered messages
+type ListDeadLettersRequest struct {
+	// Maximum number of messages returned, default 50
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous response
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListDeadLettersRequest) Reset()         { *m = ListDeadLettersRequest{} }
+func (m *ListDeadLettersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListDeadLettersRequest) ProtoMessage()    {}
+func (*ListDeadLettersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{14}
+}
+func (m *ListDeadLettersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDeadLettersRequest.Unmarshal(m, b)
+}
+func (m *ListDeadLettersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDeadLettersRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListDeadLettersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDeadLettersRequest.Merge(dst, src)
+}
+func (m *ListDeadLettersRequest) XXX_Size() int {
+	return xxx_messageInfo_ListDeadLettersRequest.Size(m)
+}
+func (m *ListDeadLettersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDeadLettersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDeadLettersRequest proto.InternalMessageInfo
+
+func (m *ListDeadLettersRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListDeadLettersRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// ListDeadLettersResponse is the page of dead-lettered messages ordered by id
+type ListDeadLettersResponse struct {
+	Messages []*Message `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	// Token of the next page, empty on the last one
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListDeadLettersResponse) Reset()         { *m = ListDeadLettersResponse{} }
+func (m *ListDeadLettersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListDeadLettersResponse) ProtoMessage()    {}
+func (*ListDeadLettersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{15}
+}
+func (m *ListDeadLettersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDeadLettersResponse.Unmarshal(m, b)
+}
+func (m *ListDeadLettersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDeadLettersResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListDeadLettersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDeadLettersResponse.Merge(dst, src)
+}
+func (m *ListDeadLettersResponse) XXX_Size() int {
+	return xxx_messageInfo_ListDeadLettersResponse.Size(m)
+}
+func (m *ListDeadLettersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDeadLettersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDeadLettersResponse proto.InternalMessageInfo
+
+func (m *ListDeadLettersResponse) GetMessages() []*Message {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func (m *ListDeadLettersResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// GetDeadLetterRequest identifies the dead-lettered message
+type GetDeadLetterRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetDeadLetterRequest) Reset()         { *m = GetDeadLetterRequest{} }
+func (m *GetDeadLetterRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDeadLetterRequest) ProtoMessage()    {}
+func (*GetDeadLetterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{16}
+}
+func (m *GetDeadLetterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDeadLetterRequest.Unmarshal(m, b)
+}
+func (m *GetDeadLetterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDeadLetterRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetDeadLetterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDeadLetterRequest.Merge(dst, src)
+}
+func (m *GetDeadLetterRequest) XXX_Size() int {
+	return xxx_messageInfo_GetDeadLetterRequest.Size(m)
+}
+func (m *GetDeadLetterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDeadLetterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDeadLetterRequest proto.InternalMessageInfo
+
+func (m *GetDeadLetterRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// DeadLetter is the message which could not be delivered
+type DeadLetter struct {
+	Message *Message `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// The email as it was sent
+	Request              *EmailRequest `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *DeadLetter) Reset()         { *m = DeadLetter{} }
+func (m *DeadLetter) String() string { return proto.CompactTextString(m) }
+func (*DeadLetter) ProtoMessage()    {}
+func (*DeadLetter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{17}
+}
+func (m *DeadLetter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeadLetter.Unmarshal(m, b)
+}
+func (m *DeadLetter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeadLetter.Marshal(b, m, deterministic)
+}
+func (dst *DeadLetter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeadLetter.Merge(dst, src)
+}
+func (m *DeadLetter) XXX_Size() int {
+	return xxx_messageInfo_DeadLetter.Size(m)
+}
+func (m *DeadLetter) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeadLetter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeadLetter proto.InternalMessageInfo
+
+func (m *DeadLetter) GetMessage() *Message {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *DeadLetter) GetRequest() *EmailRequest {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+// RequeueDeadLetterRequest identifies the dead-lettered message
+type RequeueDeadLetterRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RequeueDeadLetterRequest) Reset()         { *m = RequeueDeadLetterRequest{} }
+func (m *RequeueDeadLetterRequest) String() string { return proto.CompactTextString(m) }
+func (*RequeueDeadLetterRequest) ProtoMessage()    {}
+func (*RequeueDeadLetterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{18}
+}
+func (m *RequeueDeadLetterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RequeueDeadLetterRequest.Unmarshal(m, b)
+}
+func (m *RequeueDeadLetterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RequeueDeadLetterRequest.Marshal(b, m, deterministic)
+}
+func (dst *RequeueDeadLetterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RequeueDeadLetterRequest.Merge(dst, src)
+}
+func (m *RequeueDeadLetterRequest) XXX_Size() int {
+	return xxx_messageInfo_RequeueDeadLetterRequest.Size(m)
+}
+func (m *RequeueDeadLetterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RequeueDeadLetterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RequeueDeadLetterRequest proto.InternalMessageInfo
+
+func (m *RequeueDeadLetterRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// PurgeDeadLettersRequest selects dead-lettered messages to remove
+//
+// Either ids or all must be set. With all every message which failed before
+// the given time is removed, or every message when the time is not set.
+type PurgeDeadLettersRequest struct {
+	Ids                  []string             `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	All                  bool                 `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
+	Before               *timestamp.Timestamp `protobuf:"bytes,3,opt,name=before,proto3" json:"before,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *PurgeDeadLettersRequest) Reset()         { *m = PurgeDeadLettersRequest{} }
+func (m *PurgeDeadLettersRequest) String() string { return proto.CompactTextString(m) }
+func (*PurgeDeadLettersRequest) ProtoMessage()    {}
+func (*PurgeDeadLettersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{19}
+}
+func (m *PurgeDeadLettersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PurgeDeadLettersRequest.Unmarshal(m, b)
+}
+func (m *PurgeDeadLettersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PurgeDeadLettersRequest.Marshal(b, m, deterministic)
+}
+func (dst *PurgeDeadLettersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PurgeDeadLettersRequest.Merge(dst, src)
+}
+func (m *PurgeDeadLettersRequest) XXX_Size() int {
+	return xxx_messageInfo_PurgeDeadLettersRequest.Size(m)
+}
+func (m *PurgeDeadLettersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PurgeDeadLettersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PurgeDeadLettersRequest proto.InternalMessageInfo
+
+func (m *PurgeDeadLettersRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *PurgeDeadLettersRequest) GetAll() bool {
+	if m != nil {
+		return m.All
+	}
+	return false
+}
+
+func (m *PurgeDeadLettersRequest) GetBefore() *timestamp.Timestamp {
+	if m != nil {
+		return m.Before
+	}
+	return nil
+}
+
+// PurgeDeadLettersResponse contains the number of removed messages
+type PurgeDeadLettersResponse struct {
+	Purged               int32    `protobuf:"varint,1,opt,name=purged,proto3" json:"purged,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PurgeDeadLettersResponse) Reset()         { *m = PurgeDeadLettersResponse{} }
+func (m *PurgeDeadLettersResponse) String() string { return proto.CompactTextString(m) }
+func (*PurgeDeadLettersResponse) ProtoMessage()    {}
+func (*PurgeDeadLettersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{20}
+}
+func (m *PurgeDeadLettersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PurgeDeadLettersResponse.Unmarshal(m, b)
+}
+func (m *PurgeDeadLettersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PurgeDeadLettersResponse.Marshal(b, m, deterministic)
+}
+func (dst *PurgeDeadLettersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PurgeDeadLettersResponse.Merge(dst, src)
+}
+func (m *PurgeDeadLettersResponse) XXX_Size() int {
+	return xxx_messageInfo_PurgeDeadLettersResponse.Size(m)
+}
+func (m *PurgeDeadLettersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PurgeDeadLettersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PurgeDeadLettersResponse proto.InternalMessageInfo
+
+func (m *PurgeDeadLettersResponse) GetPurged() int32 {
+	if m != nil {
+		return m.Purged
+	}
+	return 0
+}
+
+// Template is the content of the mail rendered with the variables of SendMail
+//
+// The subject and the text body are Go text/template templates, the HTML body
+// is Go html/template template, so the variables are escaped.
+type Template struct {
+	// Identifier of the template e.g. welcome, generated when empty on create
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Subject              string               `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	TextBody             string               `protobuf:"bytes,3,opt,name=text_body,json=textBody,proto3" json:"text_body,omitempty"`
+	HtmlBody             string               `protobuf:"bytes,4,opt,name=html_body,json=htmlBody,proto3" json:"html_body,omitempty"`
+	CreatedAt            *timestamp.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt            *timestamp.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Template) Reset()         { *m = Template{} }
+func (m *Template) String() string { return proto.CompactTextString(m) }
+func (*Template) ProtoMessage()    {}
+func (*Template) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{21}
+}
+func (m *Template) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Template.Unmarshal(m, b)
+}
+func (m *Template) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Template.Marshal(b, m, deterministic)
+}
+func (dst *Template) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Template.Merge(dst, src)
+}
+func (m *Template) XXX_Size() int {
+	return xxx_messageInfo_Template.Size(m)
+}
+func (m *Template) XXX_DiscardUnknown() {
+	xxx_messageInfo_Template.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Template proto.InternalMessageInfo
+
+func (m *Template) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Template) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *Template) GetTextBody() string {
+	if m != nil {
+		return m.TextBody
+	}
+	return ""
+}
+
+func (m *Template) GetHtmlBody() string {
+	if m != nil {
+		return m.HtmlBody
+	}
+	return ""
+}
+
+func (m *Template) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *Template) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+// CreateTemplateRequest contains the template to store
+type CreateTemplateRequest struct {
+	Template             *Template `protobuf:"bytes,1,opt,name=template,proto3" json:"template,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *CreateTemplateRequest) Reset()         { *m = CreateTemplateRequest{} }
+func (m *CreateTemplateRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateTemplateRequest) ProtoMessage()    {}
+func (*CreateTemplateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{22}
+}
+func (m *CreateTemplateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateTemplateRequest.Unmarshal(m, b)
+}
+func (m *CreateTemplateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateTemplateRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateTemplateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateTemplateRequest.Merge(dst, src)
+}
+func (m *CreateTemplateRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateTemplateRequest.Size(m)
+}
+func (m *CreateTemplateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateTemplateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateTemplateRequest proto.InternalMessageInfo
+
+func (m *CreateTemplateRequest) GetTemplate() *Template {
+	if m != nil {
+		return m.Template
+	}
+	return nil
+}
+
+// GetTemplateRequest identifies the template
+type GetTemplateRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetTemplateRequest) Reset()         { *m = GetTemplateRequest{} }
+func (m *GetTemplateRequest) String() string { return proto.CompactTextString(m) }
+func (*GetTemplateRequest) ProtoMessage()    {}
+func (*GetTemplateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{23}
+}
+func (m *GetTemplateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetTemplateRequest.Unmarshal(m, b)
+}
+func (m *GetTemplateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetTemplateRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetTemplateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetTemplateRequest.Merge(dst, src)
+}
+func (m *GetTemplateRequest) XXX_Size() int {
+	return xxx_messageInfo_GetTemplateRequest.Size(m)
+}
+func (m *GetTemplateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetTemplateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetTemplateRequest proto.InternalMessageInfo
+
+func (m *GetTemplateRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListTemplatesRequest selects the page of templates
+type ListTemplatesRequest struct {
+	// Maximum number of templates returned, default 50
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous response
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListTemplatesRequest) Reset()         { *m = ListTemplatesRequest{} }
+func (m *ListTemplatesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListTemplatesRequest) ProtoMessage()    {}
+func (*ListTemplatesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{24}
+}
+func (m *ListTemplatesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListTemplatesRequest.Unmarshal(m, b)
+}
+func (m *ListTemplatesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListTemplatesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListTemplatesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListTemplatesRequest.Merge(dst, src)
+}
+func (m *ListTemplatesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListTemplatesRequest.Size(m)
+}
+func (m *ListTemplatesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListTemplatesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListTemplatesRequest proto.InternalMessageInfo
+
+func (m *ListTemplatesRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListTemplatesRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// ListTemplatesResponse is the page of templates ordered by id
+type ListTemplatesResponse struct {
+	Templates []*Template `protobuf:"bytes,1,rep,name=templates,proto3" json:"templates,omitempty"`
+	// Token of the next page, empty on the last one
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListTemplatesResponse) Reset()         { *m = ListTemplatesResponse{} }
+func (m *ListTemplatesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListTemplatesResponse) ProtoMessage()    {}
+func (*ListTemplatesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{25}
+}
+func (m *ListTemplatesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListTemplatesResponse.Unmarshal(m, b)
+}
+func (m *ListTemplatesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListTemplatesResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListTemplatesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListTemplatesResponse.Merge(dst, src)
+}
+func (m *ListTemplatesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListTemplatesResponse.Size(m)
+}
+func (m *ListTemplatesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListTemplatesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListTemplatesResponse proto.InternalMessageInfo
+
+func (m *ListTemplatesResponse) GetTemplates() []*Template {
+	if m != nil {
+		return m.Templates
+	}
+	return nil
+}
+
+func (m *ListTemplatesResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// UpdateTemplateRequest contains the new content of the existing template
+type UpdateTemplateRequest struct {
+	Template             *Template `protobuf:"bytes,1,opt,name=template,proto3" json:"template,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *UpdateTemplateRequest) Reset()         { *m = UpdateTemplateRequest{} }
+func (m *UpdateTemplateRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateTemplateRequest) ProtoMessage()    {}
+func (*UpdateTemplateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{26}
+}
+func (m *UpdateTemplateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateTemplateRequest.Unmarshal(m, b)
+}
+func (m *UpdateTemplateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateTemplateRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateTemplateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateTemplateRequest.Merge(dst, src)
+}
+func (m *UpdateTemplateRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateTemplateRequest.Size(m)
+}
+func (m *UpdateTemplateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateTemplateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateTemplateRequest proto.InternalMessageInfo
+
+func (m *UpdateTemplateRequest) GetTemplate() *Template {
+	if m != nil {
+		return m.Template
+	}
+	return nil
+}
+
+// DeleteTemplateRequest identifies the template
+type DeleteTemplateRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteTemplateRequest) Reset()         { *m = DeleteTemplateRequest{} }
+func (m *DeleteTemplateRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteTemplateRequest) ProtoMessage()    {}
+func (*DeleteTemplateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{27}
+}
+func (m *DeleteTemplateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteTemplateRequest.Unmarshal(m, b)
+}
+func (m *DeleteTemplateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteTemplateRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteTemplateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteTemplateRequest.Merge(dst, src)
+}
+func (m *DeleteTemplateRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteTemplateRequest.Size(m)
+}
+func (m *DeleteTemplateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteTemplateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteTemplateRequest proto.InternalMessageInfo
+
+func (m *DeleteTemplateRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// DeleteTemplateResponse is returned when the template was removed
+type DeleteTemplateResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteTemplateResponse) Reset()         { *m = DeleteTemplateResponse{} }
+func (m *DeleteTemplateResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteTemplateResponse) ProtoMessage()    {}
+func (*DeleteTemplateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{28}
+}
+func (m *DeleteTemplateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteTemplateResponse.Unmarshal(m, b)
+}
+func (m *DeleteTemplateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteTemplateResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteTemplateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteTemplateResponse.Merge(dst, src)
+}
+func (m *DeleteTemplateResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteTemplateResponse.Size(m)
+}
+func (m *DeleteTemplateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteTemplateResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteTemplateResponse proto.InternalMessageInfo
+
+// Suppression is the address to which mails are not sent
+type Suppression struct {
+	// The suppressed mailbox, compared case insensitive
+	Email  string             `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Reason Suppression_Reason `protobuf:"varint,2,opt,name=reason,proto3,enum=korepta.rafal.email.v1alpha1.Suppression_Reason" json:"reason,omitempty"`
+	// Details of the reason e.g. the response of the mail server
+	Description          string               `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	CreatedAt            *timestamp.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Suppression) Reset()         { *m = Suppression{} }
+func (m *Suppression) String() string { return proto.CompactTextString(m) }
+func (*Suppression) ProtoMessage()    {}
+func (*Suppression) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{29}
+}
+func (m *Suppression) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Suppression.Unmarshal(m, b)
+}
+func (m *Suppression) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Suppression.Marshal(b, m, deterministic)
+}
+func (dst *Suppression) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Suppression.Merge(dst, src)
+}
+func (m *Suppression) XXX_Size() int {
+	return xxx_messageInfo_Suppression.Size(m)
+}
+func (m *Suppression) XXX_DiscardUnknown() {
+	xxx_messageInfo_Suppression.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Suppression proto.InternalMessageInfo
+
+func (m *Suppression) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *Suppression) GetReason() Suppression_Reason {
+	if m != nil {
+		return m.Reason
+	}
+	return Suppression_REASON_UNSPECIFIED
+}
+
+func (m *Suppression) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Suppression) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+// AddSuppressionRequest contains the address to suppress
+type AddSuppressionRequest struct {
+	Suppression          *Suppression `protobuf:"bytes,1,opt,name=suppression,proto3" json:"suppression,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *AddSuppressionRequest) Reset()         { *m = AddSuppressionRequest{} }
+func (m *AddSuppressionRequest) String() string { return proto.CompactTextString(m) }
+func (*AddSuppressionRequest) ProtoMessage()    {}
+func (*AddSuppressionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{30}
+}
+func (m *AddSuppressionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AddSuppressionRequest.Unmarshal(m, b)
+}
+func (m *AddSuppressionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AddSuppressionRequest.Marshal(b, m, deterministic)
+}
+func (dst *AddSuppressionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddSuppressionRequest.Merge(dst, src)
+}
+func (m *AddSuppressionRequest) XXX_Size() int {
+	return xxx_messageInfo_AddSuppressionRequest.Size(m)
+}
+func (m *AddSuppressionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddSuppressionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddSuppressionRequest proto.InternalMessageInfo
+
+func (m *AddSuppressionRequest) GetSuppression() *Suppression {
+	if m != nil {
+		return m.Suppression
+	}
+	return nil
+}
+
+// GetSuppressionRequest identifies the suppressed address
+type GetSuppressionRequest struct {
+	Email                string   `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSuppressionRequest) Reset()         { *m = GetSuppressionRequest{} }
+func (m *GetSuppressionRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSuppressionRequest) ProtoMessage()    {}
+func (*GetSuppressionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{31}
+}
+func (m *GetSuppressionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSuppressionRequest.Unmarshal(m, b)
+}
+func (m *GetSuppressionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSuppressionRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetSuppressionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSuppressionRequest.Merge(dst, src)
+}
+func (m *GetSuppressionRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSuppressionRequest.Size(m)
+}
+func (m *GetSuppressionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSuppressionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSuppressionRequest proto.InternalMessageInfo
+
+func (m *GetSuppressionRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+// ListSuppressionsRequest selects the page of suppressed addresses
+type ListSuppressionsRequest struct {
+	// Maximum number of addresses returned, default 50
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous response
+	PageToken string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Returns only the addresses suppressed for the reason when set
+	Reason               Suppression_Reason `protobuf:"varint,3,opt,name=reason,proto3,enum=korepta.rafal.email.v1alpha1.Suppression_Reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ListSuppressionsRequest) Reset()         { *m = ListSuppressionsRequest{} }
+func (m *ListSuppressionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListSuppressionsRequest) ProtoMessage()    {}
+func (*ListSuppressionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{32}
+}
+func (m *ListSuppressionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSuppressionsRequest.Unmarshal(m, b)
+}
+func (m *ListSuppressionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSuppressionsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListSuppressionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSuppressionsRequest.Merge(dst, src)
+}
+func (m *ListSuppressionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListSuppressionsRequest.Size(m)
+}
+func (m *ListSuppressionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSuppressionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSuppressionsRequest proto.InternalMessageInfo
+
+func (m *ListSuppressionsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListSuppressionsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListSuppressionsRequest) GetReason() Suppression_Reason {
+	if m != nil {
+		return m.Reason
+	}
+	return Suppression_REASON_UNSPECIFIED
+}
+
+// ListSuppressionsResponse is the page of suppressed addresses ordered by email
+type ListSuppressionsResponse struct {
+	Suppressions []*Suppression `protobuf:"bytes,1,rep,name=suppressions,proto3" json:"suppressions,omitempty"`
+	// Token of the next page, empty on the last one
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListSuppressionsResponse) Reset()         { *m = ListSuppressionsResponse{} }
+func (m *ListSuppressionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSuppressionsResponse) ProtoMessage()    {}
+func (*ListSuppressionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{33}
+}
+func (m *ListSuppressionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSuppressionsResponse.Unmarshal(m, b)
+}
+func (m *ListSuppressionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSuppressionsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListSuppressionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSuppressionsResponse.Merge(dst, src)
+}
+func (m *ListSuppressionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSuppressionsResponse.Size(m)
+}
+func (m *ListSuppressionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSuppressionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSuppressionsResponse proto.InternalMessageInfo
+
+func (m *ListSuppressionsResponse) GetSuppressions() []*Suppression {
+	if m != nil {
+		return m.Suppressions
+	}
+	return nil
+}
+
+func (m *ListSuppressionsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// DeleteSuppressionRequest identifies the suppressed address
+type DeleteSuppressionRequest struct {
+	Email                string   `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteSuppressionRequest) Reset()         { *m = DeleteSuppressionRequest{} }
+func (m *DeleteSuppressionRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteSuppressionRequest) ProtoMessage()    {}
+func (*DeleteSuppressionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{34}
+}
+func (m *DeleteSuppressionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteSuppressionRequest.Unmarshal(m, b)
+}
+func (m *DeleteSuppressionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteSuppressionRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteSuppressionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteSuppressionRequest.Merge(dst, src)
+}
+func (m *DeleteSuppressionRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteSuppressionRequest.Size(m)
+}
+func (m *DeleteSuppressionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteSuppressionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteSuppressionRequest proto.InternalMessageInfo
+
+func (m *DeleteSuppressionRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+// DeleteSuppressionResponse is returned when the address was removed
+type DeleteSuppressionResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteSuppressionResponse) Reset()         { *m = DeleteSuppressionResponse{} }
+func (m *DeleteSuppressionResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteSuppressionResponse) ProtoMessage()    {}
+func (*DeleteSuppressionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{35}
+}
+func (m *DeleteSuppressionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteSuppressionResponse.Unmarshal(m, b)
+}
+func (m *DeleteSuppressionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteSuppressionResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteSuppressionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteSuppressionResponse.Merge(dst, src)
+}
+func (m *DeleteSuppressionResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteSuppressionResponse.Size(m)
+}
+func (m *DeleteSuppressionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteSuppressionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteSuppressionResponse proto.InternalMessageInfo
+
+// Callback is the URL to which the events of messages are posted as JSON. The
+// event must match every given filter and any value of the filter.
+type Callback struct {
+	// Identifier of the callback, generated on create
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The http or https URL which receives the events
+	Url string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// Secret of the HMAC-SHA256 signature of events, generated when empty on
+	// create and returned only then
+	Secret string              `protobuf:"bytes,3,opt,name=secret,proto3" json:"secret,omitempty"`
+	Types  []MessageEvent_Type `protobuf:"varint,4,rep,packed,name=types,proto3,enum=korepta.rafal.email.v1alpha1.MessageEvent_Type" json:"types,omitempty"`
+	Tags   []string            `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Email of the sender
+	From string `protobuf:"bytes,6,opt,name=from,proto3" json:"from,omitempty"`
+	// The callback is disabled after repeated failures
+	Disabled bool `protobuf:"varint,7,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	// Number of failed attempts since the last successful one
+	ConsecutiveFailures  int32                `protobuf:"varint,8,opt,name=consecutive_failures,json=consecutiveFailures,proto3" json:"consecutive_failures,omitempty"`
+	CreatedAt            *timestamp.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	DisabledAt           *timestamp.Timestamp `protobuf:"bytes,10,opt,name=disabled_at,json=disabledAt,proto3" json:"disabled_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Callback) Reset()         { *m = Callback{} }
+func (m *Callback) String() string { return proto.CompactTextString(m) }
+func (*Callback) ProtoMessage()    {}
+func (*Callback) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{36}
+}
+func (m *Callback) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Callback.Unmarshal(m, b)
+}
+func (m *Callback) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Callback.Marshal(b, m, deterministic)
+}
+func (dst *Callback) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Callback.Merge(dst, src)
+}
+func (m *Callback) XXX_Size() int {
+	return xxx_messageInfo_Callback.Size(m)
+}
+func (m *Callback) XXX_DiscardUnknown() {
+	xxx_messageInfo_Callback.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Callback proto.InternalMessageInfo
+
+func (m *Callback) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Callback) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *Callback) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *Callback) GetTypes() []MessageEvent_Type {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+func (m *Callback) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *Callback) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *Callback) GetDisabled() bool {
+	if m != nil {
+		return m.Disabled
+	}
+	return false
+}
+
+func (m *Callback) GetConsecutiveFailures() int32 {
+	if m != nil {
+		return m.ConsecutiveFailures
+	}
+	return 0
+}
+
+func (m *Callback) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *Callback) GetDisabledAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.DisabledAt
+	}
+	return nil
+}
+
+// CallbackAttempt is the single attempt to post the event to the callback
+type CallbackAttempt struct {
+	// Identifier of the event delivery, the same in every retry of the event.
+	// It is sent in the X-Webhook-Id header.
+	DeliveryId string            `protobuf:"bytes,1,opt,name=delivery_id,json=deliveryId,proto3" json:"delivery_id,omitempty"`
+	MessageId  string            `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	EventType  MessageEvent_Type `protobuf:"varint,3,opt,name=event_type,json=eventType,proto3,enum=korepta.rafal.email.v1alpha1.MessageEvent_Type" json:"event_type,omitempty"`
+	// Number of the attempt of the delivery, starting from 1
+	Attempt int32                `protobuf:"varint,4,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Time    *timestamp.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	// HTTP status code of the response, 0 when no response was received
+	StatusCode           int32    `protobuf:"varint,6,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Error                string   `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	Success              bool     `protobuf:"varint,8,opt,name=success,proto3" json:"success,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CallbackAttempt) Reset()         { *m = CallbackAttempt{} }
+func (m *CallbackAttempt) String() string { return proto.CompactTextString(m) }
+func (*CallbackAttempt) ProtoMessage()    {}
+func (*CallbackAttempt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{37}
+}
+func (m *CallbackAttempt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CallbackAttempt.Unmarshal(m, b)
+}
+func (m *CallbackAttempt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CallbackAttempt.Marshal(b, m, deterministic)
+}
+func (dst *CallbackAttempt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CallbackAttempt.Merge(dst, src)
+}
+func (m *CallbackAttempt) XXX_Size() int {
+	return xxx_messageInfo_CallbackAttempt.Size(m)
+}
+func (m *CallbackAttempt) XXX_DiscardUnknown() {
+	xxx_messageInfo_CallbackAttempt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CallbackAttempt proto.InternalMessageInfo
+
+func (m *CallbackAttempt) GetDeliveryId() string {
+	if m != nil {
+		return m.DeliveryId
+	}
+	return ""
+}
+
+func (m *CallbackAttempt) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *CallbackAttempt) GetEventType() MessageEvent_Type {
+	if m != nil {
+		return m.EventType
+	}
+	return MessageEvent_TYPE_UNSPECIFIED
+}
+
+func (m *CallbackAttempt) GetAttempt() int32 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
+}
+
+func (m *CallbackAttempt) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *CallbackAttempt) GetStatusCode() int32 {
+	if m != nil {
+		return m.StatusCode
+	}
+	return 0
+}
+
+func (m *CallbackAttempt) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *CallbackAttempt) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+// CreateCallbackRequest contains the callback to register
+type CreateCallbackRequest struct {
+	Callback             *Callback `protobuf:"bytes,1,opt,name=callback,proto3" json:"callback,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *CreateCallbackRequest) Reset()         { *m = CreateCallbackRequest{} }
+func (m *CreateCallbackRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateCallbackRequest) ProtoMessage()    {}
+func (*CreateCallbackRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{38}
+}
+func (m *CreateCallbackRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateCallbackRequest.Unmarshal(m, b)
+}
+func (m *CreateCallbackRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateCallbackRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateCallbackRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateCallbackRequest.Merge(dst, src)
+}
+func (m *CreateCallbackRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateCallbackRequest.Size(m)
+}
+func (m *CreateCallbackRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateCallbackRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateCallbackRequest proto.InternalMessageInfo
+
+func (m *CreateCallbackRequest) GetCallback() *Callback {
+	if m != nil {
+		return m.Callback
+	}
+	return nil
+}
+
+// GetCallbackRequest identifies the callback
+type GetCallbackRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetCallbackRequest) Reset()         { *m = GetCallbackRequest{} }
+func (m *GetCallbackRequest) String() string { return proto.CompactTextString(m) }
+func (*GetCallbackRequest) ProtoMessage()    {}
+func (*GetCallbackRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{39}
+}
+func (m *GetCallbackRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCallbackRequest.Unmarshal(m, b)
+}
+func (m *GetCallbackRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCallbackRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetCallbackRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCallbackRequest.Merge(dst, src)
+}
+func (m *GetCallbackRequest) XXX_Size() int {
+	return xxx_messageInfo_GetCallbackRequest.Size(m)
+}
+func (m *GetCallbackRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCallbackRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCallbackRequest proto.InternalMessageInfo
+
+func (m *GetCallbackRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListCallbacksRequest selects the page of callbacks
+type ListCallbacksRequest struct {
+	// Maximum number of callbacks returned, default 50
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous response
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListCallbacksRequest) Reset()         { *m = ListCallbacksRequest{} }
+func (m *ListCallbacksRequest) String() string { return proto.CompactTextString(m) }
+func (*ListCallbacksRequest) ProtoMessage()    {}
+func (*ListCallbacksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{40}
+}
+func (m *ListCallbacksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCallbacksRequest.Unmarshal(m, b)
+}
+func (m *ListCallbacksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCallbacksRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListCallbacksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCallbacksRequest.Merge(dst, src)
+}
+func (m *ListCallbacksRequest) XXX_Size() int {
+	return xxx_messageInfo_ListCallbacksRequest.Size(m)
+}
+func (m *ListCallbacksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCallbacksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCallbacksRequest proto.InternalMessageInfo
+
+func (m *ListCallbacksRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListCallbacksRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// ListCallbacksResponse is the page of callbacks ordered by id
+type ListCallbacksResponse struct {
+	Callbacks []*Callback `protobuf:"bytes,1,rep,name=callbacks,proto3" json:"callbacks,omitempty"`
+	// Token of the next page, empty on the last one
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListCallbacksResponse) Reset()         { *m = ListCallbacksResponse{} }
+func (m *ListCallbacksResponse) String() string { return proto.CompactTextString(m) }
+func (*ListCallbacksResponse) ProtoMessage()    {}
+func (*ListCallbacksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{41}
+}
+func (m *ListCallbacksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCallbacksResponse.Unmarshal(m, b)
+}
+func (m *ListCallbacksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCallbacksResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListCallbacksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCallbacksResponse.Merge(dst, src)
+}
+func (m *ListCallbacksResponse) XXX_Size() int {
+	return xxx_messageInfo_ListCallbacksResponse.Size(m)
+}
+func (m *ListCallbacksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCallbacksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCallbacksResponse proto.InternalMessageInfo
+
+func (m *ListCallbacksResponse) GetCallbacks() []*Callback {
+	if m != nil {
+		return m.Callbacks
+	}
+	return nil
+}
+
+func (m *ListCallbacksResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// DeleteCallbackRequest identifies the callback
+type DeleteCallbackRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteCallbackRequest) Reset()         { *m = DeleteCallbackRequest{} }
+func (m *DeleteCallbackRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteCallbackRequest) ProtoMessage()    {}
+func (*DeleteCallbackRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{42}
+}
+func (m *DeleteCallbackRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteCallbackRequest.Unmarshal(m, b)
+}
+func (m *DeleteCallbackRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteCallbackRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteCallbackRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteCallbackRequest.Merge(dst, src)
+}
+func (m *DeleteCallbackRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteCallbackRequest.Size(m)
+}
+func (m *DeleteCallbackRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteCallbackRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteCallbackRequest proto.InternalMessageInfo
+
+func (m *DeleteCallbackRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// DeleteCallbackResponse is returned when the callback was removed
+type DeleteCallbackResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteCallbackResponse) Reset()         { *m = DeleteCallbackResponse{} }
+func (m *DeleteCallbackResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteCallbackResponse) ProtoMessage()    {}
+func (*DeleteCallbackResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{43}
+}
+func (m *DeleteCallbackResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteCallbackResponse.Unmarshal(m, b)
+}
+func (m *DeleteCallbackResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteCallbackResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteCallbackResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteCallbackResponse.Merge(dst, src)
+}
+func (m *DeleteCallbackResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteCallbackResponse.Size(m)
+}
+func (m *DeleteCallbackResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteCallbackResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteCallbackResponse proto.InternalMessageInfo
+
+// EnableCallbackRequest identifies the callback
+type EnableCallbackRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EnableCallbackRequest) Reset()         { *m = EnableCallbackRequest{} }
+func (m *EnableCallbackRequest) String() string { return proto.CompactTextString(m) }
+func (*EnableCallbackRequest) ProtoMessage()    {}
+func (*EnableCallbackRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{44}
+}
+func (m *EnableCallbackRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnableCallbackRequest.Unmarshal(m, b)
+}
+func (m *EnableCallbackRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnableCallbackRequest.Marshal(b, m, deterministic)
+}
+func (dst *EnableCallbackRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnableCallbackRequest.Merge(dst, src)
+}
+func (m *EnableCallbackRequest) XXX_Size() int {
+	return xxx_messageInfo_EnableCallbackRequest.Size(m)
+}
+func (m *EnableCallbackRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnableCallbackRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnableCallbackRequest proto.InternalMessageInfo
+
+func (m *EnableCallbackRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListCallbackAttemptsRequest selects the page of attempts of the callback
+type ListCallbackAttemptsRequest struct {
+	CallbackId string `protobuf:"bytes,1,opt,name=callback_id,json=callbackId,proto3" json:"callback_id,omitempty"`
+	// Maximum number of attempts returned, default 50
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous response
+	PageToken            string   `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListCallbackAttemptsRequest) Reset()         { *m = ListCallbackAttemptsRequest{} }
+func (m *ListCallbackAttemptsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListCallbackAttemptsRequest) ProtoMessage()    {}
+func (*ListCallbackAttemptsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{45}
+}
+func (m *ListCallbackAttemptsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCallbackAttemptsRequest.Unmarshal(m, b)
+}
+func (m *ListCallbackAttemptsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCallbackAttemptsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListCallbackAttemptsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCallbackAttemptsRequest.Merge(dst, src)
+}
+func (m *ListCallbackAttemptsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListCallbackAttemptsRequest.Size(m)
+}
+func (m *ListCallbackAttemptsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCallbackAttemptsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCallbackAttemptsRequest proto.InternalMessageInfo
+
+func (m *ListCallbackAttemptsRequest) GetCallbackId() string {
+	if m != nil {
+		return m.CallbackId
+	}
+	return ""
+}
+
+func (m *ListCallbackAttemptsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListCallbackAttemptsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// ListCallbackAttemptsResponse is the page of attempts ordered by time
+type ListCallbackAttemptsResponse struct {
+	Attempts []*CallbackAttempt `protobuf:"bytes,1,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	// Token of the next page, empty on the last one
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListCallbackAttemptsResponse) Reset()         { *m = ListCallbackAttemptsResponse{} }
+func (m *ListCallbackAttemptsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListCallbackAttemptsResponse) ProtoMessage()    {}
+func (*ListCallbackAttemptsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{46}
+}
+func (m *ListCallbackAttemptsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCallbackAttemptsResponse.Unmarshal(m, b)
+}
+func (m *ListCallbackAttemptsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCallbackAttemptsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListCallbackAttemptsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCallbackAttemptsResponse.Merge(dst, src)
+}
+func (m *ListCallbackAttemptsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListCallbackAttemptsResponse.Size(m)
+}
+func (m *ListCallbackAttemptsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCallbackAttemptsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCallbackAttemptsResponse proto.InternalMessageInfo
+
+func (m *ListCallbackAttemptsResponse) GetAttempts() []*CallbackAttempt {
+	if m != nil {
+		return m.Attempts
+	}
+	return nil
+}
+
+func (m *ListCallbackAttemptsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// ValidateAddressRequest is the address to check
+type ValidateAddressRequest struct {
+	// The mailbox e.g. jane.doe@example.com, without the display name
+	Email                string   `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValidateAddressRequest) Reset()         { *m = ValidateAddressRequest{} }
+func (m *ValidateAddressRequest) String() string { return proto.CompactTextString(m) }
+func (*ValidateAddressRequest) ProtoMessage()    {}
+func (*ValidateAddressRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{47}
+}
+func (m *ValidateAddressRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateAddressRequest.Unmarshal(m, b)
+}
+func (m *ValidateAddressRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateAddressRequest.Marshal(b, m, deterministic)
+}
+func (dst *ValidateAddressRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateAddressRequest.Merge(dst, src)
+}
+func (m *ValidateAddressRequest) XXX_Size() int {
+	return xxx_messageInfo_ValidateAddressRequest.Size(m)
+}
+func (m *ValidateAddressRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateAddressRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateAddressRequest proto.InternalMessageInfo
+
+func (m *ValidateAddressRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+// ValidateAddressResponse is the result of the check of the address
+type ValidateAddressResponse struct {
+	Valid  bool                           `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	Result ValidateAddressResponse_Result `protobuf:"varint,2,opt,name=result,proto3,enum=korepta.rafal.email.v1alpha1.ValidateAddressResponse_Result" json:"result,omitempty"`
+	// Why the address is not valid
+	Detail string `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
+	// The address with the domain converted to punycode, which is used to send the emails
+	Email string `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	// The address with the domain in Unicode
+	UnicodeEmail string `protobuf:"bytes,5,opt,name=unicode_email,json=unicodeEmail,proto3" json:"unicode_email,omitempty"`
+	// Whether the domain gives out temporary addresses
+	Disposable           bool                               `protobuf:"varint,6,opt,name=disposable,proto3" json:"disposable,omitempty"`
+	MailServer           ValidateAddressResponse_MailServer `protobuf:"varint,7,opt,name=mail_server,json=mailServer,proto3,enum=korepta.rafal.email.v1alpha1.ValidateAddressResponse_MailServer" json:"mail_server,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                           `json:"-"`
+	XXX_unrecognized     []byte                             `json:"-"`
+	XXX_sizecache        int32                              `json:"-"`
+}
+
+func (m *ValidateAddressResponse) Reset()         { *m = ValidateAddressResponse{} }
+func (m *ValidateAddressResponse) String() string { return proto.CompactTextString(m) }
+func (*ValidateAddressResponse) ProtoMessage()    {}
+func (*ValidateAddressResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{48}
+}
+func (m *ValidateAddressResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateAddressResponse.Unmarshal(m, b)
+}
+func (m *ValidateAddressResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateAddressResponse.Marshal(b, m, deterministic)
+}
+func (dst *ValidateAddressResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateAddressResponse.Merge(dst, src)
+}
+func (m *ValidateAddressResponse) XXX_Size() int {
+	return xxx_messageInfo_ValidateAddressResponse.Size(m)
+}
+func (m *ValidateAddressResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateAddressResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateAddressResponse proto.InternalMessageInfo
+
+func (m *ValidateAddressResponse) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *ValidateAddressResponse) GetResult() ValidateAddressResponse_Result {
+	if m != nil {
+		return m.Result
+	}
+	return ValidateAddressResponse_RESULT_UNSPECIFIED
+}
+
+func (m *ValidateAddressResponse) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
+func (m *ValidateAddressResponse) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *ValidateAddressResponse) GetUnicodeEmail() string {
+	if m != nil {
+		return m.UnicodeEmail
+	}
+	return ""
+}
+
+func (m *ValidateAddressResponse) GetDisposable() bool {
+	if m != nil {
+		return m.Disposable
+	}
+	return false
+}
+
+func (m *ValidateAddressResponse) GetMailServer() ValidateAddressResponse_MailServer {
+	if m != nil {
+		return m.MailServer
+	}
+	return ValidateAddressResponse_MAIL_SERVER_UNSPECIFIED
+}
+
+// APIKey authenticates the caller, which sends it in the authorization
+// metadata or the Authorization header, e.g. "Bearer em_0123...".
+type APIKey struct {
+	// Identifier of the key, generated on create. It is a part of the key.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Who uses the key e.g. the name of the client application
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// The admin key can call AdminService and APIKeyService
+	Admin bool `protobuf:"varint,3,opt,name=admin,proto3" json:"admin,omitempty"`
+	// The key, returned only when it is created or rotated
+	Key       string               `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	RotatedAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=rotated_at,json=rotatedAt,proto3" json:"rotated_at,omitempty"`
+	// Until when the key replaced by the last rotation is accepted
+	PreviousExpiresAt    *timestamp.Timestamp `protobuf:"bytes,7,opt,name=previous_expires_at,json=previousExpiresAt,proto3" json:"previous_expires_at,omitempty"`
+	RevokedAt            *timestamp.Timestamp `protobuf:"bytes,8,opt,name=revoked_at,json=revokedAt,proto3" json:"revoked_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *APIKey) Reset()         { *m = APIKey{} }
+func (m *APIKey) String() string { return proto.CompactTextString(m) }
+func (*APIKey) ProtoMessage()    {}
+func (*APIKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{49}
+}
+func (m *APIKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_APIKey.Unmarshal(m, b)
+}
+func (m *APIKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_APIKey.Marshal(b, m, deterministic)
+}
+func (dst *APIKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_APIKey.Merge(dst, src)
+}
+func (m *APIKey) XXX_Size() int {
+	return xxx_messageInfo_APIKey.Size(m)
+}
+func (m *APIKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_APIKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_APIKey proto.InternalMessageInfo
+
+func (m *APIKey) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *APIKey) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *APIKey) GetAdmin() bool {
+	if m != nil {
+		return m.Admin
+	}
+	return false
+}
+
+func (m *APIKey) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *APIKey) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *APIKey) GetRotatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.RotatedAt
+	}
+	return nil
+}
+
+func (m *APIKey) GetPreviousExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.PreviousExpiresAt
+	}
+	return nil
+}
+
+func (m *APIKey) GetRevokedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.RevokedAt
+	}
+	return nil
+}
+
+// CreateAPIKeyRequest describes the new key
+type CreateAPIKeyRequest struct {
+	ApiKey               *APIKey  `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateAPIKeyRequest) Reset()         { *m = CreateAPIKeyRequest{} }
+func (m *CreateAPIKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateAPIKeyRequest) ProtoMessage()    {}
+func (*CreateAPIKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{50}
+}
+func (m *CreateAPIKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateAPIKeyRequest.Unmarshal(m, b)
+}
+func (m *CreateAPIKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateAPIKeyRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateAPIKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateAPIKeyRequest.Merge(dst, src)
+}
+func (m *CreateAPIKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateAPIKeyRequest.Size(m)
+}
+func (m *CreateAPIKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateAPIKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateAPIKeyRequest proto.InternalMessageInfo
+
+func (m *CreateAPIKeyRequest) GetApiKey() *APIKey {
+	if m != nil {
+		return m.ApiKey
+	}
+	return nil
+}
+
+// ListAPIKeysRequest selects the page of keys
+type ListAPIKeysRequest struct {
+	// Maximum number of keys returned, default 50
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous response
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAPIKeysRequest) Reset()         { *m = ListAPIKeysRequest{} }
+func (m *ListAPIKeysRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAPIKeysRequest) ProtoMessage()    {}
+func (*ListAPIKeysRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{51}
+}
+func (m *ListAPIKeysRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAPIKeysRequest.Unmarshal(m, b)
+}
+func (m *ListAPIKeysRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAPIKeysRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListAPIKeysRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAPIKeysRequest.Merge(dst, src)
+}
+func (m *ListAPIKeysRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAPIKeysRequest.Size(m)
+}
+func (m *ListAPIKeysRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAPIKeysRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAPIKeysRequest proto.InternalMessageInfo
+
+func (m *ListAPIKeysRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListAPIKeysRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// ListAPIKeysResponse is the page of keys ordered by id
+type ListAPIKeysResponse struct {
+	ApiKeys []*APIKey `protobuf:"bytes,1,rep,name=api_keys,json=apiKeys,proto3" json:"api_keys,omitempty"`
+	// Token of the next page, empty on the last one
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAPIKeysResponse) Reset()         { *m = ListAPIKeysResponse{} }
+func (m *ListAPIKeysResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAPIKeysResponse) ProtoMessage()    {}
+func (*ListAPIKeysResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{52}
+}
+func (m *ListAPIKeysResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAPIKeysResponse.Unmarshal(m, b)
+}
+func (m *ListAPIKeysResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAPIKeysResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListAPIKeysResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAPIKeysResponse.Merge(dst, src)
+}
+func (m *ListAPIKeysResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAPIKeysResponse.Size(m)
+}
+func (m *ListAPIKeysResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAPIKeysResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAPIKeysResponse proto.InternalMessageInfo
+
+func (m *ListAPIKeysResponse) GetApiKeys() []*APIKey {
+	if m != nil {
+		return m.ApiKeys
+	}
+	return nil
+}
+
+func (m *ListAPIKeysResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// RevokeAPIKeyRequest identifies the key
+type RevokeAPIKeyRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeAPIKeyRequest) Reset()         { *m = RevokeAPIKeyRequest{} }
+func (m *RevokeAPIKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeAPIKeyRequest) ProtoMessage()    {}
+func (*RevokeAPIKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{53}
+}
+func (m *RevokeAPIKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeAPIKeyRequest.Unmarshal(m, b)
+}
+func (m *RevokeAPIKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeAPIKeyRequest.Marshal(b, m, deterministic)
+}
+func (dst *RevokeAPIKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeAPIKeyRequest.Merge(dst, src)
+}
+func (m *RevokeAPIKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeAPIKeyRequest.Size(m)
+}
+func (m *RevokeAPIKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeAPIKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeAPIKeyRequest proto.InternalMessageInfo
+
+func (m *RevokeAPIKeyRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// RotateAPIKeyRequest identifies the key
+type RotateAPIKeyRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// How long the old key is still accepted, by default it is rejected at once
+	GracePeriod          *duration.Duration `protobuf:"bytes,2,opt,name=grace_period,json=gracePeriod,proto3" json:"grace_period,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *RotateAPIKeyRequest) Reset()         { *m = RotateAPIKeyRequest{} }
+func (m *RotateAPIKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*RotateAPIKeyRequest) ProtoMessage()    {}
+func (*RotateAPIKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_email_2793442e12fb03ac, []int{54}
+}
+func (m *RotateAPIKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RotateAPIKeyRequest.Unmarshal(m, b)
+}
+func (m *RotateAPIKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RotateAPIKeyRequest.Marshal(b, m, deterministic)
+}
+func (dst *RotateAPIKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RotateAPIKeyRequest.Merge(dst, src)
+}
+func (m *RotateAPIKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_RotateAPIKeyRequest.Size(m)
+}
+func (m *RotateAPIKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RotateAPIKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RotateAPIKeyRequest proto.InternalMessageInfo
+
+func (m *RotateAPIKeyRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RotateAPIKeyRequest) GetGracePeriod() *duration.Duration {
+	if m != nil {
+		return m.GracePeriod
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Address)(nil), "korepta.rafal.email.v1alpha1.Address")
+	proto.RegisterType((*EmailRequest)(nil), "korepta.rafal.email.v1alpha1.EmailRequest")
+	proto.RegisterMapType((map[string]string)(nil), "korepta.rafal.email.v1alpha1.EmailRequest.HeadersEntry")
+	proto.RegisterMapType((map[string]string)(nil), "korepta.rafal.email.v1alpha1.EmailRequest.VariablesEntry")
+	proto.RegisterType((*Attachment)(nil), "korepta.rafal.email.v1alpha1.Attachment")
+	proto.RegisterType((*EmailResponse)(nil), "korepta.rafal.email.v1alpha1.EmailResponse")
+	proto.RegisterType((*WatchMessagesRequest)(nil), "korepta.rafal.email.v1alpha1.WatchMessagesRequest")
+	proto.RegisterType((*MessageEvent)(nil), "korepta.rafal.email.v1alpha1.MessageEvent")
+	proto.RegisterType((*SendMailBatchRequest)(nil), "korepta.rafal.email.v1alpha1.SendMailBatchRequest")
+	proto.RegisterType((*BatchRecipient)(nil), "korepta.rafal.email.v1alpha1.BatchRecipient")
+	proto.RegisterMapType((map[string]string)(nil), "korepta.rafal.email.v1alpha1.BatchRecipient.VariablesEntry")
+	proto.RegisterType((*SendMailBatchResponse)(nil), "korepta.rafal.email.v1alpha1.SendMailBatchResponse")
+	proto.RegisterType((*BatchResult)(nil), "korepta.rafal.email.v1alpha1.BatchResult")
+	proto.RegisterType((*GetMessageRequest)(nil), "korepta.rafal.email.v1alpha1.GetMessageRequest")
+	proto.RegisterType((*CancelMessageRequest)(nil), "korepta.rafal.email.v1alpha1.CancelMessageRequest")
+	proto.RegisterType((*RescheduleMessageRequest)(nil), "korepta.rafal.email.v1alpha1.RescheduleMessageRequest")
+	proto.RegisterType((*Message)(nil), "korepta.rafal.email.v1alpha1.Message")
+	proto.RegisterType((*ListDeadLettersRequest)(nil), "korepta.rafal.email.v1alpha1.ListDeadLettersRequest")
+	proto.RegisterType((*ListDeadLettersResponse)(nil), "korepta.rafal.email.v1alpha1.ListDeadLettersResponse")
+	proto.RegisterType((*GetDeadLetterRequest)(nil), "korepta.rafal.email.v1alpha1.GetDeadLetterRequest")
+	proto.RegisterType((*DeadLetter)(nil), "korepta.rafal.email.v1alpha1.DeadLetter")
+	proto.RegisterType((*RequeueDeadLetterRequest)(nil), "korepta.rafal.email.v1alpha1.RequeueDeadLetterRequest")
+	proto.RegisterType((*PurgeDeadLettersRequest)(nil), "korepta.rafal.email.v1alpha1.PurgeDeadLettersRequest")
+	proto.RegisterType((*PurgeDeadLettersResponse)(nil), "korepta.rafal.email.v1alpha1.PurgeDeadLettersResponse")
+	proto.RegisterType((*Template)(nil), "korepta.rafal.email.v1alpha1.Template")
+	proto.RegisterType((*CreateTemplateRequest)(nil), "korepta.rafal.email.v1alpha1.CreateTemplateRequest")
+	proto.RegisterType((*GetTemplateRequest)(nil), "korepta.rafal.email.v1alpha1.GetTemplateRequest")
+	proto.RegisterType((*ListTemplatesRequest)(nil), "korepta.rafal.email.v1alpha1.ListTemplatesRequest")
+	proto.RegisterType((*ListTemplatesResponse)(nil), "korepta.rafal.email.v1alpha1.ListTemplatesResponse")
+	proto.RegisterType((*UpdateTemplateRequest)(nil), "korepta.rafal.email.v1alpha1.UpdateTemplateRequest")
+	proto.RegisterType((*DeleteTemplateRequest)(nil), "korepta.rafal.email.v1alpha1.DeleteTemplateRequest")
+	proto.RegisterType((*DeleteTemplateResponse)(nil), "korepta.rafal.email.v1alpha1.DeleteTemplateResponse")
+	proto.RegisterType((*Suppression)(nil), "korepta.rafal.email.v1alpha1.Suppression")
+	proto.RegisterType((*AddSuppressionRequest)(nil), "korepta.rafal.email.v1alpha1.AddSuppressionRequest")
+	proto.RegisterType((*GetSuppressionRequest)(nil), "korepta.rafal.email.v1alpha1.GetSuppressionRequest")
+	proto.RegisterType((*ListSuppressionsRequest)(nil), "korepta.rafal.email.v1alpha1.ListSuppressionsRequest")
+	proto.RegisterType((*ListSuppressionsResponse)(nil), "korepta.rafal.email.v1alpha1.ListSuppressionsResponse")
+	proto.RegisterType((*DeleteSuppressionRequest)(nil), "korepta.rafal.email.v1alpha1.DeleteSuppressionRequest")
+	proto.RegisterType((*DeleteSuppressionResponse)(nil), "korepta.rafal.email.v1alpha1.DeleteSuppressionResponse")
+	proto.RegisterType((*Callback)(nil), "korepta.rafal.email.v1alpha1.Callback")
+	proto.RegisterType((*CallbackAttempt)(nil), "korepta.rafal.email.v1alpha1.CallbackAttempt")
+	proto.RegisterType((*CreateCallbackRequest)(nil), "korepta.rafal.email.v1alpha1.CreateCallbackRequest")
+	proto.RegisterType((*GetCallbackRequest)(nil), "korepta.rafal.email.v1alpha1.GetCallbackRequest")
+	proto.RegisterType((*ListCallbacksRequest)(nil), "korepta.rafal.email.v1alpha1.ListCallbacksRequest")
+	proto.RegisterType((*ListCallbacksResponse)(nil), "korepta.rafal.email.v1alpha1.ListCallbacksResponse")
+	proto.RegisterType((*DeleteCallbackRequest)(nil), "korepta.rafal.email.v1alpha1.DeleteCallbackRequest")
+	proto.RegisterType((*DeleteCallbackResponse)(nil), "korepta.rafal.email.v1alpha1.DeleteCallbackResponse")
+	proto.RegisterType((*EnableCallbackRequest)(nil), "korepta.rafal.email.v1alpha1.EnableCallbackRequest")
+	proto.RegisterType((*ListCallbackAttemptsRequest)(nil), "korepta.rafal.email.v1alpha1.ListCallbackAttemptsRequest")
+	proto.RegisterType((*ListCallbackAttemptsResponse)(nil), "korepta.rafal.email.v1alpha1.ListCallbackAttemptsResponse")
+	proto.RegisterType((*ValidateAddressRequest)(nil), "korepta.rafal.email.v1alpha1.ValidateAddressRequest")
+	proto.RegisterType((*ValidateAddressResponse)(nil), "korepta.rafal.email.v1alpha1.ValidateAddressResponse")
+	proto.RegisterType((*APIKey)(nil), "korepta.rafal.email.v1alpha1.APIKey")
+	proto.RegisterType((*CreateAPIKeyRequest)(nil), "korepta.rafal.email.v1alpha1.CreateAPIKeyRequest")
+	proto.RegisterType((*ListAPIKeysRequest)(nil), "korepta.rafal.email.v1alpha1.ListAPIKeysRequest")
+	proto.RegisterType((*ListAPIKeysResponse)(nil), "korepta.rafal.email.v1alpha1.ListAPIKeysResponse")
+	proto.RegisterType((*RevokeAPIKeyRequest)(nil), "korepta.rafal.email.v1alpha1.RevokeAPIKeyRequest")
+	proto.RegisterType((*RotateAPIKeyRequest)(nil), "korepta.rafal.email.v1alpha1.RotateAPIKeyRequest")
+	proto.RegisterEnum("korepta.rafal.email.v1alpha1.Attachment_Disposition", Attachment_Disposition_name, Attachment_Disposition_value)
+	proto.RegisterEnum("korepta.rafal.email.v1alpha1.MessageEvent_Type", MessageEvent_Type_name, MessageEvent_Type_value)
+	proto.RegisterEnum("korepta.rafal.email.v1alpha1.Message_State", Message_State_name, Message_State_value)
+	proto.RegisterEnum("korepta.rafal.email.v1alpha1.Suppression_Reason", Suppression_Reason_name, Suppression_Reason_value)
+	proto.RegisterEnum("korepta.rafal.email.v1alpha1.ValidateAddressResponse_Result", ValidateAddressResponse_Result_name, ValidateAddressResponse_Result_value)
+	proto.RegisterEnum("korepta.rafal.email.v1alpha1.ValidateAddressResponse_MailServer", ValidateAddressResponse_MailServer_name, ValidateAddressResponse_MailServer_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// EmailServiceClient is the client API for EmailService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type EmailServiceClient interface {
+	// SendMail
+	SendMail(ctx context.Context, in *EmailRequest, opts ...grpc.CallOption) (*EmailResponse, error)
+	// SendMailBatch sends the shared email to every recipient separately and
+	// returns the result per recipient, so one bad address does not fail the batch
+	SendMailBatch(ctx context.Context, in *SendMailBatchRequest, opts ...grpc.CallOption) (*SendMailBatchResponse, error)
+	// GetMessage returns the delivery state of the message accepted by SendMail
+	GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// CancelMessage stops the delivery of the message which still waits in the queue
+	CancelMessage(ctx context.Context, in *CancelMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// RescheduleMessage changes the time of the delivery of the message which
+	// still waits in the queue
+	RescheduleMessage(ctx context.Context, in *RescheduleMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// ValidateAddress checks the email address the same way SendMail checks
+	// the recipients, e.g. before the address is accepted by the signup form
+	ValidateAddress(ctx context.Context, in *ValidateAddressRequest, opts ...grpc.CallOption) (*ValidateAddressResponse, error)
+	// WatchMessages streams the lifecycle events of messages as they happen.
+	// The gateway writes the events as newline-delimited JSON.
+	WatchMessages(ctx context.Context, in *WatchMessagesRequest, opts ...grpc.CallOption) (EmailService_WatchMessagesClient, error)
+}
+
+type emailServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewEmailServiceClient(cc *grpc.ClientConn) EmailServiceClient {
+	return &emailServiceClient{cc}
+}
+
+func (c *emailServiceClient) SendMail(ctx context.Context, in *EmailRequest, opts ...grpc.CallOption) (*EmailResponse, error) {
+	out := new(EmailResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/SendMail", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) SendMailBatch(ctx context.Context, in *SendMailBatchRequest, opts ...grpc.CallOption) (*SendMailBatchResponse, error) {
+	out := new(SendMailBatchResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/SendMailBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/GetMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) CancelMessage(ctx context.Context, in *CancelMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/CancelMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) RescheduleMessage(ctx context.Context, in *RescheduleMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/RescheduleMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) ValidateAddress(ctx context.Context, in *ValidateAddressRequest, opts ...grpc.CallOption) (*ValidateAddressResponse, error) {
+	out := new(ValidateAddressResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.EmailService/ValidateAddress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) WatchMessages(ctx context.Context, in *WatchMessagesRequest, opts ...grpc.CallOption) (EmailService_WatchMessagesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_EmailService_serviceDesc.Streams[0], "/korepta.rafal.email.v1alpha1.EmailService/WatchMessages", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &emailServiceWatchMessagesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EmailService_WatchMessagesClient interface {
+	Recv() (*MessageEvent, error)
+	grpc.ClientStream
+}
+
+type emailServiceWatchMessagesClient struct {
+	grpc.ClientStream
+}
+
+func (x *emailServiceWatchMessagesClient) Recv() (*MessageEvent, error) {
+	m := new(MessageEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EmailServiceServer is the server API for EmailService service.
+type EmailServiceServer interface {
+	// SendMail
+	SendMail(context.Context, *EmailRequest) (*EmailResponse, error)
+	// SendMailBatch sends the shared email to every recipient separately and
+	// returns the result per recipient, so one bad address does not fail the batch
+	SendMailBatch(context.Context, *SendMailBatchRequest) (*SendMailBatchResponse, error)
+	// GetMessage returns the delivery state of the message accepted by SendMail
+	GetMessage(context.Context, *GetMessageRequest) (*Message, error)
+	// CancelMessage stops the delivery of the message which still waits in the queue
+	CancelMessage(context.Context, *CancelMessageRequest) (*Message, error)
+	// RescheduleMessage changes the time of the delivery of the message which
+	// still waits in the queue
+	RescheduleMessage(context.Context, *RescheduleMessageRequest) (*Message, error)
+	// ValidateAddress checks the email address the same way SendMail checks
+	// the recipients, e.g. before the address is accepted by the signup form
+	ValidateAddress(context.Context, *ValidateAddressRequest) (*ValidateAddressResponse, error)
+	// WatchMessages streams the lifecycle events of messages as they happen.
+	// The gateway writes the events as newline-delimited JSON.
+	WatchMessages(*WatchMessagesRequest, EmailService_WatchMessagesServer) error
+}
+
+func RegisterEmailServiceServer(s *grpc.Server, srv EmailServiceServer) {
+	s.RegisterService(&_EmailService_serviceDesc, srv)
+}
+
+func _EmailService_SendMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).SendMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/SendMail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).SendMail(ctx, req.(*EmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_SendMailBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMailBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).SendMailBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/SendMailBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).SendMailBatch(ctx, req.(*SendMailBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_GetMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).GetMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/GetMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).GetMessage(ctx, req.(*GetMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_CancelMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).CancelMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/CancelMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).CancelMessage(ctx, req.(*CancelMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_RescheduleMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RescheduleMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).RescheduleMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/RescheduleMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).RescheduleMessage(ctx, req.(*RescheduleMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_ValidateAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).ValidateAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/ValidateAddress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).ValidateAddress(ctx, req.(*ValidateAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_WatchMessages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMessagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EmailServiceServer).WatchMessages(m, &emailServiceWatchMessagesServer{stream})
+}
+
+type EmailService_WatchMessagesServer interface {
+	Send(*MessageEvent) error
+	grpc.ServerStream
+}
+
+type emailServiceWatchMessagesServer struct {
+	grpc.ServerStream
+}
+
+func (x *emailServiceWatchMessagesServer) Send(m *MessageEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _EmailService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "korepta.rafal.email.v1alpha1.EmailService",
 	HandlerType: (*EmailServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "SendMail",
-			Handler:    _EmailService_SendMail_Handler,
+			MethodName: "SendMail",
+			Handler:    _EmailService_SendMail_Handler,
+		},
+		{
+			MethodName: "SendMailBatch",
+			Handler:    _EmailService_SendMailBatch_Handler,
+		},
+		{
+			MethodName: "GetMessage",
+			Handler:    _EmailService_GetMessage_Handler,
+		},
+		{
+			MethodName: "CancelMessage",
+			Handler:    _EmailService_CancelMessage_Handler,
+		},
+		{
+			MethodName: "RescheduleMessage",
+			Handler:    _EmailService_RescheduleMessage_Handler,
+		},
+		{
+			MethodName: "ValidateAddress",
+			Handler:    _EmailService_ValidateAddress_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMessages",
+			Handler:       _EmailService_WatchMessages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "email.proto",
+}
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	// ListDeadLetters returns the messages which failed permanently or ran out of retries
+	ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error)
+	// GetDeadLetter returns the dead-lettered message together with its content
+	GetDeadLetter(ctx context.Context, in *GetDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error)
+	// RequeueDeadLetter moves the dead-lettered message back to the queue
+	RequeueDeadLetter(ctx context.Context, in *RequeueDeadLetterRequest, opts ...grpc.CallOption) (*Message, error)
+	// PurgeDeadLetters removes dead-lettered messages for good
+	PurgeDeadLetters(ctx context.Context, in *PurgeDeadLettersRequest, opts ...grpc.CallOption) (*PurgeDeadLettersResponse, error)
+}
+
+type adminServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminServiceClient(cc *grpc.ClientConn) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error) {
+	out := new(ListDeadLettersResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.AdminService/ListDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetDeadLetter(ctx context.Context, in *GetDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error) {
+	out := new(DeadLetter)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.AdminService/GetDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RequeueDeadLetter(ctx context.Context, in *RequeueDeadLetterRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.AdminService/RequeueDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) PurgeDeadLetters(ctx context.Context, in *PurgeDeadLettersRequest, opts ...grpc.CallOption) (*PurgeDeadLettersResponse, error) {
+	out := new(PurgeDeadLettersResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.AdminService/PurgeDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+type AdminServiceServer interface {
+	// ListDeadLetters returns the messages which failed permanently or ran out of retries
+	ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error)
+	// GetDeadLetter returns the dead-lettered message together with its content
+	GetDeadLetter(context.Context, *GetDeadLetterRequest) (*DeadLetter, error)
+	// RequeueDeadLetter moves the dead-lettered message back to the queue
+	RequeueDeadLetter(context.Context, *RequeueDeadLetterRequest) (*Message, error)
+	// PurgeDeadLetters removes dead-lettered messages for good
+	PurgeDeadLetters(context.Context, *PurgeDeadLettersRequest) (*PurgeDeadLettersResponse, error)
+}
+
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
+}
+
+func _AdminService_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.AdminService/ListDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListDeadLetters(ctx, req.(*ListDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.AdminService/GetDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetDeadLetter(ctx, req.(*GetDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RequeueDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequeueDeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RequeueDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.AdminService/RequeueDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RequeueDeadLetter(ctx, req.(*RequeueDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_PurgeDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).PurgeDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.AdminService/PurgeDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).PurgeDeadLetters(ctx, req.(*PurgeDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "korepta.rafal.email.v1alpha1.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _AdminService_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "GetDeadLetter",
+			Handler:    _AdminService_GetDeadLetter_Handler,
+		},
+		{
+			MethodName: "RequeueDeadLetter",
+			Handler:    _AdminService_RequeueDeadLetter_Handler,
+		},
+		{
+			MethodName: "PurgeDeadLetters",
+			Handler:    _AdminService_PurgeDeadLetters_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "email.proto",
+}
+
+// TemplateServiceClient is the client API for TemplateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TemplateServiceClient interface {
+	// CreateTemplate stores the new template
+	CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// GetTemplate returns the template
+	GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// ListTemplates returns the page of templates ordered by id
+	ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error)
+	// UpdateTemplate replaces the content of the template
+	UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// DeleteTemplate removes the template
+	DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteTemplateResponse, error)
+}
+
+type templateServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewTemplateServiceClient(cc *grpc.ClientConn) TemplateServiceClient {
+	return &templateServiceClient{cc}
+}
+
+func (c *templateServiceClient) CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.TemplateService/CreateTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *templateServiceClient) GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.TemplateService/GetTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *templateServiceClient) ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error) {
+	out := new(ListTemplatesResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.TemplateService/ListTemplates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *templateServiceClient) UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.TemplateService/UpdateTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *templateServiceClient) DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteTemplateResponse, error) {
+	out := new(DeleteTemplateResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.TemplateService/DeleteTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TemplateServiceServer is the server API for TemplateService service.
+type TemplateServiceServer interface {
+	// CreateTemplate stores the new template
+	CreateTemplate(context.Context, *CreateTemplateRequest) (*Template, error)
+	// GetTemplate returns the template
+	GetTemplate(context.Context, *GetTemplateRequest) (*Template, error)
+	// ListTemplates returns the page of templates ordered by id
+	ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesResponse, error)
+	// UpdateTemplate replaces the content of the template
+	UpdateTemplate(context.Context, *UpdateTemplateRequest) (*Template, error)
+	// DeleteTemplate removes the template
+	DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteTemplateResponse, error)
+}
+
+func RegisterTemplateServiceServer(s *grpc.Server, srv TemplateServiceServer) {
+	s.RegisterService(&_TemplateService_serviceDesc, srv)
+}
+
+func _TemplateService_CreateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemplateServiceServer).CreateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.TemplateService/CreateTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemplateServiceServer).CreateTemplate(ctx, req.(*CreateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TemplateService_GetTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemplateServiceServer).GetTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.TemplateService/GetTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemplateServiceServer).GetTemplate(ctx, req.(*GetTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TemplateService_ListTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTemplatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemplateServiceServer).ListTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.TemplateService/ListTemplates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemplateServiceServer).ListTemplates(ctx, req.(*ListTemplatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TemplateService_UpdateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemplateServiceServer).UpdateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.TemplateService/UpdateTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemplateServiceServer).UpdateTemplate(ctx, req.(*UpdateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TemplateService_DeleteTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemplateServiceServer).DeleteTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.TemplateService/DeleteTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemplateServiceServer).DeleteTemplate(ctx, req.(*DeleteTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _TemplateService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "korepta.rafal.email.v1alpha1.TemplateService",
+	HandlerType: (*TemplateServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateTemplate",
+			Handler:    _TemplateService_CreateTemplate_Handler,
+		},
+		{
+			MethodName: "GetTemplate",
+			Handler:    _TemplateService_GetTemplate_Handler,
+		},
+		{
+			MethodName: "ListTemplates",
+			Handler:    _TemplateService_ListTemplates_Handler,
+		},
+		{
+			MethodName: "UpdateTemplate",
+			Handler:    _TemplateService_UpdateTemplate_Handler,
+		},
+		{
+			MethodName: "DeleteTemplate",
+			Handler:    _TemplateService_DeleteTemplate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "email.proto",
+}
+
+// SuppressionServiceClient is the client API for SuppressionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SuppressionServiceClient interface {
+	// AddSuppression stops sending mails to the address, the reason of the
+	// address which is already suppressed is replaced
+	AddSuppression(ctx context.Context, in *AddSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error)
+	// GetSuppression checks if the address is suppressed, NOT_FOUND is
+	// returned when mails can be sent to it
+	GetSuppression(ctx context.Context, in *GetSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error)
+	// ListSuppressions returns the page of suppressed addresses ordered by email
+	ListSuppressions(ctx context.Context, in *ListSuppressionsRequest, opts ...grpc.CallOption) (*ListSuppressionsResponse, error)
+	// DeleteSuppression allows sending mails to the address again
+	DeleteSuppression(ctx context.Context, in *DeleteSuppressionRequest, opts ...grpc.CallOption) (*DeleteSuppressionResponse, error)
+}
+
+type suppressionServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSuppressionServiceClient(cc *grpc.ClientConn) SuppressionServiceClient {
+	return &suppressionServiceClient{cc}
+}
+
+func (c *suppressionServiceClient) AddSuppression(ctx context.Context, in *AddSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error) {
+	out := new(Suppression)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.SuppressionService/AddSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *suppressionServiceClient) GetSuppression(ctx context.Context, in *GetSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error) {
+	out := new(Suppression)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.SuppressionService/GetSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *suppressionServiceClient) ListSuppressions(ctx context.Context, in *ListSuppressionsRequest, opts ...grpc.CallOption) (*ListSuppressionsResponse, error) {
+	out := new(ListSuppressionsResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.SuppressionService/ListSuppressions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *suppressionServiceClient) DeleteSuppression(ctx context.Context, in *DeleteSuppressionRequest, opts ...grpc.CallOption) (*DeleteSuppressionResponse, error) {
+	out := new(DeleteSuppressionResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.SuppressionService/DeleteSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SuppressionServiceServer is the server API for SuppressionService service.
+type SuppressionServiceServer interface {
+	// AddSuppression stops sending mails to the address, the reason of the
+	// address which is already suppressed is replaced
+	AddSuppression(context.Context, *AddSuppressionRequest) (*Suppression, error)
+	// GetSuppression checks if the address is suppressed, NOT_FOUND is
+	// returned when mails can be sent to it
+	GetSuppression(context.Context, *GetSuppressionRequest) (*Suppression, error)
+	// ListSuppressions returns the page of suppressed addresses ordered by email
+	ListSuppressions(context.Context, *ListSuppressionsRequest) (*ListSuppressionsResponse, error)
+	// DeleteSuppression allows sending mails to the address again
+	DeleteSuppression(context.Context, *DeleteSuppressionRequest) (*DeleteSuppressionResponse, error)
+}
+
+func RegisterSuppressionServiceServer(s *grpc.Server, srv SuppressionServiceServer) {
+	s.RegisterService(&_SuppressionService_serviceDesc, srv)
+}
+
+func _SuppressionService_AddSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuppressionServiceServer).AddSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.SuppressionService/AddSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuppressionServiceServer).AddSuppression(ctx, req.(*AddSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuppressionService_GetSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuppressionServiceServer).GetSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.SuppressionService/GetSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuppressionServiceServer).GetSuppression(ctx, req.(*GetSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuppressionService_ListSuppressions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSuppressionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuppressionServiceServer).ListSuppressions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.SuppressionService/ListSuppressions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuppressionServiceServer).ListSuppressions(ctx, req.(*ListSuppressionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuppressionService_DeleteSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuppressionServiceServer).DeleteSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.SuppressionService/DeleteSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuppressionServiceServer).DeleteSuppression(ctx, req.(*DeleteSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SuppressionService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "korepta.rafal.email.v1alpha1.SuppressionService",
+	HandlerType: (*SuppressionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddSuppression",
+			Handler:    _SuppressionService_AddSuppression_Handler,
+		},
+		{
+			MethodName: "GetSuppression",
+			Handler:    _SuppressionService_GetSuppression_Handler,
+		},
+		{
+			MethodName: "ListSuppressions",
+			Handler:    _SuppressionService_ListSuppressions_Handler,
+		},
+		{
+			MethodName: "DeleteSuppression",
+			Handler:    _SuppressionService_DeleteSuppression_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "email.proto",
+}
+
+// APIKeyServiceClient is the client API for APIKeyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type APIKeyServiceClient interface {
+	// CreateAPIKey generates the new key
+	CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error)
+	// ListAPIKeys returns the page of keys, including the revoked ones
+	ListAPIKeys(ctx context.Context, in *ListAPIKeysRequest, opts ...grpc.CallOption) (*ListAPIKeysResponse, error)
+	// RevokeAPIKey rejects the key from now on
+	RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error)
+	// RotateAPIKey generates the new key in place of the old one, which can be
+	// accepted for the grace period, so the clients can be updated in the meantime
+	RotateAPIKey(ctx context.Context, in *RotateAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error)
+}
+
+type aPIKeyServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAPIKeyServiceClient(cc *grpc.ClientConn) APIKeyServiceClient {
+	return &aPIKeyServiceClient{cc}
+}
+
+func (c *aPIKeyServiceClient) CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error) {
+	out := new(APIKey)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.APIKeyService/CreateAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIKeyServiceClient) ListAPIKeys(ctx context.Context, in *ListAPIKeysRequest, opts ...grpc.CallOption) (*ListAPIKeysResponse, error) {
+	out := new(ListAPIKeysResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.APIKeyService/ListAPIKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIKeyServiceClient) RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error) {
+	out := new(APIKey)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.APIKeyService/RevokeAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIKeyServiceClient) RotateAPIKey(ctx context.Context, in *RotateAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error) {
+	out := new(APIKey)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.APIKeyService/RotateAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// APIKeyServiceServer is the server API for APIKeyService service.
+type APIKeyServiceServer interface {
+	// CreateAPIKey generates the new key
+	CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*APIKey, error)
+	// ListAPIKeys returns the page of keys, including the revoked ones
+	ListAPIKeys(context.Context, *ListAPIKeysRequest) (*ListAPIKeysResponse, error)
+	// RevokeAPIKey rejects the key from now on
+	RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*APIKey, error)
+	// RotateAPIKey generates the new key in place of the old one, which can be
+	// accepted for the grace period, so the clients can be updated in the meantime
+	RotateAPIKey(context.Context, *RotateAPIKeyRequest) (*APIKey, error)
+}
+
+func RegisterAPIKeyServiceServer(s *grpc.Server, srv APIKeyServiceServer) {
+	s.RegisterService(&_APIKeyService_serviceDesc, srv)
+}
+
+func _APIKeyService_CreateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIKeyServiceServer).CreateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.APIKeyService/CreateAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIKeyServiceServer).CreateAPIKey(ctx, req.(*CreateAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _APIKeyService_ListAPIKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAPIKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIKeyServiceServer).ListAPIKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.APIKeyService/ListAPIKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIKeyServiceServer).ListAPIKeys(ctx, req.(*ListAPIKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _APIKeyService_RevokeAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIKeyServiceServer).RevokeAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.APIKeyService/RevokeAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIKeyServiceServer).RevokeAPIKey(ctx, req.(*RevokeAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _APIKeyService_RotateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIKeyServiceServer).RotateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.APIKeyService/RotateAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIKeyServiceServer).RotateAPIKey(ctx, req.(*RotateAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _APIKeyService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "korepta.rafal.email.v1alpha1.APIKeyService",
+	HandlerType: (*APIKeyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateAPIKey",
+			Handler:    _APIKeyService_CreateAPIKey_Handler,
+		},
+		{
+			MethodName: "ListAPIKeys",
+			Handler:    _APIKeyService_ListAPIKeys_Handler,
+		},
+		{
+			MethodName: "RevokeAPIKey",
+			Handler:    _APIKeyService_RevokeAPIKey_Handler,
+		},
+		{
+			MethodName: "RotateAPIKey",
+			Handler:    _APIKeyService_RotateAPIKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "email.proto",
+}
+
+// CallbackServiceClient is the client API for CallbackService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CallbackServiceClient interface {
+	// CreateCallback registers the URL, the secret used to sign the events is
+	// returned only by this call
+	CreateCallback(ctx context.Context, in *CreateCallbackRequest, opts ...grpc.CallOption) (*Callback, error)
+	// GetCallback returns the callback without its secret
+	GetCallback(ctx context.Context, in *GetCallbackRequest, opts ...grpc.CallOption) (*Callback, error)
+	// ListCallbacks returns the page of callbacks ordered by id
+	ListCallbacks(ctx context.Context, in *ListCallbacksRequest, opts ...grpc.CallOption) (*ListCallbacksResponse, error)
+	// DeleteCallback stops posting the events to the URL
+	DeleteCallback(ctx context.Context, in *DeleteCallbackRequest, opts ...grpc.CallOption) (*DeleteCallbackResponse, error)
+	// EnableCallback posts the events again to the callback which was disabled
+	// because of repeated failures
+	EnableCallback(ctx context.Context, in *EnableCallbackRequest, opts ...grpc.CallOption) (*Callback, error)
+	// ListCallbackAttempts returns the page of the recent attempts to post the
+	// events to the callback ordered by time
+	ListCallbackAttempts(ctx context.Context, in *ListCallbackAttemptsRequest, opts ...grpc.CallOption) (*ListCallbackAttemptsResponse, error)
+}
+
+type callbackServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCallbackServiceClient(cc *grpc.ClientConn) CallbackServiceClient {
+	return &callbackServiceClient{cc}
+}
+
+func (c *callbackServiceClient) CreateCallback(ctx context.Context, in *CreateCallbackRequest, opts ...grpc.CallOption) (*Callback, error) {
+	out := new(Callback)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.CallbackService/CreateCallback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *callbackServiceClient) GetCallback(ctx context.Context, in *GetCallbackRequest, opts ...grpc.CallOption) (*Callback, error) {
+	out := new(Callback)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.CallbackService/GetCallback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *callbackServiceClient) ListCallbacks(ctx context.Context, in *ListCallbacksRequest, opts ...grpc.CallOption) (*ListCallbacksResponse, error) {
+	out := new(ListCallbacksResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.CallbackService/ListCallbacks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *callbackServiceClient) DeleteCallback(ctx context.Context, in *DeleteCallbackRequest, opts ...grpc.CallOption) (*DeleteCallbackResponse, error) {
+	out := new(DeleteCallbackResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.CallbackService/DeleteCallback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *callbackServiceClient) EnableCallback(ctx context.Context, in *EnableCallbackRequest, opts ...grpc.CallOption) (*Callback, error) {
+	out := new(Callback)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.CallbackService/EnableCallback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *callbackServiceClient) ListCallbackAttempts(ctx context.Context, in *ListCallbackAttemptsRequest, opts ...grpc.CallOption) (*ListCallbackAttemptsResponse, error) {
+	out := new(ListCallbackAttemptsResponse)
+	err := c.cc.Invoke(ctx, "/korepta.rafal.email.v1alpha1.CallbackService/ListCallbackAttempts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CallbackServiceServer is the server API for CallbackService service.
+type CallbackServiceServer interface {
+	// CreateCallback registers the URL, the secret used to sign the events is
+	// returned only by this call
+	CreateCallback(context.Context, *CreateCallbackRequest) (*Callback, error)
+	// GetCallback returns the callback without its secret
+	GetCallback(context.Context, *GetCallbackRequest) (*Callback, error)
+	// ListCallbacks returns the page of callbacks ordered by id
+	ListCallbacks(context.Context, *ListCallbacksRequest) (*ListCallbacksResponse, error)
+	// DeleteCallback stops posting the events to the URL
+	DeleteCallback(context.Context, *DeleteCallbackRequest) (*DeleteCallbackResponse, error)
+	// EnableCallback posts the events again to the callback which was disabled
+	// because of repeated failures
+	EnableCallback(context.Context, *EnableCallbackRequest) (*Callback, error)
+	// ListCallbackAttempts returns the page of the recent attempts to post the
+	// events to the callback ordered by time
+	ListCallbackAttempts(context.Context, *ListCallbackAttemptsRequest) (*ListCallbackAttemptsResponse, error)
+}
+
+func RegisterCallbackServiceServer(s *grpc.Server, srv CallbackServiceServer) {
+	s.RegisterService(&_CallbackService_serviceDesc, srv)
+}
+
+func _CallbackService_CreateCallback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCallbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallbackServiceServer).CreateCallback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.CallbackService/CreateCallback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CallbackServiceServer).CreateCallback(ctx, req.(*CreateCallbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CallbackService_GetCallback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCallbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallbackServiceServer).GetCallback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.CallbackService/GetCallback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CallbackServiceServer).GetCallback(ctx, req.(*GetCallbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CallbackService_ListCallbacks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCallbacksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallbackServiceServer).ListCallbacks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.CallbackService/ListCallbacks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CallbackServiceServer).ListCallbacks(ctx, req.(*ListCallbacksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CallbackService_DeleteCallback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCallbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallbackServiceServer).DeleteCallback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.CallbackService/DeleteCallback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CallbackServiceServer).DeleteCallback(ctx, req.(*DeleteCallbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CallbackService_EnableCallback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableCallbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallbackServiceServer).EnableCallback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.CallbackService/EnableCallback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CallbackServiceServer).EnableCallback(ctx, req.(*EnableCallbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CallbackService_ListCallbackAttempts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCallbackAttemptsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallbackServiceServer).ListCallbackAttempts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/korepta.rafal.email.v1alpha1.CallbackService/ListCallbackAttempts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CallbackServiceServer).ListCallbackAttempts(ctx, req.(*ListCallbackAttemptsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _CallbackService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "korepta.rafal.email.v1alpha1.CallbackService",
+	HandlerType: (*CallbackServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateCallback",
+			Handler:    _CallbackService_CreateCallback_Handler,
+		},
+		{
+			MethodName: "GetCallback",
+			Handler:    _CallbackService_GetCallback_Handler,
+		},
+		{
+			MethodName: "ListCallbacks",
+			Handler:    _CallbackService_ListCallbacks_Handler,
+		},
+		{
+			MethodName: "DeleteCallback",
+			Handler:    _CallbackService_DeleteCallback_Handler,
+		},
+		{
+			MethodName: "EnableCallback",
+			Handler:    _CallbackService_EnableCallback_Handler,
+		},
+		{
+			MethodName: "ListCallbackAttempts",
+			Handler:    _CallbackService_ListCallbackAttempts_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "email.proto",
 }
 
-func init() { proto.RegisterFile("email.proto", fileDescriptor_email_facc9f55703e9d70) }
-
-var fileDescriptor_email_facc9f55703e9d70 = []byte{
-	// 234 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4e, 0xcd, 0x4d, 0xcc,
-	0xcc, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x92, 0xc9, 0xce, 0x2f, 0x4a, 0x2d, 0x28, 0x49,
-	0xd4, 0x2b, 0x4a, 0x4c, 0x4b, 0xcc, 0xd1, 0x83, 0x48, 0x95, 0x19, 0x26, 0xe6, 0x14, 0x64, 0x24,
-	0x1a, 0x4a, 0xc9, 0xa4, 0xe7, 0xe7, 0xa7, 0xe7, 0xa4, 0xea, 0x27, 0x16, 0x64, 0xea, 0x27, 0xe6,
-	0xe5, 0xe5, 0x97, 0x24, 0x96, 0x64, 0xe6, 0xe7, 0x15, 0x43, 0xf4, 0x2a, 0x69, 0x70, 0xf1, 0xb8,
-	0x82, 0xd4, 0x07, 0xa5, 0x16, 0x96, 0xa6, 0x16, 0x97, 0x08, 0x49, 0x70, 0xb1, 0xe7, 0xa6, 0x16,
-	0x17, 0x27, 0xa6, 0xa7, 0x4a, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0xc1, 0xb8, 0x4a, 0xaa, 0x5c,
-	0xbc, 0x50, 0x95, 0xc5, 0x05, 0xf9, 0x79, 0xc5, 0xa9, 0x42, 0x22, 0x5c, 0xac, 0xa9, 0x45, 0x45,
-	0xf9, 0x45, 0x50, 0x85, 0x10, 0x8e, 0x51, 0x3f, 0x23, 0xd4, 0xc4, 0xe0, 0xd4, 0xa2, 0xb2, 0xcc,
-	0xe4, 0x54, 0xa1, 0x7a, 0x2e, 0x8e, 0xe0, 0xd4, 0xbc, 0x14, 0xdf, 0xc4, 0xcc, 0x1c, 0x21, 0x2d,
-	0x3d, 0x7c, 0x4e, 0xd5, 0x43, 0x76, 0x89, 0x94, 0x36, 0x51, 0x6a, 0x21, 0x6e, 0x51, 0x92, 0x6a,
-	0xba, 0xfc, 0x64, 0x32, 0x93, 0x88, 0x12, 0xbf, 0x3e, 0x4c, 0x81, 0x3e, 0x58, 0xbd, 0x15, 0xa3,
-	0x96, 0x93, 0x29, 0x97, 0x42, 0x72, 0x7e, 0x2e, 0x5e, 0xd3, 0x9c, 0x38, 0xc0, 0xc6, 0x39, 0x06,
-	0x78, 0x06, 0x30, 0x46, 0xb1, 0x82, 0xe5, 0x92, 0xd8, 0xc0, 0x01, 0x64, 0x0c, 0x08, 0x00, 0x00,
-	0xff, 0xff, 0x25, 0xa7, 0xd6, 0x2d, 0x6b, 0x01, 0x00, 0x00,
+func init() { proto.RegisterFile("email.proto", fileDescriptor_email_2793442e12fb03ac) }
+
+var fileDescriptor_email_2793442e12fb03ac = []byte{
+	// 3442 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x5a, 0xdb, 0x6f, 0x1b, 0xd7,
+	0x99, 0xdf, 0xe1, 0x9d, 0x1f, 0x25, 0x99, 0x3e, 0x96, 0x6c, 0x66, 0x7c, 0x91, 0x3c, 0x8e, 0x1d,
+	0xaf, 0x92, 0x50, 0x36, 0x6d, 0x27, 0x6b, 0x25, 0x8b, 0x64, 0x24, 0x8e, 0x1d, 0xc6, 0x12, 0xa5,
+	0x0c, 0x29, 0x27, 0xd9, 0x2c, 0x40, 0x8c, 0x38, 0xc7, 0xf6, 0xac, 0x29, 0x92, 0x99, 0x19, 0x2a,
+	0x51, 0x82, 0xec, 0x02, 0xc1, 0xee, 0xc3, 0x3e, 0x65, 0x8d, 0x6c, 0x2f, 0x28, 0x90, 0x16, 0x4d,
+	0x8b, 0x14, 0x48, 0x0b, 0x14, 0x7d, 0x6f, 0x1f, 0xfa, 0xd8, 0xc7, 0xa2, 0xff, 0x42, 0x9e, 0xf2,
+	0xd6, 0xd7, 0x3e, 0x15, 0xe7, 0x36, 0x1c, 0x0e, 0x87, 0x9c, 0xa1, 0xec, 0xbe, 0xf1, 0x5c, 0xbe,
+	0x73, 0x7e, 0xf3, 0x9d, 0xef, 0x76, 0x7e, 0x87, 0x50, 0xc0, 0x07, 0x86, 0xd5, 0x29, 0xf7, 0xed,
+	0x9e, 0xdb, 0x43, 0xe7, 0x1e, 0xf7, 0x6c, 0xdc, 0x77, 0x8d, 0xb2, 0x6d, 0x3c, 0x30, 0x3a, 0x65,
+	0x36, 0x74, 0x78, 0xdd, 0xe8, 0xf4, 0x1f, 0x19, 0xd7, 0xe5, 0x73, 0x0f, 0x7b, 0xbd, 0x87, 0x1d,
+	0xbc, 0x66, 0xf4, 0xad, 0x35, 0xa3, 0xdb, 0xed, 0xb9, 0x86, 0x6b, 0xf5, 0xba, 0x0e, 0x93, 0x95,
+	0x2f, 0xf0, 0x51, 0xda, 0xda, 0x1f, 0x3c, 0x58, 0x33, 0x07, 0x36, 0x9d, 0xc0, 0xc7, 0x97, 0x83,
+	0xe3, 0xae, 0x75, 0x80, 0x1d, 0xd7, 0x38, 0xe8, 0xb3, 0x09, 0xca, 0x0d, 0xc8, 0xaa, 0xa6, 0x69,
+	0x63, 0xc7, 0x41, 0x8b, 0x90, 0xa6, 0x7b, 0x97, 0xa4, 0x15, 0xe9, 0x6a, 0x5e, 0x67, 0x0d, 0x84,
+	0x20, 0xd5, 0x35, 0x0e, 0x70, 0x29, 0x41, 0x3b, 0xe9, 0x6f, 0xe5, 0xaf, 0x19, 0x98, 0xd3, 0xc8,
+	0xa8, 0x8e, 0x3f, 0x1c, 0x60, 0xc7, 0x45, 0xb7, 0x21, 0xf5, 0xc0, 0xee, 0x1d, 0xd0, 0x49, 0x85,
+	0xca, 0xe5, 0xf2, 0xb4, 0x2f, 0x2a, 0xf3, 0xfd, 0x74, 0x2a, 0x82, 0xde, 0x84, 0x9c, 0x8d, 0xfb,
+	0x9d, 0xa3, 0x96, 0xdb, 0x2b, 0x25, 0x67, 0x11, 0xcf, 0x52, 0xb1, 0x66, 0x0f, 0xdd, 0x82, 0x84,
+	0xdb, 0x2b, 0xa5, 0x56, 0x92, 0xf1, 0x65, 0x13, 0x2e, 0x15, 0x6b, 0xb7, 0x4b, 0xe9, 0x99, 0xc4,
+	0xda, 0x6d, 0xf4, 0x2a, 0x24, 0xf7, 0xdb, 0xed, 0x52, 0x66, 0x16, 0x39, 0x22, 0x81, 0x4a, 0x90,
+	0x75, 0x06, 0xfb, 0xff, 0x81, 0xdb, 0x6e, 0x29, 0x4b, 0x75, 0x29, 0x9a, 0xe8, 0x2c, 0xe4, 0x5d,
+	0xfc, 0xb1, 0xdb, 0xda, 0xef, 0x99, 0x47, 0xa5, 0x1c, 0x1d, 0xcb, 0x91, 0x8e, 0x8d, 0x9e, 0x79,
+	0x44, 0x06, 0x1f, 0xb9, 0x07, 0x1d, 0x36, 0x98, 0x67, 0x83, 0xa4, 0x83, 0x0e, 0xbe, 0x03, 0xd9,
+	0x47, 0xd8, 0x30, 0xb1, 0xed, 0x94, 0x80, 0x02, 0x7a, 0x75, 0x3a, 0x20, 0xff, 0xa1, 0x95, 0xdf,
+	0x62, 0x92, 0x5a, 0xd7, 0xb5, 0x8f, 0x74, 0xb1, 0x0e, 0x5a, 0x86, 0x82, 0x8b, 0x0f, 0xfa, 0x1d,
+	0xc3, 0xc5, 0x2d, 0xcb, 0x2c, 0x15, 0xe8, 0x8e, 0x20, 0xba, 0x6a, 0x26, 0x7a, 0x17, 0xf2, 0x87,
+	0x86, 0x6d, 0x19, 0xfb, 0x1d, 0xec, 0x94, 0xe6, 0xe8, 0xae, 0xb7, 0x67, 0xd8, 0xf5, 0xbe, 0x90,
+	0x65, 0xfb, 0x0e, 0xd7, 0x42, 0x6f, 0x43, 0xc1, 0x70, 0x5d, 0xa3, 0xfd, 0xe8, 0x00, 0x77, 0x5d,
+	0xa7, 0x34, 0x4f, 0x97, 0xbe, 0x1a, 0xa1, 0x61, 0x4f, 0x40, 0xf7, 0x0b, 0x13, 0xab, 0x75, 0x8d,
+	0x87, 0x4e, 0x69, 0x61, 0x25, 0x49, 0xac, 0x96, 0xfc, 0x46, 0x37, 0x20, 0xeb, 0xe0, 0xae, 0xd9,
+	0x32, 0xdc, 0xd2, 0x09, 0x6a, 0x68, 0x72, 0x99, 0x79, 0x47, 0x59, 0x78, 0x47, 0xb9, 0x29, 0xbc,
+	0x43, 0xcf, 0x90, 0xa9, 0xaa, 0x2b, 0xaf, 0xc3, 0x9c, 0x5f, 0x4f, 0xa8, 0x08, 0xc9, 0xc7, 0xf8,
+	0x88, 0xbb, 0x08, 0xf9, 0x49, 0xdc, 0xe6, 0xd0, 0xe8, 0x0c, 0x84, 0x87, 0xb0, 0xc6, 0x7a, 0xe2,
+	0x5f, 0x24, 0xf9, 0x75, 0x58, 0x18, 0xfd, 0xda, 0x59, 0xa4, 0xdf, 0x4e, 0xe5, 0xa4, 0x62, 0x42,
+	0xcf, 0x1e, 0x60, 0xc7, 0x31, 0x1e, 0x62, 0xe5, 0x97, 0x09, 0x80, 0xe1, 0xd7, 0x22, 0x19, 0x72,
+	0x0f, 0xac, 0x0e, 0xa6, 0xae, 0xc9, 0x96, 0xf3, 0xda, 0xe8, 0x22, 0xcc, 0xb5, 0x7b, 0x5d, 0x17,
+	0x77, 0xdd, 0x96, 0x7b, 0xd4, 0x17, 0x4b, 0x17, 0x78, 0x5f, 0xf3, 0xa8, 0x8f, 0x89, 0x31, 0xf2,
+	0x26, 0x75, 0xba, 0x39, 0x5d, 0x34, 0xd1, 0x7d, 0x28, 0x98, 0x96, 0xd3, 0xef, 0x39, 0x16, 0x09,
+	0x23, 0xa5, 0xd4, 0x8a, 0x74, 0x75, 0xa1, 0x72, 0x33, 0xee, 0x29, 0x94, 0xab, 0x43, 0x59, 0xdd,
+	0xbf, 0x10, 0x3a, 0x0f, 0x20, 0x40, 0x59, 0x66, 0x29, 0x4d, 0x21, 0xe5, 0x79, 0x4f, 0xcd, 0x54,
+	0xee, 0x40, 0xc1, 0x27, 0x8a, 0xce, 0xc2, 0x99, 0x6a, 0xad, 0xb1, 0xbb, 0xd3, 0xa8, 0x35, 0x6b,
+	0x3b, 0xf5, 0xd6, 0x5e, 0xbd, 0xb1, 0xab, 0x6d, 0xd6, 0xee, 0xd4, 0xb4, 0x6a, 0xf1, 0x9f, 0xd0,
+	0x02, 0x80, 0xda, 0x6c, 0xaa, 0x9b, 0x6f, 0x6d, 0x6b, 0xf5, 0x66, 0x51, 0x42, 0x00, 0x99, 0x5a,
+	0x7d, 0xab, 0x56, 0xd7, 0x8a, 0x09, 0xe5, 0xd7, 0x12, 0xcc, 0x73, 0x7b, 0x73, 0xfa, 0xbd, 0xae,
+	0x83, 0x69, 0x58, 0xb3, 0xed, 0x9e, 0xed, 0x85, 0x35, 0xd2, 0x20, 0x70, 0xb8, 0x66, 0x09, 0x1c,
+	0xa6, 0xa1, 0x3c, 0xef, 0xa9, 0x99, 0x44, 0xbd, 0x7d, 0xbb, 0x77, 0x68, 0x99, 0xd8, 0xa6, 0x0a,
+	0xca, 0xeb, 0x5e, 0x1b, 0xd5, 0x00, 0x9c, 0x41, 0xbf, 0x4f, 0x3c, 0x1b, 0x9b, 0x3c, 0xee, 0xfc,
+	0xf3, 0x74, 0x05, 0x35, 0xf8, 0x7c, 0xa2, 0x15, 0x9f, 0xb0, 0xf2, 0x8d, 0x04, 0x8b, 0xef, 0x1a,
+	0x6e, 0xfb, 0xd1, 0x36, 0xdb, 0xd9, 0x11, 0x01, 0x75, 0x19, 0x0a, 0x43, 0x78, 0x4e, 0x49, 0xa2,
+	0x66, 0x0c, 0x1e, 0xbe, 0xa1, 0x81, 0x27, 0x7c, 0x06, 0x8e, 0x78, 0x14, 0x66, 0x80, 0xe9, 0x6f,
+	0xa4, 0x41, 0x9a, 0xd8, 0x80, 0x43, 0x71, 0x2e, 0x54, 0xd6, 0xa6, 0xe3, 0xe4, 0x30, 0xb4, 0x43,
+	0x72, 0x94, 0xc4, 0x50, 0x74, 0x26, 0xad, 0x7c, 0x9b, 0x84, 0x39, 0xff, 0x60, 0x40, 0x7f, 0x52,
+	0x50, 0x7f, 0x9b, 0x90, 0xf2, 0x4c, 0xef, 0x18, 0xbb, 0x52, 0x61, 0x54, 0x86, 0x14, 0x49, 0x57,
+	0xa5, 0x64, 0xa4, 0xb7, 0xd2, 0x79, 0xde, 0xf7, 0xa7, 0x7c, 0xdf, 0x2f, 0xf4, 0x94, 0xf6, 0xe9,
+	0xc9, 0x7f, 0xb8, 0x99, 0xc0, 0xe1, 0xca, 0x90, 0x33, 0x5c, 0x12, 0xed, 0x5c, 0x87, 0x86, 0xe9,
+	0xb4, 0xee, 0xb5, 0x87, 0x96, 0x94, 0xf3, 0x59, 0x92, 0xf2, 0x85, 0x04, 0x29, 0xea, 0x53, 0x8b,
+	0x50, 0x6c, 0xbe, 0xbf, 0xab, 0x05, 0x8c, 0x15, 0x20, 0xf3, 0xce, 0x9e, 0xb6, 0xa7, 0x55, 0x8b,
+	0x12, 0x2a, 0x40, 0xb6, 0xa1, 0xd5, 0xab, 0xb5, 0xfa, 0xdd, 0x62, 0x02, 0xe5, 0x20, 0xd5, 0x20,
+	0xf6, 0x9b, 0x44, 0x73, 0x90, 0xab, 0x6a, 0x77, 0x34, 0x5d, 0xd7, 0xaa, 0xc5, 0x14, 0x9a, 0x87,
+	0x7c, 0x55, 0xdb, 0xaa, 0xdd, 0xd7, 0x48, 0x33, 0x4d, 0x64, 0x36, 0x76, 0xf6, 0xea, 0x9b, 0x5a,
+	0xb5, 0x98, 0x21, 0x8b, 0xed, 0xec, 0x6a, 0x75, 0xad, 0x5a, 0xcc, 0x92, 0xdf, 0x77, 0xd4, 0xda,
+	0x96, 0x56, 0x2d, 0xe6, 0xc8, 0x0a, 0x9b, 0x6a, 0x7d, 0x53, 0x23, 0xad, 0x3c, 0xb5, 0xaa, 0x06,
+	0xee, 0x9a, 0xdb, 0x86, 0xd5, 0xd9, 0x20, 0xd6, 0x25, 0xac, 0xea, 0x4d, 0x7f, 0x86, 0x2f, 0x54,
+	0x56, 0xe3, 0x87, 0x6d, 0x51, 0x0d, 0x6c, 0x01, 0xd8, 0xb8, 0x6d, 0xf5, 0x2d, 0x1a, 0xa2, 0x13,
+	0xd4, 0xf6, 0x5f, 0x9a, 0xbe, 0x0c, 0x47, 0xc0, 0x85, 0x74, 0x9f, 0xbc, 0xf2, 0xbd, 0x04, 0x0b,
+	0xa3, 0xc3, 0xe8, 0x0d, 0xc8, 0x1a, 0x2c, 0x6b, 0x72, 0x90, 0x71, 0xab, 0x01, 0x2e, 0x85, 0xde,
+	0xf7, 0xa7, 0x27, 0x06, 0xf0, 0xb5, 0x59, 0x00, 0x4e, 0x4e, 0x50, 0x4f, 0x17, 0xcf, 0x95, 0x7f,
+	0x87, 0xa5, 0xc0, 0xa1, 0xf0, 0x00, 0xb5, 0x09, 0x59, 0x1b, 0x3b, 0x83, 0x8e, 0xcb, 0xfc, 0x3c,
+	0x32, 0x98, 0x08, 0xe9, 0x41, 0xc7, 0xd5, 0x85, 0xa4, 0xf2, 0x07, 0x09, 0x0a, 0xbe, 0x81, 0x28,
+	0xff, 0xf4, 0xbb, 0x40, 0x22, 0xe0, 0x02, 0x08, 0x52, 0xed, 0x9e, 0xc9, 0xdc, 0x2e, 0xad, 0xd3,
+	0xdf, 0x43, 0xd3, 0x4f, 0xf9, 0x83, 0xe8, 0x68, 0x24, 0x4c, 0xaf, 0x48, 0xd1, 0xe0, 0x27, 0x45,
+	0xc2, 0x4b, 0x70, 0xf2, 0x2e, 0x76, 0x79, 0x24, 0x10, 0xf6, 0xba, 0x00, 0x09, 0x0f, 0x7c, 0xc2,
+	0x32, 0x95, 0x2b, 0xb0, 0xb8, 0x69, 0x74, 0xdb, 0xb8, 0x13, 0x31, 0xaf, 0x05, 0x25, 0x1d, 0x3b,
+	0xed, 0x47, 0xd8, 0x1c, 0x74, 0xf0, 0xf4, 0xb9, 0xfe, 0xaa, 0x20, 0x11, 0xb7, 0x2a, 0x50, 0x7e,
+	0x97, 0x82, 0x2c, 0x5f, 0x77, 0x6c, 0x41, 0x15, 0xd2, 0x8e, 0x6b, 0xb8, 0x22, 0xf6, 0xbd, 0x18,
+	0x2b, 0xf6, 0x95, 0x1b, 0x44, 0x44, 0x67, 0x92, 0xe8, 0x36, 0x40, 0xdb, 0xc6, 0x86, 0x8b, 0x29,
+	0xac, 0xe8, 0xf0, 0x97, 0xe7, 0xb3, 0x55, 0x52, 0x89, 0xc3, 0xa0, 0x6f, 0x0a, 0xd1, 0x54, 0xb4,
+	0x28, 0x9f, 0xad, 0xba, 0x68, 0x03, 0x4e, 0x74, 0x49, 0x19, 0xca, 0xe3, 0x1d, 0x91, 0x4f, 0x47,
+	0xca, 0xcf, 0x13, 0x11, 0x95, 0x49, 0xa8, 0xee, 0xd4, 0xd0, 0x5a, 0x86, 0x53, 0xe2, 0x77, 0xcb,
+	0x67, 0x9b, 0xac, 0x18, 0x3e, 0x29, 0x86, 0xb6, 0xfd, 0x36, 0xea, 0x85, 0xe2, 0x5c, 0x20, 0x14,
+	0x9f, 0x07, 0xe8, 0x18, 0x8e, 0xdb, 0x62, 0x46, 0xc9, 0xca, 0xe2, 0x3c, 0xe9, 0xd1, 0x68, 0x4c,
+	0x7e, 0x22, 0x41, 0x9a, 0x6a, 0x14, 0x2d, 0xc1, 0xc9, 0x46, 0x53, 0x6d, 0x3e, 0x75, 0x54, 0xf6,
+	0x85, 0xe1, 0xb4, 0x2f, 0xf4, 0x66, 0x46, 0x42, 0x6f, 0x76, 0x34, 0x78, 0xe7, 0x7c, 0xf1, 0x3a,
+	0xaf, 0x34, 0xe1, 0xf4, 0x96, 0xe5, 0xb8, 0x55, 0x6c, 0x98, 0x5b, 0xd8, 0x75, 0xb1, 0xed, 0x25,
+	0xfb, 0xb3, 0x90, 0xef, 0x13, 0x65, 0x38, 0xd6, 0x27, 0xac, 0x98, 0x4b, 0xeb, 0x39, 0xd2, 0xd1,
+	0xb0, 0x3e, 0xc1, 0xe4, 0x4b, 0xe9, 0xa0, 0xdb, 0x7b, 0x8c, 0xbb, 0xa2, 0x50, 0x21, 0x3d, 0x4d,
+	0xd2, 0xa1, 0xfc, 0xb7, 0x04, 0x67, 0xc6, 0x96, 0xe5, 0x81, 0x45, 0x85, 0x1c, 0xd7, 0xb3, 0x88,
+	0x2c, 0x97, 0x63, 0x19, 0xa3, 0xee, 0x89, 0xa1, 0x2b, 0xdc, 0x26, 0xc6, 0x20, 0xd0, 0x73, 0xdf,
+	0xf5, 0x60, 0x5c, 0x81, 0xc5, 0xbb, 0xd8, 0x07, 0x62, 0x92, 0x67, 0x7e, 0x29, 0x01, 0x0c, 0x67,
+	0x91, 0x68, 0xcf, 0xb7, 0x8a, 0x17, 0xed, 0x05, 0x40, 0x21, 0x85, 0xaa, 0x24, 0x76, 0xd2, 0xad,
+	0x4a, 0x89, 0x99, 0x73, 0x9a, 0x10, 0x55, 0x56, 0x49, 0xbc, 0xf8, 0x70, 0x80, 0x07, 0x38, 0xfa,
+	0x0b, 0x3e, 0x84, 0x33, 0xbb, 0x03, 0xfb, 0x21, 0x0e, 0x39, 0xc7, 0x22, 0x24, 0x87, 0xc5, 0x1a,
+	0xf9, 0x49, 0x7a, 0x8c, 0x4e, 0x87, 0x42, 0xcb, 0xe9, 0xe4, 0x27, 0xaa, 0x40, 0x66, 0x1f, 0x3f,
+	0xe8, 0xd9, 0x71, 0xaa, 0x1a, 0x3e, 0x53, 0xa9, 0x40, 0x69, 0x7c, 0x4b, 0x7e, 0xc6, 0xa7, 0x21,
+	0xd3, 0x27, 0x63, 0x26, 0x37, 0x1c, 0xde, 0x52, 0xbe, 0x93, 0x20, 0xd7, 0xe4, 0x97, 0xb6, 0xb1,
+	0x10, 0xe5, 0xbb, 0x8a, 0x26, 0xa6, 0x5c, 0x45, 0x93, 0xd3, 0xae, 0xa2, 0xa9, 0xc0, 0x55, 0x74,
+	0x34, 0x66, 0xa5, 0x8f, 0x1f, 0xb3, 0x32, 0x33, 0xc4, 0x2c, 0xe5, 0x03, 0x58, 0xda, 0xa4, 0xeb,
+	0x88, 0x6f, 0x15, 0x67, 0xb1, 0x01, 0x39, 0x71, 0x67, 0xe5, 0xa6, 0x75, 0x65, 0xba, 0x65, 0x78,
+	0x0b, 0x78, 0x72, 0xca, 0xf3, 0x80, 0xee, 0x62, 0x37, 0xb8, 0x72, 0xd0, 0x20, 0x74, 0x58, 0x24,
+	0x0e, 0x28, 0xa6, 0x3d, 0x13, 0xaf, 0xfe, 0x1f, 0x09, 0x96, 0x02, 0x8b, 0xf2, 0xf3, 0xae, 0x92,
+	0x03, 0xe2, 0x9d, 0xdc, 0xa9, 0xe3, 0x7e, 0xd8, 0x50, 0x30, 0xb6, 0x5b, 0x7f, 0x00, 0x4b, 0x7b,
+	0x54, 0xd7, 0xff, 0x08, 0xf5, 0xbe, 0x00, 0x4b, 0x55, 0xdc, 0xc1, 0x2e, 0x8e, 0xd2, 0x70, 0x09,
+	0x4e, 0x07, 0x27, 0x32, 0x6d, 0x90, 0x4b, 0x71, 0xc1, 0x57, 0x51, 0x4c, 0xa0, 0xb0, 0xde, 0x82,
+	0x8c, 0x8d, 0x0d, 0xa7, 0xd7, 0xe5, 0x29, 0xf9, 0x5a, 0xec, 0x12, 0xa5, 0xac, 0x53, 0x39, 0x9d,
+	0xcb, 0xa3, 0x15, 0x28, 0x98, 0xd8, 0x69, 0xdb, 0x56, 0x9f, 0x5e, 0x8e, 0x99, 0x83, 0xf8, 0xbb,
+	0x02, 0x6e, 0x90, 0x9a, 0xc1, 0x0d, 0x94, 0xf7, 0x20, 0xc3, 0xb6, 0x43, 0xa7, 0x01, 0xe9, 0x9a,
+	0xda, 0x18, 0xbb, 0xf8, 0x02, 0x64, 0x58, 0x12, 0x2a, 0x4a, 0x24, 0xd3, 0x6c, 0xee, 0x6c, 0xef,
+	0x6e, 0xa9, 0xb5, 0x7a, 0xb3, 0x98, 0x40, 0x27, 0xa0, 0xb0, 0x57, 0x6f, 0xec, 0x6d, 0x34, 0x36,
+	0xf5, 0xda, 0x86, 0x56, 0x4c, 0x92, 0xb9, 0xdb, 0x6a, 0x7d, 0x4f, 0xdd, 0x2a, 0xa6, 0x14, 0x13,
+	0x96, 0x54, 0xd3, 0xf4, 0x97, 0x5e, 0x5c, 0xd3, 0xf7, 0xa0, 0xe0, 0x0c, 0x7b, 0x4b, 0xd2, 0xac,
+	0x15, 0x9c, 0x5f, 0x5a, 0x79, 0x19, 0x96, 0xee, 0x62, 0x37, 0x64, 0x97, 0xd0, 0x53, 0x51, 0x7e,
+	0xc6, 0x33, 0x97, 0x4f, 0xe0, 0x59, 0xf8, 0x8e, 0xef, 0xb4, 0x93, 0x4f, 0x77, 0xda, 0xa4, 0x8a,
+	0x28, 0x8d, 0x23, 0xe4, 0x8e, 0xb8, 0x0d, 0x73, 0xbe, 0x8f, 0x8f, 0x59, 0xba, 0xfb, 0x95, 0x33,
+	0x22, 0x1e, 0xdb, 0x23, 0xaf, 0x41, 0x89, 0xf9, 0x42, 0x6c, 0x3d, 0x9f, 0x85, 0xe7, 0x42, 0x24,
+	0xb8, 0x03, 0xfd, 0x2d, 0x01, 0xb9, 0x4d, 0xa3, 0xd3, 0xd9, 0x37, 0xda, 0x8f, 0xc7, 0xd2, 0x44,
+	0x11, 0x92, 0x03, 0xbb, 0xc3, 0x71, 0x90, 0x9f, 0x24, 0xdb, 0x38, 0xb8, 0x6d, 0x63, 0x97, 0x9b,
+	0x3e, 0x6f, 0x3d, 0x23, 0x96, 0x21, 0xf4, 0xb2, 0x2e, 0x2e, 0xf5, 0x19, 0xdf, 0xa5, 0x5e, 0x86,
+	0x9c, 0x69, 0x39, 0xe4, 0x1e, 0xc6, 0xca, 0xc7, 0x9c, 0xee, 0xb5, 0xd1, 0x75, 0x58, 0x6c, 0x93,
+	0x4f, 0x6b, 0x0f, 0x5c, 0xeb, 0x10, 0xb7, 0x1e, 0x18, 0x56, 0x67, 0x60, 0x63, 0x51, 0x41, 0x9e,
+	0xf2, 0x8d, 0xdd, 0xe1, 0x43, 0x01, 0x9f, 0xcd, 0xcf, 0x92, 0xba, 0x5e, 0x83, 0x82, 0xd8, 0x99,
+	0xc8, 0x42, 0xa4, 0x2c, 0x88, 0xe9, 0xaa, 0xab, 0xfc, 0x3e, 0x01, 0x27, 0x84, 0xf2, 0x79, 0x09,
+	0x4d, 0x88, 0x1f, 0x13, 0x77, 0xac, 0x43, 0x6c, 0x1f, 0x0d, 0x2f, 0x6e, 0x20, 0xba, 0x6a, 0x66,
+	0x14, 0x71, 0x55, 0x07, 0xc0, 0x87, 0x1e, 0xf3, 0x97, 0x3c, 0x1e, 0xfd, 0x92, 0xa7, 0x4b, 0x08,
+	0xa2, 0x90, 0x17, 0xdd, 0x34, 0x98, 0xa5, 0x75, 0xd1, 0xf4, 0xd8, 0x99, 0x74, 0x4c, 0x76, 0x66,
+	0x19, 0x0a, 0x8e, 0x6b, 0xb8, 0x03, 0xa7, 0x45, 0x6f, 0x97, 0x19, 0xba, 0x1a, 0xb0, 0xae, 0xcd,
+	0x91, 0x3b, 0x66, 0xd6, 0x7f, 0xc7, 0xa4, 0xb5, 0x4a, 0xbb, 0x8d, 0x1d, 0x76, 0x84, 0x39, 0x5d,
+	0x34, 0x87, 0xb9, 0x5f, 0xe8, 0xd0, 0x97, 0x9c, 0xda, 0xbc, 0x2b, 0x5e, 0x72, 0xf2, 0x16, 0xf0,
+	0xe4, 0x78, 0xee, 0x0f, 0xae, 0x3c, 0x21, 0xf7, 0x8b, 0x69, 0xcf, 0x34, 0xf7, 0xfb, 0x16, 0x1d,
+	0xe6, 0x7e, 0x81, 0x2f, 0x66, 0xee, 0xf7, 0xf0, 0x0f, 0x05, 0x63, 0x47, 0x1a, 0x2f, 0x3d, 0x47,
+	0x29, 0xc1, 0x4b, 0xcf, 0xc3, 0x89, 0x3c, 0xba, 0xbc, 0x00, 0x4b, 0x5a, 0x97, 0x58, 0x7b, 0xd4,
+	0x12, 0x9f, 0xc0, 0x59, 0xff, 0x27, 0x73, 0x67, 0xf0, 0xb3, 0xa1, 0x02, 0xbf, 0xcf, 0x29, 0x44,
+	0x57, 0xcd, 0x1c, 0xd5, 0x77, 0x62, 0xaa, 0xbe, 0x93, 0x41, 0x7d, 0x3f, 0x91, 0xe0, 0x5c, 0xf8,
+	0xe6, 0x5c, 0xed, 0x35, 0xdf, 0x3d, 0x94, 0x69, 0xfd, 0xe5, 0x78, 0x5a, 0xe7, 0x2b, 0xf9, 0xae,
+	0xad, 0x71, 0x75, 0x5f, 0x86, 0xd3, 0xf7, 0x8d, 0x8e, 0x65, 0x1a, 0x2e, 0x16, 0x04, 0xd7, 0xd4,
+	0x18, 0xff, 0x4d, 0x0a, 0xce, 0x8c, 0x09, 0x0c, 0xf9, 0xef, 0x43, 0x32, 0x44, 0x25, 0x72, 0x3a,
+	0x6b, 0xa0, 0x26, 0xc9, 0x92, 0x84, 0x29, 0xe2, 0x35, 0xd1, 0xeb, 0xd3, 0x3f, 0x69, 0xc2, 0xe2,
+	0x65, 0x4e, 0x43, 0xf1, 0xb5, 0x48, 0x7e, 0x30, 0xb1, 0x4b, 0xe0, 0xf1, 0xfc, 0xc0, 0x5a, 0x43,
+	0xd4, 0x29, 0x7f, 0x5d, 0x76, 0x09, 0xe6, 0x07, 0x5d, 0x8b, 0x44, 0x83, 0x16, 0x1b, 0x65, 0xaf,
+	0x02, 0x73, 0xbc, 0x93, 0xde, 0xd5, 0xd0, 0x05, 0x00, 0xf6, 0x8c, 0x40, 0xec, 0x88, 0x46, 0x8d,
+	0x9c, 0xee, 0xeb, 0x41, 0x06, 0x14, 0xc8, 0xbc, 0x96, 0x83, 0xed, 0x43, 0xcc, 0x62, 0xc7, 0x42,
+	0xe5, 0xcd, 0xe3, 0x7d, 0x0d, 0xe1, 0xe6, 0x1a, 0x74, 0x1d, 0x1d, 0x0e, 0xbc, 0xdf, 0xca, 0x47,
+	0xa4, 0x30, 0xe3, 0xdf, 0x87, 0x74, 0xad, 0xb1, 0xb7, 0xd5, 0x0c, 0x14, 0x66, 0x79, 0x48, 0xdf,
+	0x57, 0xb7, 0x6a, 0x84, 0x4d, 0x40, 0xb0, 0x50, 0xab, 0xd3, 0x46, 0xab, 0xf1, 0x7e, 0xbd, 0xa9,
+	0xbe, 0x57, 0x4c, 0xf8, 0xfb, 0xaa, 0x3b, 0xdb, 0x6a, 0xad, 0x5e, 0x4c, 0x92, 0xbe, 0xfa, 0x4e,
+	0x6b, 0x5b, 0xad, 0x6d, 0xb5, 0x1a, 0x9a, 0x7e, 0x5f, 0xd3, 0x8b, 0x29, 0xf2, 0xb0, 0xc1, 0x5e,
+	0x3d, 0xd4, 0x8d, 0x2d, 0xad, 0x98, 0x56, 0x76, 0x01, 0x86, 0x90, 0xc8, 0x9b, 0x88, 0x6f, 0xfa,
+	0x38, 0x82, 0x3b, 0x3b, 0x7b, 0xf5, 0x2a, 0xab, 0x0c, 0xeb, 0x3b, 0xcd, 0x16, 0x6b, 0x26, 0x08,
+	0x73, 0xb1, 0x57, 0xbf, 0x57, 0xdf, 0x79, 0xb7, 0x5e, 0x4c, 0x2a, 0x7f, 0x4e, 0x40, 0x46, 0xdd,
+	0xad, 0xdd, 0xc3, 0x47, 0x63, 0xd9, 0x3e, 0xe4, 0xa1, 0x97, 0x9c, 0x9b, 0x61, 0x1e, 0x58, 0xcc,
+	0x6b, 0x72, 0x3a, 0x6b, 0x08, 0xd6, 0x33, 0x35, 0x64, 0x3d, 0x9f, 0xee, 0xf2, 0x67, 0xf7, 0xdc,
+	0x19, 0x2e, 0x7f, 0x7c, 0xb6, 0xea, 0xa2, 0xb7, 0x09, 0xa1, 0x84, 0x0f, 0xad, 0xde, 0xc0, 0x69,
+	0xe1, 0x8f, 0xfb, 0x96, 0x8d, 0x1d, 0xb2, 0x46, 0x36, 0x72, 0x8d, 0x93, 0x42, 0x4c, 0x63, 0x52,
+	0x1c, 0x06, 0x3e, 0xec, 0x3d, 0x66, 0x30, 0x72, 0x31, 0x60, 0xb0, 0xd9, 0xaa, 0xab, 0x34, 0xe1,
+	0x14, 0xcb, 0x43, 0x4c, 0xb1, 0xc2, 0x53, 0xff, 0x15, 0xb2, 0x46, 0xdf, 0x6a, 0x09, 0x7e, 0xb8,
+	0x50, 0x79, 0x3e, 0x82, 0xc9, 0x66, 0xd2, 0x19, 0xa3, 0x6f, 0xdd, 0xc3, 0x47, 0xca, 0x2e, 0x20,
+	0x12, 0x95, 0x58, 0xef, 0x33, 0x49, 0x2c, 0xff, 0x09, 0xa7, 0x46, 0x56, 0xe4, 0xf1, 0xe1, 0x0d,
+	0xc8, 0x71, 0x9c, 0x22, 0xbc, 0xc5, 0x03, 0x9a, 0x65, 0x40, 0xe3, 0x07, 0xb5, 0xcb, 0x70, 0x4a,
+	0xa7, 0x4a, 0x1b, 0xd5, 0x53, 0x30, 0x17, 0xb4, 0xe1, 0x94, 0x4e, 0x8f, 0x78, 0xea, 0x34, 0xf4,
+	0x3a, 0xcc, 0x3d, 0xb4, 0x8d, 0x36, 0x6e, 0xf5, 0xb1, 0x6d, 0xf5, 0x4c, 0x4e, 0xff, 0x3c, 0x37,
+	0x76, 0x64, 0x55, 0xfe, 0x87, 0x08, 0xbd, 0x40, 0xa7, 0xef, 0xd2, 0xd9, 0x95, 0xaf, 0x72, 0xfc,
+	0x1f, 0x0c, 0xc4, 0xb7, 0xac, 0x36, 0x46, 0xff, 0x05, 0x39, 0xc1, 0xce, 0xa3, 0x19, 0x38, 0x24,
+	0xf9, 0xc5, 0x58, 0x73, 0x79, 0x3e, 0x94, 0x3f, 0xff, 0xcb, 0x77, 0x5f, 0x26, 0x16, 0x95, 0x13,
+	0x6b, 0x62, 0xc2, 0x1a, 0x9d, 0xbf, 0x2e, 0xad, 0xa2, 0x9f, 0x48, 0x30, 0x3f, 0xf2, 0x3e, 0x80,
+	0x2a, 0x11, 0x77, 0x89, 0x90, 0x17, 0x1e, 0xf9, 0xc6, 0x4c, 0x32, 0x1c, 0xd6, 0x0a, 0x85, 0x25,
+	0x2b, 0x4b, 0x41, 0x58, 0xfb, 0x64, 0x1a, 0x01, 0xf7, 0xb9, 0x04, 0x30, 0xa4, 0xe7, 0x51, 0x44,
+	0x41, 0x39, 0x46, 0xe4, 0xcb, 0xf1, 0x68, 0x3d, 0xe5, 0x1c, 0x05, 0x72, 0x1a, 0x2d, 0x06, 0x80,
+	0xac, 0x7d, 0x6a, 0x99, 0x9f, 0xa1, 0x2f, 0x24, 0x98, 0x1f, 0xa1, 0xff, 0xa3, 0x34, 0x14, 0xf6,
+	0x56, 0x10, 0x17, 0xca, 0x25, 0x0a, 0xe5, 0xbc, 0x72, 0x36, 0x0c, 0xca, 0x7a, 0x9b, 0xae, 0x8c,
+	0x7e, 0x2a, 0xc1, 0xc9, 0xb1, 0x87, 0x06, 0xf4, 0xca, 0xf4, 0x1d, 0x26, 0xbd, 0x4c, 0xc4, 0x45,
+	0xb6, 0x4a, 0x91, 0x3d, 0xaf, 0x2c, 0x87, 0x22, 0xb3, 0xbd, 0xd5, 0xc9, 0xb9, 0xfd, 0x42, 0x82,
+	0x13, 0x81, 0x64, 0x87, 0x6e, 0xce, 0x98, 0x1b, 0x19, 0xb8, 0x5b, 0xc7, 0xca, 0xa8, 0x9e, 0x1a,
+	0x4b, 0x41, 0xd3, 0x3a, 0xe4, 0x02, 0x04, 0xe5, 0xff, 0x49, 0x30, 0x3f, 0xf2, 0x0a, 0x1e, 0x75,
+	0xb0, 0x61, 0x4f, 0xe6, 0xf2, 0x6a, 0xfc, 0x5b, 0x8e, 0x72, 0x9e, 0xc2, 0x3a, 0x83, 0xc6, 0x2c,
+	0xfe, 0x23, 0xb2, 0xf2, 0x35, 0xa9, 0xf2, 0x6d, 0x1a, 0xe6, 0x54, 0x92, 0xec, 0x44, 0x7c, 0x20,
+	0x9a, 0x0c, 0xf0, 0xec, 0x51, 0x9a, 0x0c, 0x67, 0xfb, 0xe5, 0x5b, 0x33, 0x4a, 0x8d, 0x6a, 0x12,
+	0xf9, 0x0c, 0x92, 0x66, 0xe3, 0x35, 0x13, 0x1b, 0x66, 0x87, 0x23, 0xfa, 0x91, 0x04, 0xf3, 0x23,
+	0x3c, 0x7c, 0x94, 0x26, 0xc3, 0x48, 0x7b, 0x39, 0xe2, 0x3f, 0x37, 0x43, 0x01, 0xe5, 0x2a, 0x05,
+	0xa5, 0xa0, 0x95, 0x29, 0xa0, 0x98, 0xf3, 0xfe, 0x8a, 0xba, 0x4a, 0x80, 0x63, 0x8f, 0x76, 0x95,
+	0x70, 0x52, 0x3e, 0xae, 0xab, 0xdc, 0xa0, 0xf0, 0x5e, 0x56, 0xae, 0x46, 0xc1, 0x5b, 0xb7, 0xd9,
+	0x4e, 0xc4, 0x1a, 0x7f, 0x2b, 0x41, 0x31, 0x48, 0xb7, 0xa3, 0x88, 0x43, 0x9b, 0xf0, 0x22, 0x20,
+	0xbf, 0x32, 0xab, 0x18, 0x3f, 0xec, 0x97, 0x28, 0xf0, 0x2b, 0xca, 0xc5, 0x29, 0xc0, 0xd7, 0x29,
+	0xd3, 0xbf, 0x2e, 0xad, 0x56, 0x9e, 0x64, 0xe0, 0x84, 0xa0, 0x46, 0x85, 0xbd, 0xfe, 0xbf, 0x04,
+	0x0b, 0xa3, 0xcc, 0x38, 0x8a, 0xc8, 0x0d, 0xa1, 0x3c, 0xba, 0x1c, 0x93, 0xd6, 0x55, 0x2e, 0x53,
+	0xc4, 0xcb, 0xca, 0xa9, 0x21, 0x62, 0x8f, 0x6e, 0x5e, 0xf7, 0x38, 0x5f, 0xf4, 0xbf, 0x12, 0x14,
+	0x7c, 0x9c, 0x3a, 0xba, 0x16, 0x69, 0x9e, 0xc7, 0x05, 0xc4, 0x93, 0x1a, 0x2a, 0x85, 0x00, 0x62,
+	0x26, 0xf9, 0x03, 0x09, 0xe6, 0x47, 0x48, 0xf6, 0x28, 0x67, 0x09, 0xa3, 0xf9, 0xe5, 0x1b, 0x33,
+	0xc9, 0xf0, 0xf3, 0x3d, 0x4b, 0xc1, 0x2d, 0xa1, 0x30, 0x6d, 0xa1, 0xaf, 0x25, 0x58, 0x18, 0x65,
+	0xdd, 0xa3, 0x8e, 0x2e, 0x94, 0xa3, 0x8f, 0xad, 0x29, 0xee, 0x25, 0xf2, 0xc5, 0x50, 0x4d, 0x89,
+	0x9f, 0x65, 0xe2, 0x26, 0xc3, 0x83, 0xfc, 0x4a, 0x82, 0x85, 0x51, 0x52, 0x3e, 0x0a, 0x64, 0x28,
+	0xd7, 0x2f, 0xdf, 0x9c, 0x4d, 0x68, 0xb4, 0x62, 0x59, 0x9d, 0x78, 0xb8, 0x95, 0xaf, 0xd3, 0x80,
+	0x7c, 0x84, 0xa7, 0x70, 0x0b, 0x02, 0x7b, 0x94, 0x0a, 0x8f, 0x82, 0x1d, 0x4a, 0x9c, 0xcb, 0xf1,
+	0x79, 0x5e, 0xe5, 0x45, 0x8a, 0xf5, 0xb2, 0x72, 0x7a, 0x88, 0xd5, 0xcf, 0xfc, 0xae, 0xfb, 0x39,
+	0x74, 0xf4, 0x63, 0x09, 0x16, 0x46, 0x49, 0xf4, 0x28, 0x7c, 0xa1, 0x94, 0xfb, 0x2c, 0xf8, 0xae,
+	0x50, 0x7c, 0x2b, 0xe8, 0x42, 0x38, 0xbe, 0xb5, 0x4f, 0xa9, 0xec, 0x67, 0xe8, 0xe7, 0x12, 0x14,
+	0x83, 0x6c, 0x38, 0x8a, 0x91, 0xcc, 0x42, 0xf8, 0x7d, 0xf9, 0x95, 0x59, 0xc5, 0xf8, 0xb9, 0x5f,
+	0xa0, 0x58, 0x4b, 0x68, 0x82, 0x2e, 0xd1, 0x6f, 0x24, 0x38, 0x39, 0x46, 0x76, 0x47, 0x65, 0x99,
+	0x49, 0x7c, 0xba, 0xfc, 0xea, 0xcc, 0x72, 0x1c, 0x26, 0x57, 0xe9, 0x6a, 0x84, 0x4a, 0x2b, 0xdf,
+	0xa7, 0x60, 0x9e, 0xdd, 0x72, 0x84, 0x7d, 0x3e, 0x91, 0x60, 0xce, 0x7f, 0x99, 0x44, 0xd7, 0xe3,
+	0x04, 0xed, 0x91, 0x9b, 0x92, 0x1c, 0xeb, 0xfa, 0x26, 0x52, 0xb7, 0x72, 0x26, 0x98, 0x62, 0x8c,
+	0xbe, 0x45, 0xee, 0x82, 0xeb, 0xe2, 0xf6, 0x8a, 0xbe, 0x94, 0xa0, 0xe0, 0xbb, 0x38, 0x46, 0xc5,
+	0xec, 0xf1, 0x5b, 0xab, 0x7c, 0x7d, 0x06, 0x09, 0xae, 0xc2, 0x65, 0x0a, 0xef, 0x39, 0x34, 0x09,
+	0x1e, 0x49, 0x70, 0x73, 0xfe, 0xeb, 0x64, 0x94, 0xa6, 0x42, 0xae, 0x9e, 0x31, 0x35, 0x25, 0x1c,
+	0xf8, 0xd2, 0x04, 0x28, 0xa2, 0x82, 0x20, 0xeb, 0x93, 0x0a, 0x6c, 0xce, 0x7f, 0x7d, 0x8d, 0x84,
+	0x35, 0x7e, 0xd5, 0x8d, 0x09, 0xab, 0x4c, 0x61, 0x5d, 0x8d, 0x82, 0x45, 0xd7, 0x27, 0x55, 0xc2,
+	0x9f, 0xb2, 0xc3, 0xd7, 0x86, 0xf1, 0x2a, 0x41, 0x8c, 0xc4, 0xab, 0x12, 0x02, 0x7c, 0xae, 0x1c,
+	0x93, 0x86, 0x0e, 0xab, 0x12, 0x3c, 0x62, 0x7a, 0xdd, 0x23, 0xdf, 0x45, 0x95, 0xe0, 0x61, 0x8a,
+	0xae, 0x12, 0x8e, 0x0b, 0x28, 0xa4, 0x4a, 0xf0, 0x00, 0x8d, 0x56, 0x09, 0x42, 0x24, 0x56, 0x95,
+	0x10, 0x7c, 0x10, 0x90, 0x6f, 0xcc, 0x24, 0x33, 0xb9, 0x4a, 0x18, 0xd2, 0xf8, 0xc3, 0x04, 0x1c,
+	0xf7, 0xe8, 0x42, 0xd9, 0x7c, 0xf9, 0xe6, 0x6c, 0x42, 0x93, 0x13, 0x70, 0x40, 0x6f, 0x3f, 0x94,
+	0x60, 0x61, 0x94, 0xfc, 0x8f, 0xc2, 0x17, 0xfa, 0x54, 0x10, 0xfb, 0x24, 0x5f, 0xa0, 0x88, 0x2e,
+	0x2a, 0xcb, 0x93, 0x10, 0xad, 0x63, 0xba, 0x3e, 0xfa, 0xa3, 0x34, 0xfa, 0x68, 0x23, 0xf8, 0x7e,
+	0x74, 0x3b, 0xfe, 0x19, 0x05, 0x1e, 0x28, 0xe4, 0xf5, 0xe3, 0x88, 0x72, 0x55, 0x56, 0x28, 0xf0,
+	0x97, 0xd0, 0x6a, 0x28, 0x70, 0xdf, 0xbb, 0xc7, 0x67, 0x6b, 0xe2, 0x1d, 0x61, 0xe3, 0x16, 0xac,
+	0xb4, 0x7b, 0x07, 0x53, 0x37, 0xdd, 0xc8, 0x51, 0xee, 0x49, 0xdd, 0xad, 0xed, 0x4a, 0xff, 0xc6,
+	0x08, 0xf7, 0xfd, 0x0c, 0x65, 0xc5, 0x6e, 0xfc, 0x7d, 0x00, 0x66, 0x22, 0x3c, 0x51, 0x7f, 0x34,
+	0x00, 0x00,
 }
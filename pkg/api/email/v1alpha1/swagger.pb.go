@@ -18,6 +18,414 @@ const (
     "application/json"
   ],
   "paths": {
+    "/v1alpha1/admin/apikeys": {
+      "get": {
+        "summary": "SendMailBatch sends the shared email to every recipient separately and\nreturns the result per recipient, so one bad address does not fail the batch",
+        "operationId": "ListAPIKeys",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ListAPIKeysResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "page_size",
+            "description": "Maximum number of keys returned, default 50.",
+            "in": "query",
+            "required": false,
+            "type": "integer",
+            "format": "int32"
+          },
+          {
+            "name": "page_token",
+            "description": "The next_page_token of the previous response.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "APIKeyService"
+        ]
+      },
+      "post": {
+        "summary": "SendMail",
+        "operationId": "CreateAPIKey",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1APIKey"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1APIKey"
+            }
+          }
+        ],
+        "tags": [
+          "APIKeyService"
+        ]
+      }
+    },
+    "/v1alpha1/admin/apikeys/{id}:revoke": {
+      "post": {
+        "summary": "GetMessage returns the delivery state of the message accepted by SendMail",
+        "operationId": "RevokeAPIKey",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1APIKey"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "APIKeyService"
+        ]
+      }
+    },
+    "/v1alpha1/admin/apikeys/{id}:rotate": {
+      "post": {
+        "summary": "CancelMessage stops the delivery of the message which still waits in the queue",
+        "operationId": "RotateAPIKey",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1APIKey"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1RotateAPIKeyRequest"
+            }
+          }
+        ],
+        "tags": [
+          "APIKeyService"
+        ]
+      }
+    },
+    "/v1alpha1/admin/deadletters": {
+      "get": {
+        "summary": "SendMail",
+        "operationId": "ListDeadLetters",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ListDeadLettersResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "page_size",
+            "description": "Maximum number of messages returned, default 50.",
+            "in": "query",
+            "required": false,
+            "type": "integer",
+            "format": "int32"
+          },
+          {
+            "name": "page_token",
+            "description": "The next_page_token of the previous response.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "AdminService"
+        ]
+      }
+    },
+    "/v1alpha1/admin/deadletters/{id}": {
+      "get": {
+        "summary": "SendMailBatch sends the shared email to every recipient separately and\nreturns the result per recipient, so one bad address does not fail the batch",
+        "operationId": "GetDeadLetter",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1DeadLetter"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "AdminService"
+        ]
+      }
+    },
+    "/v1alpha1/admin/deadletters/{id}:requeue": {
+      "post": {
+        "summary": "GetMessage returns the delivery state of the message accepted by SendMail",
+        "operationId": "RequeueDeadLetter",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Message"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1RequeueDeadLetterRequest"
+            }
+          }
+        ],
+        "tags": [
+          "AdminService"
+        ]
+      }
+    },
+    "/v1alpha1/admin/deadletters:purge": {
+      "post": {
+        "summary": "CancelMessage stops the delivery of the message which still waits in the queue",
+        "operationId": "PurgeDeadLetters",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1PurgeDeadLettersResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1PurgeDeadLettersRequest"
+            }
+          }
+        ],
+        "tags": [
+          "AdminService"
+        ]
+      }
+    },
+    "/v1alpha1/callbacks": {
+      "get": {
+        "summary": "GetMessage returns the delivery state of the message accepted by SendMail",
+        "operationId": "ListCallbacks",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ListCallbacksResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "page_size",
+            "description": "Maximum number of callbacks returned, default 50.",
+            "in": "query",
+            "required": false,
+            "type": "integer",
+            "format": "int32"
+          },
+          {
+            "name": "page_token",
+            "description": "The next_page_token of the previous response.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "CallbackService"
+        ]
+      },
+      "post": {
+        "summary": "SendMail",
+        "operationId": "CreateCallback",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Callback"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Callback"
+            }
+          }
+        ],
+        "tags": [
+          "CallbackService"
+        ]
+      }
+    },
+    "/v1alpha1/callbacks/{callback_id}/attempts": {
+      "get": {
+        "summary": "ValidateAddress checks the email address the same way SendMail checks\nthe recipients, e.g. before the address is accepted by the signup form",
+        "operationId": "ListCallbackAttempts",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ListCallbackAttemptsResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "callback_id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
+          {
+            "name": "page_size",
+            "description": "Maximum number of attempts returned, default 50.",
+            "in": "query",
+            "required": false,
+            "type": "integer",
+            "format": "int32"
+          },
+          {
+            "name": "page_token",
+            "description": "The next_page_token of the previous response.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "CallbackService"
+        ]
+      }
+    },
+    "/v1alpha1/callbacks/{id}": {
+      "get": {
+        "summary": "SendMailBatch sends the shared email to every recipient separately and\nreturns the result per recipient, so one bad address does not fail the batch",
+        "operationId": "GetCallback",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Callback"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "CallbackService"
+        ]
+      },
+      "delete": {
+        "summary": "CancelMessage stops the delivery of the message which still waits in the queue",
+        "operationId": "DeleteCallback",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1DeleteCallbackResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "CallbackService"
+        ]
+      }
+    },
+    "/v1alpha1/callbacks/{id}:enable": {
+      "post": {
+        "summary": "RescheduleMessage changes the time of the delivery of the message which\nstill waits in the queue",
+        "operationId": "EnableCallback",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Callback"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "CallbackService"
+        ]
+      }
+    },
     "/v1alpha1/email": {
       "post": {
         "summary": "SendMail",
@@ -26,42 +434,1279 @@ const (
           "200": {
             "description": "",
             "schema": {
-              "$ref": "#/definitions/v1alpha1EmailResponse"
+              "$ref": "#/definitions/v1alpha1EmailResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1EmailRequest"
+            }
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/email/{id}": {
+      "get": {
+        "summary": "GetMessage returns the delivery state of the message accepted by SendMail",
+        "operationId": "GetMessage",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Message"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "description": "The message_id returned by SendMail",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/email/{id}:cancel": {
+      "post": {
+        "summary": "CancelMessage stops the delivery of the message which still waits in the queue",
+        "operationId": "CancelMessage",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Message"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "description": "The message_id returned by SendMail",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/email/{id}:reschedule": {
+      "post": {
+        "summary": "RescheduleMessage changes the time of the delivery of the message which\nstill waits in the queue",
+        "operationId": "RescheduleMessage",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Message"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "description": "The message_id returned by SendMail",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1RescheduleMessageRequest"
+            }
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/email:batch": {
+      "post": {
+        "summary": "SendMailBatch sends the shared email to every recipient separately and\nreturns the result per recipient, so one bad address does not fail the batch",
+        "operationId": "SendMailBatch",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1SendMailBatchResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1SendMailBatchRequest"
+            }
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/email:validate": {
+      "post": {
+        "summary": "ValidateAddress checks the email address the same way SendMail checks\nthe recipients, e.g. before the address is accepted by the signup form",
+        "operationId": "ValidateAddress",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ValidateAddressResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ValidateAddressRequest"
+            }
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/email:watch": {
+      "get": {
+        "summary": "WatchMessages streams the lifecycle events of messages as they happen.\nThe gateway writes the events as newline-delimited JSON.",
+        "operationId": "WatchMessages",
+        "responses": {
+          "200": {
+            "description": "(streaming responses)",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1MessageEvent"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "message_ids",
+            "in": "query",
+            "required": false,
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "tags",
+            "in": "query",
+            "required": false,
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "from",
+            "description": "Email of the sender.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          },
+          {
+            "name": "types",
+            "description": " - QUEUED: Accepted and waits in the queue\n - SENDING: Taken by the worker for the delivery attempt\n - SENT: Accepted by the provider\n - DEFERRED: Failed temporarily and waits for the next attempt\n - DELIVERED: Accepted by the recipient mail server\n - BOUNCED: Rejected by the recipient mail server\n - OPENED: Opened by the recipient\n - FAILED: Failed permanently or ran out of retries\n - CANCELED: Canceled before the delivery",
+            "in": "query",
+            "required": false,
+            "type": "array",
+            "items": {
+              "type": "string",
+              "enum": [
+                "TYPE_UNSPECIFIED",
+                "QUEUED",
+                "SENDING",
+                "SENT",
+                "DEFERRED",
+                "DELIVERED",
+                "BOUNCED",
+                "OPENED",
+                "FAILED",
+                "CANCELED"
+              ]
+            }
+          }
+        ],
+        "tags": [
+          "EmailService"
+        ]
+      }
+    },
+    "/v1alpha1/suppressions": {
+      "get": {
+        "summary": "GetMessage returns the delivery state of the message accepted by SendMail",
+        "operationId": "ListSuppressions",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ListSuppressionsResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "page_size",
+            "description": "Maximum number of addresses returned, default 50.",
+            "in": "query",
+            "required": false,
+            "type": "integer",
+            "format": "int32"
+          },
+          {
+            "name": "page_token",
+            "description": "The next_page_token of the previous response.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          },
+          {
+            "name": "reason",
+            "description": "Returns only the addresses suppressed for the reason when set.\n\n - BOUNCE: The mail server of the recipient rejected the mail permanently\n - COMPLAINT: The recipient marked the mail as spam\n - UNSUBSCRIBE: The recipient does not want to receive mails\n - MANUAL: Added by the operator",
+            "in": "query",
+            "required": false,
+            "type": "string",
+            "enum": [
+              "REASON_UNSPECIFIED",
+              "BOUNCE",
+              "COMPLAINT",
+              "UNSUBSCRIBE",
+              "MANUAL"
+            ],
+            "default": "REASON_UNSPECIFIED"
+          }
+        ],
+        "tags": [
+          "SuppressionService"
+        ]
+      },
+      "post": {
+        "summary": "SendMail",
+        "operationId": "AddSuppression",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Suppression"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Suppression"
+            }
+          }
+        ],
+        "tags": [
+          "SuppressionService"
+        ]
+      }
+    },
+    "/v1alpha1/suppressions/{email}": {
+      "get": {
+        "summary": "SendMailBatch sends the shared email to every recipient separately and\nreturns the result per recipient, so one bad address does not fail the batch",
+        "operationId": "GetSuppression",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Suppression"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "email",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "SuppressionService"
+        ]
+      },
+      "delete": {
+        "summary": "CancelMessage stops the delivery of the message which still waits in the queue",
+        "operationId": "DeleteSuppression",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1DeleteSuppressionResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "email",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "SuppressionService"
+        ]
+      }
+    },
+    "/v1alpha1/templates": {
+      "get": {
+        "summary": "GetMessage returns the delivery state of the message accepted by SendMail",
+        "operationId": "ListTemplates",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1ListTemplatesResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "page_size",
+            "description": "Maximum number of templates returned, default 50.",
+            "in": "query",
+            "required": false,
+            "type": "integer",
+            "format": "int32"
+          },
+          {
+            "name": "page_token",
+            "description": "The next_page_token of the previous response.",
+            "in": "query",
+            "required": false,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "TemplateService"
+        ]
+      },
+      "post": {
+        "summary": "SendMail",
+        "operationId": "CreateTemplate",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Template"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Template"
+            }
+          }
+        ],
+        "tags": [
+          "TemplateService"
+        ]
+      }
+    },
+    "/v1alpha1/templates/{id}": {
+      "get": {
+        "summary": "SendMailBatch sends the shared email to every recipient separately and\nreturns the result per recipient, so one bad address does not fail the batch",
+        "operationId": "GetTemplate",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Template"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "TemplateService"
+        ]
+      },
+      "delete": {
+        "summary": "RescheduleMessage changes the time of the delivery of the message which\nstill waits in the queue",
+        "operationId": "DeleteTemplate",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1DeleteTemplateResponse"
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          }
+        ],
+        "tags": [
+          "TemplateService"
+        ]
+      }
+    },
+    "/v1alpha1/templates/{template.id}": {
+      "put": {
+        "summary": "CancelMessage stops the delivery of the message which still waits in the queue",
+        "operationId": "UpdateTemplate",
+        "responses": {
+          "200": {
+            "description": "",
+            "schema": {
+              "$ref": "#/definitions/v1alpha1Template"
             }
           }
         },
         "parameters": [
+          {
+            "name": "template.id",
+            "description": "Identifier of the template e.g. welcome, generated when empty on create",
+            "in": "path",
+            "required": true,
+            "type": "string"
+          },
           {
             "name": "body",
             "in": "body",
             "required": true,
             "schema": {
-              "$ref": "#/definitions/v1alpha1EmailRequest"
+              "$ref": "#/definitions/v1alpha1Template"
             }
           }
         ],
         "tags": [
-          "EmailService"
+          "TemplateService"
         ]
       }
     }
   },
   "definitions": {
-    "v1alpha1EmailRequest": {
+    "AttachmentDisposition": {
+      "type": "string",
+      "enum": [
+        "DISPOSITION_UNSPECIFIED",
+        "ATTACHMENT",
+        "INLINE"
+      ],
+      "default": "DISPOSITION_UNSPECIFIED",
+      "description": "- DISPOSITION_UNSPECIFIED: The same as ATTACHMENT\n - ATTACHMENT: Shown as the downloadable file\n - INLINE: Embedded in the HTML body and referenced with cid:content_id",
+      "title": "Disposition tells how the attachment is presented"
+    },
+    "MessageState": {
+      "type": "string",
+      "enum": [
+        "STATE_UNSPECIFIED",
+        "QUEUED",
+        "SENDING",
+        "SENT",
+        "DEFERRED",
+        "BOUNCED",
+        "FAILED",
+        "CANCELED",
+        "DELIVERED",
+        "OPENED"
+      ],
+      "default": "STATE_UNSPECIFIED",
+      "description": "- QUEUED: Waits in the queue for the delivery\n - SENDING: Is being delivered to the provider\n - SENT: Was accepted by the provider\n - DEFERRED: Failed temporarily and waits for the next attempt\n - BOUNCED: Was rejected by the recipient mail server\n - FAILED: Could not be delivered\n - CANCELED: Was canceled before the delivery\n - DELIVERED: Was accepted by the recipient mail server\n - OPENED: Was opened by the recipient",
+      "title": "State is the step of the message lifecycle"
+    },
+    "SuppressionReason": {
+      "type": "string",
+      "enum": [
+        "REASON_UNSPECIFIED",
+        "BOUNCE",
+        "COMPLAINT",
+        "UNSUBSCRIBE",
+        "MANUAL"
+      ],
+      "default": "REASON_UNSPECIFIED",
+      "description": "- BOUNCE: The mail server of the recipient rejected the mail permanently\n - COMPLAINT: The recipient marked the mail as spam\n - UNSUBSCRIBE: The recipient does not want to receive mails\n - MANUAL: Added by the operator",
+      "title": "Why the address is suppressed"
+    },
+    "ValidateAddressResponseMailServer": {
+      "type": "string",
+      "enum": [
+        "MAIL_SERVER_UNSPECIFIED",
+        "FOUND",
+        "NOT_FOUND",
+        "UNKNOWN"
+      ],
+      "default": "MAIL_SERVER_UNSPECIFIED",
+      "title": "- MAIL_SERVER_UNSPECIFIED: The mail server was not looked up\n - FOUND: The domain has MX record or, without it, the address record\n - NOT_FOUND: The domain does not exist, has no records or has the null MX record\n - UNKNOWN: The lookup failed temporarily"
+    },
+    "ValidateAddressResponseResult": {
+      "type": "string",
+      "enum": [
+        "RESULT_UNSPECIFIED",
+        "VALID",
+        "INVALID_SYNTAX",
+        "INVALID_DOMAIN",
+        "NO_MAIL_SERVER",
+        "DISPOSABLE"
+      ],
+      "default": "RESULT_UNSPECIFIED",
+      "title": "- VALID: The address can receive emails as far as it can be checked\n - INVALID_SYNTAX: The address is not the RFC 5322 addr-spec\n - INVALID_DOMAIN: The domain is not the valid host name, e.g. has the unknown character\n - NO_MAIL_SERVER: The domain does not exist or does not accept emails\n - DISPOSABLE: The domain gives out temporary addresses and they are rejected"
+    },
+    "v1alpha1APIKey": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string",
+          "description": "Identifier of the key, generated on create. It is a part of the key."
+        },
+        "name": {
+          "type": "string",
+          "title": "Who uses the key e.g. the name of the client application"
+        },
+        "admin": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "The admin key can call AdminService and APIKeyService"
+        },
+        "key": {
+          "type": "string",
+          "title": "The key, returned only when it is created or rotated"
+        },
+        "created_at": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "rotated_at": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "previous_expires_at": {
+          "type": "string",
+          "format": "date-time",
+          "title": "Until when the key replaced by the last rotation is accepted"
+        },
+        "revoked_at": {
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "description": "APIKey authenticates the caller, which sends it in the authorization\nmetadata or the Authorization header, e.g. \"Bearer em_0123...\"."
+    },
+    "v1alpha1Address": {
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "title": "The mailbox e.g. jane.doe@example.com"
+        },
+        "name": {
+          "type": "string",
+          "title": "The display name e.g. Jane Doe"
+        }
+      },
+      "title": "Address is a single mailbox, optionally with a display name"
+    },
+    "v1alpha1Attachment": {
+      "type": "object",
+      "properties": {
+        "filename": {
+          "type": "string"
+        },
+        "content_type": {
+          "type": "string",
+          "title": "Type of the content e.g. application/pdf, it is detected from the content when empty"
+        },
+        "content": {
+          "type": "string",
+          "format": "byte"
+        },
+        "disposition": {
+          "$ref": "#/definitions/AttachmentDisposition"
+        },
+        "content_id": {
+          "type": "string",
+          "title": "Identifier used by the HTML body e.g. \u003cimg src=\"cid:logo\"\u003e, required for INLINE"
+        }
+      },
+      "title": "Attachment is the file sent together with the email"
+    },
+    "v1alpha1BatchRecipient": {
+      "type": "object",
+      "properties": {
+        "address": {
+          "$ref": "#/definitions/v1alpha1Address"
+        },
+        "variables": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          },
+          "title": "Variables of the template which override the shared ones"
+        }
+      },
+      "title": "BatchRecipient receives its own copy of the batch email"
+    },
+    "v1alpha1BatchResult": {
+      "type": "object",
+      "properties": {
+        "message_id": {
+          "type": "string",
+          "title": "Identifier of the accepted message, empty on error"
+        },
+        "provider": {
+          "type": "string",
+          "title": "Name of the provider which delivered the message, empty when the message was queued"
+        },
+        "code": {
+          "type": "integer",
+          "format": "int32",
+          "title": "The gRPC status code, 0 when the message was accepted"
+        },
+        "error": {
+          "type": "string"
+        },
+        "suppressed": {
+          "$ref": "#/definitions/v1alpha1Suppression",
+          "title": "The recipient was not sent the message because it is suppressed"
+        }
+      },
+      "title": "BatchResult is the outcome of sending the email to the single recipient"
+    },
+    "v1alpha1Callback": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string",
+          "title": "Identifier of the callback, generated on create"
+        },
+        "url": {
+          "type": "string",
+          "title": "The http or https URL which receives the events"
+        },
+        "secret": {
+          "type": "string",
+          "title": "Secret of the HMAC-SHA256 signature of events, generated when empty on\ncreate and returned only then"
+        },
+        "types": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1MessageEventType"
+          }
+        },
+        "tags": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "from": {
+          "type": "string",
+          "title": "Email of the sender"
+        },
+        "disabled": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "The callback is disabled after repeated failures"
+        },
+        "consecutive_failures": {
+          "type": "integer",
+          "format": "int32",
+          "title": "Number of failed attempts since the last successful one"
+        },
+        "created_at": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "disabled_at": {
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "description": "Callback is the URL to which the events of messages are posted as JSON. The\nevent must match every given filter and any value of the filter."
+    },
+    "v1alpha1CallbackAttempt": {
+      "type": "object",
+      "properties": {
+        "delivery_id": {
+          "type": "string",
+          "description": "Identifier of the event delivery, the same in every retry of the event.\nIt is sent in the X-Webhook-Id header."
+        },
+        "message_id": {
+          "type": "string"
+        },
+        "event_type": {
+          "$ref": "#/definitions/v1alpha1MessageEventType"
+        },
+        "attempt": {
+          "type": "integer",
+          "format": "int32",
+          "title": "Number of the attempt of the delivery, starting from 1"
+        },
+        "time": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "status_code": {
+          "type": "integer",
+          "format": "int32",
+          "title": "HTTP status code of the response, 0 when no response was received"
+        },
+        "error": {
+          "type": "string"
+        },
+        "success": {
+          "type": "boolean",
+          "format": "boolean"
+        }
+      },
+      "title": "CallbackAttempt is the single attempt to post the event to the callback"
+    },
+    "v1alpha1DeadLetter": {
       "type": "object",
       "properties": {
         "message": {
+          "$ref": "#/definitions/v1alpha1Message"
+        },
+        "request": {
+          "$ref": "#/definitions/v1alpha1EmailRequest",
+          "title": "The email as it was sent"
+        }
+      },
+      "title": "DeadLetter is the message which could not be delivered"
+    },
+    "v1alpha1DeleteCallbackResponse": {
+      "type": "object",
+      "title": "DeleteCallbackResponse is returned when the callback was removed"
+    },
+    "v1alpha1DeleteSuppressionResponse": {
+      "type": "object",
+      "title": "DeleteSuppressionResponse is returned when the address was removed"
+    },
+    "v1alpha1DeleteTemplateResponse": {
+      "type": "object",
+      "title": "DeleteTemplateResponse is returned when the template was removed"
+    },
+    "v1alpha1EmailRequest": {
+      "type": "object",
+      "properties": {
+        "from": {
+          "$ref": "#/definitions/v1alpha1Address"
+        },
+        "reply_to": {
+          "$ref": "#/definitions/v1alpha1Address"
+        },
+        "to": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Address"
+          }
+        },
+        "cc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Address"
+          }
+        },
+        "bcc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Address"
+          }
+        },
+        "subject": {
+          "type": "string"
+        },
+        "text_body": {
+          "type": "string"
+        },
+        "html_body": {
           "type": "string"
+        },
+        "headers": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          },
+          "description": "Custom headers e.g. X-Campaign-Id. Headers that are derived from\nthe envelope like From or Subject can not be overridden."
+        },
+        "template_id": {
+          "type": "string",
+          "title": "Identifier of the stored template used instead of the subject and bodies"
+        },
+        "variables": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          },
+          "title": "Variables available in the template e.g. {{.name}}"
+        },
+        "attachments": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Attachment"
+          },
+          "title": "Files attached to the email or embedded in the HTML body"
+        },
+        "tags": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          },
+          "description": "Tags label the message e.g. newsletter, at most 10. They are passed to\nthe providers which support them and can be used to filter the events."
+        },
+        "send_at": {
+          "type": "string",
+          "format": "date-time",
+          "description": "When the email should be sent, at most 30 days ahead. The email is sent\nimmediately when it is not set or already passed. Requires the queue."
         }
-      }
+      },
+      "description": "At least one recipient in to, cc or bcc is required together with\nthe sender, the subject and a text or HTML body.",
+      "title": "EmailRequest describes the envelope and the content of a single email"
     },
     "v1alpha1EmailResponse": {
       "type": "object",
       "properties": {
         "error": {
           "type": "string"
+        },
+        "message_id": {
+          "type": "string",
+          "title": "Identifier of the accepted message"
+        },
+        "provider": {
+          "type": "string",
+          "title": "Name of the provider which delivered the message, empty when the message was queued"
+        },
+        "suppressed": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Suppression"
+          },
+          "description": "Recipients which were dropped because they are suppressed. The message is\nnot sent at all when every recipient is suppressed."
+        }
+      }
+    },
+    "v1alpha1ListAPIKeysResponse": {
+      "type": "object",
+      "properties": {
+        "api_keys": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1APIKey"
+          }
+        },
+        "next_page_token": {
+          "type": "string",
+          "title": "Token of the next page, empty on the last one"
+        }
+      },
+      "title": "ListAPIKeysResponse is the page of keys ordered by id"
+    },
+    "v1alpha1ListCallbackAttemptsResponse": {
+      "type": "object",
+      "properties": {
+        "attempts": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1CallbackAttempt"
+          }
+        },
+        "next_page_token": {
+          "type": "string",
+          "title": "Token of the next page, empty on the last one"
+        }
+      },
+      "title": "ListCallbackAttemptsResponse is the page of attempts ordered by time"
+    },
+    "v1alpha1ListCallbacksResponse": {
+      "type": "object",
+      "properties": {
+        "callbacks": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Callback"
+          }
+        },
+        "next_page_token": {
+          "type": "string",
+          "title": "Token of the next page, empty on the last one"
+        }
+      },
+      "title": "ListCallbacksResponse is the page of callbacks ordered by id"
+    },
+    "v1alpha1ListDeadLettersResponse": {
+      "type": "object",
+      "properties": {
+        "messages": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Message"
+          }
+        },
+        "next_page_token": {
+          "type": "string",
+          "title": "Token of the next page, empty on the last one"
+        }
+      },
+      "title": "ListDeadLettersResponse is the page of dead-lettered messages ordered by id"
+    },
+    "v1alpha1ListSuppressionsResponse": {
+      "type": "object",
+      "properties": {
+        "suppressions": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Suppression"
+          }
+        },
+        "next_page_token": {
+          "type": "string",
+          "title": "Token of the next page, empty on the last one"
+        }
+      },
+      "title": "ListSuppressionsResponse is the page of suppressed addresses ordered by email"
+    },
+    "v1alpha1ListTemplatesResponse": {
+      "type": "object",
+      "properties": {
+        "templates": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1Template"
+          }
+        },
+        "next_page_token": {
+          "type": "string",
+          "title": "Token of the next page, empty on the last one"
+        }
+      },
+      "title": "ListTemplatesResponse is the page of templates ordered by id"
+    },
+    "v1alpha1Message": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string",
+          "title": "Identifier of the message"
+        },
+        "state": {
+          "$ref": "#/definitions/MessageState"
+        },
+        "created_at": {
+          "type": "string",
+          "format": "date-time",
+          "title": "When the message was accepted"
+        },
+        "updated_at": {
+          "type": "string",
+          "format": "date-time",
+          "title": "When the state of the message changed for the last time"
+        },
+        "next_attempt_at": {
+          "type": "string",
+          "format": "date-time",
+          "title": "When the next delivery attempt is due, set only for queued and deferred messages"
+        },
+        "provider": {
+          "type": "string",
+          "title": "Name of the provider which delivered the message"
+        },
+        "provider_message_id": {
+          "type": "string",
+          "title": "Identifier of the message assigned by the provider"
+        },
+        "attempts": {
+          "type": "integer",
+          "format": "int32",
+          "title": "Number of delivery attempts"
+        },
+        "last_error": {
+          "type": "string",
+          "title": "Error of the last failed attempt"
+        }
+      },
+      "title": "Message is the email accepted by the service together with its delivery state"
+    },
+    "v1alpha1MessageEvent": {
+      "type": "object",
+      "properties": {
+        "message_id": {
+          "type": "string"
+        },
+        "type": {
+          "$ref": "#/definitions/v1alpha1MessageEventType"
+        },
+        "time": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "from": {
+          "type": "string",
+          "title": "Email of the sender"
+        },
+        "tags": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "provider": {
+          "type": "string"
+        },
+        "attempts": {
+          "type": "integer",
+          "format": "int32"
+        },
+        "error": {
+          "type": "string",
+          "title": "The reason of DEFERRED, BOUNCED and FAILED events"
+        }
+      },
+      "title": "MessageEvent is the change in the lifecycle of the message"
+    },
+    "v1alpha1MessageEventType": {
+      "type": "string",
+      "enum": [
+        "TYPE_UNSPECIFIED",
+        "QUEUED",
+        "SENDING",
+        "SENT",
+        "DEFERRED",
+        "DELIVERED",
+        "BOUNCED",
+        "OPENED",
+        "FAILED",
+        "CANCELED"
+      ],
+      "default": "TYPE_UNSPECIFIED",
+      "title": "- QUEUED: Accepted and waits in the queue\n - SENDING: Taken by the worker for the delivery attempt\n - SENT: Accepted by the provider\n - DEFERRED: Failed temporarily and waits for the next attempt\n - DELIVERED: Accepted by the recipient mail server\n - BOUNCED: Rejected by the recipient mail server\n - OPENED: Opened by the recipient\n - FAILED: Failed permanently or ran out of retries\n - CANCELED: Canceled before the delivery"
+    },
+    "v1alpha1PurgeDeadLettersRequest": {
+      "type": "object",
+      "properties": {
+        "ids": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "all": {
+          "type": "boolean",
+          "format": "boolean"
+        },
+        "before": {
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "description": "Either ids or all must be set. With all every message which failed before\nthe given time is removed, or every message when the time is not set.",
+      "title": "PurgeDeadLettersRequest selects dead-lettered messages to remove"
+    },
+    "v1alpha1PurgeDeadLettersResponse": {
+      "type": "object",
+      "properties": {
+        "purged": {
+          "type": "integer",
+          "format": "int32"
+        }
+      },
+      "title": "PurgeDeadLettersResponse contains the number of removed messages"
+    },
+    "v1alpha1RequeueDeadLetterRequest": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string"
+        }
+      },
+      "title": "RequeueDeadLetterRequest identifies the dead-lettered message"
+    },
+    "v1alpha1RescheduleMessageRequest": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string",
+          "title": "The message_id returned by SendMail"
+        },
+        "send_at": {
+          "type": "string",
+          "format": "date-time",
+          "title": "When the email should be sent, at most 30 days ahead"
+        }
+      },
+      "title": "RescheduleMessageRequest changes the time of the delivery of the message"
+    },
+    "v1alpha1RotateAPIKeyRequest": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string"
+        },
+        "grace_period": {
+          "type": "string",
+          "title": "How long the old key is still accepted, by default it is rejected at once"
+        }
+      },
+      "title": "RotateAPIKeyRequest identifies the key"
+    },
+    "v1alpha1SendMailBatchRequest": {
+      "type": "object",
+      "properties": {
+        "email": {
+          "$ref": "#/definitions/v1alpha1EmailRequest",
+          "description": "The email shared by the recipients, its to, cc and bcc must be empty.\nThe variables are the defaults of the recipient variables."
+        },
+        "recipients": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1BatchRecipient"
+          },
+          "title": "The recipients, at most 1000"
+        }
+      },
+      "title": "SendMailBatchRequest is the email sent to many recipients"
+    },
+    "v1alpha1SendMailBatchResponse": {
+      "type": "object",
+      "properties": {
+        "results": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/v1alpha1BatchResult"
+          },
+          "title": "The results in the order of recipients"
         }
       }
+    },
+    "v1alpha1Suppression": {
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "title": "The suppressed mailbox, compared case insensitive"
+        },
+        "reason": {
+          "$ref": "#/definitions/SuppressionReason"
+        },
+        "description": {
+          "type": "string",
+          "title": "Details of the reason e.g. the response of the mail server"
+        },
+        "created_at": {
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "title": "Suppression is the address to which mails are not sent"
+    },
+    "v1alpha1Template": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string",
+          "title": "Identifier of the template e.g. welcome, generated when empty on create"
+        },
+        "subject": {
+          "type": "string"
+        },
+        "text_body": {
+          "type": "string"
+        },
+        "html_body": {
+          "type": "string"
+        },
+        "created_at": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "updated_at": {
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "description": "The subject and the text body are Go text/template templates, the HTML body\nis Go html/template template, so the variables are escaped.",
+      "title": "Template is the content of the mail rendered with the variables of SendMail"
+    },
+    "v1alpha1ValidateAddressRequest": {
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "title": "The mailbox e.g. jane.doe@example.com, without the display name"
+        }
+      },
+      "title": "ValidateAddressRequest is the address to check"
+    },
+    "v1alpha1ValidateAddressResponse": {
+      "type": "object",
+      "properties": {
+        "valid": {
+          "type": "boolean",
+          "format": "boolean"
+        },
+        "result": {
+          "$ref": "#/definitions/ValidateAddressResponseResult"
+        },
+        "detail": {
+          "type": "string",
+          "title": "Why the address is not valid"
+        },
+        "email": {
+          "type": "string",
+          "title": "The address with the domain converted to punycode, which is used to send the emails"
+        },
+        "unicode_email": {
+          "type": "string",
+          "title": "The address with the domain in Unicode"
+        },
+        "disposable": {
+          "type": "boolean",
+          "format": "boolean",
+          "title": "Whether the domain gives out temporary addresses"
+        },
+        "mail_server": {
+          "$ref": "#/definitions/ValidateAddressResponseMailServer"
+        }
+      },
+      "title": "ValidateAddressResponse is the result of the check of the address"
     }
   }
 }
@@ -0,0 +1,164 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package auth authenticates the callers with API keys, client certificates
+// or the JWT bearer tokens of the OIDC provider.
+// The key is sent in the authorization metadata of gRPC calls, or in the
+// Authorization header which the gateway passes as such metadata, e.g.
+// "Bearer em_0123...". Only the salted and stretched hash of the key is
+// stored, so the key can not be read back from the store.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// keyPrefix marks the API keys, so they can be found e.g. by secret scanners
+	keyPrefix = "em_"
+	// idBytes and secretBytes are the random parts of the key
+	idBytes     = 8
+	secretBytes = 32
+	// hashCost of bcrypt makes guessing the keys from the stolen store expensive
+	hashCost = bcrypt.DefaultCost
+)
+
+const (
+	// MethodKey authenticates the caller with the API key
+	MethodKey = "key"
+	// MethodCertificate authenticates the caller with the client certificate
+	MethodCertificate = "cert"
+)
+
+// Identity is the authenticated caller
+type Identity struct {
+	// Method by which the caller was authenticated
+	Method string
+	// ID identifies the caller authenticated by the method, e.g. the id of the API key
+	ID string
+	// Name of the caller
+	Name string
+	// Admin can manage the service, e.g. the API keys
+	Admin bool
+	// Scopes granted to the caller by the token
+	Scopes []string
+}
+
+// HasScope reports whether the token granted the scope to the caller
+func (id *Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the method and the id of the caller, e.g. "key:0123456789abcdef"
+func (id *Identity) String() string {
+	return id.Method + ":" + id.ID
+}
+
+type identityKey struct{}
+
+// NewContext returns the context of the call made by the identity
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity of the authenticated caller
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// Key is the stored API key. The key itself is kept only as the hash.
+type Key struct {
+	ID    string
+	Name  string
+	Admin bool
+	Hash  string
+	// PreviousHash is the hash of the key replaced by the rotation, which is
+	// accepted until PreviousExpiresAt
+	PreviousHash      string    `json:",omitempty"`
+	PreviousExpiresAt time.Time `json:",omitempty"`
+	CreatedAt         time.Time
+	RotatedAt         time.Time `json:",omitempty"`
+	RevokedAt         time.Time `json:",omitempty"`
+}
+
+// Revoked reports whether the key was revoked
+func (k *Key) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// KeyStore keeps the API keys. StorageKeyStore keeps them in the local store,
+// the other implementation can keep them e.g. in the database shared by all
+// instances of the service.
+type KeyStore interface {
+	// Get returns the key, the missing key is not an error
+	Get(id string) (*Key, bool, error)
+	// Put creates or replaces the key
+	Put(key *Key) error
+	// List returns up to limit keys whose id is greater than after, ordered by id
+	List(after string, limit int) ([]*Key, error)
+}
+
+// newKey generates the id and the secret of the key. The id is a part of the
+// key, so the key can be found without scanning all hashes.
+func newKey() (id, key string, err error) {
+	b := make([]byte, idBytes+secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b[:idBytes])
+	return id, keyPrefix + id + "_" + hex.EncodeToString(b[idBytes:]), nil
+}
+
+// keyID returns the id of the well-formed key
+func keyID(key string) (string, bool) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(key, keyPrefix), "_")
+	if len(parts) != 2 || len(parts[0]) != 2*idBytes || len(parts[1]) != 2*secretBytes {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// hashKey returns the bcrypt hash of the random part of the key. The id
+// and the prefix are not secret, and bcrypt uses only 72 bytes.
+func hashKey(key string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(keySecret(key)), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// verifyKey checks the key against the hash in constant time
+func verifyKey(key, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(keySecret(key))) == nil
+}
+
+// keySecret returns the random part of the key, which follows the id
+func keySecret(key string) string {
+	return key[strings.LastIndex(key, "_")+1:]
+}
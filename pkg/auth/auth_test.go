@@ -0,0 +1,328 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestKeys(t *testing.T) *Keys {
+	keys := NewKeys(NewStorageKeyStore(openTestStore(t, "apikeys")))
+	keys.cost = bcrypt.MinCost
+	return keys
+}
+
+func TestHashKey(t *testing.T) {
+	// Arrange
+	_, key, err := newKey()
+	assert.NoError(t, err, "Key should be generated")
+
+	// Act
+	hash, err := hashKey(key, bcrypt.MinCost)
+
+	// Assert
+	assert.NoError(t, err, "Key should be hashed")
+	assert.Regexp(t, `^\$2a\$04\$[./A-Za-z0-9]{53}$`, hash, "Hash must be in the bcrypt format")
+	assert.NotContains(t, hash, key[len(keyPrefix):], "Hash must not contain the key")
+	assert.True(t, verifyKey(key, hash), "Key must match its hash")
+	assert.False(t, verifyKey(key+"0", hash), "Other key must not match the hash")
+	assert.False(t, verifyKey(key, "$pbkdf2-sha256$10$x$y"), "Unknown scheme must not match")
+	other, err := hashKey(key, bcrypt.MinCost)
+	assert.NoError(t, err, "Key should be hashed")
+	assert.NotEqual(t, hash, other, "Hashes of the same key must be salted")
+}
+
+func TestKeys(t *testing.T) {
+	// Arrange
+	keys := newTestKeys(t)
+	now := time.Unix(1500000000, 0)
+	keys.now = func() time.Time { return now }
+
+	t.Run("Created key is authenticated", func(t *testing.T) {
+		// Act
+		key, secret, err := keys.Create("billing", false)
+
+		// Assert
+		assert.NoError(t, err, "Key should be created")
+		id, _ := keyID(secret)
+		assert.Equal(t, key.ID, id, "Key must contain its id")
+		identity, err := keys.Authenticate(secret)
+		assert.NoError(t, err, "Key should be authenticated")
+		assert.Equal(t, &Identity{Method: MethodKey, ID: key.ID, Name: "billing"}, identity, "Identity must be the key")
+		identity, err = keys.Authenticate(secret)
+		assert.NoError(t, err, "Verified key should be authenticated again")
+	})
+
+	t.Run("Invalid keys are rejected", func(t *testing.T) {
+		// Arrange
+		_, secret, err := keys.Create("billing", false)
+		assert.NoError(t, err, "Key should be created")
+
+		last := "0"
+		if strings.HasSuffix(secret, last) {
+			last = "1"
+		}
+		for _, invalid := range []string{"", "secret", secret[:len(secret)-1] + "x",
+			secret[:len(secret)-1] + last, keyPrefix + "0123456789abcdef_" + secret[len(secret)-64:]} {
+			// Act
+			_, err := keys.Authenticate(invalid)
+
+			// Assert
+			assert.Equal(t, codes.Unauthenticated, status.Code(err), "Key %q must be rejected", invalid)
+		}
+	})
+
+	t.Run("Rejected key is remembered", func(t *testing.T) {
+		// Arrange
+		key, secret, err := keys.Create("billing", false)
+		assert.NoError(t, err, "Key should be created")
+		invalid := secret[:len(secret)-1] + "x"
+
+		// Act
+		_, err = keys.Authenticate(invalid)
+		_, again := keys.Authenticate(invalid)
+
+		// Assert
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Invalid key must be rejected")
+		assert.Equal(t, codes.Unauthenticated, status.Code(again), "Remembered invalid key must be rejected")
+		assert.Equal(t, key.Hash, keys.rejected[sha256.Sum256([]byte(invalid))], "Invalid key must not be hashed again")
+		_, err = keys.Authenticate(secret)
+		assert.NoError(t, err, "Valid key should be authenticated")
+	})
+
+	t.Run("Revoked key is rejected", func(t *testing.T) {
+		// Arrange
+		key, secret, err := keys.Create("billing", false)
+		assert.NoError(t, err, "Key should be created")
+		_, err = keys.Authenticate(secret)
+		assert.NoError(t, err, "Key should be authenticated")
+
+		// Act
+		revoked, err := keys.Revoke(key.ID)
+
+		// Assert
+		assert.NoError(t, err, "Key should be revoked")
+		assert.Equal(t, now, revoked.RevokedAt, "Revocation time must be stored")
+		_, err = keys.Authenticate(secret)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Revoked key must be rejected")
+		_, _, err = keys.Rotate(key.ID, 0)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Revoked key must not be rotated")
+	})
+
+	t.Run("Rotated key is accepted for the grace period", func(t *testing.T) {
+		// Arrange
+		key, old, err := keys.Create("billing", true)
+		assert.NoError(t, err, "Key should be created")
+
+		// Act
+		rotated, secret, err := keys.Rotate(key.ID, time.Hour)
+
+		// Assert
+		assert.NoError(t, err, "Key should be rotated")
+		assert.Equal(t, key.ID, rotated.ID, "Rotated key must keep its id")
+		assert.NotEqual(t, old, secret, "Rotated key must be new")
+		assert.Equal(t, now.Add(time.Hour), rotated.PreviousExpiresAt, "Old key must expire after the grace period")
+		identity, err := keys.Authenticate(secret)
+		assert.NoError(t, err, "New key should be authenticated")
+		assert.True(t, identity.Admin, "Rotated key must keep the admin role")
+		_, err = keys.Authenticate(old)
+		assert.NoError(t, err, "Old key should be authenticated in the grace period")
+		now = now.Add(time.Hour)
+		_, err = keys.Authenticate(old)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Old key must be rejected after the grace period")
+
+		// Act
+		_, newest, err := keys.Rotate(key.ID, 0)
+
+		// Assert
+		assert.NoError(t, err, "Key should be rotated")
+		_, err = keys.Authenticate(secret)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Key rotated without the grace period must be rejected")
+		_, err = keys.Authenticate(newest)
+		assert.NoError(t, err, "New key should be authenticated")
+	})
+
+	t.Run("Invalid requests are rejected", func(t *testing.T) {
+		_, _, err := keys.Create(" ", false)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Empty name must be rejected")
+		_, _, err = keys.Create("a\nb", false)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Name with new line must be rejected")
+		_, err = keys.Revoke("missing")
+		assert.Equal(t, codes.NotFound, status.Code(err), "Missing key must not be revoked")
+		key, _, err := keys.Create("billing", false)
+		assert.NoError(t, err, "Key should be created")
+		_, _, err = keys.Rotate(key.ID, -time.Second)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Negative grace period must be rejected")
+	})
+}
+
+func TestStorageKeyStore_List(t *testing.T) {
+	// Arrange
+	keys := newTestKeys(t)
+	created := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		key, _, err := keys.Create("key", false)
+		assert.NoError(t, err, "Key should be created")
+		created[key.ID] = true
+	}
+
+	// Act
+	first, err := keys.List("", 3)
+	assert.NoError(t, err, "Keys should be listed")
+	second, err := keys.List(first[len(first)-1].ID, 3)
+	assert.NoError(t, err, "Keys should be listed")
+
+	// Assert
+	assert.Len(t, first, 3, "First page must be full")
+	assert.Len(t, second, 2, "Second page must have the remaining keys")
+	for i, key := range append(first, second...) {
+		assert.True(t, created[key.ID], "Listed key %d must be created", i)
+		assert.NotEmpty(t, key.Hash, "Listed key %d must have its hash", i)
+		if i > 0 {
+			assert.True(t, key.ID > append(first, second...)[i-1].ID, "Keys must be ordered by id")
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	// Arrange
+	keys := newTestKeys(t)
+	_, user, err := keys.Create("user", false)
+	assert.NoError(t, err, "Key should be created")
+	_, admin, err := keys.Create("admin", true)
+	assert.NoError(t, err, "Key should be created")
+	isAdmin := func(method string) bool { return method == "/Admin" }
+
+	call := func(required bool, method string, md metadata.MD) (*Identity, error) {
+		var identity *Identity
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := UnaryServerInterceptor(required, isAdmin, keys.AuthenticateContext)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				identity, _ = FromContext(ctx)
+				return nil, nil
+			})
+		return identity, err
+	}
+
+	testCases := []struct {
+		name     string
+		required bool
+		method   string
+		md       metadata.MD
+		code     codes.Code
+		identity string
+	}{
+		{name: "Bearer key is accepted", required: true, method: "/Send",
+			md: metadata.Pairs("authorization", "Bearer "+user), code: codes.OK, identity: "user"},
+		{name: "Bare key is accepted", required: true, method: "/Send",
+			md: metadata.Pairs("authorization", user), code: codes.OK, identity: "user"},
+		{name: "Missing key is rejected", required: true, method: "/Send",
+			md: metadata.MD{}, code: codes.Unauthenticated},
+		{name: "Invalid key is rejected", required: true, method: "/Send",
+			md: metadata.Pairs("authorization", "Bearer secret"), code: codes.Unauthenticated},
+		{name: "User key can not call the admin method", required: true, method: "/Admin",
+			md: metadata.Pairs("authorization", "Bearer "+user), code: codes.PermissionDenied},
+		{name: "Admin key can call the admin method", required: true, method: "/Admin",
+			md: metadata.Pairs("authorization", "bearer "+admin), code: codes.OK, identity: "admin"},
+		{name: "Optional authentication accepts the calls without the key", method: "/Admin",
+			md: metadata.MD{}, code: codes.OK},
+		{name: "Optional authentication rejects the invalid key", method: "/Send",
+			md: metadata.Pairs("authorization", "Bearer em_secret"), code: codes.Unauthenticated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			identity, err := call(tc.required, tc.method, tc.md)
+
+			// Assert
+			assert.Equal(t, tc.code, status.Code(err), "Status code must match")
+			if tc.identity != "" {
+				assert.Equal(t, tc.identity, identity.Name, "Identity must be passed to the handler")
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	// Arrange
+	keys := newTestKeys(t)
+	_, secret, err := keys.Create("prometheus", false)
+	assert.NoError(t, err, "Key should be created")
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := FromContext(r.Context())
+		w.Write([]byte(identity.Name))
+	}), keys.AuthenticateContext)
+
+	for _, header := range []string{"", "Bearer secret", "Bearer " + secret + "0"} {
+		// Act
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		handler.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "Request with %q must be rejected", header)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"), "Authentication scheme must be sent")
+	}
+
+	// Act
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code, "Request with the key must be served")
+	assert.Equal(t, "prometheus", rec.Body.String(), "Identity must be passed to the handler")
+
+	t.Run("Verified client certificate is accepted", func(t *testing.T) {
+		// Arrange
+		cert := newTestCertificate(t, pkix.Name{CommonName: "prometheus"}, nil, "")
+		certs, err := NewCertificates([]CertificateIdentity{{Name: "prometheus", Subject: "CN=prometheus"}}, nil, nil)
+		assert.NoError(t, err, "Certificates should be created")
+		handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, _ := FromContext(r.Context())
+			w.Write([]byte(identity.String()))
+		}), keys.AuthenticateContext, certs.AuthenticateContext)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+
+		// Act
+		handler.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Request with the certificate must be served")
+		assert.Equal(t, "cert:prometheus", rec.Body.String(), "Identity must be the certificate")
+	})
+}
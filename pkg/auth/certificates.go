@@ -0,0 +1,214 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ClientCertificateMetadata carries the certificate of the REST client, which
+// the gateway forwards to the gRPC server
+const ClientCertificateMetadata = "x-client-certificate"
+
+// MTLSConfig requires the client certificates
+type MTLSConfig struct {
+	// ClientCAFile is the PEM bundle of the authorities of the client certificates
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// Identities map the certificates to the callers
+	Identities []CertificateIdentity `mapstructure:"identities"`
+}
+
+// Enabled reports whether the client certificates are required
+func (c MTLSConfig) Enabled() bool {
+	return c.ClientCAFile != ""
+}
+
+// CertificateIdentity is the caller with the certificate which matches all
+// of the set fields
+type CertificateIdentity struct {
+	Name  string `mapstructure:"name"`
+	Admin bool   `mapstructure:"admin"`
+	// Subject is the distinguished name, e.g. "CN=billing,O=Example"
+	Subject string `mapstructure:"subject"`
+	// DNSName, URI and Email are matched against the subject alternative names
+	DNSName string `mapstructure:"dns_name"`
+	URI     string `mapstructure:"uri"`
+	Email   string `mapstructure:"email"`
+}
+
+func (ci CertificateIdentity) matches(cert *x509.Certificate) bool {
+	if ci.Subject != "" && ci.Subject != cert.Subject.String() {
+		return false
+	}
+	if ci.DNSName != "" && !containsFold(cert.DNSNames, ci.DNSName) {
+		return false
+	}
+	if ci.Email != "" && !containsFold(cert.EmailAddresses, ci.Email) {
+		return false
+	}
+	if ci.URI != "" {
+		found := false
+		for _, u := range cert.URIs {
+			found = found || u.String() == ci.URI
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Certificates identifies the callers by the verified client certificates
+type Certificates struct {
+	identities []CertificateIdentity
+	// roots are the authorities of the client certificates, the certificates
+	// forwarded by the gateway are verified against them
+	roots *x509.CertPool
+	// gateway is the certificate with which the gateway calls the gRPC
+	// server, only its calls can forward the certificates of the clients
+	gateway []byte
+}
+
+// NewCertificates creates the mapping of the certificates signed by the roots.
+// The gateway certificate is trusted to forward the certificates of the REST
+// clients.
+func NewCertificates(identities []CertificateIdentity, roots *x509.CertPool,
+	gateway *x509.Certificate) (*Certificates, error) {
+	for i, ci := range identities {
+		if ci.Name == "" {
+			return nil, fmt.Errorf("certificate identity %d has no name", i)
+		}
+		if ci.Subject == "" && ci.DNSName == "" && ci.URI == "" && ci.Email == "" {
+			return nil, fmt.Errorf("certificate identity %q matches every certificate", ci.Name)
+		}
+	}
+	c := &Certificates{identities: identities, roots: roots}
+	if gateway != nil {
+		c.gateway = gateway.Raw
+	}
+	return c, nil
+}
+
+// Identify returns the caller with the certificate. The certificate without
+// the mapping is identified by its subject and is not the admin.
+func (c *Certificates) Identify(cert *x509.Certificate) *Identity {
+	for _, ci := range c.identities {
+		if ci.matches(cert) {
+			return &Identity{Method: MethodCertificate, ID: ci.Name, Name: ci.Name, Admin: ci.Admin}
+		}
+	}
+	subject := cert.Subject.String()
+	return &Identity{Method: MethodCertificate, ID: subject, Name: subject}
+}
+
+// AuthenticateContext returns the caller with the certificate verified by
+// the TLS handshake, or the REST client with the certificate forwarded by the
+// gateway. It returns nil for the calls without the certificate.
+func (c *Certificates) AuthenticateContext(ctx context.Context) (*Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil, nil
+	}
+	cert := info.State.VerifiedChains[0][0]
+	if c.gateway == nil || !bytes.Equal(cert.Raw, c.gateway) {
+		return c.Identify(cert), nil
+	}
+
+	// The gateway is not the caller, it calls on behalf of the REST client
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(ClientCertificateMetadata)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	// The clients can send the metadata through the gateway too, the value
+	// added by the gateway is the last one
+	forwarded, err := c.verifyForwarded(values[len(values)-1])
+	if err != nil {
+		return nil, err
+	}
+	return c.Identify(forwarded), nil
+}
+
+// GatewayMetadata forwards the verified certificate chain of the REST client,
+// it is the metadata annotator of the gateway
+func GatewayMetadata(_ context.Context, r *http.Request) metadata.MD {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	chain := make([]string, len(r.TLS.VerifiedChains[0]))
+	for i, cert := range r.TLS.VerifiedChains[0] {
+		chain[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+	}
+	return metadata.Pairs(ClientCertificateMetadata, strings.Join(chain, ","))
+}
+
+// verifyForwarded parses the forwarded chain, the certificate of the client
+// followed by its intermediates, and verifies it against the roots, so the
+// gateway can not forward the certificate which the TLS handshake would reject
+func (c *Certificates) verifyForwarded(value string) (*x509.Certificate, error) {
+	if c.roots == nil {
+		// The nil pool would verify against the system authorities
+		return nil, status.Error(codes.Unauthenticated, "forwarded client certificates are not trusted")
+	}
+	var chain []*x509.Certificate
+	for _, encoded := range strings.Split(value, ",") {
+		der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "forwarded client certificate is not valid base64")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "forwarded client certificate is not valid: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "forwarded client certificate is not trusted: %v", err)
+	}
+	return chain[0], nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newTestCertificate(t *testing.T, subject pkix.Name, dnsNames []string, uri string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "Key should be generated")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if uri != "" {
+		u, err := url.Parse(uri)
+		assert.NoError(t, err, "URI should be parsed")
+		template.URIs = []*url.URL{u}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err, "Certificate should be created")
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err, "Certificate should be parsed")
+	return cert
+}
+
+func withCertificate(ctx context.Context, cert *x509.Certificate) context.Context {
+	return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{cert}},
+	}}})
+}
+
+func TestCertificates(t *testing.T) {
+	// Arrange
+	billing := newTestCertificate(t, pkix.Name{CommonName: "billing", Organization: []string{"Example"}},
+		[]string{"billing.example.com"}, "")
+	ops := newTestCertificate(t, pkix.Name{CommonName: "ops"}, nil, "spiffe://example.com/ops")
+	other := newTestCertificate(t, pkix.Name{CommonName: "other"}, nil, "")
+	untrusted := newTestCertificate(t, pkix.Name{CommonName: "ops"}, nil, "spiffe://example.com/ops")
+	gateway := newTestCertificate(t, pkix.Name{CommonName: "gateway"}, nil, "")
+	// The certificates are self-signed, so they are their own authorities
+	roots := x509.NewCertPool()
+	for _, cert := range []*x509.Certificate{billing, ops, other} {
+		roots.AddCert(cert)
+	}
+	certs, err := NewCertificates([]CertificateIdentity{
+		{Name: "billing", Subject: "CN=billing,O=Example", DNSName: "Billing.example.com"},
+		{Name: "ops", Admin: true, URI: "spiffe://example.com/ops"},
+	}, roots, gateway)
+	assert.NoError(t, err, "Certificates should be created")
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		identity *Identity
+	}{
+		{name: "Certificate is mapped by subject and DNS name", ctx: withCertificate(context.Background(), billing),
+			identity: &Identity{Method: MethodCertificate, ID: "billing", Name: "billing"}},
+		{name: "Certificate is mapped by URI", ctx: withCertificate(context.Background(), ops),
+			identity: &Identity{Method: MethodCertificate, ID: "ops", Name: "ops", Admin: true}},
+		{name: "Certificate without the mapping is identified by its subject", ctx: withCertificate(context.Background(), other),
+			identity: &Identity{Method: MethodCertificate, ID: "CN=other", Name: "CN=other"}},
+		{name: "Gateway forwards the certificate of its client", ctx: withCertificate(metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(ClientCertificateMetadata, base64.StdEncoding.EncodeToString(other.Raw),
+				ClientCertificateMetadata, base64.StdEncoding.EncodeToString(ops.Raw))), gateway),
+			identity: &Identity{Method: MethodCertificate, ID: "ops", Name: "ops", Admin: true}},
+		{name: "Only the gateway forwards the certificates", ctx: withCertificate(metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(ClientCertificateMetadata, base64.StdEncoding.EncodeToString(ops.Raw))), billing),
+			identity: &Identity{Method: MethodCertificate, ID: "billing", Name: "billing"}},
+		{name: "Call without the certificate has no identity", ctx: peer.NewContext(context.Background(), &peer.Peer{})},
+		{name: "Gateway without the forwarded certificate has no identity", ctx: withCertificate(context.Background(), gateway)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			identity, err := certs.AuthenticateContext(tc.ctx)
+
+			// Assert
+			assert.NoError(t, err, "Caller should be identified")
+			assert.Equal(t, tc.identity, identity, "Identity must match")
+		})
+	}
+
+	t.Run("Invalid forwarded certificate is rejected", func(t *testing.T) {
+		// Arrange
+		ctx := withCertificate(metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(ClientCertificateMetadata, "invalid")), gateway)
+
+		// Act
+		_, err := certs.AuthenticateContext(ctx)
+
+		// Assert
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Invalid certificate must be rejected")
+	})
+
+	t.Run("Forwarded certificate must be signed by the authorities", func(t *testing.T) {
+		// Arrange
+		ctx := withCertificate(metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(ClientCertificateMetadata, base64.StdEncoding.EncodeToString(untrusted.Raw))), gateway)
+
+		// Act
+		_, err := certs.AuthenticateContext(ctx)
+
+		// Assert
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Untrusted certificate must be rejected")
+	})
+
+	t.Run("Gateway forwards the verified certificate", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest("GET", "/v1alpha1/email", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{billing, other}}}
+
+		// Act
+		md := GatewayMetadata(context.Background(), req)
+
+		// Assert
+		assert.Equal(t, []string{base64.StdEncoding.EncodeToString(billing.Raw) + "," +
+			base64.StdEncoding.EncodeToString(other.Raw)}, md.Get(ClientCertificateMetadata),
+			"Certificate chain must be forwarded")
+		assert.Empty(t, GatewayMetadata(context.Background(), httptest.NewRequest("GET", "/", nil)),
+			"Request without the certificate must not forward anything")
+	})
+}
+
+func TestNewCertificates(t *testing.T) {
+	_, err := NewCertificates([]CertificateIdentity{{Subject: "CN=billing"}}, nil, nil)
+	assert.Error(t, err, "Identity without the name must be rejected")
+	_, err = NewCertificates([]CertificateIdentity{{Name: "billing"}}, nil, nil)
+	assert.Error(t, err, "Identity matching every certificate must be rejected")
+}
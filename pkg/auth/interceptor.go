@@ -0,0 +1,172 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// authorizationMetadata carries the key, the gateway passes the Authorization header as it
+	authorizationMetadata = "authorization"
+	bearerScheme          = "bearer"
+	// methodTag and idTag add the identity of the caller to the logs
+	methodTag = "auth.method"
+	idTag     = "auth.id"
+)
+
+// Config enables the authentication
+type Config struct {
+	// Enabled requires the API key in every call
+	Enabled bool `mapstructure:"enabled"`
+	// Exempt are the HTTP paths served without the key, only /metrics,
+	// /swagger.json and /swagger-ui/ can be exempt
+	Exempt []string `mapstructure:"exempt"`
+}
+
+// IsExempt reports whether the path is served without the key
+func (c Config) IsExempt(path string) bool {
+	for _, exempt := range c.Exempt {
+		if path == exempt {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminFunc reports whether the method can be called only by the admin
+type AdminFunc func(fullMethod string) bool
+
+// Authenticator returns the identity of the caller, nil when the call does
+// not carry the credentials checked by the authenticator
+type Authenticator func(ctx context.Context) (*Identity, error)
+
+// UnaryServerInterceptor identifies the caller with the first authenticator
+// which finds its credentials. When the identity is not required, the calls
+// without the credentials are accepted.
+func UnaryServerInterceptor(required bool, admin AdminFunc, authenticators ...Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, required, admin, authenticators, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor identifies the caller of the stream as UnaryServerInterceptor
+func StreamServerInterceptor(required bool, admin AdminFunc, authenticators ...Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), required, admin, authenticators, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+func authenticate(ctx context.Context, required bool, admin AdminFunc, authenticators []Authenticator,
+	method string) (context.Context, error) {
+	var id *Identity
+	for _, a := range authenticators {
+		var err error
+		if id, err = a(ctx); err != nil {
+			return nil, err
+		}
+		if id != nil {
+			break
+		}
+	}
+	if id == nil {
+		if required {
+			return nil, status.Error(codes.Unauthenticated, "credentials are required")
+		}
+		return ctx, nil
+	}
+	if admin != nil && admin(method) && !id.Admin {
+		return nil, status.Errorf(codes.PermissionDenied, "%s can not call %s", id, method)
+	}
+	tags := grpc_ctxtags.Extract(ctx)
+	tags.Set(methodTag, id.Method)
+	tags.Set(idTag, id.ID)
+	return NewContext(ctx, id), nil
+}
+
+// Handler serves the HTTP requests only with the valid credentials in the
+// Authorization header or the verified client certificate, which are checked
+// by the authenticators as the authorization metadata and the TLS peer.
+// Without the authenticators all requests are served.
+func Handler(next http.Handler, authenticators ...Authenticator) http.Handler {
+	if len(authenticators) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := metadata.NewIncomingContext(r.Context(),
+			metadata.Pairs(authorizationMetadata, r.Header.Get("Authorization")))
+		if r.TLS != nil {
+			ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *r.TLS}})
+		}
+		for _, a := range authenticators {
+			id, err := a(ctx)
+			if err != nil {
+				unauthorized(w, status.Convert(err).Message())
+				return
+			}
+			if id != nil {
+				next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+				return
+			}
+		}
+		unauthorized(w, "credentials are required")
+	})
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, message, http.StatusUnauthorized)
+}
+
+// bearerToken returns the token in the authorization metadata
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationMetadata)
+	if len(values) == 0 {
+		return ""
+	}
+	return token(values[0])
+}
+
+// token returns the token of the "Bearer <token>" credentials or the bare token
+func token(credentials string) string {
+	credentials = strings.TrimSpace(credentials)
+	if i := strings.IndexByte(credentials, ' '); i > 0 && strings.EqualFold(credentials[:i], bearerScheme) {
+		return strings.TrimSpace(credentials[i+1:])
+	}
+	return credentials
+}
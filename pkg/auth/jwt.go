@@ -0,0 +1,465 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MethodJWT authenticates the caller with the token of the OIDC provider
+	MethodJWT = "jwt"
+	// ScopeSend allows sending the emails
+	ScopeSend = "email:send"
+	// ScopeAdmin allows managing the service, it implies ScopeSend
+	ScopeAdmin = "email:admin"
+
+	defaultJWKSRefresh  = 15 * time.Minute
+	defaultJWTClockSkew = time.Minute
+	defaultJWKSTimeout  = 10 * time.Second
+	// minJWKSRefresh limits the refreshes caused by the tokens with unknown keys
+	minJWKSRefresh = 30 * time.Second
+	maxJWKSSize    = 1 << 20
+)
+
+// JWTConfig trusts the tokens of the OIDC provider
+type JWTConfig struct {
+	// Issuer is the required iss claim
+	Issuer string `mapstructure:"issuer"`
+	// Audience is the value required in the aud claim
+	Audience string `mapstructure:"audience"`
+	// JWKSFile or JWKSURL is the JSON Web Key Set with the keys of the issuer
+	JWKSFile string `mapstructure:"jwks_file"`
+	JWKSURL  string `mapstructure:"jwks_url"`
+	// RefreshInterval is how often the keys are loaded again, 15 minutes by default
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	// ClockSkew is the tolerance of exp and nbf claims, 1 minute by default
+	ClockSkew time.Duration `mapstructure:"clock_skew"`
+}
+
+// Enabled reports whether the tokens are accepted
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+func (c JWTConfig) withDefaults() JWTConfig {
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = defaultJWKSRefresh
+	}
+	if c.ClockSkew <= 0 {
+		c.ClockSkew = defaultJWTClockSkew
+	}
+	return c
+}
+
+// JWT validates the RS256 and ES256 tokens signed with the keys of the JWKS
+type JWT struct {
+	cfg    JWTConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+
+	// refreshMu lets only one refresh fetch the keys, attempted is when the
+	// last one started, whether it succeeded or not
+	refreshMu sync.Mutex
+	attempted time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewJWT loads the keys of the issuer
+func NewJWT(cfg JWTConfig) (*JWT, error) {
+	if !cfg.Enabled() {
+		return nil, errors.New("jwks_file or jwks_url is required")
+	}
+	if cfg.JWKSFile != "" && cfg.JWKSURL != "" {
+		return nil, errors.New("only one of jwks_file and jwks_url can be set")
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("issuer and audience of tokens are required")
+	}
+	j := &JWT{
+		cfg:    cfg.withDefaults(),
+		client: &http.Client{Timeout: defaultJWKSTimeout},
+		now:    time.Now,
+	}
+	if err := j.refresh(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Start refreshes the keys periodically
+func (j *JWT) Start() {
+	j.stop = make(chan struct{})
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.cfg.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				if err := j.refresh(); err != nil {
+					zap.L().Error("Can not refresh JWKS", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the keys
+func (j *JWT) Stop() {
+	if j.stop != nil {
+		close(j.stop)
+		j.wg.Wait()
+	}
+}
+
+// AuthenticateContext returns the identity of the caller with the token in
+// the authorization metadata, nil when the call does not carry the token
+func (j *JWT) AuthenticateContext(ctx context.Context) (*Identity, error) {
+	token := bearerToken(ctx)
+	if strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+	return j.Authenticate(token)
+}
+
+// Authenticate validates the token and maps its claims to the identity
+func (j *JWT) Authenticate(token string) (*Identity, error) {
+	claims, err := j.verify(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "token is not valid: %v", err)
+	}
+	if err := j.validate(claims); err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "token is not valid: %v", err)
+	}
+	id := &Identity{Method: MethodJWT, ID: claims.Subject, Name: claims.Subject, Scopes: claims.scopes()}
+	if claims.Name != "" {
+		id.Name = claims.Name
+	}
+	for _, scope := range id.Scopes {
+		id.Admin = id.Admin || scope == ScopeAdmin
+	}
+	if !id.Admin && !id.HasScope(ScopeSend) {
+		return nil, status.Errorf(codes.PermissionDenied, "token of %s has neither %s nor %s scope", id, ScopeSend, ScopeAdmin)
+	}
+	return id, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer    string      `json:"iss"`
+	Subject   string      `json:"sub"`
+	Audience  interface{} `json:"aud"`
+	ExpiresAt *int64      `json:"exp"`
+	NotBefore *int64      `json:"nbf"`
+	Name      string      `json:"name"`
+	// Scope is the space-separated list, some providers send the scp list instead
+	Scope string      `json:"scope"`
+	Scp   interface{} `json:"scp"`
+}
+
+func (c *jwtClaims) scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	switch scp := c.Scp.(type) {
+	case string:
+		scopes = append(scopes, strings.Fields(scp)...)
+	case []interface{}:
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+func (c *jwtClaims) hasAudience(audience string) bool {
+	switch aud := c.Audience.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verify checks the signature and returns the claims of the token
+func (j *JWT) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	key, err := j.key(header)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		// The ES256 signature is r and s of 32 bytes each, RFC 7518 section 3.4
+		if len(signature) != 64 {
+			return nil, errors.New("invalid signature")
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return nil, errors.New("invalid signature")
+		}
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %v", err)
+	}
+	return &claims, nil
+}
+
+func (j *JWT) validate(claims *jwtClaims) error {
+	now := j.now()
+	switch {
+	case claims.Issuer != j.cfg.Issuer:
+		return fmt.Errorf("issuer %q is not trusted", claims.Issuer)
+	case !claims.hasAudience(j.cfg.Audience):
+		return errors.New("audience does not match")
+	case claims.Subject == "":
+		return errors.New("subject is missing")
+	case claims.ExpiresAt == nil:
+		return errors.New("expiration time is missing")
+	case !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(j.cfg.ClockSkew)):
+		return errors.New("token is expired")
+	case claims.NotBefore != nil && now.Add(j.cfg.ClockSkew).Before(time.Unix(*claims.NotBefore, 0)):
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+// key returns the key of the token algorithm, the unknown key id refreshes
+// the keys, since the issuer could rotate them
+func (j *JWT) key(header jwtHeader) (crypto.PublicKey, error) {
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return nil, fmt.Errorf("algorithm %q is not allowed", header.Alg)
+	}
+	key, ok := j.lookup(header)
+	if !ok {
+		j.refreshStale()
+		key, ok = j.lookup(header)
+	}
+	if !ok {
+		return nil, fmt.Errorf("key %q is not known", header.Kid)
+	}
+	return key, nil
+}
+
+func (j *JWT) lookup(header jwtHeader) (crypto.PublicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok := j.keys[header.Kid]
+	if !ok {
+		return nil, false
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return key, header.Alg == "RS256"
+	case *ecdsa.PublicKey:
+		return key, header.Alg == "ES256"
+	}
+	return nil, false
+}
+
+// refreshStale refreshes the keys unless it was attempted recently. The
+// concurrent callers wait for the refresh in progress instead of starting
+// their own, and the unavailable JWKS is not fetched on every request.
+func (j *JWT) refreshStale() {
+	j.refreshMu.Lock()
+	defer j.refreshMu.Unlock()
+	if j.now().Sub(j.attempted) < minJWKSRefresh {
+		return
+	}
+	if err := j.fetch(); err != nil {
+		zap.L().Error("Can not refresh JWKS", zap.Error(err))
+	}
+}
+
+// refresh loads the keys from the JWKS file or URL
+func (j *JWT) refresh() error {
+	j.refreshMu.Lock()
+	defer j.refreshMu.Unlock()
+	return j.fetch()
+}
+
+func (j *JWT) fetch() error {
+	j.attempted = j.now()
+	data, err := j.load()
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *JWT) load() ([]byte, error) {
+	if j.cfg.JWKSFile != "" {
+		return ioutil.ReadFile(j.cfg.JWKSFile)
+	}
+	resp, err := j.client.Get(j.cfg.JWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can not fetch JWKS: %s", resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the RSA and P-256 signing keys by their ids, other keys
+// are skipped
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("malformed JWKS: %v", err)
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q of JWKS: %v", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no RS256 or ES256 keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA" && (k.Alg == "" || k.Alg == "RS256"):
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256" && (k.Alg == "" || k.Alg == "ES256"):
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on P-256 curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("malformed key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
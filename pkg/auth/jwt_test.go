@@ -0,0 +1,323 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testIssuer   = "https://portal.example.com"
+	testAudience = "email-service"
+)
+
+// testIssuerKeys signs the tokens and serves their JWKS
+type testIssuerKeys struct {
+	rsa      *rsa.PrivateKey
+	ec       *ecdsa.PrivateKey
+	rotated  *rsa.PrivateKey
+	rotate   int32
+	requests int32
+}
+
+func newTestIssuerKeys(t *testing.T) *testIssuerKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err, "RSA key should be generated")
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err, "RSA key should be generated")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "EC key should be generated")
+	return &testIssuerKeys{rsa: rsaKey, ec: ecKey, rotated: rotated}
+}
+
+func (k *testIssuerKeys) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&k.requests, 1)
+	b64 := base64.RawURLEncoding.EncodeToString
+	keys := []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "alg": "RS256",
+			"n": b64(k.rsa.N.Bytes()), "e": b64(big.NewInt(int64(k.rsa.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256",
+			"x": b64(k.ec.X.Bytes()), "y": b64(k.ec.Y.Bytes())},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}
+	if atomic.LoadInt32(&k.rotate) == 1 {
+		keys = append(keys, map[string]string{"kty": "RSA", "kid": "rotated",
+			"n": b64(k.rotated.N.Bytes()), "e": b64(big.NewInt(int64(k.rotated.E)).Bytes())})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+// sign returns the token signed with the key of the algorithm
+func (k *testIssuerKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.NoError(t, err, "Header should be marshaled")
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err, "Claims should be marshaled")
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch {
+	case alg == "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		assert.NoError(t, err, "Token should be signed")
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case kid == "rotated":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rotated, crypto.SHA256, digest[:])
+		assert.NoError(t, err, "Token should be signed")
+	default:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		assert.NoError(t, err, "Token should be signed")
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(now time.Time, scope string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"sub":   "user-1",
+		"aud":   []string{"portal", testAudience},
+		"exp":   now.Add(time.Hour).Unix(),
+		"nbf":   now.Add(-time.Minute).Unix(),
+		"name":  "Jan Kowalski",
+		"scope": scope,
+	}
+}
+
+func TestJWT(t *testing.T) {
+	// Arrange
+	keys := newTestIssuerKeys(t)
+	jwks := httptest.NewServer(keys)
+	defer jwks.Close()
+	j, err := NewJWT(JWTConfig{Issuer: testIssuer, Audience: testAudience, JWKSURL: jwks.URL})
+	assert.NoError(t, err, "JWKS should be loaded")
+	now := time.Unix(1500000000, 0)
+	j.now = func() time.Time { return now }
+	j.attempted = now
+
+	with := func(change func(claims map[string]interface{})) map[string]interface{} {
+		claims := validClaims(now, "openid email:send")
+		change(claims)
+		return claims
+	}
+
+	testCases := []struct {
+		name     string
+		token    string
+		code     codes.Code
+		identity *Identity
+	}{
+		{name: "RS256 token is accepted", token: keys.sign(t, "RS256", "rsa", validClaims(now, "openid email:send")),
+			identity: &Identity{Method: MethodJWT, ID: "user-1", Name: "Jan Kowalski", Scopes: []string{"openid", "email:send"}}},
+		{name: "ES256 token with admin scope is accepted", token: keys.sign(t, "ES256", "ec", validClaims(now, "email:admin")),
+			identity: &Identity{Method: MethodJWT, ID: "user-1", Name: "Jan Kowalski", Admin: true, Scopes: []string{"email:admin"}}},
+		{name: "Token with scp list is accepted", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			delete(c, "scope")
+			delete(c, "name")
+			c["aud"] = testAudience
+			c["scp"] = []string{"email:send"}
+		})), identity: &Identity{Method: MethodJWT, ID: "user-1", Name: "user-1", Scopes: []string{"email:send"}}},
+		{name: "Token expired within the clock skew is accepted", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			c["exp"] = now.Add(-30 * time.Second).Unix()
+		})), identity: &Identity{Method: MethodJWT, ID: "user-1", Name: "Jan Kowalski", Scopes: []string{"openid", "email:send"}}},
+		{name: "Expired token is rejected", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			c["exp"] = now.Add(-2 * time.Minute).Unix()
+		})), code: codes.Unauthenticated},
+		{name: "Token without expiration is rejected", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			delete(c, "exp")
+		})), code: codes.Unauthenticated},
+		{name: "Token not valid yet is rejected", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			c["nbf"] = now.Add(2 * time.Minute).Unix()
+		})), code: codes.Unauthenticated},
+		{name: "Token of other issuer is rejected", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			c["iss"] = "https://evil.example.com"
+		})), code: codes.Unauthenticated},
+		{name: "Token for other audience is rejected", token: keys.sign(t, "RS256", "rsa", with(func(c map[string]interface{}) {
+			c["aud"] = "portal"
+		})), code: codes.Unauthenticated},
+		{name: "Token without email scopes is denied", token: keys.sign(t, "RS256", "rsa", validClaims(now, "openid")),
+			code: codes.PermissionDenied},
+		{name: "Token with algorithm not matching the key is rejected", token: keys.sign(t, "ES256", "rsa", validClaims(now, "email:send")),
+			code: codes.Unauthenticated},
+		{name: "Token with unknown key is rejected", token: keys.sign(t, "RS256", "missing", validClaims(now, "email:send")),
+			code: codes.Unauthenticated},
+		{name: "Token with tampered claims is rejected", token: func() string {
+			token := keys.sign(t, "RS256", "rsa", validClaims(now, "email:send"))
+			other := keys.sign(t, "RS256", "rsa", validClaims(now, "email:admin"))
+			return token[:len(token)-342] + other[len(other)-342:]
+		}(), code: codes.Unauthenticated},
+		{name: "Malformed token is rejected", token: "header.claims", code: codes.Unauthenticated},
+		{name: "Unsigned token is rejected", token: keys.sign(t, "none", "rsa", validClaims(now, "email:send")),
+			code: codes.Unauthenticated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			identity, err := j.Authenticate(tc.token)
+
+			// Assert
+			assert.Equal(t, tc.code, status.Code(err), "Status code must match: %v", err)
+			assert.Equal(t, tc.identity, identity, "Identity must match")
+		})
+	}
+
+	t.Run("Keys are refreshed when the token has unknown key", func(t *testing.T) {
+		// Arrange
+		atomic.StoreInt32(&keys.rotate, 1)
+		token := keys.sign(t, "RS256", "rotated", validClaims(now, "email:send"))
+		requests := atomic.LoadInt32(&keys.requests)
+
+		// Act
+		_, err := j.Authenticate(token)
+
+		// Assert
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Keys must not be refreshed too often")
+		assert.Equal(t, requests, atomic.LoadInt32(&keys.requests), "JWKS must not be fetched")
+
+		// Act
+		now = now.Add(minJWKSRefresh)
+		identity, err := j.Authenticate(token)
+
+		// Assert
+		assert.NoError(t, err, "Token signed with the new key should be accepted")
+		assert.Equal(t, "user-1", identity.ID, "Identity must be the subject")
+	})
+
+	t.Run("Bearer token is read from the metadata", func(t *testing.T) {
+		// Arrange
+		token := keys.sign(t, "RS256", "rsa", validClaims(now, "email:send"))
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+		// Act
+		identity, err := j.AuthenticateContext(ctx)
+		other, otherErr := j.AuthenticateContext(metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs("authorization", "Bearer em_0123")))
+
+		// Assert
+		assert.NoError(t, err, "Token should be accepted")
+		assert.Equal(t, "user-1", identity.ID, "Identity must be the subject")
+		assert.NoError(t, otherErr, "Other tokens must be left to other authenticators")
+		assert.Nil(t, other, "Other tokens must not be identified")
+	})
+}
+
+func TestJWT_RefreshFailure(t *testing.T) {
+	// Arrange
+	keys := newTestIssuerKeys(t)
+	var down int32
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			atomic.AddInt32(&keys.requests, 1)
+			time.Sleep(50 * time.Millisecond)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		keys.ServeHTTP(w, r)
+	}))
+	defer jwks.Close()
+	j, err := NewJWT(JWTConfig{Issuer: testIssuer, Audience: testAudience, JWKSURL: jwks.URL})
+	assert.NoError(t, err, "JWKS should be loaded")
+	now := time.Now().Add(time.Hour)
+	j.now = func() time.Time { return now }
+	atomic.StoreInt32(&down, 1)
+	atomic.StoreInt32(&keys.requests, 0)
+	token := keys.sign(t, "RS256", "missing", validClaims(now, "email:send"))
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.Authenticate(token)
+		}()
+	}
+	wg.Wait()
+	_, err = j.Authenticate(token)
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Token with unknown key must be rejected")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&keys.requests), "Failed refresh must be attempted once")
+}
+
+func TestJWT_Refresh(t *testing.T) {
+	// Arrange
+	keys := newTestIssuerKeys(t)
+	jwks := httptest.NewServer(keys)
+	defer jwks.Close()
+	j, err := NewJWT(JWTConfig{Issuer: testIssuer, Audience: testAudience, JWKSURL: jwks.URL,
+		RefreshInterval: 10 * time.Millisecond})
+	assert.NoError(t, err, "JWKS should be loaded")
+	atomic.StoreInt32(&keys.rotate, 1)
+
+	// Act
+	j.Start()
+	time.Sleep(100 * time.Millisecond)
+	j.Stop()
+
+	// Assert
+	_, err = j.Authenticate(keys.sign(t, "RS256", "rotated", validClaims(time.Now(), "email:send")))
+	assert.NoError(t, err, "Keys should be refreshed periodically")
+}
+
+func TestNewJWT(t *testing.T) {
+	// Arrange
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+
+	testCases := []struct {
+		name string
+		cfg  JWTConfig
+	}{
+		{name: "JWKS is required", cfg: JWTConfig{Issuer: testIssuer, Audience: testAudience}},
+		{name: "Only one JWKS source is allowed", cfg: JWTConfig{Issuer: testIssuer, Audience: testAudience,
+			JWKSFile: "jwks.json", JWKSURL: failing.URL}},
+		{name: "Issuer and audience are required", cfg: JWTConfig{JWKSURL: failing.URL}},
+		{name: "JWKS must be loaded", cfg: JWTConfig{Issuer: testIssuer, Audience: testAudience, JWKSURL: failing.URL}},
+		{name: "JWKS file must exist", cfg: JWTConfig{Issuer: testIssuer, Audience: testAudience, JWKSFile: "missing.json"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, err := NewJWT(tc.cfg)
+
+			// Assert
+			assert.Error(t, err, "JWT must not be created")
+		})
+	}
+}
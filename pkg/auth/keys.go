@@ -0,0 +1,253 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxKeyNameLength = 100
+	// maxVerifiedKeys bounds the remembered keys, they are forgotten when it is reached
+	maxVerifiedKeys = 10000
+	// maxRejectedKeys bounds the remembered invalid keys
+	maxRejectedKeys = 10000
+)
+
+// Keys creates, rotates and revokes the API keys and authenticates the callers
+type Keys struct {
+	store KeyStore
+	cost  int
+	now   func() time.Time
+
+	// mu guards the changes of keys, verified and rejected
+	mu sync.Mutex
+	// verified remembers the hash which the key matched, so the hash is
+	// computed only once for the key
+	verified map[[sha256.Size]byte]string
+	// rejected remembers the hash which the key did not match, so repeating
+	// the invalid key does not compute the hash again
+	rejected map[[sha256.Size]byte]string
+}
+
+// NewKeys creates the keys kept in the store
+func NewKeys(store KeyStore) *Keys {
+	return &Keys{
+		store:    store,
+		cost:     hashCost,
+		now:      time.Now,
+		verified: make(map[[sha256.Size]byte]string),
+		rejected: make(map[[sha256.Size]byte]string),
+	}
+}
+
+// Create generates the new key. The key itself is returned only here.
+func (k *Keys) Create(name string, admin bool) (*Key, string, error) {
+	if err := validateName(name); err != nil {
+		return nil, "", err
+	}
+	id, secret, err := newKey()
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "can not generate api key: %v", err)
+	}
+	hash, err := hashKey(secret, k.cost)
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "can not hash api key: %v", err)
+	}
+	key := &Key{
+		ID:        id,
+		Name:      name,
+		Admin:     admin,
+		Hash:      hash,
+		CreatedAt: k.now(),
+	}
+	if err := k.put(key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// Get returns the key
+func (k *Keys) Get(id string) (*Key, error) {
+	key, ok, err := k.store.Get(id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not read api key: %v", err)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "api key %q not found", id)
+	}
+	return key, nil
+}
+
+// List returns up to limit keys whose id is greater than after
+func (k *Keys) List(after string, limit int) ([]*Key, error) {
+	keys, err := k.store.List(after, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not list api keys: %v", err)
+	}
+	return keys, nil
+}
+
+// Revoke rejects the key from now on, the revoked key is kept for the audit
+func (k *Keys) Revoke(id string) (*Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, err := k.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked() {
+		return key, nil
+	}
+	key.RevokedAt = k.now()
+	key.PreviousHash = ""
+	key.PreviousExpiresAt = time.Time{}
+	if err := k.put(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Rotate generates the new key in place of the old one, which is accepted
+// for the grace period. The new key is returned only here.
+func (k *Keys) Rotate(id string, grace time.Duration) (*Key, string, error) {
+	if grace < 0 {
+		return nil, "", status.Error(codes.InvalidArgument, "grace period can not be negative")
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, err := k.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.Revoked() {
+		return nil, "", status.Errorf(codes.FailedPrecondition, "api key %q is revoked", id)
+	}
+	_, random, err := newKey()
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "can not generate api key: %v", err)
+	}
+	// The id stays the same, so the key keeps its name and the identity of the caller
+	secret := keyPrefix + id + random[len(keyPrefix)+2*idBytes:]
+	hash, err := hashKey(secret, k.cost)
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "can not hash api key: %v", err)
+	}
+	now := k.now()
+	key.PreviousHash, key.PreviousExpiresAt = "", time.Time{}
+	if grace > 0 {
+		key.PreviousHash = key.Hash
+		key.PreviousExpiresAt = now.Add(grace)
+	}
+	key.Hash = hash
+	key.RotatedAt = now
+	if err := k.put(key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// Authenticate returns the identity of the caller with the key. The error
+// does not tell why the key was rejected.
+func (k *Keys) Authenticate(secret string) (*Identity, error) {
+	invalid := status.Error(codes.Unauthenticated, "api key is not valid")
+	id, ok := keyID(secret)
+	if !ok {
+		return nil, invalid
+	}
+	key, ok, err := k.store.Get(id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not read api key: %v", err)
+	}
+	if !ok || key.Revoked() {
+		return nil, invalid
+	}
+	if !k.matches(secret, key.Hash) &&
+		(key.PreviousHash == "" || !k.now().Before(key.PreviousExpiresAt) || !k.matches(secret, key.PreviousHash)) {
+		return nil, invalid
+	}
+	return &Identity{Method: MethodKey, ID: key.ID, Name: key.Name, Admin: key.Admin}, nil
+}
+
+// AuthenticateContext returns the identity of the caller with the key in the
+// authorization metadata, nil when the call does not carry the key. Other
+// bearer tokens are left to the other authenticators.
+func (k *Keys) AuthenticateContext(ctx context.Context) (*Identity, error) {
+	secret := bearerToken(ctx)
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, nil
+	}
+	return k.Authenticate(secret)
+}
+
+// matches checks the key against the hash, the key which matched or did not
+// match the hash before is not hashed again
+func (k *Keys) matches(secret, hash string) bool {
+	sum := sha256.Sum256([]byte(secret))
+	k.mu.Lock()
+	verified, ok := k.verified[sum]
+	rejected := k.rejected[sum]
+	k.mu.Unlock()
+	if ok && verified == hash {
+		return true
+	}
+	if rejected == hash {
+		return false
+	}
+
+	matched := verifyKey(secret, hash)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !matched {
+		k.rejected = remember(k.rejected, maxRejectedKeys, sum, hash)
+		return false
+	}
+	k.verified = remember(k.verified, maxVerifiedKeys, sum, hash)
+	return true
+}
+
+// remember adds the hash of the key, all keys are forgotten when there are too many
+func remember(m map[[sha256.Size]byte]string, max int, sum [sha256.Size]byte, hash string) map[[sha256.Size]byte]string {
+	if len(m) >= max {
+		m = make(map[[sha256.Size]byte]string)
+	}
+	m[sum] = hash
+	return m
+}
+
+func (k *Keys) put(key *Key) error {
+	if err := k.store.Put(key); err != nil {
+		return status.Errorf(codes.Internal, "can not store api key: %v", err)
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" || len(name) > maxKeyNameLength {
+		return status.Errorf(codes.InvalidArgument, "api key name must have from 1 to %d characters", maxKeyNameLength)
+	}
+	if strings.ContainsAny(name, "\r\n") {
+		return status.Error(codes.InvalidArgument, "api key name can not contain new line characters")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+)
+
+// testRoot holds the stores of all tests, it is removed after they finish
+var testRoot string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Temporary directory should be created:", err)
+		os.Exit(1)
+	}
+	testRoot = dir
+	code := m.Run()
+	os.RemoveAll(testRoot)
+	os.Exit(code)
+}
+
+// openTestStore opens the store in the new directory of the test
+func openTestStore(t *testing.T, name string) *storage.Store {
+	dir, err := ioutil.TempDir(testRoot, name)
+	if err != nil {
+		t.Fatalf("Temporary directory should be created: %v", err)
+	}
+	store, err := storage.Open(dir, storage.WithSync(false))
+	if err != nil {
+		t.Fatalf("Store should be opened: %v", err)
+	}
+	return store
+}
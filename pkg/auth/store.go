@@ -0,0 +1,77 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+)
+
+const keyRecordPrefix = "apikey/"
+
+// StorageKeyStore keeps the keys in the local store
+type StorageKeyStore struct {
+	store *storage.Store
+}
+
+// NewStorageKeyStore creates the KeyStore backed by the store
+func NewStorageKeyStore(store *storage.Store) *StorageKeyStore {
+	return &StorageKeyStore{store: store}
+}
+
+// Get returns the key, the missing key is not an error
+func (s *StorageKeyStore) Get(id string) (*Key, bool, error) {
+	value, ok := s.store.Get(keyRecordPrefix + id)
+	if !ok {
+		return nil, false, nil
+	}
+	var key Key
+	if err := json.Unmarshal(value, &key); err != nil {
+		return nil, false, fmt.Errorf("api key %q is corrupted: %v", id, err)
+	}
+	return &key, true, nil
+}
+
+// Put creates or replaces the key
+func (s *StorageKeyStore) Put(key *Key) error {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(keyRecordPrefix+key.ID, value)
+}
+
+// List returns up to limit keys whose id is greater than after, ordered by id
+func (s *StorageKeyStore) List(after string, limit int) ([]*Key, error) {
+	var (
+		keys []*Key
+		err  error
+	)
+	s.store.Range(keyRecordPrefix, func(k string, value []byte) bool {
+		if strings.TrimPrefix(k, keyRecordPrefix) <= after {
+			return true
+		}
+		var key Key
+		if err = json.Unmarshal(value, &key); err != nil {
+			err = fmt.Errorf("api key %q is corrupted: %v", k, err)
+			return false
+		}
+		keys = append(keys, &key)
+		return len(keys) < limit
+	})
+	return keys, err
+}
@@ -0,0 +1,114 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package backend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// forwardedForMetadata is the client address added by the gateway
+	forwardedForMetadata = "x-forwarded-for"
+	// servicePrefix starts the full names of the methods
+	servicePrefix = "/korepta.rafal.email.v1alpha1."
+)
+
+// callerIdentity is the authenticated client, e.g. "key:<id>", otherwise its
+// address
+func callerIdentity(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		return id.String()
+	}
+	return peerAddress(ctx)
+}
+
+// peerAddress is the address of the client. The gateway calls the gRPC server
+// from the loopback address, so for such calls the address of the client which
+// the gateway appended to X-Forwarded-For is used.
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if forwarded := forwardedFor(ctx); forwarded != "" {
+			return forwarded
+		}
+	}
+	return host
+}
+
+// requestAddress is the address of the HTTP client
+func requestAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// authFailed reports whether the call was rejected because of the credentials
+func authFailed(err error) bool {
+	return status.Code(err) == codes.Unauthenticated
+}
+
+// forwardedFor returns the last address of X-Forwarded-For, the one added by the gateway
+func forwardedFor(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(forwardedForMetadata)
+	if len(values) == 0 {
+		return ""
+	}
+	hops := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(hops[len(hops)-1])
+}
+
+var (
+	// adminServices manage the service for all callers, e.g. the suppressed
+	// addresses of every sender
+	adminServices = []string{"AdminService", "APIKeyService", "TemplateService", "SuppressionService"}
+	// adminMethods of other services expose the data of all callers
+	adminMethods = map[string]bool{
+		servicePrefix + "EmailService/WatchMessages": true,
+	}
+)
+
+// adminMethod reports whether only the admin can call the method. The other
+// methods can be called by every authenticated caller, CallbackService
+// manages only the callbacks of the caller.
+func adminMethod(fullMethod string) bool {
+	for _, service := range adminServices {
+		if strings.HasPrefix(fullMethod, servicePrefix+service+"/") {
+			return true
+		}
+	}
+	return adminMethods[fullMethod]
+}
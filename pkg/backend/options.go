@@ -13,6 +13,17 @@
 // limitations under the License.
 package backend
 
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"github.com/RafalKorepta/coding-challenge/pkg/services"
+)
+
 var (
 	defaultOptions = &options{}
 )
@@ -22,6 +33,20 @@ type options struct {
 	keyFile            string
 	secure             bool
 	serverOverrideName string
+	provider           services.Provider
+	queue              *services.Queue
+	idempotency        *services.Idempotency
+	templates          *services.Templates
+	suppressions       *services.Suppressions
+	callbacks          *services.Callbacks
+	webhooks           services.WebhookConfig
+	attachmentPolicy   services.AttachmentPolicy
+	addressValidator   *services.AddressValidator
+	rateLimit          ratelimit.Config
+	apiKeys            *auth.Keys
+	auth               auth.Config
+	mtls               auth.MTLSConfig
+	jwt                *auth.JWT
 }
 
 func evaluateOptions(opts []Option) *options {
@@ -35,6 +60,66 @@ func evaluateOptions(opts []Option) *options {
 
 type Option func(*options)
 
+// authenticators identify the callers, the API key or the token sent by the
+// caller takes precedence over its certificate. The gateway certificate is
+// trusted to forward the certificates of the REST clients.
+func (o *options) authenticators(gateway *tls.Certificate) ([]auth.Authenticator, error) {
+	for _, path := range o.auth.Exempt {
+		if path != "/metrics" && path != "/swagger.json" && path != swaggerUIPrefix {
+			return nil, fmt.Errorf("%s can not be exempt from authentication", path)
+		}
+	}
+	var authenticators []auth.Authenticator
+	keys, err := o.authKeys()
+	if err != nil {
+		return nil, err
+	}
+	if keys != nil {
+		authenticators = append(authenticators, keys.AuthenticateContext)
+	}
+	if o.jwt != nil {
+		authenticators = append(authenticators, o.jwt.AuthenticateContext)
+	}
+	if o.mtls.Enabled() {
+		if !o.secure {
+			return nil, errors.New("client certificates can be required only by the secure server")
+		}
+		roots, err := createPool(o.mtls.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		var gatewayCert *x509.Certificate
+		if gateway != nil {
+			gatewayCert = gateway.Leaf
+		}
+		certs, err := auth.NewCertificates(o.mtls.Identities, roots, gatewayCert)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, certs.AuthenticateContext)
+	}
+	return authenticators, nil
+}
+
+// authKeys returns the keys which the callers must send, nil when the
+// authentication is disabled
+func (o *options) authKeys() (*auth.Keys, error) {
+	if !o.auth.Enabled {
+		return nil, nil
+	}
+	if o.apiKeys == nil {
+		return nil, errors.New("authentication is enabled, but api keys are not configured")
+	}
+	return o.apiKeys, nil
+}
+
+// authRequired reports whether every call must be authenticated. It is
+// required whenever the callers can authenticate, so the call without the
+// token or the certificate is not let in anonymously.
+func (o *options) authRequired() bool {
+	return o.auth.Enabled || o.jwt != nil || o.mtls.Enabled()
+}
+
 // WithCertFile setup where the certificate should be found
 func WithCertFile(c string) Option {
 	return func(o *options) {
@@ -62,3 +147,105 @@ func WithServerOverrideName(s string) Option {
 		o.serverOverrideName = s
 	}
 }
+
+// WithProvider setup the provider which delivers the emails
+func WithProvider(p services.Provider) Option {
+	return func(o *options) {
+		o.provider = p
+	}
+}
+
+// WithQueue setup the queue in which the emails wait for the delivery
+func WithQueue(q *services.Queue) Option {
+	return func(o *options) {
+		o.queue = q
+	}
+}
+
+// WithIdempotency setup where the responses for idempotency keys are remembered
+func WithIdempotency(i *services.Idempotency) Option {
+	return func(o *options) {
+		o.idempotency = i
+	}
+}
+
+// WithTemplates setup where the email templates are stored
+func WithTemplates(t *services.Templates) Option {
+	return func(o *options) {
+		o.templates = t
+	}
+}
+
+// WithSuppressions setup where the addresses to which emails are not sent are stored
+func WithSuppressions(s *services.Suppressions) Option {
+	return func(o *options) {
+		o.suppressions = s
+	}
+}
+
+// WithCallbacks setup where the URLs which receive the events of messages are stored
+func WithCallbacks(c *services.Callbacks) Option {
+	return func(o *options) {
+		o.callbacks = c
+	}
+}
+
+// WithWebhooks setup the endpoints which receive the events of providers
+func WithWebhooks(cfg services.WebhookConfig) Option {
+	return func(o *options) {
+		o.webhooks = cfg
+	}
+}
+
+// WithAttachmentPolicy setup the limits of attachments, the gRPC server
+// accepts messages big enough to carry them
+func WithAttachmentPolicy(p services.AttachmentPolicy) Option {
+	return func(o *options) {
+		o.attachmentPolicy = p
+	}
+}
+
+// WithAddressValidator setup the checks of recipient addresses
+func WithAddressValidator(v *services.AddressValidator) Option {
+	return func(o *options) {
+		o.addressValidator = v
+	}
+}
+
+// WithRateLimit setup the limits of calls of every client, without the rate
+// the calls are not limited
+func WithRateLimit(cfg ratelimit.Config) Option {
+	return func(o *options) {
+		o.rateLimit = cfg
+	}
+}
+
+// WithAPIKeys setup the API keys which authenticate the callers
+func WithAPIKeys(k *auth.Keys) Option {
+	return func(o *options) {
+		o.apiKeys = k
+	}
+}
+
+// WithAuth setup whether the callers must send the API key and which HTTP
+// endpoints are served without it
+func WithAuth(cfg auth.Config) Option {
+	return func(o *options) {
+		o.auth = cfg
+	}
+}
+
+// WithMTLS setup the authorities of the client certificates, which the secure
+// server requires, and the identities of the clients
+func WithMTLS(cfg auth.MTLSConfig) Option {
+	return func(o *options) {
+		o.mtls = cfg
+	}
+}
+
+// WithJWT setup the validation of the bearer tokens issued by the OIDC provider
+func WithJWT(j *auth.JWT) Option {
+	return func(o *options) {
+		o.jwt = j
+	}
+}
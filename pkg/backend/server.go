@@ -24,6 +24,7 @@ import (
 
 	"mime"
 	"net/http"
+	"net/textproto"
 	"strings"
 
 	"crypto/tls"
@@ -34,11 +35,16 @@ import (
 
 	"net"
 
+	"sync"
+
 	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
 	"github.com/RafalKorepta/coding-challenge/pkg/certs"
 	"github.com/RafalKorepta/coding-challenge/pkg/log"
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
 	"github.com/RafalKorepta/coding-challenge/pkg/services"
 	"github.com/RafalKorepta/coding-challenge/pkg/ui/data/swagger"
+	"github.com/golang/protobuf/proto"
 	"github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	"github.com/grpc-ecosystem/go-grpc-middleware/recovery"
@@ -59,10 +65,25 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
+const (
+	// ndjsonContentType is the media type of the gateway's streamed responses
+	ndjsonContentType = "application/x-ndjson"
+	// sendGridWebhookURI receives the events of the SendGrid Event Webhook
+	sendGridWebhookURI = "/webhooks/sendgrid"
+	// sesWebhookURI receives the SES notifications delivered by Amazon SNS
+	sesWebhookURI = "/webhooks/ses"
+	// swaggerUIPrefix serves the swagger-ui files
+	swaggerUIPrefix = "/swagger-ui/"
+)
+
 type Server struct {
 	opts     *options
 	listener net.Listener
 	pb.EmailServiceServer
+
+	mu       sync.Mutex
+	srv      *http.Server
+	shutdown bool
 }
 
 // NewServer constructor of Server
@@ -85,13 +106,35 @@ func (s *Server) Serve() error {
 	srv, grpcServer, err := createHTTPServer(s.listener.Addr().String(),
 		WithCertFile(s.opts.certFile),
 		WithKeyFile(s.opts.keyFile),
-		WithSecure(s.opts.secure))
+		WithSecure(s.opts.secure),
+		WithProvider(s.opts.provider),
+		WithQueue(s.opts.queue),
+		WithIdempotency(s.opts.idempotency),
+		WithTemplates(s.opts.templates),
+		WithSuppressions(s.opts.suppressions),
+		WithCallbacks(s.opts.callbacks),
+		WithWebhooks(s.opts.webhooks),
+		WithAttachmentPolicy(s.opts.attachmentPolicy),
+		WithAddressValidator(s.opts.addressValidator),
+		WithRateLimit(s.opts.rateLimit),
+		WithAPIKeys(s.opts.apiKeys),
+		WithAuth(s.opts.auth),
+		WithMTLS(s.opts.mtls),
+		WithJWT(s.opts.jwt))
 	if err != nil {
 		return err
 	}
 
 	grpc_prometheus.Register(grpcServer)
 
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.srv = srv
+	s.mu.Unlock()
+
 	defer s.listener.Close()
 	if s.opts.secure {
 		return srv.ServeTLS(s.listener, "", "") // The certificates are initialized already
@@ -100,6 +143,24 @@ func (s *Server) Serve() error {
 	return srv.Serve(s.listener)
 }
 
+// Shutdown stops accepting the connections and waits until the calls in
+// progress finish. When the context is done first the remaining connections
+// are closed. Serve returns http.ErrServerClosed afterwards.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shutdown = true
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		_ = srv.Close()
+		return err
+	}
+	return nil
+}
+
 // initializeGlobalTracer will set global tracer using jeager tracer
 func initializeGlobalTracer(logger *zap.Logger, sugar *zap.SugaredLogger) (io.Closer, error) {
 	zapWrapper := log.ZapWrapper{
@@ -122,16 +183,39 @@ func initializeGlobalTracer(logger *zap.Logger, sugar *zap.SugaredLogger) (io.Cl
 	return closer, nil
 }
 
-func registerEmailService(serverOpts ...grpc.ServerOption) *grpc.Server {
+func registerServices(o *options, serverOpts ...grpc.ServerOption) *grpc.Server {
 	grpcServer := grpc.NewServer(serverOpts...)
+	// Without the authentication anyone could create the admin key, so the
+	// keys can be managed only when the callers are authenticated
+	var apiKeys *auth.Keys
+	if o.auth.Enabled {
+		apiKeys = o.apiKeys
+	}
 
-	pb.RegisterEmailServiceServer(grpcServer, services.EmailService{})
+	serviceOpts := []services.Option{
+		services.WithProvider(o.provider),
+		services.WithQueue(o.queue),
+		services.WithIdempotency(o.idempotency),
+		services.WithTemplates(o.templates),
+		services.WithSuppressions(o.suppressions),
+		services.WithCallbacks(o.callbacks),
+		services.WithAttachmentPolicy(o.attachmentPolicy),
+		services.WithAddressValidator(o.addressValidator),
+		services.WithAPIKeys(apiKeys),
+	}
+	pb.RegisterEmailServiceServer(grpcServer, services.NewEmailService(serviceOpts...))
+	pb.RegisterAdminServiceServer(grpcServer, services.NewAdminService(serviceOpts...))
+	pb.RegisterTemplateServiceServer(grpcServer, services.NewTemplateService(serviceOpts...))
+	pb.RegisterSuppressionServiceServer(grpcServer, services.NewSuppressionService(serviceOpts...))
+	pb.RegisterCallbackServiceServer(grpcServer, services.NewCallbackService(serviceOpts...))
+	pb.RegisterAPIKeyServiceServer(grpcServer, services.NewAPIKeyService(serviceOpts...))
 
 	return grpcServer
 }
 
-func createGRPCOptions(addr string, secure bool, certFile string) ([]grpc.ServerOption, error) {
-	var opts []grpc.ServerOption
+func createGRPCOptions(addr string, secure bool, certFile string, maxRecvMsgSize int,
+	authRequired bool, authenticators []auth.Authenticator, limiter, failures *ratelimit.Limiter) ([]grpc.ServerOption, error) {
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(maxRecvMsgSize)}
 
 	grpc_zap.ReplaceGrpcLogger(zap.L())
 
@@ -152,6 +236,9 @@ func createGRPCOptions(addr string, secure bool, certFile string) ([]grpc.Server
 		grpc_prometheus.StreamServerInterceptor,
 		grpc_zap.StreamServerInterceptor(zap.L(), optZap...),
 		grpc_recovery.StreamServerInterceptor(),
+		ratelimit.FailuresStreamServerInterceptor(failures, peerAddress, authFailed),
+		auth.StreamServerInterceptor(authRequired, adminMethod, authenticators...),
+		ratelimit.StreamServerInterceptor(limiter, callerIdentity),
 	)))
 
 	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
@@ -160,6 +247,9 @@ func createGRPCOptions(addr string, secure bool, certFile string) ([]grpc.Server
 		grpc_prometheus.UnaryServerInterceptor,
 		grpc_zap.UnaryServerInterceptor(zap.L(), optZap...),
 		grpc_recovery.UnaryServerInterceptor(),
+		ratelimit.FailuresUnaryServerInterceptor(failures, peerAddress, authFailed),
+		auth.UnaryServerInterceptor(authRequired, adminMethod, authenticators...),
+		ratelimit.UnaryServerInterceptor(limiter, callerIdentity),
 	)))
 
 	if secure {
@@ -172,41 +262,120 @@ func createGRPCOptions(addr string, secure bool, certFile string) ([]grpc.Server
 	return opts, nil
 }
 
-func registerServerMux(addr string, dialOpts ...grpc.DialOption) (*http.ServeMux, error) {
+func registerServerMux(addr string, webhooks *services.Webhooks, authRequired bool, authCfg auth.Config,
+	authenticators []auth.Authenticator, failures *ratelimit.Limiter, dialOpts ...grpc.DialOption) (*http.ServeMux, error) {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/swagger.json", func(w http.ResponseWriter, req *http.Request) {
+	protect := func(path string, h http.Handler) {
+		if authRequired && !authCfg.IsExempt(path) {
+			h = ratelimit.FailuresHandler(auth.Handler(h, authenticators...), failures, requestAddress,
+				http.StatusUnauthorized)
+		}
+		mux.Handle(path, h)
+	}
+	protect("/swagger.json", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		var n int64
 		n, err := io.Copy(w, strings.NewReader(pb.Swagger))
 		if err != nil {
 			zap.L().Error("Coping operation failed", zap.Int64("wrriten", n), zap.Error(err))
 			http.Error(w, "swagger.json is currently unavailable", http.StatusInternalServerError)
 		}
-	})
+	}))
 
-	gwmux := runtime.NewServeMux()
+	gwmux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithForwardResponseOption(streamContentType),
+		runtime.WithMetadata(auth.GatewayMetadata),
+	)
 	ctx := context.Background()
 	err := pb.RegisterEmailServiceHandlerFromEndpoint(ctx, gwmux, addr, dialOpts)
 	if err != nil {
 		return nil, fmt.Errorf("unable to register gRPC gateway: %v", err)
 	}
+	err = pb.RegisterAdminServiceHandlerFromEndpoint(ctx, gwmux, addr, dialOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to register gRPC gateway: %v", err)
+	}
+	err = pb.RegisterTemplateServiceHandlerFromEndpoint(ctx, gwmux, addr, dialOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to register gRPC gateway: %v", err)
+	}
+	err = pb.RegisterSuppressionServiceHandlerFromEndpoint(ctx, gwmux, addr, dialOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to register gRPC gateway: %v", err)
+	}
+	err = pb.RegisterCallbackServiceHandlerFromEndpoint(ctx, gwmux, addr, dialOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to register gRPC gateway: %v", err)
+	}
+	err = pb.RegisterAPIKeyServiceHandlerFromEndpoint(ctx, gwmux, addr, dialOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to register gRPC gateway: %v", err)
+	}
 
-	mux.Handle("/metrics", promhttp.Handler())
+	if h := webhooks.SendGrid(); h != nil {
+		mux.Handle(sendGridWebhookURI, h)
+	}
+	if h := webhooks.SNS(); h != nil {
+		mux.Handle(sesWebhookURI, h)
+	}
+	// The gateway passes the Authorization header to the gRPC server, which
+	// checks it, the webhooks are authenticated by the signatures of providers
+	protect("/metrics", promhttp.Handler())
 	mux.Handle("/", gwmux)
-	serveSwagger(mux)
+	protect(swaggerUIPrefix, swaggerUIHandler())
 
 	return mux, nil
 }
 
-func createDialOpts(serverOverrideName, certFile string, secure bool) ([]grpc.DialOption, error) {
+// incomingHeaderMatcher passes the Idempotency-Key header to the gRPC metadata
+// together with the headers passed by default
+func incomingHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == services.IdempotencyKeyHeader {
+		return services.IdempotencyKeyMetadata, true
+	}
+	if strings.EqualFold(key, runtime.MetadataHeaderPrefix+auth.ClientCertificateMetadata) {
+		return "", false
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher sends the retry-after metadata as the Retry-After
+// header of the 429 response, other metadata is prefixed as by default
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == ratelimit.RetryAfterMetadata {
+		return "Retry-After", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// streamContentType marks streamed responses as newline-delimited JSON. The gateway
+// calls forward response options with a nil message once, before the first
+// message of a stream is written.
+func streamContentType(_ context.Context, w http.ResponseWriter, resp proto.Message) error {
+	if resp == nil {
+		w.Header().Set("Content-Type", ndjsonContentType)
+	}
+	return nil
+}
+
+// createDialOpts returns the options with which the gateway dials the gRPC
+// server. When the server requires the client certificates, the gateway
+// presents its own certificate.
+func createDialOpts(serverOverrideName, certFile string, secure bool, gateway *tls.Certificate) ([]grpc.DialOption, error) {
 	if secure {
 		certPool, err := createPool(certFile)
 		if err != nil {
 			return nil, err
 		}
-		dcreds := credentials.NewTLS(&tls.Config{
+		tlsCfg := &tls.Config{
 			ServerName: serverOverrideName, // Only connection from localhost will be accepted until certificate will have Subject Alternative Name init
 			RootCAs:    certPool,
-		})
+		}
+		if gateway != nil {
+			tlsCfg.Certificates = []tls.Certificate{*gateway}
+		}
+		dcreds := credentials.NewTLS(tlsCfg)
 		return []grpc.DialOption{grpc.WithTransportCredentials(dcreds)}, nil
 	}
 	return []grpc.DialOption{grpc.WithInsecure()}, nil
@@ -238,24 +407,46 @@ func createServerMainHandler(secure bool, grpcServer, mux http.Handler) http.Han
 func createHTTPServer(addr string, opts ...Option) (*http.Server, *grpc.Server, error) {
 	o := evaluateOptions(opts)
 
-	serverOpts, err := createGRPCOptions(addr, o.secure, o.certFile)
+	var gateway *tls.Certificate
+	if o.mtls.Enabled() {
+		cert, err := newGatewayCertificate()
+		if err != nil {
+			return nil, nil, err
+		}
+		gateway = &cert
+	}
+	authenticators, err := o.authenticators(gateway)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The failed authentications are limited before the credentials are
+	// checked, so guessing them can not use up the CPU
+	failures := o.rateLimit.FailuresLimiter()
+	serverOpts, err := createGRPCOptions(addr, o.secure, o.certFile, o.attachmentPolicy.MaxRequestSize(),
+		o.authRequired(), authenticators, ratelimit.NewLimiter(o.rateLimit), failures)
 	if err != nil {
 		return nil, nil, err
 	}
-	grpcServer := registerEmailService(serverOpts...)
+	grpcServer := registerServices(o, serverOpts...)
 
-	dialOpts, err := createDialOpts(o.serverOverrideName, o.certFile, o.secure) // This hardcoded localhost is necessery
+	dialOpts, err := createDialOpts(o.serverOverrideName, o.certFile, o.secure, gateway) // This hardcoded localhost is necessery
 	if err != nil {
 		return nil, nil, err
 	}
-	mux, err := registerServerMux(addr, dialOpts...)
+	webhooks, err := services.NewWebhooks(o.webhooks,
+		services.WithQueue(o.queue),
+		services.WithSuppressions(o.suppressions))
+	if err != nil {
+		return nil, nil, err
+	}
+	mux, err := registerServerMux(addr, webhooks, o.authRequired(), o.auth, authenticators, failures, dialOpts...)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	rootHandler := createServerMainHandler(o.secure, grpcServer, mux)
 
-	tlsCfg, err := createTLSConfig(o.secure, o.certFile, o.keyFile)
+	tlsCfg, err := createTLSConfig(o.secure, o.certFile, o.keyFile, o.mtls.ClientCAFile, gateway)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -266,17 +457,32 @@ func createHTTPServer(addr string, opts ...Option) (*http.Server, *grpc.Server,
 	}, grpcServer, nil
 }
 
-func createTLSConfig(secure bool, certFile, keyFile string) (*tls.Config, error) {
+// createTLSConfig returns the configuration of the secure server. With the
+// client CA file every client must present the certificate signed by one of
+// its authorities, except the gateway which presents its own certificate.
+func createTLSConfig(secure bool, certFile, keyFile, clientCAFile string, gateway *tls.Certificate) (*tls.Config, error) {
 	if secure {
 		keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
 		if err != nil {
 			return nil, fmt.Errorf("unable to create x509 key pair certificate: %v", err)
 		}
 
-		return &tls.Config{
+		tlsCfg := &tls.Config{
 			Certificates: []tls.Certificate{keyPair},
 			NextProtos:   []string{"h2"},
-		}, nil
+		}
+		if clientCAFile != "" {
+			clientCAs, err := createPool(clientCAFile)
+			if err != nil {
+				return nil, err
+			}
+			if gateway != nil {
+				clientCAs.AddCert(gateway.Leaf)
+			}
+			tlsCfg.ClientCAs = clientCAs
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		return tlsCfg, nil
 	}
 	return nil, nil
 }
@@ -295,10 +501,10 @@ func grpcHandlerFunc(grpcServer, otherHandler http.Handler) http.Handler {
 	})
 }
 
-// serveSwagger will register `/swagger-ui` endpoint into root mux.
+// swaggerUIHandler serves the `/swagger-ui` endpoint.
 // This will provide visual representation of gRPC contract
 // The swagger-ui is auto generated by script located in `hack/build-ui.sh`
-func serveSwagger(mux *http.ServeMux) {
+func swaggerUIHandler() http.Handler {
 	err := mime.AddExtensionType(".svg", "image/svg+xml")
 	if err != nil {
 		zap.L().Error("Unable to add extension type", zap.Error(err))
@@ -310,6 +516,5 @@ func serveSwagger(mux *http.ServeMux) {
 		AssetDir: swagger.AssetDir,
 		Prefix:   "third_party/swagger-ui",
 	})
-	prefix := "/swagger-ui/"
-	mux.Handle(prefix, http.StripPrefix(prefix, fileServer))
+	return http.StripPrefix(swaggerUIPrefix, fileServer)
 }
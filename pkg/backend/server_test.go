@@ -14,7 +14,22 @@
 package backend
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
 	"net/http"
+	"path/filepath"
 
 	"net/http/httptest"
 
@@ -28,16 +43,44 @@ import (
 
 	"context"
 
+	"time"
+
+	"net"
+
+	"os"
+
 	"github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"github.com/RafalKorepta/coding-challenge/pkg/services"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/prometheus/util/promlint"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
-const emailURI = "/v1alpha1/email"
+const (
+	emailURI = "/v1alpha1/email"
+	// testSendGridKey verifies the signatures of SendGrid events
+	testSendGridKey = "MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEdQnLDyJ118Hl2TCswAoCD3vFsPGOZYZah274W+6Y8y6cNjErl0cIn5UqCdPGGPvne+eWrB3/4FPDu7Ul++aCDA=="
+)
+
+func newEmailRequest() *email.EmailRequest {
+	return &email.EmailRequest{
+		From:     &email.Address{Email: "sender@example.com"},
+		To:       []*email.Address{{Email: "recipient@example.com", Name: "Recipient"}},
+		Subject:  "Hello",
+		TextBody: "Hello",
+	}
+}
 
 var _ = Describe("Server that register REST and gRPC endpoint on the same port", func() {
 	var (
@@ -57,6 +100,11 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 		conn            *grpc.ClientConn
 	)
 
+	BeforeEach(func() {
+		requestedURI = ""
+		postBody = nil
+	})
+
 	JustBeforeEach(func() {
 		srv, grpcServer, err = createHTTPServer(listener.Addr().String(),
 			opts...)
@@ -161,15 +209,101 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 			})
 		})
 
+		Context("when GET method on unknown message URI is called", func() {
+			BeforeEach(func() {
+				requestedURI = emailURI + "/unknown"
+			})
+
+			It("should return not found", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(string(body)).To(ContainSubstring("not found"))
+			})
+		})
+
+		Context("when suppressions URI is called without the suppressions", func() {
+			BeforeEach(func() {
+				requestedURI = "/v1alpha1/suppressions/bounced@example.com"
+			})
+
+			It("should return failed precondition", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusPreconditionFailed))
+				Expect(string(body)).To(ContainSubstring("suppressions are not configured"))
+			})
+		})
+
+		Context("when callbacks URI is called without the callbacks", func() {
+			BeforeEach(func() {
+				requestedURI = "/v1alpha1/callbacks"
+			})
+
+			It("should return failed precondition", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusPreconditionFailed))
+				Expect(string(body)).To(ContainSubstring("callbacks are not configured"))
+			})
+		})
+
+		Context("when SendGrid webhook URI is called", func() {
+			BeforeEach(func() {
+				requestedURI = "/webhooks/sendgrid"
+				postBody = bytes.NewReader([]byte(`[{"email":"a@example.com","event":"processed"}]`))
+			})
+
+			It("should reject the unsigned events", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("when SES webhook URI is called without the certificate", func() {
+			BeforeEach(func() {
+				requestedURI = "/webhooks/ses"
+				postBody = bytes.NewReader([]byte("{}"))
+			})
+
+			It("should not be found", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when cancel URI is called on unknown message", func() {
+			BeforeEach(func() {
+				requestedURI = emailURI + "/unknown:cancel"
+				postBody = bytes.NewReader([]byte("{}"))
+			})
+
+			It("should return not found", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(string(body)).To(ContainSubstring("not found"))
+			})
+		})
+
+		Context("when dead letters URI is called without the queue", func() {
+			BeforeEach(func() {
+				requestedURI = "/v1alpha1/admin/deadletters"
+			})
+
+			It("should return failed precondition", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusPreconditionFailed))
+				Expect(string(body)).To(ContainSubstring("dead letters are not configured"))
+			})
+		})
+
+		Context("when watch URI is called without the queue", func() {
+			BeforeEach(func() {
+				requestedURI = emailURI + ":watch"
+			})
+
+			It("should return failed precondition", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusPreconditionFailed))
+				Expect(string(body)).To(ContainSubstring("not available without the queue"))
+			})
+		})
+
 		Context("when POST method on email URI is called", func() {
 			BeforeEach(func() {
 				var marshaledProto []byte
 				requestedURI = emailURI
-				emailRequest := email.EmailRequest{
-					Message: "Hello",
-				}
 				marshaller = runtime.JSONPb{}
-				marshaledProto, err = marshaller.Marshal(emailRequest)
+				marshaledProto, err = marshaller.Marshal(newEmailRequest())
 				Expect(err).NotTo(HaveOccurred())
 				postBody = bytes.NewReader(marshaledProto)
 			})
@@ -179,7 +313,72 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 				Expect(response.StatusCode).To(Equal(http.StatusOK))
 				Expect(response.Header.Get("Content-Type")).To(Equal(marshaller.ContentType()))
 				r, err = marshaller.Marshal(email.EmailResponse{
-					Error: "Hello",
+					MessageId: "fake-id",
+					Provider:  "fake",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body).To(Equal(r))
+			})
+		})
+
+		Context("when POST method on email URI is called without recipients", func() {
+			BeforeEach(func() {
+				var marshaledProto []byte
+				requestedURI = emailURI
+				emailRequest := newEmailRequest()
+				emailRequest.To = nil
+				marshaller = runtime.JSONPb{}
+				marshaledProto, err = marshaller.Marshal(emailRequest)
+				Expect(err).NotTo(HaveOccurred())
+				postBody = bytes.NewReader(marshaledProto)
+			})
+
+			It("should return bad request", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(string(body)).To(ContainSubstring("at least one recipient"))
+			})
+		})
+
+		Context("when validate URI is called with international domain", func() {
+			BeforeEach(func() {
+				requestedURI = emailURI + ":validate"
+				postBody = bytes.NewReader([]byte(`{"email":"jan@bücher.example"}`))
+			})
+
+			It("should return the address in punycode", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+				Expect(string(body)).To(ContainSubstring(`"valid":true`))
+				Expect(string(body)).To(ContainSubstring(`"email":"jan@xn--bcher-kva.example"`))
+			})
+		})
+
+		Context("when POST method on email batch URI is called", func() {
+			BeforeEach(func() {
+				var marshaledProto []byte
+				requestedURI = emailURI + ":batch"
+				emailRequest := newEmailRequest()
+				emailRequest.To = nil
+				marshaller = runtime.JSONPb{}
+				marshaledProto, err = marshaller.Marshal(&email.SendMailBatchRequest{
+					Email: emailRequest,
+					Recipients: []*email.BatchRecipient{
+						{Address: &email.Address{Email: "first@example.com"}},
+						{Address: &email.Address{Email: "not an address"}},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				postBody = bytes.NewReader(marshaledProto)
+			})
+
+			It("should return result per recipient", func() {
+				var r []byte
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+				// The gateway keeps the original names of proto fields
+				r, err = (&runtime.JSONPb{OrigName: true}).Marshal(&email.SendMailBatchResponse{
+					Results: []*email.BatchResult{
+						{MessageId: "fake-id", Provider: "fake"},
+						{Code: int32(codes.InvalidArgument), Error: `to address "not an address" is not valid`},
+					},
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(body).To(Equal(r))
@@ -195,7 +394,7 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 			BeforeEach(func() {
 				var dialOpts []grpc.DialOption
 				o := evaluateOptions(opts)
-				dialOpts, err = createDialOpts("localhost", "test_data/server.pem", o.secure)
+				dialOpts, err = createDialOpts("localhost", "test_data/server.pem", o.secure, nil)
 				Expect(err).NotTo(HaveOccurred())
 				conn, err = grpc.Dial(newMockServer.Listener.Addr().String(), dialOpts...)
 				clientRPC = email.NewEmailServiceClient(conn)
@@ -203,9 +402,8 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 
 			JustBeforeEach(func() {
 				ctx := context.Background()
-				responseGRPC, err = clientRPC.SendMail(ctx, &email.EmailRequest{
-					Message: "Hello",
-				}, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+				responseGRPC, err = clientRPC.SendMail(ctx, newEmailRequest(),
+					grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 			})
 
 			AfterEach(func() {
@@ -215,18 +413,23 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 
 			It("should return correct response", func() {
 				Expect(err).NotTo(HaveOccurred())
-				Expect(responseGRPC.Error).To(Equal("Hello"))
+				Expect(responseGRPC.MessageId).To(Equal("fake-id"))
+				Expect(responseGRPC.Provider).To(Equal("fake"))
 			})
 		})
 	}
 
 	Describe("secure server", func() {
 		BeforeEach(func() {
+			listener = secureListener
 			opts = []Option{
 				WithCertFile("test_data/server.pem"),
 				WithKeyFile("test_data/server.key"),
 				WithServerOverrideName("localhost"),
 				WithSecure(true),
+				WithProvider(fakeProvider{}),
+				WithWebhooks(services.WebhookConfig{SendGrid: services.SendGridWebhookConfig{
+					Enabled: true, VerificationKey: testSendGridKey}}),
 			}
 		})
 
@@ -254,8 +457,12 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 
 	Describe("Insecure server", func() {
 		BeforeEach(func() {
+			listener = insecureListener
 			opts = []Option{
 				WithSecure(false),
+				WithProvider(fakeProvider{}),
+				WithWebhooks(services.WebhookConfig{SendGrid: services.SendGridWebhookConfig{
+					Enabled: true, VerificationKey: testSendGridKey}}),
 			}
 		})
 
@@ -264,21 +471,17 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 			Expect(srv).NotTo(BeNil())
 			Expect(grpcServer).NotTo(BeNil())
 
-			newMockServer = httptest.NewServer(srv.Handler)
-			//newMockServer = &httptest.Server{
-			//	Listener: listener,
-			//	Config:   srv,
-			//}
-			//newMockServer.Start()
+			// The gRPC gateway dials the listener address, so the insecure server
+			// can not share the listener with the TLS one
+			newMockServer = &httptest.Server{
+				Listener: listener,
+				Config:   srv,
+			}
+			newMockServer.Start()
 
 			JustBeforeHTTPAndGrpcContext()
 		})
 
-		//AfterEach(func() {
-		//	newMockServer.CloseClientConnections()
-		//	newMockServer.Close()
-		//})
-
 		Describe("In combine test suite", func() {
 			Describe("Http1.1", http1)
 
@@ -319,6 +522,21 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 		})
 	})
 
+	Describe("Not valid SNS certificate", func() {
+		BeforeEach(func() {
+			opts = []Option{
+				WithSecure(false),
+				WithWebhooks(services.WebhookConfig{SNS: services.SNSWebhookConfig{CertificateFile: "invalid.pem"}}),
+			}
+		})
+
+		It("should failed to initialize server", func() {
+			Expect(srv).To(BeNil())
+			Expect(grpcServer).To(BeNil())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("pre-initialization of global tracer", func() {
 		var (
 			noopTracer opentracing.Tracer
@@ -354,6 +572,271 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 	})
 })
 
+var _ = Describe("Server shutdown", func() {
+	// Serve initializes the global tracer only once per process, so the
+	// HTTP server is started here directly
+	It("should stop serving the connections", func() {
+		l := newLocalListener()
+		server := NewServer(l, WithSecure(false))
+		srv, _, err := createHTTPServer(l.Addr().String(), WithSecure(false))
+		Expect(err).NotTo(HaveOccurred())
+		server.srv = srv
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(l) }()
+		Eventually(func() error {
+			resp, err := http.Get("http://" + l.Addr().String() + "/metrics")
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		}).ShouldNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		Expect(server.Shutdown(ctx)).To(Succeed())
+
+		Eventually(served).Should(Receive(Equal(http.ErrServerClosed)))
+		_, err = http.Get("http://" + l.Addr().String() + "/metrics")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Gateway incoming header matcher", func() {
+	It("should pass Idempotency-Key header as metadata", func() {
+		key, ok := incomingHeaderMatcher("Idempotency-Key")
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal(services.IdempotencyKeyMetadata))
+	})
+
+	It("should keep default behavior for other headers", func() {
+		key, ok := incomingHeaderMatcher("Grpc-Metadata-Foo")
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal("Foo"))
+		_, ok = incomingHeaderMatcher("X-Custom")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Gateway outgoing header matcher", func() {
+	It("should send retry-after metadata as Retry-After header", func() {
+		key, ok := outgoingHeaderMatcher(ratelimit.RetryAfterMetadata)
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal("Retry-After"))
+	})
+
+	It("should keep default behavior for other metadata", func() {
+		key, ok := outgoingHeaderMatcher("foo")
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal("Grpc-Metadata-foo"))
+	})
+
+	It("should turn exceeded rate limit into too many requests with Retry-After", func() {
+		mux := runtime.NewServeMux(runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher))
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{
+			HeaderMD: metadata.Pairs(ratelimit.RetryAfterMetadata, "3"),
+		})
+		w := httptest.NewRecorder()
+
+		runtime.DefaultHTTPError(ctx, mux, &runtime.JSONPb{}, w, nil,
+			ratelimit.Exhausted(3*time.Second, "rate limit exceeded"))
+
+		Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(w.Header().Get("Retry-After")).To(Equal("3"))
+		Expect(w.Body.String()).To(ContainSubstring("rate limit exceeded"))
+	})
+})
+
+var _ = Describe("Caller identity", func() {
+	withPeer := func(addr string) context.Context {
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs("x-forwarded-for", "198.51.100.1, 203.0.113.7"))
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 5000}})
+	}
+
+	It("should use the client address appended by the gateway", func() {
+		Expect(callerIdentity(withPeer("127.0.0.1"))).To(Equal("203.0.113.7"))
+	})
+
+	It("should not trust forwarded address of remote clients", func() {
+		Expect(callerIdentity(withPeer("192.0.2.10"))).To(Equal("192.0.2.10"))
+	})
+
+	It("should be empty without the peer", func() {
+		Expect(callerIdentity(context.Background())).To(BeEmpty())
+	})
+
+	It("should prefer the API key of the authenticated client", func() {
+		ctx := auth.NewContext(withPeer("127.0.0.1"), &auth.Identity{Method: auth.MethodKey, ID: "0123456789abcdef"})
+		Expect(callerIdentity(ctx)).To(Equal("key:0123456789abcdef"))
+	})
+})
+
+var _ = Describe("Authentication", func() {
+	var (
+		dir    string
+		keys   *auth.Keys
+		secret string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "apikeys")
+		Expect(err).NotTo(HaveOccurred())
+		store, err := storage.Open(dir, storage.WithSync(false))
+		Expect(err).NotTo(HaveOccurred())
+		keys = auth.NewKeys(auth.NewStorageKeyStore(store))
+		_, secret, err = keys.Create("prometheus", false)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should require the admin key only for admin services", func() {
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.APIKeyService/CreateAPIKey")).To(BeTrue())
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.AdminService/ListDeadLetters")).To(BeTrue())
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.TemplateService/CreateTemplate")).To(BeTrue())
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.SuppressionService/DeleteSuppression")).To(BeTrue())
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.EmailService/WatchMessages")).To(BeTrue())
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.EmailService/SendMail")).To(BeFalse())
+		Expect(adminMethod("/korepta.rafal.email.v1alpha1.CallbackService/CreateCallback")).To(BeFalse())
+	})
+
+	It("should not be enabled without the keys", func() {
+		o := evaluateOptions([]Option{WithAuth(auth.Config{Enabled: true})})
+		_, err := o.authKeys()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not exempt the API", func() {
+		o := evaluateOptions([]Option{WithAPIKeys(keys), WithAuth(auth.Config{Enabled: true, Exempt: []string{emailURI}})})
+		_, err := o.authenticators(nil)
+		Expect(err).To(MatchError(ContainSubstring(emailURI)))
+	})
+
+	It("should protect the HTTP endpoints which are not exempt", func() {
+		webhooks, err := services.NewWebhooks(services.WebhookConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		mux, err := registerServerMux("localhost:0", webhooks, true, auth.Config{Enabled: true, Exempt: []string{"/metrics"}},
+			[]auth.Authenticator{keys.AuthenticateContext}, ratelimit.Config{}.FailuresLimiter(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		get := func(path, authorization string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			return w
+		}
+
+		Expect(get("/metrics", "").Code).To(Equal(http.StatusOK))
+		Expect(get("/swagger.json", "").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("/swagger.json", "Bearer secret").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("/swagger.json", "Bearer "+secret).Code).To(Equal(http.StatusOK))
+		Expect(get("/swagger-ui/", "").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("/swagger-ui/", "Bearer "+secret).Code).To(Equal(http.StatusOK))
+	})
+
+	It("should limit the failed authentications of the client", func() {
+		webhooks, err := services.NewWebhooks(services.WebhookConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		failures := ratelimit.NewLimiter(ratelimit.Config{Rate: 0.01, Burst: 2})
+		mux, err := registerServerMux("localhost:0", webhooks, true, auth.Config{Enabled: true},
+			[]auth.Authenticator{keys.AuthenticateContext}, failures, grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		get := func(authorization string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/swagger.json", nil)
+			req.Header.Set("Authorization", authorization)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			return w
+		}
+
+		Expect(get("Bearer " + secret).Code).To(Equal(http.StatusOK))
+		Expect(get("Bearer secret").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("Bearer secret").Code).To(Equal(http.StatusUnauthorized))
+		rejected := get("Bearer " + secret)
+		Expect(rejected.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rejected.Header().Get("Retry-After")).NotTo(BeEmpty())
+	})
+
+	It("should accept the tokens of the OIDC provider", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"keys": [{"kty": "RSA", "kid": "portal", "n": "%s", "e": "AQAB"}]}`,
+				base64.RawURLEncoding.EncodeToString(key.N.Bytes()))
+		}))
+		defer jwks.Close()
+		jwt, err := auth.NewJWT(auth.JWTConfig{Issuer: "https://portal.example.com", Audience: "email", JWKSURL: jwks.URL})
+		Expect(err).NotTo(HaveOccurred())
+		sign := func(scope string) string {
+			claims, err := json.Marshal(map[string]interface{}{"iss": "https://portal.example.com", "aud": "email",
+				"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix(), "scope": scope})
+			Expect(err).NotTo(HaveOccurred())
+			signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"portal"}`)) + "." +
+				base64.RawURLEncoding.EncodeToString(claims)
+			digest := sha256.Sum256([]byte(signed))
+			signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			Expect(err).NotTo(HaveOccurred())
+			return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+		}
+
+		o := evaluateOptions([]Option{WithAPIKeys(keys), WithAuth(auth.Config{Enabled: true}), WithJWT(jwt)})
+		authenticators, err := o.authenticators(nil)
+		Expect(err).NotTo(HaveOccurred())
+		authenticate := func(token, method string) (*auth.Identity, error) {
+			var identity *auth.Identity
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+			_, err := auth.UnaryServerInterceptor(true, adminMethod, authenticators...)(ctx, nil,
+				&grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+					identity, _ = auth.FromContext(ctx)
+					return nil, nil
+				})
+			return identity, err
+		}
+
+		identity, err := authenticate(sign("email:send"), "/korepta.rafal.email.v1alpha1.EmailService/SendMail")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity.String()).To(Equal("jwt:user-1"))
+		_, err = authenticate(sign("email:send"), "/korepta.rafal.email.v1alpha1.AdminService/ListDeadLetters")
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = authenticate(sign("email:admin"), "/korepta.rafal.email.v1alpha1.AdminService/ListDeadLetters")
+		Expect(err).NotTo(HaveOccurred())
+		identity, err = authenticate(secret, "/korepta.rafal.email.v1alpha1.EmailService/SendMail")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity.Method).To(Equal(auth.MethodKey))
+
+		// the token is required when only the tokens are configured
+		o = evaluateOptions([]Option{WithAPIKeys(keys), WithJWT(jwt)})
+		Expect(o.authRequired()).To(BeTrue())
+		authenticators, err = o.authenticators(nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = auth.UnaryServerInterceptor(o.authRequired(), adminMethod, authenticators...)(context.Background(), nil,
+			&grpc.UnaryServerInfo{FullMethod: "/korepta.rafal.email.v1alpha1.EmailService/SendMail"},
+			func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+	})
+})
+
+var _ = Describe("Gateway stream content type", func() {
+	It("should mark streamed responses as newline-delimited JSON", func() {
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Type", "application/json")
+		Expect(streamContentType(context.Background(), w, nil)).To(Succeed())
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/x-ndjson"))
+	})
+
+	It("should keep content type of unary responses", func() {
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Type", "application/json")
+		Expect(streamContentType(context.Background(), w, newEmailRequest())).To(Succeed())
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+	})
+})
+
 //func Test_initializeTracer(t *testing.T) {
 //	// Arrange
 //	noopTracer := opentracing.GlobalTracer()
@@ -372,7 +855,7 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 //func Test_serveSwagger(t *testing.T) {
 //	// Arrange
 //	mux := http.NewServeMux()
-//	serveSwagger(mux)
+//	mux.Handle(swaggerUIPrefix, swaggerUIHandler())
 //	contentType := mime.TypeByExtension(".html")
 //
 //	t.Run("Mux has swagger-ui path registered", func(t *testing.T) {
@@ -525,3 +1008,133 @@ var _ = Describe("Server that register REST and gRPC endpoint on the same port",
 //		})
 //	})
 //}
+
+var _ = Describe("Mutual TLS", func() {
+	var (
+		dir          string
+		server       *httptest.Server
+		billing, ops tls.Certificate
+		serverCAs    *x509.CertPool
+	)
+
+	newCertificate := func(template, parent *x509.Certificate, parentKey crypto.Signer) (tls.Certificate, *x509.Certificate) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := x509.ParseCertificate(der)
+		Expect(err).NotTo(HaveOccurred())
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, cert
+	}
+
+	clientTLS := func(certs ...tls.Certificate) *tls.Config {
+		return &tls.Config{ServerName: "localhost", RootCAs: serverCAs, Certificates: certs}
+	}
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "mtls")
+		Expect(err).NotTo(HaveOccurred())
+		serverCAs, err = createPool("test_data/server.pem")
+		Expect(err).NotTo(HaveOccurred())
+
+		validity := func(t *x509.Certificate) *x509.Certificate {
+			t.NotBefore, t.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+			return t
+		}
+		ca, caCert := newCertificate(validity(&x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "Clients CA"},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}), nil, nil)
+		caFile := filepath.Join(dir, "ca.pem")
+		Expect(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600)).To(Succeed())
+		billing, _ = newCertificate(validity(&x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "billing"},
+			DNSNames:     []string{"billing.example.com"},
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}), caCert, ca.PrivateKey.(crypto.Signer))
+		ops, _ = newCertificate(validity(&x509.Certificate{
+			SerialNumber: big.NewInt(3),
+			Subject:      pkix.Name{CommonName: "ops"},
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}), caCert, ca.PrivateKey.(crypto.Signer))
+
+		// The gateway dials the listener address, so every server has its own listener
+		l := newLocalListener()
+		srv, _, err := createHTTPServer(l.Addr().String(),
+			WithCertFile("test_data/server.pem"),
+			WithKeyFile("test_data/server.key"),
+			WithServerOverrideName("localhost"),
+			WithSecure(true),
+			WithProvider(fakeProvider{}),
+			WithMTLS(auth.MTLSConfig{
+				ClientCAFile: caFile,
+				Identities: []auth.CertificateIdentity{
+					{Name: "billing", DNSName: "billing.example.com"},
+					{Name: "ops", Subject: "CN=ops", Admin: true},
+				},
+			}))
+		Expect(err).NotTo(HaveOccurred())
+		server = &httptest.Server{Listener: l, TLS: srv.TLSConfig, Config: srv}
+		server.StartTLS()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	get := func(cfg *tls.Config, uri string) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		return client.Get(server.URL + uri)
+	}
+
+	It("should reject the clients without the certificate", func() {
+		_, err := get(clientTLS(), "/v1alpha1/admin/deadletters")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should authorize the REST clients by the certificates forwarded by the gateway", func() {
+		response, err := get(clientTLS(billing), "/v1alpha1/admin/deadletters")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+		response, err = get(clientTLS(ops), "/v1alpha1/admin/deadletters")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusPreconditionFailed))
+	})
+
+	It("should authorize the gRPC clients by their certificates", func() {
+		call := func(cert tls.Certificate) error {
+			conn, err := grpc.Dial(server.Listener.Addr().String(),
+				grpc.WithTransportCredentials(credentials.NewTLS(clientTLS(cert))))
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+			_, err = email.NewAdminServiceClient(conn).ListDeadLetters(context.Background(), &email.ListDeadLettersRequest{})
+			return err
+		}
+
+		Expect(status.Code(call(billing))).To(Equal(codes.PermissionDenied))
+		Expect(status.Code(call(ops))).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("should not trust the server certificate to forward the client certificates", func() {
+		serverCert, err := tls.LoadX509KeyPair("test_data/server.pem", "test_data/server.key")
+		Expect(err).NotTo(HaveOccurred())
+		conn, err := grpc.Dial(server.Listener.Addr().String(),
+			grpc.WithTransportCredentials(credentials.NewTLS(clientTLS(serverCert))))
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		ctx := metadata.AppendToOutgoingContext(context.Background(), auth.ClientCertificateMetadata,
+			base64.StdEncoding.EncodeToString(ops.Certificate[0]))
+
+		_, err = email.NewAdminServiceClient(conn).ListDeadLetters(ctx, &email.ListDeadLettersRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+	})
+})
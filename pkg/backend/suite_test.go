@@ -1,10 +1,13 @@
 package backend
 
 import (
+	"context"
 	"testing"
 
 	"net"
 
+	"github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/services"
 	. "github.com/onsi/ginkgo"
 	"github.com/onsi/ginkgo/reporters"
 	. "github.com/onsi/gomega"
@@ -27,20 +30,40 @@ func init() {
 }
 
 var (
-	err      error
-	listener net.Listener
-	opts     []Option
+	err              error
+	listener         net.Listener
+	secureListener   net.Listener
+	insecureListener net.Listener
+	opts             []Option
 )
 
 var _ = BeforeSuite(func() {
-	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	secureListener = newLocalListener()
+	insecureListener = newLocalListener()
+	listener = secureListener
+})
+
+// fakeProvider accepts every email without sending it anywhere
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string {
+	return "fake"
+}
+
+func (fakeProvider) Send(ctx context.Context, req *email.EmailRequest) (*services.Receipt, error) {
+	return &services.Receipt{Provider: "fake", MessageID: "fake-id"}, nil
+}
+
+func newLocalListener() net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		listener, err = net.Listen("tcp6", "[::1]:0")
+		l, err = net.Listen("tcp6", "[::1]:0")
 		Expect(err).NotTo(HaveOccurred())
 	}
 	Expect(err).NotTo(HaveOccurred())
-	Expect(listener).NotTo(BeNil())
-})
+	Expect(l).NotTo(BeNil())
+	return l
+}
 
 var _ = AfterSuite(func() {
 })
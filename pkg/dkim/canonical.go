@@ -0,0 +1,125 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dkim
+
+import (
+	"bytes"
+	"strings"
+)
+
+// field is the single header field as it appears in the message,
+// the continuation lines included
+type field struct {
+	name string
+	raw  string
+}
+
+// splitMessage returns the header fields in order and the body of the message
+func splitMessage(message []byte) ([]field, []byte) {
+	var header, body []byte
+	if i := bytes.Index(message, []byte("\r\n\r\n")); i >= 0 {
+		header, body = message[:i+2], message[i+4:]
+	} else {
+		header = message
+	}
+
+	var fields []field
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += line
+			continue
+		}
+		name := line
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			name = line[:i]
+		}
+		fields = append(fields, field{name: strings.TrimSpace(name), raw: line})
+	}
+	return fields, body
+}
+
+// relaxedHeader canonicalizes the header field with the relaxed algorithm of
+// RFC 6376 section 3.4.2. The result ends with CRLF.
+func relaxedHeader(raw string) string {
+	i := strings.IndexByte(raw, ':')
+	if i < 0 {
+		return strings.ToLower(strings.TrimSpace(raw)) + ":\r\n"
+	}
+	name := strings.ToLower(strings.TrimRight(raw[:i], " \t"))
+	value := strings.Replace(raw[i+1:], "\r\n", "", -1)
+	return name + ":" + strings.Join(strings.Fields(value), " ") + "\r\n"
+}
+
+// relaxedBody canonicalizes the body with the relaxed algorithm of
+// RFC 6376 section 3.4.4
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	var buf bytes.Buffer
+	empty := 0
+	for _, line := range lines {
+		line = strings.TrimRight(collapseWSP(line), " ")
+		if line == "" {
+			empty++
+			continue
+		}
+		for ; empty > 0; empty-- {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(line)
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+// collapseWSP reduces every sequence of spaces and tabs to the single space
+func collapseWSP(s string) string {
+	if !strings.ContainsAny(s, " \t") {
+		return s
+	}
+	var b bytes.Buffer
+	inWSP := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\t' {
+			if !inWSP {
+				b.WriteByte(' ')
+			}
+			inWSP = true
+			continue
+		}
+		inWSP = false
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// selectFields picks the fields listed in h= tag, every occurrence of the
+// name takes the next field from the bottom of the header. The names without
+// the matching field are skipped, as they sign the field absence.
+func selectFields(fields []field, names []string) []string {
+	used := make(map[int]bool)
+	var selected []string
+	for _, name := range names {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && strings.EqualFold(fields[i].name, name) {
+				used[i] = true
+				selected = append(selected, fields[i].raw)
+				break
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,295 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package dkim signs and verifies messages with DomainKeys Identified Mail
+// signatures of RFC 6376. The rsa-sha256 and ed25519-sha256 (RFC 8463)
+// algorithms are supported, the header and body are always canonicalized with
+// the relaxed algorithm.
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The signing algorithms
+const (
+	RSASHA256     = "rsa-sha256"
+	Ed25519SHA256 = "ed25519-sha256"
+)
+
+const (
+	headerName       = "DKIM-Signature"
+	canonicalization = "relaxed/relaxed"
+	// maxLineLength is the length to which the signature header is folded
+	maxLineLength = 78
+)
+
+// DefaultHeaders are signed when the configuration does not list them
+var DefaultHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// KeyConfig is the signing key of the domain e.g.:
+//
+//	dkim:
+//	  - domain: example.com
+//	    selector: mail2018
+//	    private_key_file: /etc/dkim/example.com.pem
+type KeyConfig struct {
+	// Domain is the d= tag, the key signs messages from the domain and its subdomains
+	Domain string `mapstructure:"domain"`
+	// Selector is the s= tag, the public key is published at <selector>._domainkey.<domain>
+	Selector string `mapstructure:"selector"`
+	// PrivateKey is the PEM encoded PKCS#1 or PKCS#8 RSA or Ed25519 key
+	PrivateKey string `mapstructure:"private_key"`
+	// PrivateKeyFile is read when PrivateKey is empty
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	// Headers are the names of signed header fields, default is DefaultHeaders
+	Headers []string `mapstructure:"headers"`
+}
+
+// Signer adds the DKIM-Signature header to the messages of the domain
+type Signer struct {
+	domain    string
+	selector  string
+	algorithm string
+	key       crypto.Signer
+	headers   []string
+	now       func() time.Time
+}
+
+// NewSigner constructor of Signer
+func NewSigner(cfg KeyConfig) (*Signer, error) {
+	if cfg.Domain == "" {
+		return nil, errors.New("dkim: domain can not be empty")
+	}
+	if cfg.Selector == "" {
+		return nil, fmt.Errorf("dkim: selector of %s can not be empty", cfg.Domain)
+	}
+	pemKey := []byte(cfg.PrivateKey)
+	if len(pemKey) == 0 {
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("dkim: private key of %s is required", cfg.Domain)
+		}
+		var err error
+		if pemKey, err = ioutil.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("dkim: unable to read private key of %s: %v", cfg.Domain, err)
+		}
+	}
+	key, err := parsePrivateKey(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: private key of %s: %v", cfg.Domain, err)
+	}
+
+	s := &Signer{
+		domain:   strings.ToLower(cfg.Domain),
+		selector: cfg.Selector,
+		key:      key,
+		headers:  cfg.Headers,
+		now:      time.Now,
+	}
+	if len(s.headers) == 0 {
+		s.headers = DefaultHeaders
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		s.algorithm = RSASHA256
+	default:
+		s.algorithm = Ed25519SHA256
+	}
+	return s, nil
+}
+
+func parsePrivateKey(pemKey []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("PEM block not found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// Domain returns the d= tag of signatures
+func (s *Signer) Domain() string {
+	return s.domain
+}
+
+// Algorithm returns the a= tag of signatures
+func (s *Signer) Algorithm() string {
+	return s.algorithm
+}
+
+// PublicKeyRecord returns the TXT record which must be published at
+// <selector>._domainkey.<domain> for the receivers to verify the signature
+func (s *Signer) PublicKeyRecord() (string, error) {
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", err
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub), nil
+	default:
+		return "", fmt.Errorf("dkim: unsupported key type %T", pub)
+	}
+}
+
+// Sign returns the message with the DKIM-Signature header prepended.
+// The message must have CRLF line endings.
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	fields, body := splitMessage(message)
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	// Every field present in the message is signed and so is the absence of
+	// the remaining ones, so they can not be added later on
+	var names []string
+	for _, name := range s.headers {
+		n := 0
+		for _, f := range fields {
+			if strings.EqualFold(f.name, name) {
+				n++
+			}
+		}
+		for i := 0; i == 0 || i < n; i++ {
+			names = append(names, name)
+		}
+	}
+
+	// The header is folded before signing, as the folding whitespace is
+	// the part of the signed data
+	var f folder
+	f.write(headerName+":", false)
+	for _, tag := range []string{
+		"v=1;",
+		"a=" + s.algorithm + ";",
+		"c=" + canonicalization + ";",
+		"d=" + s.domain + ";",
+		"s=" + s.selector + ";",
+		"t=" + strconv.FormatInt(s.now().Unix(), 10) + ";",
+	} {
+		f.write(tag, true)
+	}
+	for i, name := range names {
+		piece := name + ":"
+		if i == len(names)-1 {
+			piece = name + ";"
+		}
+		if i == 0 {
+			f.write("h="+piece, true)
+		} else {
+			f.write(piece, false)
+		}
+	}
+	f.write("bh="+base64.StdEncoding.EncodeToString(bodyHash[:])+";", true)
+	f.write("b=", true)
+
+	signature, err := s.sign(signedData(fields, names, f.buf.String()))
+	if err != nil {
+		return nil, fmt.Errorf("dkim: unable to sign message: %v", err)
+	}
+	f.writeSplit(base64.StdEncoding.EncodeToString(signature))
+	f.buf.WriteString("\r\n")
+	f.buf.Write(message)
+	return f.buf.Bytes(), nil
+}
+
+func (s *Signer) sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	if s.algorithm == Ed25519SHA256 {
+		// RFC 8463 - the PureEdDSA signs the SHA-256 hash of the data
+		return s.key.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	}
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// signedData returns the input of the header hash of RFC 6376 section 3.7.
+// The signature field must have the empty b= tag and it is not terminated by CRLF.
+func signedData(fields []field, names []string, signatureField string) []byte {
+	var buf bytes.Buffer
+	for _, raw := range selectFields(fields, names) {
+		buf.WriteString(relaxedHeader(raw))
+	}
+	buf.WriteString(strings.TrimSuffix(relaxedHeader(signatureField), "\r\n"))
+	return buf.Bytes()
+}
+
+// folder writes the signature header folded to maxLineLength. The folding
+// whitespace is allowed between the tags and inside h= and b= values.
+type folder struct {
+	buf     bytes.Buffer
+	lineLen int
+}
+
+// write appends the piece, separated with the space from the previous one if requested
+func (f *folder) write(piece string, space bool) {
+	sep := 0
+	if space {
+		sep = 1
+	}
+	if f.lineLen > 1 && f.lineLen+sep+len(piece) > maxLineLength {
+		f.buf.WriteString("\r\n ")
+		f.lineLen = 1
+	} else if space {
+		f.buf.WriteByte(' ')
+		f.lineLen++
+	}
+	f.buf.WriteString(piece)
+	f.lineLen += len(piece)
+}
+
+// writeSplit appends the value split into as many lines as needed
+func (f *folder) writeSplit(value string) {
+	for len(value) > 0 {
+		n := maxLineLength - f.lineLen
+		if n <= 0 {
+			f.buf.WriteString("\r\n ")
+			f.lineLen, n = 1, maxLineLength-1
+		}
+		if n > len(value) {
+			n = len(value)
+		}
+		f.buf.WriteString(value[:n])
+		f.lineLen += n
+		value = value[n:]
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dkim
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMessage = "From: Sender <sender@example.com>\r\n" +
+	"To: recipient@example.org\r\n" +
+	"Subject: Hello\r\n" +
+	"Date: Fri, 01 Jun 2018 12:00:00 +0000\r\n" +
+	"Message-ID: <id@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Hello world\r\n"
+
+func rsaKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func ed25519Key(t *testing.T) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// fakeDNS publishes the key records of signers
+type fakeDNS map[string]string
+
+func (d fakeDNS) publish(t *testing.T, cfg KeyConfig, s *Signer) {
+	record, err := s.PublicKeyRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d[cfg.Selector+"._domainkey."+cfg.Domain] = record
+}
+
+func (d fakeDNS) lookup(name string) ([]string, error) {
+	if record, ok := d[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, fmt.Errorf("no such host %s", name)
+}
+
+func newTestSigner(t *testing.T, dns fakeDNS, cfg KeyConfig) *Signer {
+	s, err := NewSigner(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dns.publish(t, cfg, s)
+	return s
+}
+
+func TestRelaxedCanonicalization(t *testing.T) {
+	// The example of RFC 6376 section 3.4.5
+
+	t.Run("Header", func(t *testing.T) {
+		// Act
+		a := relaxedHeader("A: X\r\n")
+		b := relaxedHeader("B : Y\t\r\n\tZ  \r\n")
+
+		// Assert
+		assert.Equal(t, "a:X\r\nb:Y Z\r\n", a+b, "Header must be canonicalized")
+	})
+
+	t.Run("Body", func(t *testing.T) {
+		// Act
+		body := relaxedBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))
+
+		// Assert
+		assert.Equal(t, " C\r\nD E\r\n", string(body), "Body must be canonicalized")
+	})
+
+	t.Run("Empty body", func(t *testing.T) {
+		// Act
+		body := relaxedBody([]byte("\r\n\r\n"))
+
+		// Assert
+		assert.Empty(t, body, "Empty lines must be removed")
+	})
+}
+
+func TestSigner_Sign(t *testing.T) {
+	keys := []struct {
+		algorithm string
+		key       func(t *testing.T) string
+	}{
+		{RSASHA256, rsaKey},
+		{Ed25519SHA256, ed25519Key},
+	}
+	for _, k := range keys {
+		t.Run(k.algorithm, func(t *testing.T) {
+			// Arrange
+			dns := fakeDNS{}
+			cfg := KeyConfig{Domain: "example.com", Selector: "mail", PrivateKey: k.key(t)}
+			s := newTestSigner(t, dns, cfg)
+
+			// Act
+			signed, err := s.Sign([]byte(testMessage))
+
+			// Assert
+			assert.NoError(t, err, "Error should not occur")
+			assert.Equal(t, k.algorithm, s.Algorithm(), "Algorithm must match the key")
+			unfolded := strings.Replace(string(signed), "\r\n ", " ", -1)
+			assert.True(t, strings.HasPrefix(unfolded, "DKIM-Signature: v=1; a="+k.algorithm+"; c=relaxed/relaxed; d=example.com; s=mail;"),
+				"Signature must be prepended")
+			assert.Contains(t, strings.Replace(unfolded, " ", "", -1),
+				"h=From:Reply-To:Subject:Date:To:Cc:Message-ID:MIME-Version:Content-Type:Content-Transfer-Encoding;",
+				"Headers must be signed")
+			for _, line := range strings.Split(string(signed), "\r\n") {
+				assert.True(t, len(line) <= maxLineLength, "Line must be folded: %q", line)
+			}
+			assert.NoError(t, Verify(signed, dns.lookup), "Signature must be valid")
+
+			t.Run("Relaxed changes are tolerated", func(t *testing.T) {
+				// Arrange
+				relayed := strings.Replace(string(signed), "Subject: Hello\r\n", "subject:   Hello \r\n", 1)
+				relayed = strings.Replace(relayed, "Hello world\r\n", "Hello   world  \r\n\r\n", 1)
+				relayed = "Received: from relay\r\n" + relayed
+
+				// Act
+				err := Verify([]byte(relayed), dns.lookup)
+
+				// Assert
+				assert.NoError(t, err, "Whitespace and new headers must not break signature")
+			})
+
+			tampered := []struct {
+				name, old, new string
+			}{
+				{"Tampered subject", "Subject: Hello", "Subject: Hi"},
+				{"Tampered body", "Hello world", "Hello there"},
+				{"Added signed header", "Subject: Hello\r\n", "Subject: Hello\r\nCc: other@example.org\r\n"},
+			}
+			for _, tt := range tampered {
+				t.Run(tt.name, func(t *testing.T) {
+					// Act
+					err := Verify([]byte(strings.Replace(string(signed), tt.old, tt.new, 1)), dns.lookup)
+
+					// Assert
+					assert.Error(t, err, "Signature must not be valid")
+				})
+			}
+		})
+	}
+
+	t.Run("Configured headers are signed", func(t *testing.T) {
+		// Arrange
+		dns := fakeDNS{}
+		cfg := KeyConfig{Domain: "example.com", Selector: "mail", PrivateKey: ed25519Key(t), Headers: []string{"From"}}
+		s := newTestSigner(t, dns, cfg)
+
+		// Act
+		signed, err := s.Sign([]byte(testMessage))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Contains(t, string(signed), " h=From;", "Only configured header must be signed")
+		assert.NoError(t, Verify(signed, dns.lookup), "Signature must be valid")
+	})
+
+	t.Run("Missing key record", func(t *testing.T) {
+		// Arrange
+		s, err := NewSigner(KeyConfig{Domain: "example.com", Selector: "mail", PrivateKey: ed25519Key(t)})
+		assert.NoError(t, err, "Error should not occur")
+		signed, _ := s.Sign([]byte(testMessage))
+
+		// Act
+		err = Verify(signed, fakeDNS{}.lookup)
+
+		// Assert
+		assert.Error(t, err, "Signature must not be valid")
+	})
+}
+
+func TestVerify_NotSigned(t *testing.T) {
+	// Act
+	err := Verify([]byte(testMessage), fakeDNS{}.lookup)
+
+	// Assert
+	assert.Equal(t, ErrNoSignature, err, "Missing signature must be reported")
+}
+
+func TestNewSigner(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  KeyConfig
+	}{
+		{"Domain is required", KeyConfig{Selector: "mail", PrivateKey: "key"}},
+		{"Selector is required", KeyConfig{Domain: "example.com", PrivateKey: "key"}},
+		{"Key is required", KeyConfig{Domain: "example.com", Selector: "mail"}},
+		{"Key must be PEM", KeyConfig{Domain: "example.com", Selector: "mail", PrivateKey: "key"}},
+		{"Key file must exist", KeyConfig{Domain: "example.com", Selector: "mail", PrivateKeyFile: "/nonexistent.pem"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			s, err := NewSigner(tt.cfg)
+
+			// Assert
+			assert.Error(t, err, "Error must occur")
+			assert.Nil(t, s, "Signer must not exist")
+		})
+	}
+}
+
+func TestKeyring_Sign(t *testing.T) {
+	// Arrange
+	dns := fakeDNS{}
+	rsaCfg := KeyConfig{Domain: "example.com", Selector: "rsa", PrivateKey: rsaKey(t)}
+	edCfg := KeyConfig{Domain: "example.com", Selector: "ed", PrivateKey: ed25519Key(t)}
+	keyring, err := NewKeyring([]KeyConfig{rsaCfg, edCfg})
+	assert.NoError(t, err, "Error should not occur")
+	for i, cfg := range []KeyConfig{rsaCfg, edCfg} {
+		dns.publish(t, cfg, keyring.signers["example.com"][i])
+	}
+
+	t.Run("Message is signed with every key of the domain", func(t *testing.T) {
+		// Act
+		signed, err := keyring.Sign("example.com", []byte(testMessage))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, 2, strings.Count(string(signed), "DKIM-Signature:"), "Every key must sign")
+		assert.NoError(t, Verify(signed, dns.lookup), "Signatures must be valid")
+	})
+
+	t.Run("Key of parent domain is used for subdomain", func(t *testing.T) {
+		// Act
+		signed, err := keyring.Sign("Mail.Example.com", []byte(testMessage))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Contains(t, string(signed), "d=example.com;", "Parent domain must sign")
+	})
+
+	t.Run("Message of unknown domain is not signed", func(t *testing.T) {
+		// Act
+		signed, err := keyring.Sign("example.org", []byte(testMessage))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, testMessage, string(signed), "Message must not be changed")
+	})
+
+	t.Run("Duplicated selector", func(t *testing.T) {
+		// Act
+		_, err := NewKeyring([]KeyConfig{rsaCfg, rsaCfg})
+
+		// Assert
+		assert.Error(t, err, "Error must occur")
+	})
+}
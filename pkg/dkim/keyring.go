@@ -0,0 +1,78 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dkim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keyring holds the signers of the sender domains. The domain can have more
+// signers, e.g. rsa-sha256 and ed25519-sha256 as recommended by RFC 8463, then
+// the message gets every signature.
+type Keyring struct {
+	signers map[string][]*Signer
+}
+
+// NewKeyring creates the signers of all keys
+func NewKeyring(keys []KeyConfig) (*Keyring, error) {
+	k := &Keyring{signers: make(map[string][]*Signer)}
+	for _, cfg := range keys {
+		s, err := NewSigner(cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range k.signers[s.domain] {
+			if other.selector == s.selector {
+				return nil, fmt.Errorf("dkim: selector %s of %s is duplicated", s.selector, s.domain)
+			}
+		}
+		k.signers[s.domain] = append(k.signers[s.domain], s)
+	}
+	return k, nil
+}
+
+// Len returns the number of signers
+func (k *Keyring) Len() int {
+	n := 0
+	for _, signers := range k.signers {
+		n += len(signers)
+	}
+	return n
+}
+
+// Sign signs the message of the sender domain. The key of the parent domain is
+// used when the subdomain has no key. The message without the key for its
+// domain is returned unchanged.
+func (k *Keyring) Sign(domain string, message []byte) ([]byte, error) {
+	domain = strings.ToLower(domain)
+	for domain != "" {
+		if signers, ok := k.signers[domain]; ok {
+			for _, s := range signers {
+				signed, err := s.Sign(message)
+				if err != nil {
+					return nil, err
+				}
+				message = signed
+			}
+			return message, nil
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return message, nil
+}
@@ -0,0 +1,181 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dkim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoSignature is returned by Verify when the message is not signed
+var ErrNoSignature = errors.New("dkim: message is not signed")
+
+// LookupTXT returns the TXT records of the name, e.g. net.LookupTXT
+type LookupTXT func(name string) ([]string, error)
+
+// Verify checks every DKIM-Signature of the message against the public keys
+// returned by lookup. Only the relaxed/relaxed canonicalization is supported.
+func Verify(message []byte, lookup LookupTXT) error {
+	fields, body := splitMessage(message)
+	signed := false
+	for _, f := range fields {
+		if !strings.EqualFold(f.name, headerName) {
+			continue
+		}
+		signed = true
+		if err := verifySignature(f, fields, body, lookup); err != nil {
+			return err
+		}
+	}
+	if !signed {
+		return ErrNoSignature
+	}
+	return nil
+}
+
+func verifySignature(signature field, fields []field, body []byte, lookup LookupTXT) error {
+	value := signature.raw[strings.IndexByte(signature.raw, ':')+1:]
+	tags, err := parseTags(value)
+	if err != nil {
+		return err
+	}
+	if tags["v"] != "1" {
+		return fmt.Errorf("dkim: unsupported version %q", tags["v"])
+	}
+	if tags["c"] != canonicalization {
+		return fmt.Errorf("dkim: unsupported canonicalization %q", tags["c"])
+	}
+	if _, ok := tags["l"]; ok {
+		return errors.New("dkim: body length limit is not supported")
+	}
+	domain, selector := tags["d"], tags["s"]
+	if domain == "" || selector == "" || tags["h"] == "" {
+		return errors.New("dkim: signature is missing d=, s= or h= tag")
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return fmt.Errorf("dkim: body hash of %s does not match", domain)
+	}
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fmt.Errorf("dkim: signature of %s is not valid base64", domain)
+	}
+
+	key, err := lookupKey(domain, selector, lookup)
+	if err != nil {
+		return err
+	}
+	names := strings.Split(tags["h"], ":")
+	digest := sha256.Sum256(signedData(fields, names, withoutSignature(signature.raw)))
+
+	switch tags["a"] {
+	case RSASHA256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("dkim: key of %s is not rsa", domain)
+		}
+		err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+	case Ed25519SHA256:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("dkim: key of %s is not ed25519", domain)
+		}
+		if !ed25519.Verify(pub, digest[:], sig) {
+			err = errors.New("verification failed")
+		}
+	default:
+		return fmt.Errorf("dkim: unsupported algorithm %q", tags["a"])
+	}
+	if err != nil {
+		return fmt.Errorf("dkim: signature of %s is not valid: %v", domain, err)
+	}
+	return nil
+}
+
+// parseTags parses the tag list of RFC 6376 section 3.2, the whitespace
+// inside the values is removed
+func parseTags(value string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(value, ";") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		i := strings.IndexByte(tag, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("dkim: malformed tag %q", tag)
+		}
+		name := strings.TrimSpace(tag[:i])
+		if _, ok := tags[name]; ok {
+			return nil, fmt.Errorf("dkim: duplicated tag %q", name)
+		}
+		tags[name] = strings.Join(strings.Fields(tag[i+1:]), "")
+	}
+	return tags, nil
+}
+
+// withoutSignature removes the value of the b= tag from the signature field
+func withoutSignature(raw string) string {
+	i := strings.IndexByte(raw, ':')
+	tags := strings.Split(raw[i+1:], ";")
+	for j, tag := range tags {
+		if eq := strings.IndexByte(tag, '='); eq >= 0 && strings.TrimSpace(tag[:eq]) == "b" {
+			tags[j] = tag[:eq+1]
+		}
+	}
+	return raw[:i+1] + strings.Join(tags, ";")
+}
+
+func lookupKey(domain, selector string, lookup LookupTXT) (crypto.PublicKey, error) {
+	name := selector + "._domainkey." + domain
+	records, err := lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: unable to lookup %s: %v", name, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("dkim: key record %s not found", name)
+	}
+	tags, err := parseTags(strings.Join(records, ""))
+	if err != nil {
+		return nil, err
+	}
+	p, err := base64.StdEncoding.DecodeString(tags["p"])
+	if err != nil || len(p) == 0 {
+		return nil, fmt.Errorf("dkim: key record %s has no valid key", name)
+	}
+
+	switch tags["k"] {
+	case "", "rsa":
+		if key, err := x509.ParsePKIXPublicKey(p); err == nil {
+			return key, nil
+		}
+		// RFC 6376 defines p= as RSAPublicKey, which is also used in practice
+		return x509.ParsePKCS1PublicKey(p)
+	case "ed25519":
+		if len(p) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("dkim: key record %s has invalid ed25519 key", name)
+		}
+		return ed25519.PublicKey(p), nil
+	default:
+		return nil, fmt.Errorf("dkim: key record %s has unsupported key type %q", name, tags["k"])
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// Encoding is the Content-Transfer-Encoding of the part
+type Encoding string
+
+// The transfer encodings supported by the builder
+const (
+	// SevenBit is used for ASCII text with short lines
+	SevenBit Encoding = "7bit"
+	// QuotedPrintable is used for text with non-ASCII characters or long lines
+	QuotedPrintable Encoding = "quoted-printable"
+	// Base64 is used for binary attachments
+	Base64 Encoding = "base64"
+)
+
+// maxBodyLineLength is the limit of RFC 5322 section 2.1.1 without CRLF
+const maxBodyLineLength = 998
+
+// base64LineLength is the line length required by RFC 2045 section 6.8
+const base64LineLength = 76
+
+// textEncoding picks the encoding for the text part
+func textEncoding(text string) Encoding {
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) > maxBodyLineLength {
+			return QuotedPrintable
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= 0x80 || (c < ' ' && c != '\r' && c != '\n' && c != '\t') {
+			return QuotedPrintable
+		}
+	}
+	return SevenBit
+}
+
+// encode writes the content in the transfer encoding with CRLF line endings
+func encode(buf *bytes.Buffer, enc Encoding, content []byte) error {
+	switch enc {
+	case QuotedPrintable:
+		qp := quotedprintable.NewWriter(buf)
+		if _, err := qp.Write(content); err != nil {
+			return err
+		}
+		return qp.Close()
+	case Base64:
+		encoded := base64.StdEncoding.EncodeToString(content)
+		for len(encoded) > base64LineLength {
+			buf.WriteString(encoded[:base64LineLength])
+			buf.WriteString("\r\n")
+			encoded = encoded[base64LineLength:]
+		}
+		buf.WriteString(encoded)
+		return nil
+	default:
+		text := strings.Replace(string(content), "\r\n", "\n", -1)
+		buf.WriteString(strings.Replace(text, "\n", "\r\n", -1))
+		return nil
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mime
+
+import (
+	"bytes"
+	stdmime "mime"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength is the length recommended by RFC 5322 section 2.1.1, lines of
+// headers are folded to fit in it whenever there is a whitespace to fold at
+const maxLineLength = 78
+
+// Address is the mailbox with optional display name
+type Address struct {
+	Name  string
+	Email string
+}
+
+// FormatAddress returns the address as it is written in the header. The non-ASCII
+// display name is encoded according to RFC 2047.
+func FormatAddress(a Address) string {
+	return (&mail.Address{Name: a.Name, Address: a.Email}).String()
+}
+
+// FormatAddressList returns the comma separated list of addresses
+func FormatAddressList(addrs []Address) string {
+	formatted := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		formatted = append(formatted, FormatAddress(a))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// EncodeHeader encodes the unstructured header value e.g. subject according
+// to RFC 2047 when it contains non-ASCII characters. The Q encoding is used for
+// mostly ASCII text and the B encoding otherwise, whichever is shorter.
+func EncodeHeader(value string) string {
+	nonASCII := 0
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			nonASCII++
+		}
+	}
+	if nonASCII == 0 {
+		return value
+	}
+	if nonASCII*3 > len(value) {
+		return stdmime.BEncoding.Encode("utf-8", value)
+	}
+	return stdmime.QEncoding.Encode("utf-8", value)
+}
+
+// writeHeader writes the header field folded according to RFC 5322 section 2.2.3.
+// The value is folded before the whitespace, so unfolding restores it.
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	line := name + ":"
+	lineLen := len(line)
+	buf.WriteString(line)
+
+	words := strings.Split(value, " ")
+	for i, word := range words {
+		if i > 0 && lineLen+1+len(word) > maxLineLength && lineLen > 1 {
+			buf.WriteString("\r\n")
+			lineLen = 0
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(word)
+		lineLen += 1 + len(word)
+	}
+	buf.WriteString("\r\n")
+}
@@ -0,0 +1,106 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mime
+
+import (
+	"bytes"
+	stdmime "mime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeHeader(t *testing.T) {
+	dec := new(stdmime.WordDecoder)
+	tests := []struct {
+		name   string
+		value  string
+		prefix string
+	}{
+		{"ASCII is not encoded", "Hello world", "Hello world"},
+		{"Mostly ASCII uses Q encoding", "Café au lait, déjà vu", "=?utf-8?q?"},
+		{"Non-ASCII uses B encoding", "こんにちは世界", "=?utf-8?b?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			encoded := EncodeHeader(tt.value)
+
+			// Assert
+			assert.True(t, strings.HasPrefix(encoded, tt.prefix), "Value must be encoded with proper encoding: %s", encoded)
+			decoded, err := dec.DecodeHeader(encoded)
+			assert.NoError(t, err, "Encoded value must be decodable")
+			assert.Equal(t, tt.value, decoded, "Decoded value must be equal to original")
+		})
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	// Act
+	formatted := FormatAddressList([]Address{
+		{Email: "alice@example.com"},
+		{Name: "Bob Smith", Email: "bob@example.com"},
+		{Name: "Łukasz", Email: "lukasz@example.com"},
+	})
+
+	// Assert
+	assert.Equal(t, "<alice@example.com>, \"Bob Smith\" <bob@example.com>, =?utf-8?q?=C5=81ukasz?= <lukasz@example.com>",
+		formatted, "Addresses must be formatted and the non-ASCII name encoded")
+}
+
+func TestWriteHeader(t *testing.T) {
+	t.Run("Short header is not folded", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+
+		// Act
+		writeHeader(&buf, "Subject", "Hello")
+
+		// Assert
+		assert.Equal(t, "Subject: Hello\r\n", buf.String(), "Header must be written in single line")
+	})
+
+	t.Run("Long header is folded at whitespace", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		value := strings.Repeat("lorem ipsum ", 20)
+
+		// Act
+		writeHeader(&buf, "Subject", value)
+
+		// Assert
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+		assert.True(t, len(lines) > 1, "Header must be folded")
+		for i, line := range lines {
+			assert.True(t, len(line) <= maxLineLength, "Line must not be longer than %d: %q", maxLineLength, line)
+			if i > 0 {
+				assert.True(t, strings.HasPrefix(line, " "), "Continuation line must start with whitespace")
+			}
+		}
+		assert.Equal(t, "Subject: "+value, strings.Join(lines, ""), "Unfolding must restore header")
+	})
+
+	t.Run("Long word is not split", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		word := strings.Repeat("x", 100)
+
+		// Act
+		writeHeader(&buf, "X-Token", word)
+
+		// Assert
+		assert.Equal(t, "X-Token: "+word+"\r\n", buf.String(), "Header without whitespace must not be folded")
+	})
+}
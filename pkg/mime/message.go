@@ -0,0 +1,314 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package mime builds RFC 5322 messages with MIME bodies for providers that
+// accept raw emails.
+package mime
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	stdmime "mime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	textContentType = "text/plain; charset=utf-8"
+	htmlContentType = "text/html; charset=utf-8"
+	// defaultAttachmentType is used when the type of attachment is not given
+	defaultAttachmentType = "application/octet-stream"
+)
+
+// Attachment is the file attached to the message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	// Inline attachments are embedded in the HTML body, which references
+	// them with cid:ContentID
+	Inline    bool
+	ContentID string
+}
+
+// Message describes the email. The Bcc recipients are never written to the
+// headers, they are only returned by Recipients for the envelope.
+type Message struct {
+	From    Address
+	ReplyTo *Address
+	To      []Address
+	Cc      []Address
+	Bcc     []Address
+	Subject string
+	// Header holds the additional header fields, the non-ASCII values are encoded
+	Header map[string]string
+	Text   string
+	HTML   string
+	// Attachments turn the message into multipart/mixed, the inline ones are
+	// grouped with the body in multipart/related
+	Attachments []Attachment
+	// Date is the time of the message creation, current time is used when zero
+	Date time.Time
+	// MessageID is generated from the sender domain when empty
+	MessageID string
+}
+
+// Recipients returns the envelope recipients including the Bcc ones
+func (m *Message) Recipients() []string {
+	var rcpt []string
+	for _, list := range [][]Address{m.To, m.Cc, m.Bcc} {
+		for _, a := range list {
+			rcpt = append(rcpt, a.Email)
+		}
+	}
+	return rcpt
+}
+
+// Bytes returns the message with CRLF line endings. The body is
+// multipart/alternative when both text and HTML are set. It is wrapped in
+// multipart/related when there are inline attachments and in multipart/mixed
+// when there are regular ones.
+func (m *Message) Bytes() ([]byte, error) {
+	if m.Text == "" && m.HTML == "" {
+		return nil, errors.New("message must have text or html body")
+	}
+	if err := m.validateHeader(); err != nil {
+		return nil, err
+	}
+	messageID := m.MessageID
+	if messageID == "" {
+		var err error
+		if messageID, err = NewMessageID(Domain(m.From.Email)); err != nil {
+			return nil, err
+		}
+	}
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	body, err := m.body()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "From", FormatAddress(m.From))
+	if m.ReplyTo != nil {
+		writeHeader(&buf, "Reply-To", FormatAddress(*m.ReplyTo))
+	}
+	if len(m.To) > 0 {
+		writeHeader(&buf, "To", FormatAddressList(m.To))
+	}
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", FormatAddressList(m.Cc))
+	}
+	writeHeader(&buf, "Subject", EncodeHeader(m.Subject))
+	writeHeader(&buf, "Message-ID", messageID)
+	keys := make([]string, 0, len(m.Header))
+	for k := range m.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(&buf, k, EncodeHeader(m.Header[k]))
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+	if err := body.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validateHeader prevents the header injection through the line breaks
+func (m *Message) validateHeader() error {
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return errors.New("subject must not contain line breaks")
+	}
+	for _, a := range m.Attachments {
+		if strings.ContainsAny(a.Filename+a.ContentType+a.ContentID, "\r\n") {
+			return fmt.Errorf("attachment %q is invalid", a.Filename)
+		}
+	}
+	for k, v := range m.Header {
+		if strings.ContainsAny(k, "\r\n: ") || strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("header %q is invalid", k)
+		}
+	}
+	return nil
+}
+
+func (m *Message) body() (*part, error) {
+	var text, html *part
+	if m.Text != "" {
+		text = textPart(textContentType, m.Text)
+	}
+	if m.HTML != "" {
+		html = textPart(htmlContentType, m.HTML)
+	}
+
+	var body *part
+	switch {
+	case text != nil && html != nil:
+		alternative, err := multipartPart("alternative", nil, text, html)
+		if err != nil {
+			return nil, err
+		}
+		body = alternative
+	case text != nil:
+		body = text
+	default:
+		body = html
+	}
+
+	var inline, regular []*part
+	for _, a := range m.Attachments {
+		if a.Inline {
+			inline = append(inline, attachmentPart(a))
+		} else {
+			regular = append(regular, attachmentPart(a))
+		}
+	}
+	if len(inline) > 0 {
+		// RFC 2387 - the type parameter is the type of the root part
+		rootType, _, _ := stdmime.ParseMediaType(body.header[0][1])
+		related, err := multipartPart("related", map[string]string{"type": rootType}, append([]*part{body}, inline...)...)
+		if err != nil {
+			return nil, err
+		}
+		body = related
+	}
+	if len(regular) > 0 {
+		return multipartPart("mixed", nil, append([]*part{body}, regular...)...)
+	}
+	return body, nil
+}
+
+// part is the node of the MIME tree, it has either content or children
+type part struct {
+	header   [][2]string
+	encoding Encoding
+	content  []byte
+	boundary string
+	children []*part
+}
+
+func textPart(contentType, text string) *part {
+	enc := textEncoding(text)
+	return &part{
+		header: [][2]string{
+			{"Content-Type", contentType},
+			{"Content-Transfer-Encoding", string(enc)},
+		},
+		encoding: enc,
+		content:  []byte(text),
+	}
+}
+
+func attachmentPart(a Attachment) *part {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = defaultAttachmentType
+	}
+	disposition := "attachment"
+	if a.Inline {
+		disposition = "inline"
+	}
+	if a.Filename != "" {
+		disposition = stdmime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})
+	}
+	p := &part{
+		header: [][2]string{
+			{"Content-Type", contentType},
+			{"Content-Transfer-Encoding", string(Base64)},
+			{"Content-Disposition", disposition},
+		},
+		encoding: Base64,
+		content:  a.Content,
+	}
+	if a.ContentID != "" {
+		p.header = append(p.header, [2]string{"Content-ID", "<" + a.ContentID + ">"})
+	}
+	return p
+}
+
+func multipartPart(subtype string, params map[string]string, children ...*part) (*part, error) {
+	boundary, err := newBoundary()
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = make(map[string]string)
+	}
+	params["boundary"] = boundary
+	return &part{
+		header: [][2]string{
+			{"Content-Type", stdmime.FormatMediaType("multipart/"+subtype, params)},
+		},
+		boundary: boundary,
+		children: children,
+	}, nil
+}
+
+func (p *part) write(buf *bytes.Buffer) error {
+	for _, h := range p.header {
+		writeHeader(buf, h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+	if p.boundary == "" {
+		return encode(buf, p.encoding, p.content)
+	}
+	for _, child := range p.children {
+		buf.WriteString("\r\n--" + p.boundary + "\r\n")
+		if err := child.write(buf); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("\r\n--" + p.boundary + "--\r\n")
+	return nil
+}
+
+// newBoundary returns the boundary starting with "=_" which never occurs in the
+// quoted-printable nor base64 encoded content
+func newBoundary() (string, error) {
+	b := make([]byte, 15)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate boundary: %v", err)
+	}
+	return "=_" + hex.EncodeToString(b), nil
+}
+
+// NewMessageID returns the unique Message-ID for the domain of the sender
+func NewMessageID(domain string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate message id: %v", err)
+	}
+	if domain == "" {
+		domain = "localhost"
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
+
+// Domain returns the domain part of the email address
+func Domain(email string) string {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return email[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,269 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	stdmime "mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMessage() *Message {
+	return &Message{
+		From:    Address{Name: "Sender", Email: "sender@example.com"},
+		To:      []Address{{Name: "Zoë", Email: "zoe@example.com"}},
+		Cc:      []Address{{Email: "cc@example.com"}},
+		Bcc:     []Address{{Email: "hidden@example.com"}},
+		Subject: "Zażółć gęślą jaźń",
+		Date:    time.Date(2018, time.June, 1, 12, 0, 0, 0, time.UTC),
+		Text:    "Hello",
+	}
+}
+
+func parseMessage(t *testing.T, m *Message) *mail.Message {
+	raw, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > maxBodyLineLength {
+			t.Fatalf("line is longer than %d characters", maxBodyLineLength)
+		}
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func decodeBody(t *testing.T, h map[string][]string, r *multipart.Part) string {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch Encoding(h["Content-Transfer-Encoding"][0]) {
+	case QuotedPrintable:
+		body, err = ioutil.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	case Base64:
+		body, err = base64.StdEncoding.DecodeString(strings.Replace(string(body), "\r\n", "", -1))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	dec := new(stdmime.WordDecoder)
+
+	t.Run("Headers are written", func(t *testing.T) {
+		// Act
+		msg := parseMessage(t, testMessage())
+
+		// Assert
+		subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+		assert.NoError(t, err, "Subject must be decodable")
+		assert.Equal(t, "Zażółć gęślą jaźń", subject, "Subject must be encoded")
+		to, err := msg.Header.AddressList("To")
+		assert.NoError(t, err, "To must be parsable")
+		assert.Equal(t, []*mail.Address{{Name: "Zoë", Address: "zoe@example.com"}}, to, "To must be written")
+		assert.Equal(t, "<cc@example.com>", msg.Header.Get("Cc"), "Cc must be written")
+		assert.Empty(t, msg.Header.Get("Bcc"), "Bcc must not be written")
+		date, err := msg.Header.Date()
+		assert.NoError(t, err, "Date must be parsable")
+		assert.True(t, date.Equal(testMessage().Date), "Date must be written")
+		assert.Regexp(t, "^<[0-9a-f]{32}@example.com>$", msg.Header.Get("Message-ID"), "Message-ID must be generated")
+		assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"), "MIME version must be written")
+	})
+
+	t.Run("Custom headers are written", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.MessageID = "<id@example.com>"
+		m.Header = map[string]string{"X-Campaign": "summer", "X-Note": "Ünïcödé"}
+
+		// Act
+		msg := parseMessage(t, m)
+
+		// Assert
+		assert.Equal(t, "<id@example.com>", msg.Header.Get("Message-ID"), "Message-ID must be used")
+		assert.Equal(t, "summer", msg.Header.Get("X-Campaign"), "Custom header must be written")
+		note, _ := dec.DecodeHeader(msg.Header.Get("X-Note"))
+		assert.Equal(t, "Ünïcödé", note, "Non-ASCII custom header must be encoded")
+	})
+
+	t.Run("Header injection is rejected", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.Header = map[string]string{"X-Note": "a\r\nBcc: victim@example.com"}
+
+		// Act
+		raw, err := m.Bytes()
+
+		// Assert
+		assert.Error(t, err, "Error must occur")
+		assert.Nil(t, raw, "Message must not be built")
+	})
+
+	t.Run("Message without body is rejected", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.Text = ""
+
+		// Act
+		_, err := m.Bytes()
+
+		// Assert
+		assert.Error(t, err, "Error must occur")
+	})
+
+	t.Run("ASCII text is not encoded", func(t *testing.T) {
+		// Act
+		msg := parseMessage(t, testMessage())
+
+		// Assert
+		assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"), "Content type must be text")
+		assert.Equal(t, "7bit", msg.Header.Get("Content-Transfer-Encoding"), "ASCII text must be 7bit")
+		body, _ := ioutil.ReadAll(msg.Body)
+		assert.Equal(t, "Hello", string(body), "Body must be written")
+	})
+
+	t.Run("Non-ASCII and long lines are quoted-printable", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.Text = ""
+		m.HTML = "<p>Cześć</p>" + strings.Repeat("<br>", 300)
+
+		// Act
+		msg := parseMessage(t, m)
+
+		// Assert
+		assert.Equal(t, "text/html; charset=utf-8", msg.Header.Get("Content-Type"), "Content type must be html")
+		assert.Equal(t, "quoted-printable", msg.Header.Get("Content-Transfer-Encoding"), "Encoding must be quoted-printable")
+		body, _ := ioutil.ReadAll(quotedprintable.NewReader(msg.Body))
+		assert.Equal(t, m.HTML, string(body), "Body must be decodable")
+	})
+
+	t.Run("Text and html are alternatives", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.HTML = "<p>Hello</p>"
+
+		// Act
+		msg := parseMessage(t, m)
+
+		// Assert
+		mediaType, params, err := stdmime.ParseMediaType(msg.Header.Get("Content-Type"))
+		assert.NoError(t, err, "Content type must be parsable")
+		assert.Equal(t, "multipart/alternative", mediaType, "Message must be alternative")
+		mr := multipart.NewReader(msg.Body, params["boundary"])
+		var bodies []string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			bodies = append(bodies, p.Header.Get("Content-Type")+" "+decodeBody(t, p.Header, p))
+		}
+		assert.Equal(t, []string{
+			"text/plain; charset=utf-8 Hello",
+			"text/html; charset=utf-8 <p>Hello</p>",
+		}, bodies, "Plain text must precede html")
+	})
+
+	t.Run("Attachments are mixed", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.HTML = "<p>Hello</p>"
+		content := bytes.Repeat([]byte{0, 1, 2, 0xff}, 100)
+		m.Attachments = []Attachment{{Filename: "raport końcowy.bin", Content: content}}
+
+		// Act
+		msg := parseMessage(t, m)
+
+		// Assert
+		mediaType, params, _ := stdmime.ParseMediaType(msg.Header.Get("Content-Type"))
+		assert.Equal(t, "multipart/mixed", mediaType, "Message must be mixed")
+		mr := multipart.NewReader(msg.Body, params["boundary"])
+
+		p, err := mr.NextPart()
+		assert.NoError(t, err, "Body part must exist")
+		mediaType, _, _ = stdmime.ParseMediaType(p.Header.Get("Content-Type"))
+		assert.Equal(t, "multipart/alternative", mediaType, "Body must be alternative")
+
+		p, err = mr.NextPart()
+		assert.NoError(t, err, "Attachment part must exist")
+		assert.Equal(t, "application/octet-stream", p.Header.Get("Content-Type"), "Default content type must be used")
+		assert.Equal(t, "raport końcowy.bin", p.FileName(), "File name must be encoded")
+		assert.Equal(t, string(content), decodeBody(t, p.Header, p), "Attachment must be base64 encoded")
+
+		_, err = mr.NextPart()
+		assert.Error(t, err, "There must be no more parts")
+	})
+	t.Run("Inline attachments are related to html", func(t *testing.T) {
+		// Arrange
+		m := testMessage()
+		m.Text = ""
+		m.HTML = `<img src="cid:logo">`
+		m.Attachments = []Attachment{
+			{Filename: "logo.png", ContentType: "image/png", Content: []byte("png"), Inline: true, ContentID: "logo"},
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Content: []byte("pdf")},
+		}
+
+		// Act
+		msg := parseMessage(t, m)
+
+		// Assert
+		mediaType, params, _ := stdmime.ParseMediaType(msg.Header.Get("Content-Type"))
+		assert.Equal(t, "multipart/mixed", mediaType, "Message must be mixed")
+		mixed := multipart.NewReader(msg.Body, params["boundary"])
+
+		p, err := mixed.NextPart()
+		assert.NoError(t, err, "Related part must exist")
+		mediaType, params, _ = stdmime.ParseMediaType(p.Header.Get("Content-Type"))
+		assert.Equal(t, "multipart/related", mediaType, "Body must be related")
+		assert.Equal(t, "text/html", params["type"], "Type of the root part must be given")
+		related := multipart.NewReader(p, params["boundary"])
+		root, err := related.NextPart()
+		assert.NoError(t, err, "Root part must exist")
+		assert.Equal(t, m.HTML, decodeBody(t, root.Header, root), "Root part must be html")
+		logo, err := related.NextPart()
+		assert.NoError(t, err, "Inline part must exist")
+		assert.Equal(t, "<logo>", logo.Header.Get("Content-ID"), "Content id must be written")
+		assert.True(t, strings.HasPrefix(logo.Header.Get("Content-Disposition"), "inline"), "Disposition must be inline")
+		assert.Equal(t, "png", decodeBody(t, logo.Header, logo), "Inline content must be written")
+
+		p, err = mixed.NextPart()
+		assert.NoError(t, err, "Attachment part must exist")
+		assert.Equal(t, "invoice.pdf", p.FileName(), "Regular attachment must follow related part")
+	})
+}
+
+func TestMessage_Recipients(t *testing.T) {
+	// Act
+	rcpt := testMessage().Recipients()
+
+	// Assert
+	assert.Equal(t, []string{"zoe@example.com", "cc@example.com", "hidden@example.com"}, rcpt, "Envelope must include Bcc")
+}
@@ -0,0 +1,214 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterMetadata is the header metadata with the number of seconds after
+// which the call rejected because of the limit can be made again
+const RetryAfterMetadata = "retry-after"
+
+var rejectedCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "email_rate_limited_calls_total",
+	Help: "Total number of calls rejected because the caller exceeded its rate limit partitioned by the method.",
+}, []string{"grpc_method"})
+
+func init() {
+	prometheus.MustRegister(rejectedCalls)
+}
+
+// Error is the RESOURCE_EXHAUSTED status of the call which exceeded the limit
+type Error struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+// Exhausted creates the error of the exceeded limit
+func Exhausted(retryAfter time.Duration, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// GRPCStatus returns the RESOURCE_EXHAUSTED status
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Message)
+}
+
+// RetryAfter returns the delay of the error caused by the exceeded limit
+func RetryAfter(err error) (time.Duration, bool) {
+	if e, ok := err.(*Error); ok {
+		return e.RetryAfter, true
+	}
+	return 0, false
+}
+
+// retryAfterHeader is the header metadata of the error caused by the exceeded limit
+func retryAfterHeader(err error) (metadata.MD, bool) {
+	d, ok := RetryAfter(err)
+	if !ok {
+		return nil, false
+	}
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return metadata.Pairs(RetryAfterMetadata, strconv.FormatInt(seconds, 10)), true
+}
+
+// IdentityFunc returns the key of the caller whose calls are limited together
+type IdentityFunc func(ctx context.Context) string
+
+// UnaryServerInterceptor rejects the calls of the caller which exceeded its
+// limit. The retry-after header metadata is sent together with every error
+// caused by the limit, including the ones returned by the handler.
+func UnaryServerInterceptor(l *Limiter, identity IdentityFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if err := check(ctx, l, identity, info.FullMethod); err != nil {
+			setRetryAfter(err, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		setRetryAfter(err, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor rejects the streams of the caller which exceeded its limit
+func StreamServerInterceptor(l *Limiter, identity IdentityFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if err := check(stream.Context(), l, identity, info.FullMethod); err != nil {
+			setRetryAfter(err, stream.SetHeader)
+			return err
+		}
+		err := handler(srv, stream)
+		setRetryAfter(err, stream.SetHeader)
+		return err
+	}
+}
+
+// FailedFunc reports whether the call failed in the way which is limited,
+// e.g. because of invalid credentials
+type FailedFunc func(err error) bool
+
+// FailuresUnaryServerInterceptor rejects the calls of the caller which failed
+// too many times. Only the failed calls take the tokens, so it is placed before
+// the costly checks e.g. of credentials and limits the attempts of guessing them.
+func FailuresUnaryServerInterceptor(l *Limiter, identity IdentityFunc, failed FailedFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		caller := identity(ctx)
+		if err := checkFailures(l, caller, info.FullMethod); err != nil {
+			setRetryAfter(err, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		if err != nil && failed(err) {
+			l.Take(caller)
+		}
+		return resp, err
+	}
+}
+
+// FailuresStreamServerInterceptor rejects the streams of the caller which failed too many times
+func FailuresStreamServerInterceptor(l *Limiter, identity IdentityFunc, failed FailedFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		caller := identity(stream.Context())
+		if err := checkFailures(l, caller, info.FullMethod); err != nil {
+			setRetryAfter(err, stream.SetHeader)
+			return err
+		}
+		err := handler(srv, stream)
+		if err != nil && failed(err) {
+			l.Take(caller)
+		}
+		return err
+	}
+}
+
+// FailuresHandler rejects the requests of the client which got too many
+// responses with one of the status codes, e.g. 401 Unauthorized
+func FailuresHandler(next http.Handler, l *Limiter, identity func(r *http.Request) string, codes ...int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := identity(r)
+		if wait := l.Delay(caller); wait > 0 {
+			rejectedCalls.WithLabelValues(r.URL.Path).Inc()
+			seconds := int64(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+			http.Error(w, "too many failed requests", http.StatusTooManyRequests)
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		for _, code := range codes {
+			if rec.code == code {
+				l.Take(caller)
+				return
+			}
+		}
+	})
+}
+
+// statusRecorder remembers the status code of the response
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func checkFailures(l *Limiter, identity string, method string) error {
+	if wait := l.Delay(identity); wait > 0 {
+		rejectedCalls.WithLabelValues(method).Inc()
+		return Exhausted(wait, "too many failed calls, retry after %v", wait.Round(time.Millisecond))
+	}
+	return nil
+}
+
+func check(ctx context.Context, l *Limiter, identity IdentityFunc, method string) error {
+	if wait := l.Take(identity(ctx)); wait > 0 {
+		rejectedCalls.WithLabelValues(method).Inc()
+		return Exhausted(wait, "rate limit exceeded, retry after %v", wait.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// setRetryAfter sends the header metadata, which fails when the headers were
+// already sent e.g. by the stream
+func setRetryAfter(err error, set func(metadata.MD) error) {
+	if md, ok := retryAfterHeader(err); ok {
+		_ = set(md)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package ratelimit limits the rate of calls with token buckets. The callers of
+// the gRPC server are limited by the interceptors, which send the retry-after
+// header metadata with the RESOURCE_EXHAUSTED status.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets is the number of buckets after which the full ones are removed
+const maxIdleBuckets = 10000
+
+// Limit is the rate of the token bucket
+type Limit struct {
+	// Rate is the number of tokens added per second, zero means no limit
+	Rate float64 `mapstructure:"rate"`
+	// Burst is the capacity of the bucket, default is the rate rounded up
+	Burst int `mapstructure:"burst"`
+}
+
+// Unlimited reports whether the limit lets every call through
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+func (l Limit) withDefaults() Limit {
+	if l.Burst <= 0 {
+		l.Burst = int(math.Ceil(l.Rate))
+	}
+	return l
+}
+
+// Bucket is the token bucket safe for concurrent use. The nil bucket lets
+// every call through.
+type Bucket struct {
+	limit Limit
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates the full bucket, the unlimited limit gives the nil bucket
+func NewBucket(l Limit) *Bucket {
+	return newBucket(l, time.Now)
+}
+
+func newBucket(l Limit, now func() time.Time) *Bucket {
+	if l.Unlimited() {
+		return nil
+	}
+	l = l.withDefaults()
+	return &Bucket{
+		limit:  l,
+		now:    now,
+		tokens: float64(l.Burst),
+		last:   now(),
+	}
+}
+
+// Take removes the token from the bucket. The empty bucket is not changed and
+// the time after which the next token is added is returned.
+func (b *Bucket) Take() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return b.delay()
+}
+
+// Delay returns the time after which the token can be taken, without taking it
+func (b *Bucket) Delay() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens >= 1 {
+		return 0
+	}
+	return b.delay()
+}
+
+func (b *Bucket) refill() {
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+	}
+	b.last = now
+}
+
+func (b *Bucket) delay() time.Duration {
+	return time.Duration(math.Ceil((1 - b.tokens) / b.limit.Rate * float64(time.Second)))
+}
+
+// full reports whether the bucket got all tokens back, so it can be forgotten
+func (b *Bucket) full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+// Config of the Limiter, e.g.:
+//
+//	rate_limit:
+//	  rate: 10
+//	  burst: 20
+//	  clients:
+//	    newsletter:
+//	      rate: 100
+//	      burst: 200
+type Config struct {
+	// Rate is the number of calls per second of every caller without its own limit
+	Rate float64 `mapstructure:"rate"`
+	// Burst is the number of calls above the rate the caller can make at once
+	Burst int `mapstructure:"burst"`
+	// Clients are the limits of the given callers
+	Clients map[string]Limit `mapstructure:"clients"`
+	// Failures is the limit of failed authentications of every address, which
+	// is checked before the caller is authenticated. Default is one failure
+	// per second with the burst of 10, the negative rate turns it off.
+	Failures Limit `mapstructure:"failures"`
+}
+
+// defaultFailures is the limit of failed authentications when none is set
+var defaultFailures = Limit{Rate: 1, Burst: 10}
+
+func (c Config) limit(identity string) Limit {
+	if l, ok := c.Clients[identity]; ok {
+		return l
+	}
+	return Limit{Rate: c.Rate, Burst: c.Burst}
+}
+
+// FailuresLimiter creates the limiter of failed authentications of addresses
+func (c Config) FailuresLimiter() *Limiter {
+	l := c.Failures
+	if l.Rate == 0 {
+		l = defaultFailures
+	}
+	return NewLimiter(Config{Rate: l.Rate, Burst: l.Burst})
+}
+
+// Limiter keeps the bucket of every caller. The nil limiter lets every call through.
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewLimiter creates the limiter of callers
+func NewLimiter(cfg Config) *Limiter {
+	return newLimiter(cfg, time.Now)
+}
+
+func newLimiter(cfg Config, now func() time.Time) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		now:     now,
+		buckets: make(map[string]*Bucket),
+	}
+}
+
+// Take removes the token from the bucket of the caller. When the caller
+// exceeded its limit the time after which it can call again is returned.
+func (l *Limiter) Take(identity string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	limit := l.cfg.limit(identity)
+	if limit.Unlimited() {
+		return 0
+	}
+
+	l.mu.Lock()
+	b, ok := l.buckets[identity]
+	if !ok {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.forgetFull()
+		}
+		b = newBucket(limit, l.now)
+		l.buckets[identity] = b
+	}
+	l.mu.Unlock()
+	return b.Take()
+}
+
+// Delay returns the time after which the caller can call again, without
+// taking the token
+func (l *Limiter) Delay(identity string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	b, ok := l.buckets[identity]
+	l.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	return b.Delay()
+}
+
+// forgetFull removes the buckets of callers which did not call recently
+func (l *Limiter) forgetFull() {
+	for identity, b := range l.buckets {
+		if b.full() {
+			delete(l.buckets, identity)
+		}
+	}
+}
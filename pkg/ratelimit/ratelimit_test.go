@@ -0,0 +1,230 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestBucket(t *testing.T) {
+	t.Run("Burst is taken at once and refilled at the rate", func(t *testing.T) {
+		// Arrange
+		clock := &fakeClock{now: time.Unix(1500000000, 0)}
+		b := newBucket(Limit{Rate: 2, Burst: 3}, clock.Now)
+
+		// Act & Assert
+		for i := 0; i < 3; i++ {
+			assert.Zero(t, b.Take(), "Token %d of the burst must be taken", i)
+		}
+		assert.Equal(t, 500*time.Millisecond, b.Take(), "Empty bucket must return the time of the next token")
+		clock.Add(250 * time.Millisecond)
+		assert.Equal(t, 250*time.Millisecond, b.Delay(), "Part of the token must be refilled")
+		clock.Add(250 * time.Millisecond)
+		assert.Zero(t, b.Delay(), "Token must be refilled")
+		assert.Zero(t, b.Take(), "Refilled token must be taken")
+		assert.NotZero(t, b.Take(), "Only one token must be refilled")
+		clock.Add(time.Hour)
+		for i := 0; i < 3; i++ {
+			assert.Zero(t, b.Take(), "Bucket must be refilled up to the burst")
+		}
+		assert.NotZero(t, b.Take(), "Bucket must not exceed the burst")
+	})
+
+	t.Run("Default burst is the rate", func(t *testing.T) {
+		// Arrange
+		b := newBucket(Limit{Rate: 1.5}, (&fakeClock{}).Now)
+
+		// Act & Assert
+		assert.Zero(t, b.Take(), "First token must be taken")
+		assert.Zero(t, b.Take(), "Second token must be taken")
+		assert.NotZero(t, b.Take(), "Burst must be the rate rounded up")
+	})
+
+	t.Run("Unlimited bucket", func(t *testing.T) {
+		// Arrange
+		b := NewBucket(Limit{})
+
+		// Act & Assert
+		assert.Nil(t, b, "Unlimited bucket must be nil")
+		assert.Zero(t, b.Take(), "Nil bucket must let every call through")
+		assert.Zero(t, b.Delay(), "Nil bucket must not delay")
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	// Arrange
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	l := newLimiter(Config{
+		Rate:    1,
+		Burst:   1,
+		Clients: map[string]Limit{"newsletter": {Rate: 10, Burst: 5}, "internal": {}},
+	}, clock.Now)
+
+	t.Run("Callers are limited separately", func(t *testing.T) {
+		// Act & Assert
+		assert.Zero(t, l.Take("10.0.0.1"), "First call must be allowed")
+		assert.Equal(t, time.Second, l.Take("10.0.0.1"), "Second call must be rejected")
+		assert.Zero(t, l.Take("10.0.0.2"), "Other caller must be allowed")
+	})
+
+	t.Run("Client has its own limit", func(t *testing.T) {
+		// Act & Assert
+		for i := 0; i < 5; i++ {
+			assert.Zero(t, l.Take("newsletter"), "Call %d must be allowed", i)
+		}
+		assert.Equal(t, 100*time.Millisecond, l.Take("newsletter"), "Call above the burst must be rejected")
+		for i := 0; i < 10; i++ {
+			assert.Zero(t, l.Take("internal"), "Unlimited client must be allowed")
+		}
+	})
+
+	t.Run("Nil limiter", func(t *testing.T) {
+		// Act & Assert
+		assert.Zero(t, (*Limiter)(nil).Take("10.0.0.1"), "Nil limiter must let every call through")
+	})
+
+	t.Run("Delay does not take the token", func(t *testing.T) {
+		// Act & Assert
+		assert.Zero(t, l.Delay("10.0.0.5"), "Unknown client must not wait")
+		assert.Zero(t, l.Take("10.0.0.5"), "First call must be allowed")
+		assert.Equal(t, time.Second, l.Delay("10.0.0.5"), "Delay must be the time of the next token")
+		assert.Equal(t, time.Second, l.Take("10.0.0.5"), "Delay must not take the token")
+	})
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestInterceptors(t *testing.T) {
+	identity := func(context.Context) string { return "client" }
+
+	t.Run("Unary call above the limit is rejected", func(t *testing.T) {
+		// Arrange
+		interceptor := UnaryServerInterceptor(NewLimiter(Config{Rate: 0.5, Burst: 1}), identity)
+		info := &grpc.UnaryServerInfo{FullMethod: "/email.EmailService/SendMail"}
+		called := 0
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			called++
+			return "ok", nil
+		}
+
+		// Act
+		resp, err := interceptor(context.Background(), nil, info, handler)
+		_, rejected := interceptor(context.Background(), nil, info, handler)
+
+		// Assert
+		assert.NoError(t, err, "First call must be allowed")
+		assert.Equal(t, "ok", resp, "Response of the handler must be returned")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(rejected), "Second call must be rejected")
+		retryAfter, ok := RetryAfter(rejected)
+		assert.True(t, ok, "Rejection must have the delay")
+		assert.InDelta(t, 2*time.Second, retryAfter, float64(10*time.Millisecond), "Delay must be the time of the next token")
+		assert.Equal(t, 1, called, "Rejected call must not reach the handler")
+	})
+
+	t.Run("Stream above the limit is rejected with retry-after", func(t *testing.T) {
+		// Arrange
+		interceptor := StreamServerInterceptor(NewLimiter(Config{Rate: 0.4, Burst: 1}), identity)
+		info := &grpc.StreamServerInfo{FullMethod: "/email.EmailService/WatchMessages"}
+		handler := func(srv interface{}, stream grpc.ServerStream) error { return nil }
+		stream := &fakeServerStream{}
+
+		// Act
+		err := interceptor(nil, stream, info, handler)
+		rejected := interceptor(nil, stream, info, handler)
+
+		// Assert
+		assert.NoError(t, err, "First stream must be allowed")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(rejected), "Second stream must be rejected")
+		assert.Equal(t, []string{"3"}, stream.header.Get(RetryAfterMetadata), "Delay must be rounded up to seconds")
+	})
+
+	t.Run("Only failed calls are limited", func(t *testing.T) {
+		// Arrange
+		interceptor := FailuresUnaryServerInterceptor(NewLimiter(Config{Rate: 0.5, Burst: 2}), identity,
+			func(err error) bool { return status.Code(err) == codes.Unauthenticated })
+		info := &grpc.UnaryServerInfo{FullMethod: "/email.EmailService/SendMail"}
+		code := codes.OK
+		called := 0
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			called++
+			return nil, status.Error(code, "failed")
+		}
+
+		// Act
+		for i := 0; i < 5; i++ {
+			_, err := interceptor(context.Background(), nil, info, handler)
+			assert.Equal(t, codes.OK, status.Code(err), "Call %d must not be limited", i)
+		}
+		code = codes.Unauthenticated
+		for i := 0; i < 2; i++ {
+			_, err := interceptor(context.Background(), nil, info, handler)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err), "Failure %d must reach the handler", i)
+		}
+		code = codes.OK
+		_, rejected := interceptor(context.Background(), nil, info, handler)
+
+		// Assert
+		assert.Equal(t, codes.ResourceExhausted, status.Code(rejected), "Call after the failures must be rejected")
+		assert.Equal(t, 7, called, "Rejected call must not reach the handler")
+	})
+
+	t.Run("Limit error of the handler gets retry-after", func(t *testing.T) {
+		// Arrange
+		interceptor := StreamServerInterceptor(nil, identity)
+		info := &grpc.StreamServerInfo{FullMethod: "/email.EmailService/WatchMessages"}
+		handler := func(srv interface{}, stream grpc.ServerStream) error {
+			return Exhausted(100*time.Millisecond, "provider limit")
+		}
+		stream := &fakeServerStream{}
+
+		// Act
+		err := interceptor(nil, stream, info, handler)
+
+		// Assert
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "Error of the handler must be returned")
+		assert.Equal(t, []string{"1"}, stream.header.Get(RetryAfterMetadata), "Delay must be at least one second")
+	})
+}
@@ -0,0 +1,485 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"golang.org/x/net/idna"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxLocalPartLength and maxAddressLength are the limits of RFC 5321 section 4.5.3.1
+	maxLocalPartLength = 64
+	maxAddressLength   = 254
+	// maxMXCacheSize bounds the remembered lookups, the cache is cleared when it is full
+	maxMXCacheSize = 10000
+	// atext are the characters of the atom besides letters and digits, RFC 5322 section 3.2.3
+	atext = "!#$%&'*+-/=?^_`{|}~"
+)
+
+// idnaProfile converts the domains for the lookup as IDNA2008 does, which
+// unlike idna.Lookup keeps the deviation characters e.g. ß
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
+
+// disposableDomains are the well known providers of temporary mailboxes.
+// Their subdomains are disposable as well.
+var disposableDomains = []string{
+	"10minutemail.com",
+	"discard.email",
+	"dispostable.com",
+	"fakeinbox.com",
+	"getairmail.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"mailinator.com",
+	"maildrop.cc",
+	"mintemail.com",
+	"mohmal.com",
+	"sharklasers.com",
+	"spamgourmet.com",
+	"temp-mail.org",
+	"throwawaymail.com",
+	"trashmail.com",
+	"yopmail.com",
+}
+
+// AddressValidation configures the checks of recipient addresses done besides the syntax
+type AddressValidation struct {
+	// CheckMX rejects the recipients whose domain has no mail server
+	CheckMX bool `mapstructure:"check_mx"`
+	// MXTimeout limits the lookup of the mail server, default 5s
+	MXTimeout time.Duration `mapstructure:"mx_timeout"`
+	// MXCacheTTL is how long the found or missing mail server is remembered, default 10m
+	MXCacheTTL time.Duration `mapstructure:"mx_cache_ttl"`
+	// RejectDisposable rejects the recipients from domains which give out temporary addresses
+	RejectDisposable bool `mapstructure:"reject_disposable"`
+	// DisposableDomains are added to the built-in list of disposable domains
+	DisposableDomains []string `mapstructure:"disposable_domains"`
+	// DisposableDomainsFile lists further disposable domains, one per line
+	DisposableDomainsFile string `mapstructure:"disposable_domains_file"`
+}
+
+func (c AddressValidation) withDefaults() AddressValidation {
+	if c.MXTimeout <= 0 {
+		c.MXTimeout = 5 * time.Second
+	}
+	if c.MXCacheTTL <= 0 {
+		c.MXCacheTTL = 10 * time.Minute
+	}
+	return c
+}
+
+// MXResolver looks up the mail server of the domain, *net.Resolver implements it
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// AddressValidator checks the addresses of emails and converts their
+// international domains to punycode
+type AddressValidator struct {
+	cfg        AddressValidation
+	resolver   MXResolver
+	disposable map[string]struct{}
+
+	mu sync.Mutex
+	mx map[string]mailServer
+}
+
+// mailServer is the remembered result of the lookup
+type mailServer struct {
+	state   pb.ValidateAddressResponse_MailServer
+	detail  string
+	expires time.Time
+}
+
+// addressError is the reason why the address is not valid
+type addressError struct {
+	result pb.ValidateAddressResponse_Result
+	detail string
+}
+
+func (e *addressError) Error() string {
+	return e.detail
+}
+
+func addressErrorf(result pb.ValidateAddressResponse_Result, format string, args ...interface{}) *addressError {
+	return &addressError{result: result, detail: fmt.Sprintf(format, args...)}
+}
+
+// NewAddressValidator creates the validator which looks up the mail servers
+// with the resolver, without the resolver net.DefaultResolver is used
+func NewAddressValidator(cfg AddressValidation, resolver MXResolver) (*AddressValidator, error) {
+	domains := append([]string{}, cfg.DisposableDomains...)
+	if cfg.DisposableDomainsFile != "" {
+		listed, err := readDomains(cfg.DisposableDomainsFile)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, listed...)
+	}
+	v := newAddressValidator(cfg, resolver)
+	for _, d := range domains {
+		ascii, err := idnaProfile.ToASCII(strings.TrimSuffix(d, "."))
+		if err != nil {
+			return nil, fmt.Errorf("disposable domain %q is not valid: %v", d, err)
+		}
+		v.disposable[ascii] = struct{}{}
+	}
+	return v, nil
+}
+
+func newAddressValidator(cfg AddressValidation, resolver MXResolver) *AddressValidator {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	v := &AddressValidator{
+		cfg:        cfg.withDefaults(),
+		resolver:   resolver,
+		disposable: make(map[string]struct{}, len(disposableDomains)),
+		mx:         make(map[string]mailServer),
+	}
+	for _, d := range disposableDomains {
+		v.disposable[d] = struct{}{}
+	}
+	return v
+}
+
+// readDomains reads the file with one domain per line, empty lines and
+// lines starting with # are skipped
+func readDomains(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read disposable domains: %v", err)
+	}
+	defer f.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read disposable domains: %v", err)
+	}
+	return domains, nil
+}
+
+// Validate checks the address as SendMail checks the recipients. The mail
+// server which can not be looked up at the moment does not make the address
+// invalid.
+func (v *AddressValidator) Validate(ctx context.Context, email string) *pb.ValidateAddressResponse {
+	resp := &pb.ValidateAddressResponse{}
+	addr, err := parseAddress(email)
+	if err != nil {
+		resp.Result = err.result
+		resp.Detail = err.detail
+		return resp
+	}
+	resp.Email = addr.ascii()
+	resp.UnicodeEmail = addr.unicode()
+	resp.Disposable = v.isDisposable(addr.domain)
+	if v.cfg.CheckMX {
+		resp.MailServer, resp.Detail = v.lookup(ctx, addr.domain)
+	}
+
+	switch {
+	case resp.MailServer == pb.ValidateAddressResponse_NOT_FOUND:
+		resp.Result = pb.ValidateAddressResponse_NO_MAIL_SERVER
+	case resp.Disposable && v.cfg.RejectDisposable:
+		resp.Result = pb.ValidateAddressResponse_DISPOSABLE
+		resp.Detail = fmt.Sprintf("domain %q gives out disposable addresses", addr.domain)
+	default:
+		resp.Valid = true
+		resp.Result = pb.ValidateAddressResponse_VALID
+		resp.Detail = ""
+	}
+	return resp
+}
+
+// check rejects the recipients which can not receive the email and converts
+// the domains of all addresses to punycode. The email is copied when any
+// address is changed.
+func (v *AddressValidator) check(ctx context.Context, email *pb.EmailRequest) (*pb.EmailRequest, error) {
+	changed := false
+	convert := func(field string, a *pb.Address, recipient bool) (*pb.Address, error) {
+		if a == nil {
+			return nil, nil
+		}
+		var converted string
+		if recipient {
+			resp := v.Validate(ctx, a.GetEmail())
+			if !resp.GetValid() {
+				rejectedAddresses.WithLabelValues(strings.ToLower(resp.GetResult().String())).Inc()
+				return nil, status.Errorf(codes.InvalidArgument, "%s address %q is rejected: %s",
+					field, a.GetEmail(), resp.GetDetail())
+			}
+			if resp.GetMailServer() == pb.ValidateAddressResponse_UNKNOWN {
+				zap.L().Debug("Mail server of the recipient is not known, the email is sent anyway",
+					zap.String("detail", resp.GetDetail()))
+			}
+			converted = resp.GetEmail()
+		} else {
+			addr, err := parseAddress(a.GetEmail())
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s address %q is not valid", field, a.GetEmail())
+			}
+			converted = addr.ascii()
+		}
+		if converted == a.GetEmail() {
+			return a, nil
+		}
+		changed = true
+		return &pb.Address{Email: converted, Name: a.GetName()}, nil
+	}
+	convertAll := func(field string, addresses []*pb.Address) ([]*pb.Address, error) {
+		converted := make([]*pb.Address, 0, len(addresses))
+		for _, a := range addresses {
+			c, err := convert(field, a, true)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, c)
+		}
+		return converted, nil
+	}
+
+	from, err := convert("from", email.GetFrom(), false)
+	if err != nil {
+		return nil, err
+	}
+	replyTo, err := convert("reply_to", email.GetReplyTo(), false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := convertAll("to", email.GetTo())
+	if err != nil {
+		return nil, err
+	}
+	cc, err := convertAll("cc", email.GetCc())
+	if err != nil {
+		return nil, err
+	}
+	bcc, err := convertAll("bcc", email.GetBcc())
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return email, nil
+	}
+	checked := proto.Clone(email).(*pb.EmailRequest)
+	checked.From = from
+	checked.ReplyTo = replyTo
+	checked.To = to
+	checked.Cc = cc
+	checked.Bcc = bcc
+	return checked, nil
+}
+
+// isDisposable checks the domain and its parent domains
+func (v *AddressValidator) isDisposable(domain string) bool {
+	for {
+		if _, ok := v.disposable[domain]; ok {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// lookup finds the mail server of the domain, the failed lookup is not remembered
+func (v *AddressValidator) lookup(ctx context.Context, domain string) (pb.ValidateAddressResponse_MailServer, string) {
+	v.mu.Lock()
+	cached, ok := v.mx[domain]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.state, cached.detail
+	}
+
+	state, detail := v.lookupMailServer(ctx, domain)
+	if state != pb.ValidateAddressResponse_UNKNOWN {
+		v.mu.Lock()
+		if len(v.mx) >= maxMXCacheSize {
+			v.mx = make(map[string]mailServer)
+		}
+		v.mx[domain] = mailServer{state: state, detail: detail, expires: time.Now().Add(v.cfg.MXCacheTTL)}
+		v.mu.Unlock()
+	}
+	return state, detail
+}
+
+func (v *AddressValidator) lookupMailServer(ctx context.Context, domain string) (pb.ValidateAddressResponse_MailServer, string) {
+	ctx, cancel := context.WithTimeout(ctx, v.cfg.MXTimeout)
+	defer cancel()
+
+	records, err := v.resolver.LookupMX(ctx, domain)
+	switch {
+	case err != nil && !isNotFound(err):
+		return pb.ValidateAddressResponse_UNKNOWN, fmt.Sprintf("lookup of domain %q failed: %v", domain, err)
+	case len(records) == 1 && strings.TrimSuffix(records[0].Host, ".") == "":
+		// RFC 7505 - the null MX record
+		return pb.ValidateAddressResponse_NOT_FOUND, fmt.Sprintf("domain %q does not accept emails", domain)
+	case len(records) > 0:
+		return pb.ValidateAddressResponse_FOUND, ""
+	}
+
+	// RFC 5321 section 5.1 - without MX records the domain itself is the mail server
+	if _, err := v.resolver.LookupHost(ctx, domain); err != nil {
+		if isNotFound(err) {
+			return pb.ValidateAddressResponse_NOT_FOUND, fmt.Sprintf("domain %q has no mail server", domain)
+		}
+		return pb.ValidateAddressResponse_UNKNOWN, fmt.Sprintf("lookup of domain %q failed: %v", domain, err)
+	}
+	return pb.ValidateAddressResponse_FOUND, ""
+}
+
+func isNotFound(err error) bool {
+	dnsErr, ok := err.(*net.DNSError)
+	return ok && dnsErr.IsNotFound
+}
+
+// parsedAddress is the addr-spec with the domain in punycode
+type parsedAddress struct {
+	local  string
+	domain string
+}
+
+func (a *parsedAddress) ascii() string {
+	return a.local + "@" + a.domain
+}
+
+func (a *parsedAddress) unicode() string {
+	domain, err := idna.Display.ToUnicode(a.domain)
+	if err != nil {
+		return a.ascii()
+	}
+	return a.local + "@" + domain
+}
+
+// parseAddress parses the RFC 5322 addr-spec without the display name. The
+// local part is the dot-atom or the quoted string, the obsolete syntax and
+// comments are not accepted. The domain must be the host name, which is
+// converted to punycode when it is international. The local part must be
+// ASCII, since not every provider supports SMTPUTF8.
+func parseAddress(email string) (*parsedAddress, *addressError) {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX, "address %q has no domain", email)
+	}
+	local, domain := email[:at], email[at+1:]
+	if err := validateLocalPart(local); err != nil {
+		return nil, err
+	}
+	if domain == "" {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX, "address %q has no domain", email)
+	}
+	if strings.HasPrefix(domain, "[") {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_DOMAIN, "domain literal %s is not accepted", domain)
+	}
+	ascii, err := idnaProfile.ToASCII(domain)
+	if err != nil || strings.HasSuffix(domain, ".") {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_DOMAIN, "domain %q is not valid host name", domain)
+	}
+	dot := strings.LastIndexByte(ascii, '.')
+	if dot < 0 {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_DOMAIN, "domain %q must have top level domain", domain)
+	}
+	if strings.Trim(ascii[dot+1:], "0123456789") == "" {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_DOMAIN, "domain %q can not be IP address", domain)
+	}
+	addr := &parsedAddress{local: local, domain: ascii}
+	if len(addr.ascii()) > maxAddressLength {
+		return nil, addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX,
+			"address can have at most %d characters", maxAddressLength)
+	}
+	return addr, nil
+}
+
+func validateLocalPart(local string) *addressError {
+	if local == "" {
+		return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX, "local part can not be empty")
+	}
+	if len(local) > maxLocalPartLength {
+		return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX,
+			"local part can have at most %d characters", maxLocalPartLength)
+	}
+	if strings.HasPrefix(local, `"`) {
+		return validateQuotedString(local)
+	}
+	for _, atom := range strings.Split(local, ".") {
+		if atom == "" {
+			return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX,
+				"local part %q can not have empty atom", local)
+		}
+		for _, c := range atom {
+			if !isAtext(c) {
+				return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX,
+					"local part %q can not contain %q unless it is quoted", local, c)
+			}
+		}
+	}
+	return nil
+}
+
+// validateQuotedString checks the quoted local part, RFC 5322 section 3.2.4
+func validateQuotedString(local string) *addressError {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX, "local part %s is not closed", local)
+	}
+	content := local[1 : len(local)-1]
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\':
+			i++
+			if i == len(content) || !isQuotedPair(content[i]) {
+				return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX, "local part %s has invalid escape", local)
+			}
+		case c == '"':
+			return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX, "local part %s has unescaped quote", local)
+		case c != ' ' && c != '\t' && (c < 33 || c > 126):
+			return addressErrorf(pb.ValidateAddressResponse_INVALID_SYNTAX,
+				"local part %s can contain only printable ASCII characters", local)
+		}
+	}
+	return nil
+}
+
+func isAtext(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune(atext, c)
+}
+
+func isQuotedPair(c byte) bool {
+	return c == ' ' || c == '\t' || c >= 33 && c <= 126
+}
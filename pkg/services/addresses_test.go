@@ -0,0 +1,340 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeResolver answers from the records, the domain without them does not exist
+type fakeResolver struct {
+	mx      map[string][]*net.MX
+	hosts   map[string][]string
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func newFakeResolver() *fakeResolver {
+	return &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com":           {{Host: "mx.example.com.", Pref: 10}},
+			"xn--bcher-kva.example": {{Host: "mx.xn--bcher-kva.example.", Pref: 10}},
+			"nomail.example.com":    {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{
+			"host.example.com": {"192.0.2.1"},
+		},
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	valid := []struct {
+		email, ascii, unicode string
+	}{
+		{"jane.doe@example.com", "jane.doe@example.com", "jane.doe@example.com"},
+		{"jane+news@Example.COM", "jane+news@example.com", "jane+news@example.com"},
+		{"o'brien@sub.example.co.uk", "o'brien@sub.example.co.uk", "o'brien@sub.example.co.uk"},
+		{`"john doe"@example.com`, `"john doe"@example.com`, `"john doe"@example.com`},
+		{`"a\"b"@example.com`, `"a\"b"@example.com`, `"a\"b"@example.com`},
+		{"jan@bücher.example", "jan@xn--bcher-kva.example", "jan@bücher.example"},
+		{"jan@xn--bcher-kva.example", "jan@xn--bcher-kva.example", "jan@bücher.example"},
+		{"hans@straße.example", "hans@xn--strae-oqa.example", "hans@straße.example"},
+	}
+	for _, tt := range valid {
+		t.Run(tt.email, func(t *testing.T) {
+			// Act
+			addr, err := parseAddress(tt.email)
+
+			// Assert
+			if assert.Nil(t, err, "Address must be valid") {
+				assert.Equal(t, tt.ascii, addr.ascii(), "Domain must be converted to punycode")
+				assert.Equal(t, tt.unicode, addr.unicode(), "Domain must be converted to Unicode")
+			}
+		})
+	}
+
+	invalid := []struct {
+		email  string
+		result pb.ValidateAddressResponse_Result
+	}{
+		{"example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"jane@", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{".jane@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"jane.@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"jane doe@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"Jane <jane@example.com>", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"jane(comment)@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"jane@doe@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{`"jane@example.com`, pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{`"ja"ne"@example.com`, pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"żaneta@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{strings.Repeat("a", 65) + "@example.com", pb.ValidateAddressResponse_INVALID_SYNTAX},
+		{"jane@[192.0.2.1]", pb.ValidateAddressResponse_INVALID_DOMAIN},
+		{"jane@192.0.2.1", pb.ValidateAddressResponse_INVALID_DOMAIN},
+		{"jane@localhost", pb.ValidateAddressResponse_INVALID_DOMAIN},
+		{"jane@example.com.", pb.ValidateAddressResponse_INVALID_DOMAIN},
+		{"jane@exa_mple.com", pb.ValidateAddressResponse_INVALID_DOMAIN},
+		{"jane@-example.com", pb.ValidateAddressResponse_INVALID_DOMAIN},
+		{"jane@example..com", pb.ValidateAddressResponse_INVALID_DOMAIN},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.email, func(t *testing.T) {
+			// Act
+			addr, err := parseAddress(tt.email)
+
+			// Assert
+			assert.Nil(t, addr, "Address must not be parsed")
+			if assert.NotNil(t, err, "Address must be rejected") {
+				assert.Equal(t, tt.result, err.result, "Reason must be given: %s", err.detail)
+			}
+		})
+	}
+}
+
+func TestAddressValidator_Validate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Mail server is looked up", func(t *testing.T) {
+		// Arrange
+		v, err := NewAddressValidator(AddressValidation{CheckMX: true}, newFakeResolver())
+		assert.NoError(t, err, "Error should not occur")
+
+		tests := []struct {
+			email  string
+			valid  bool
+			result pb.ValidateAddressResponse_Result
+			server pb.ValidateAddressResponse_MailServer
+		}{
+			{"jane@example.com", true, pb.ValidateAddressResponse_VALID, pb.ValidateAddressResponse_FOUND},
+			{"jan@bücher.example", true, pb.ValidateAddressResponse_VALID, pb.ValidateAddressResponse_FOUND},
+			{"jane@host.example.com", true, pb.ValidateAddressResponse_VALID, pb.ValidateAddressResponse_FOUND},
+			{"user@gmial.con", false, pb.ValidateAddressResponse_NO_MAIL_SERVER, pb.ValidateAddressResponse_NOT_FOUND},
+			{"jane@nomail.example.com", false, pb.ValidateAddressResponse_NO_MAIL_SERVER, pb.ValidateAddressResponse_NOT_FOUND},
+			{"jane@", false, pb.ValidateAddressResponse_INVALID_SYNTAX, pb.ValidateAddressResponse_MAIL_SERVER_UNSPECIFIED},
+		}
+		for _, tt := range tests {
+			// Act
+			resp := v.Validate(ctx, tt.email)
+
+			// Assert
+			assert.Equal(t, tt.valid, resp.Valid, "Validity of %s must match", tt.email)
+			assert.Equal(t, tt.result, resp.Result, "Result of %s must match: %s", tt.email, resp.Detail)
+			assert.Equal(t, tt.server, resp.MailServer, "Mail server of %s must match", tt.email)
+		}
+	})
+
+	t.Run("Found mail server is remembered", func(t *testing.T) {
+		// Arrange
+		resolver := newFakeResolver()
+		v, _ := NewAddressValidator(AddressValidation{CheckMX: true}, resolver)
+
+		// Act
+		v.Validate(ctx, "jane@example.com")
+		v.Validate(ctx, "john@example.com")
+		v.Validate(ctx, "user@gmial.con")
+		v.Validate(ctx, "user@gmial.con")
+
+		// Assert
+		assert.Equal(t, 2, resolver.lookups, "Domains must be looked up once")
+	})
+
+	t.Run("Failed lookup does not reject the address", func(t *testing.T) {
+		// Arrange
+		resolver := newFakeResolver()
+		resolver.err = &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}
+		v, _ := NewAddressValidator(AddressValidation{CheckMX: true}, resolver)
+
+		// Act
+		resp := v.Validate(ctx, "jane@example.com")
+		v.Validate(ctx, "jane@example.com")
+
+		// Assert
+		assert.True(t, resp.Valid, "Address must be valid")
+		assert.Equal(t, pb.ValidateAddressResponse_UNKNOWN, resp.MailServer, "Mail server must be unknown")
+		assert.Equal(t, 2, resolver.lookups, "Failed lookup must not be remembered")
+	})
+
+	t.Run("Mail server is not looked up by default", func(t *testing.T) {
+		// Arrange
+		resolver := newFakeResolver()
+		v, _ := NewAddressValidator(AddressValidation{}, resolver)
+
+		// Act
+		resp := v.Validate(ctx, "user@gmial.con")
+
+		// Assert
+		assert.True(t, resp.Valid, "Address must be valid")
+		assert.Equal(t, pb.ValidateAddressResponse_MAIL_SERVER_UNSPECIFIED, resp.MailServer, "Mail server must not be checked")
+		assert.Zero(t, resolver.lookups, "Resolver must not be called")
+	})
+
+	t.Run("Disposable domains are detected", func(t *testing.T) {
+		// Arrange
+		file, err := ioutil.TempFile("", "disposable")
+		assert.NoError(t, err, "Temporary file should be created")
+		defer os.Remove(file.Name())
+		_, err = file.WriteString("# temporary mailboxes\n\ntempbox.example\n")
+		assert.NoError(t, err, "Temporary file should be written")
+		file.Close()
+		v, err := NewAddressValidator(AddressValidation{
+			DisposableDomains:     []string{"Throwaway.EXAMPLE"},
+			DisposableDomainsFile: file.Name(),
+		}, nil)
+		assert.NoError(t, err, "Error should not occur")
+
+		for _, email := range []string{"a@mailinator.com", "a@eu.mailinator.com", "a@throwaway.example", "a@tempbox.example"} {
+			// Act
+			resp := v.Validate(ctx, email)
+
+			// Assert
+			assert.True(t, resp.Disposable, "Domain of %s must be disposable", email)
+			assert.True(t, resp.Valid, "Disposable address %s must be valid unless it is rejected", email)
+		}
+		assert.False(t, v.Validate(ctx, "a@example.com").Disposable, "Domain must not be disposable")
+		assert.False(t, v.Validate(ctx, "a@notmailinator.com").Disposable, "Only subdomains must be disposable")
+	})
+
+	t.Run("Disposable addresses are rejected", func(t *testing.T) {
+		// Arrange
+		v, _ := NewAddressValidator(AddressValidation{RejectDisposable: true}, nil)
+
+		// Act
+		resp := v.Validate(ctx, "a@yopmail.com")
+
+		// Assert
+		assert.False(t, resp.Valid, "Address must not be valid")
+		assert.Equal(t, pb.ValidateAddressResponse_DISPOSABLE, resp.Result, "Result must be disposable")
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		// Act
+		_, listErr := NewAddressValidator(AddressValidation{DisposableDomains: []string{"exa mple.com"}}, nil)
+		_, fileErr := NewAddressValidator(AddressValidation{DisposableDomainsFile: "/nonexistent/domains"}, nil)
+
+		// Assert
+		assert.Error(t, listErr, "Invalid domain must be rejected")
+		assert.Error(t, fileErr, "Missing file must be rejected")
+	})
+}
+
+func TestEmailService_AddressValidation(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	provider := &fakeProvider{name: "fake"}
+	v, err := NewAddressValidator(AddressValidation{CheckMX: true, RejectDisposable: true}, newFakeResolver())
+	assert.NoError(t, err, "Error should not occur")
+	es := NewEmailService(WithProvider(provider), WithAddressValidator(v))
+
+	t.Run("International domains are sent in punycode", func(t *testing.T) {
+		// Arrange
+		req := validRequest()
+		req.From.Email = "sender@bücher.example"
+		req.To = []*pb.Address{{Email: "jan@bücher.example", Name: "Jan"}}
+		req.Cc = []*pb.Address{{Email: "jane@example.com"}}
+
+		// Act
+		_, err := es.SendMail(ctx, req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		if assert.Len(t, provider.requests, 1, "Provider must receive the request") {
+			sent := provider.requests[0]
+			assert.Equal(t, "sender@xn--bcher-kva.example", sent.From.Email, "Sender domain must be converted")
+			assert.Equal(t, &pb.Address{Email: "jan@xn--bcher-kva.example", Name: "Jan"}, sent.To[0],
+				"Recipient domain must be converted")
+			assert.Equal(t, "jane@example.com", sent.Cc[0].Email, "ASCII address must not change")
+		}
+		assert.Equal(t, "jan@bücher.example", req.To[0].Email, "Request of the client must not change")
+	})
+
+	for _, email := range []string{"user@gmial.con", "user@nomail.example.com", "user@mailinator.com"} {
+		t.Run("Recipient "+email+" is rejected", func(t *testing.T) {
+			// Arrange
+			req := validRequest()
+			req.Bcc = []*pb.Address{{Email: email}}
+
+			// Act
+			resp, err := es.SendMail(ctx, req)
+
+			// Assert
+			assert.Nil(t, resp, "Response must not exist")
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Recipient must be rejected as invalid argument")
+			assert.Contains(t, status.Convert(err).Message(), email, "Rejected address must be reported")
+		})
+	}
+
+	t.Run("Batch reports rejected recipient", func(t *testing.T) {
+		// Act
+		resp, err := es.SendMailBatch(ctx, batchRequest("jane@example.com", "user@gmial.con"))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		if assert.Len(t, resp.Results, 2, "Every recipient must have result") {
+			assert.Zero(t, resp.Results[0].Code, "Valid recipient must be sent")
+			assert.Equal(t, int32(codes.InvalidArgument), resp.Results[1].Code, "Recipient must be rejected")
+		}
+	})
+
+	t.Run("ValidateAddress", func(t *testing.T) {
+		// Act
+		resp, err := es.ValidateAddress(ctx, &pb.ValidateAddressRequest{Email: "user@gmial.con"})
+		_, emptyErr := es.ValidateAddress(ctx, &pb.ValidateAddressRequest{})
+
+		// Assert
+		assert.NoError(t, err, "Invalid address must not be an error")
+		assert.False(t, resp.Valid, "Address must not be valid")
+		assert.Equal(t, pb.ValidateAddressResponse_NO_MAIL_SERVER, resp.Result, "Missing mail server must be reported")
+		assert.Equal(t, codes.InvalidArgument, status.Code(emptyErr), "Empty email must be rejected")
+	})
+
+	t.Run("Default validator checks only the syntax", func(t *testing.T) {
+		// Act
+		resp, err := NewEmailService().ValidateAddress(ctx, &pb.ValidateAddressRequest{Email: "jan@BÜCHER.example"})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.True(t, resp.Valid, "Address must be valid")
+		assert.Equal(t, "jan@xn--bcher-kva.example", resp.Email, "Domain must be converted to punycode")
+		assert.Equal(t, "jan@bücher.example", resp.UnicodeEmail, "Domain must be converted to Unicode")
+	})
+}
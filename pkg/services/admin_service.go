@@ -0,0 +1,129 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminService allow operators to manage the delivery of emails
+type AdminService struct {
+	pb.AdminServiceServer
+	opts *options
+}
+
+// NewAdminService constructor of AdminService
+func NewAdminService(opts ...Option) *AdminService {
+	return &AdminService{
+		opts: evaluateOptions(opts),
+	}
+}
+
+// ListDeadLetters returns the page of messages which could not be delivered
+func (as *AdminService) ListDeadLetters(ctx context.Context, req *pb.ListDeadLettersRequest) (*pb.ListDeadLettersResponse, error) {
+	deadLetters, err := as.deadLetters()
+	if err != nil {
+		return nil, err
+	}
+	messages, next, err := deadLetters.List(int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListDeadLettersResponse{NextPageToken: next}
+	for _, msg := range messages {
+		m, err := messageToProto(msg)
+		if err != nil {
+			return nil, err
+		}
+		resp.Messages = append(resp.Messages, m)
+	}
+	return resp, nil
+}
+
+// GetDeadLetter returns the message which could not be delivered together with its content
+func (as *AdminService) GetDeadLetter(ctx context.Context, req *pb.GetDeadLetterRequest) (*pb.DeadLetter, error) {
+	deadLetters, err := as.deadLetters()
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message id is required")
+	}
+	msg, err := deadLetters.Get(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	m, err := messageToProto(msg)
+	if err != nil {
+		return nil, err
+	}
+	emailReq, err := msg.EmailRequest()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not unmarshal email: %v", err)
+	}
+	return &pb.DeadLetter{Message: m, Request: emailReq}, nil
+}
+
+// RequeueDeadLetter moves the message back to the queue
+func (as *AdminService) RequeueDeadLetter(ctx context.Context, req *pb.RequeueDeadLetterRequest) (*pb.Message, error) {
+	if _, err := as.deadLetters(); err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message id is required")
+	}
+	msg, err := as.opts.queue.Requeue(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return messageToProto(msg)
+}
+
+// PurgeDeadLetters removes the messages which could not be delivered
+func (as *AdminService) PurgeDeadLetters(ctx context.Context, req *pb.PurgeDeadLettersRequest) (*pb.PurgeDeadLettersResponse, error) {
+	deadLetters, err := as.deadLetters()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetIds()) == 0 && !req.GetAll() {
+		return nil, status.Error(codes.InvalidArgument, "ids or all is required")
+	}
+	if len(req.GetIds()) > 0 && req.GetAll() {
+		return nil, status.Error(codes.InvalidArgument, "ids and all can not be used together")
+	}
+	var before time.Time
+	if req.GetBefore() != nil {
+		if before, err = ptypes.Timestamp(req.GetBefore()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid before: %v", err)
+		}
+	}
+	purged, err := deadLetters.Purge(req.GetIds(), before)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PurgeDeadLettersResponse{Purged: int32(purged)}, nil
+}
+
+func (as *AdminService) deadLetters() (*DeadLetters, error) {
+	if as.opts.queue == nil || as.opts.queue.DeadLetters() == nil {
+		return nil, status.Error(codes.FailedPrecondition, "dead letters are not configured")
+	}
+	return as.opts.queue.DeadLetters(), nil
+}
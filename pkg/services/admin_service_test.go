@@ -0,0 +1,123 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// deadLetter puts the new email straight into dead letters
+func deadLetter(t *testing.T, q *Queue) string {
+	queued, err := q.Enqueue(validRequest())
+	assert.NoError(t, err, "Email should be queued")
+	msg, err := q.Next(context.Background())
+	assert.NoError(t, err, "Email should be taken from the queue")
+	assert.NoError(t, q.Fail(msg, status.Error(codes.InvalidArgument, "bad address")), "Email should be dead-lettered")
+	return queued.ID
+}
+
+func TestAdminService_DeadLetters(t *testing.T) {
+	// Arrange
+	q := newTestQueue(t)
+	as := NewAdminService(WithQueue(q))
+	ids := []string{deadLetter(t, q), deadLetter(t, q), deadLetter(t, q)}
+
+	t.Run("List is paginated", func(t *testing.T) {
+		// Act
+		first, err := as.ListDeadLetters(context.Background(), &pb.ListDeadLettersRequest{PageSize: 2})
+		assert.NoError(t, err, "Error should not occur")
+		second, err := as.ListDeadLetters(context.Background(), &pb.ListDeadLettersRequest{
+			PageSize:  2,
+			PageToken: first.NextPageToken,
+		})
+		assert.NoError(t, err, "Error should not occur")
+
+		// Assert
+		assert.Len(t, first.Messages, 2, "First page must be full")
+		assert.NotEmpty(t, first.NextPageToken, "Next page must exist")
+		assert.Len(t, second.Messages, 1, "Second page must hold the rest")
+		assert.Empty(t, second.NextPageToken, "Second page must be the last one")
+		var listed []string
+		for _, m := range append(first.Messages, second.Messages...) {
+			listed = append(listed, m.Id)
+			assert.Equal(t, pb.Message_FAILED, m.State, "Dead letter must be failed")
+		}
+		assert.ElementsMatch(t, ids, listed, "All dead letters must be listed")
+	})
+
+	t.Run("Dead letter is inspected", func(t *testing.T) {
+		// Act
+		dl, err := as.GetDeadLetter(context.Background(), &pb.GetDeadLetterRequest{Id: ids[0]})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, ids[0], dl.Message.Id, "Message must be returned")
+		assert.Contains(t, dl.Message.LastError, "bad address", "Error must be returned")
+		assert.Equal(t, validRequest().Subject, dl.Request.Subject, "Content must be returned")
+	})
+
+	t.Run("Dead letter is requeued", func(t *testing.T) {
+		// Act
+		msg, err := as.RequeueDeadLetter(context.Background(), &pb.RequeueDeadLetterRequest{Id: ids[0]})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, pb.Message_QUEUED, msg.State, "Message must be queued")
+		_, err = as.GetDeadLetter(context.Background(), &pb.GetDeadLetterRequest{Id: ids[0]})
+		assert.Equal(t, codes.NotFound, status.Code(err), "Message must leave dead letters")
+	})
+
+	t.Run("Purge requires selection", func(t *testing.T) {
+		// Act
+		_, err := as.PurgeDeadLetters(context.Background(), &pb.PurgeDeadLettersRequest{})
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+	})
+
+	t.Run("Dead letters are purged by id", func(t *testing.T) {
+		// Act
+		resp, err := as.PurgeDeadLetters(context.Background(), &pb.PurgeDeadLettersRequest{Ids: []string{ids[1], "unknown"}})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, int32(1), resp.Purged, "Only existing dead letter must be purged")
+	})
+
+	t.Run("All dead letters are purged", func(t *testing.T) {
+		// Act
+		resp, err := as.PurgeDeadLetters(context.Background(), &pb.PurgeDeadLettersRequest{All: true})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, int32(1), resp.Purged, "Remaining dead letter must be purged")
+	})
+}
+
+func TestAdminService_WithoutQueue(t *testing.T) {
+	// Arrange
+	as := NewAdminService()
+
+	// Act
+	_, err := as.ListDeadLetters(context.Background(), &pb.ListDeadLettersRequest{})
+
+	// Assert
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Failed precondition must be returned")
+}
@@ -0,0 +1,145 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyService manages the API keys which authenticate the callers
+type APIKeyService struct {
+	pb.APIKeyServiceServer
+	opts *options
+}
+
+// NewAPIKeyService constructor of APIKeyService
+func NewAPIKeyService(opts ...Option) *APIKeyService {
+	return &APIKeyService{
+		opts: evaluateOptions(opts),
+	}
+}
+
+// CreateAPIKey generates the new key
+func (ks *APIKeyService) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.APIKey, error) {
+	keys, err := ks.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, secret, err := keys.Create(req.GetApiKey().GetName(), req.GetApiKey().GetAdmin())
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyProto(key, secret)
+}
+
+// ListAPIKeys returns the page of keys, including the revoked ones
+func (ks *APIKeyService) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	keys, err := ks.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := normalizePageSize(int(req.GetPageSize()))
+	list, err := keys.List(req.GetPageToken(), pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListAPIKeysResponse{}
+	if len(list) > pageSize {
+		list = list[:pageSize]
+		resp.NextPageToken = list[pageSize-1].ID
+	}
+	for _, key := range list {
+		k, err := apiKeyProto(key, "")
+		if err != nil {
+			return nil, err
+		}
+		resp.ApiKeys = append(resp.ApiKeys, k)
+	}
+	return resp, nil
+}
+
+// RevokeAPIKey rejects the key from now on
+func (ks *APIKeyService) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.APIKey, error) {
+	keys, err := ks.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keys.Revoke(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyProto(key, "")
+}
+
+// RotateAPIKey generates the new key in place of the old one
+func (ks *APIKeyService) RotateAPIKey(ctx context.Context, req *pb.RotateAPIKeyRequest) (*pb.APIKey, error) {
+	keys, err := ks.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var grace time.Duration
+	if req.GetGracePeriod() != nil {
+		if grace, err = ptypes.Duration(req.GetGracePeriod()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid grace period: %v", err)
+		}
+	}
+	key, secret, err := keys.Rotate(req.GetId(), grace)
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyProto(key, secret)
+}
+
+// keys returns the keys when the caller is the authenticated admin. The
+// interceptor checks it too, but only when the authentication is enabled.
+func (ks *APIKeyService) keys(ctx context.Context) (*auth.Keys, error) {
+	if ks.opts.apiKeys == nil {
+		return nil, status.Error(codes.FailedPrecondition, "api keys can be managed only when authentication is enabled")
+	}
+	if id, ok := auth.FromContext(ctx); !ok || !id.Admin {
+		return nil, status.Error(codes.PermissionDenied, "only the admin can manage api keys")
+	}
+	return ks.opts.apiKeys, nil
+}
+
+// apiKeyProto converts the stored key, the hashes are never returned
+func apiKeyProto(key *auth.Key, secret string) (*pb.APIKey, error) {
+	k := &pb.APIKey{
+		Id:    key.ID,
+		Name:  key.Name,
+		Admin: key.Admin,
+		Key:   secret,
+	}
+	var err error
+	if k.CreatedAt, err = timestampProto(key.CreatedAt); err != nil {
+		return nil, err
+	}
+	if k.RotatedAt, err = timestampProto(key.RotatedAt); err != nil {
+		return nil, err
+	}
+	if k.PreviousExpiresAt, err = timestampProto(key.PreviousExpiresAt); err != nil {
+		return nil, err
+	}
+	if k.RevokedAt, err = timestampProto(key.RevokedAt); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
@@ -0,0 +1,124 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	// Arrange
+	keys := auth.NewKeys(auth.NewStorageKeyStore(openTestStore(t, "apikeys")))
+	ks := NewAPIKeyService(WithAPIKeys(keys))
+	ctx := auth.NewContext(context.Background(), &auth.Identity{Method: auth.MethodKey, ID: "admin", Admin: true})
+
+	t.Run("Key is created, rotated and revoked", func(t *testing.T) {
+		// Act
+		created, err := ks.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{ApiKey: &pb.APIKey{Name: "billing", Admin: true}})
+
+		// Assert
+		assert.NoError(t, err, "Key should be created")
+		assert.NotEmpty(t, created.GetId(), "Key must have its id")
+		assert.NotNil(t, created.GetCreatedAt(), "Key must have its creation time")
+		identity, err := keys.Authenticate(created.GetKey())
+		assert.NoError(t, err, "Created key should be authenticated")
+		assert.True(t, identity.Admin, "Created key must be the admin key")
+
+		// Act
+		rotated, err := ks.RotateAPIKey(ctx, &pb.RotateAPIKeyRequest{Id: created.GetId(), GracePeriod: ptypes.DurationProto(time.Minute)})
+
+		// Assert
+		assert.NoError(t, err, "Key should be rotated")
+		assert.NotEqual(t, created.GetKey(), rotated.GetKey(), "Rotated key must be new")
+		assert.NotNil(t, rotated.GetPreviousExpiresAt(), "Old key must expire")
+		_, err = keys.Authenticate(created.GetKey())
+		assert.NoError(t, err, "Old key should be authenticated in the grace period")
+
+		// Act
+		revoked, err := ks.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: created.GetId()})
+
+		// Assert
+		assert.NoError(t, err, "Key should be revoked")
+		assert.NotNil(t, revoked.GetRevokedAt(), "Key must have its revocation time")
+		assert.Empty(t, revoked.GetKey(), "Revoked key must not be returned")
+		_, err = keys.Authenticate(rotated.GetKey())
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "Revoked key must be rejected")
+	})
+
+	t.Run("Keys are listed without the secrets", func(t *testing.T) {
+		// Arrange
+		for i := 0; i < 2; i++ {
+			_, err := ks.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{ApiKey: &pb.APIKey{Name: "client"}})
+			assert.NoError(t, err, "Key should be created")
+		}
+
+		// Act
+		first, err := ks.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{PageSize: 2})
+		assert.NoError(t, err, "Keys should be listed")
+		second, err := ks.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{PageSize: 2, PageToken: first.GetNextPageToken()})
+		assert.NoError(t, err, "Keys should be listed")
+
+		// Assert
+		assert.Len(t, first.GetApiKeys(), 2, "First page must be full")
+		assert.NotEmpty(t, first.GetNextPageToken(), "First page must have the next one")
+		assert.Len(t, second.GetApiKeys(), 1, "Second page must have the remaining key")
+		assert.Empty(t, second.GetNextPageToken(), "Second page must be the last one")
+		for _, key := range append(first.GetApiKeys(), second.GetApiKeys()...) {
+			assert.Empty(t, key.GetKey(), "Listed key %q must not contain the secret", key.GetId())
+		}
+	})
+
+	t.Run("Invalid requests are rejected", func(t *testing.T) {
+		_, err := ks.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Key without the name must be rejected")
+		_, err = ks.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err), "Missing key must not be revoked")
+	})
+
+	t.Run("Only the authenticated admin manages the keys", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			ctx  context.Context
+		}{
+			{name: "Anonymous caller", ctx: context.Background()},
+			{name: "Caller which is not the admin", ctx: auth.NewContext(context.Background(),
+				&auth.Identity{Method: auth.MethodKey, ID: "user"})},
+		}
+		for _, tc := range testCases {
+			// Act
+			_, err := ks.CreateAPIKey(tc.ctx, &pb.CreateAPIKeyRequest{ApiKey: &pb.APIKey{Name: "root", Admin: true}})
+
+			// Assert
+			assert.Equal(t, codes.PermissionDenied, status.Code(err), "%s must not create the key", tc.name)
+		}
+	})
+
+	t.Run("Keys are not managed when authentication is disabled", func(t *testing.T) {
+		// Act
+		_, err := NewAPIKeyService().CreateAPIKey(context.Background(),
+			&pb.CreateAPIKeyRequest{ApiKey: &pb.APIKey{Name: "root", Admin: true}})
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Keys must not be created without authentication")
+	})
+}
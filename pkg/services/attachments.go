@@ -0,0 +1,154 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMaxAttachmentSize      = 10 << 20
+	defaultMaxTotalAttachmentSize = 20 << 20
+	// requestOverhead is the room left in the gRPC message for the envelope and bodies
+	requestOverhead = 1 << 20
+)
+
+// AttachmentPolicy limits the attachments accepted by SendMail. The content
+// types are media types without parameters, the subtype can be the wildcard
+// e.g. image/*.
+type AttachmentPolicy struct {
+	// MaxFileSize is the limit of the single attachment in bytes
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+	// MaxTotalSize is the limit of all attachments of the email in bytes
+	MaxTotalSize int64 `mapstructure:"max_total_size"`
+	// AllowedTypes are the only accepted content types, every type is allowed when empty
+	AllowedTypes []string `mapstructure:"allowed_types"`
+	// DeniedTypes are rejected even if they are allowed
+	DeniedTypes []string `mapstructure:"denied_types"`
+}
+
+func (p AttachmentPolicy) withDefaults() AttachmentPolicy {
+	if p.MaxFileSize <= 0 {
+		p.MaxFileSize = defaultMaxAttachmentSize
+	}
+	if p.MaxTotalSize <= 0 {
+		p.MaxTotalSize = defaultMaxTotalAttachmentSize
+	}
+	return p
+}
+
+// MaxRequestSize returns the size of the gRPC message which must be accepted
+// by the server to receive the attachments within the limits
+func (p AttachmentPolicy) MaxRequestSize() int {
+	return int(p.withDefaults().MaxTotalSize) + requestOverhead
+}
+
+// validate checks the attachments of the email before it is queued.
+// The returned error is a gRPC status with InvalidArgument code.
+func (p AttachmentPolicy) validate(attachments []*pb.Attachment) error {
+	var total int64
+	contentIDs := make(map[string]struct{})
+	for i, a := range attachments {
+		name := a.GetFilename()
+		if name == "" {
+			name = "#" + strconv.Itoa(i+1)
+		}
+		if containsNewLine(a.GetFilename()) {
+			return status.Errorf(codes.InvalidArgument, "attachment %q filename can not contain new line characters", name)
+		}
+		if a.GetDisposition() != pb.Attachment_INLINE && a.GetFilename() == "" {
+			return status.Errorf(codes.InvalidArgument, "attachment %q filename is required", name)
+		}
+		if len(a.GetContent()) == 0 {
+			return status.Errorf(codes.InvalidArgument, "attachment %q can not be empty", name)
+		}
+		size := int64(len(a.GetContent()))
+		if size > p.MaxFileSize {
+			return status.Errorf(codes.InvalidArgument, "attachment %q exceeds the limit of %d bytes", name, p.MaxFileSize)
+		}
+		if total += size; total > p.MaxTotalSize {
+			return status.Errorf(codes.InvalidArgument, "attachments exceed the limit of %d bytes in total", p.MaxTotalSize)
+		}
+		if err := p.validateContentType(name, a); err != nil {
+			return err
+		}
+
+		id := a.GetContentId()
+		if a.GetDisposition() == pb.Attachment_INLINE && id == "" {
+			return status.Errorf(codes.InvalidArgument, "inline attachment %q content_id is required", name)
+		}
+		if id == "" {
+			continue
+		}
+		// RFC 2392 - the content id is written inside angle brackets
+		if strings.ContainsAny(id, "<>\"\\ \t\r\n") {
+			return status.Errorf(codes.InvalidArgument, "attachment %q content_id %q is not valid", name, id)
+		}
+		if _, ok := contentIDs[id]; ok {
+			return status.Errorf(codes.InvalidArgument, "attachment %q content_id %q is not unique", name, id)
+		}
+		contentIDs[id] = struct{}{}
+	}
+	return nil
+}
+
+// validateContentType applies the allow and deny lists. The deny list is also
+// checked against the type detected from the content, so the declared type can
+// not hide e.g. the executable.
+func (p AttachmentPolicy) validateContentType(name string, a *pb.Attachment) error {
+	declared := a.GetContentType()
+	if declared == "" {
+		declared = http.DetectContentType(a.GetContent())
+	}
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "attachment %q content type %q is not valid", name, declared)
+	}
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(a.GetContent()))
+	if matchesMediaType(p.DeniedTypes, mediaType) || matchesMediaType(p.DeniedTypes, detected) {
+		return status.Errorf(codes.InvalidArgument, "attachment %q content type %q is not allowed", name, mediaType)
+	}
+	if len(p.AllowedTypes) > 0 && !matchesMediaType(p.AllowedTypes, mediaType) {
+		return status.Errorf(codes.InvalidArgument, "attachment %q content type %q is not allowed", name, mediaType)
+	}
+	return nil
+}
+
+// attachmentContentType returns the declared type or the one detected from the content
+func attachmentContentType(a *pb.Attachment) string {
+	if a.GetContentType() != "" {
+		return a.GetContentType()
+	}
+	return http.DetectContentType(a.GetContent())
+}
+
+func matchesMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == mediaType {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
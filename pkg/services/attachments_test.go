@@ -0,0 +1,159 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func pdfAttachment() *pb.Attachment {
+	return &pb.Attachment{
+		Filename:    "invoice.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4 invoice"),
+	}
+}
+
+func logoAttachment() *pb.Attachment {
+	return &pb.Attachment{
+		ContentType: "image/png",
+		Content:     []byte("\x89PNG\r\n\x1a\nlogo"),
+		Disposition: pb.Attachment_INLINE,
+		ContentId:   "logo",
+	}
+}
+
+func TestAttachmentPolicy_Validate(t *testing.T) {
+	policy := AttachmentPolicy{
+		MaxFileSize:  32,
+		MaxTotalSize: 40,
+		AllowedTypes: []string{"application/pdf", "image/*", "text/plain"},
+		DeniedTypes:  []string{"image/svg+xml", "application/zip"},
+	}.withDefaults()
+
+	t.Run("Valid attachments are accepted", func(t *testing.T) {
+		// Act
+		err := policy.validate([]*pb.Attachment{pdfAttachment(), logoAttachment()})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+	})
+
+	t.Run("Content type is detected when empty", func(t *testing.T) {
+		// Arrange
+		a := pdfAttachment()
+		a.ContentType = ""
+
+		// Act
+		err := policy.validate([]*pb.Attachment{a})
+
+		// Assert
+		assert.NoError(t, err, "Detected type must be allowed")
+		assert.Equal(t, "application/pdf", attachmentContentType(a), "Type must be detected from content")
+	})
+
+	tests := []struct {
+		name        string
+		attachments func() []*pb.Attachment
+	}{
+		{"Missing filename", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.Filename = ""
+			return []*pb.Attachment{a}
+		}},
+		{"New line in filename", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.Filename = "invoice\r\n.pdf"
+			return []*pb.Attachment{a}
+		}},
+		{"Empty content", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.Content = nil
+			return []*pb.Attachment{a}
+		}},
+		{"File too big", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.Content = bytes.Repeat([]byte("x"), 33)
+			return []*pb.Attachment{a}
+		}},
+		{"Total too big", func() []*pb.Attachment {
+			return []*pb.Attachment{pdfAttachment(), pdfAttachment(), pdfAttachment()}
+		}},
+		{"Invalid content type", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.ContentType = "pdf"
+			return []*pb.Attachment{a}
+		}},
+		{"Type not allowed", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.ContentType = "application/msword"
+			return []*pb.Attachment{a}
+		}},
+		{"Denied type wins over allowed wildcard", func() []*pb.Attachment {
+			a := logoAttachment()
+			a.ContentType = "image/svg+xml"
+			return []*pb.Attachment{a}
+		}},
+		{"Detected type is denied", func() []*pb.Attachment {
+			a := pdfAttachment()
+			a.Content = []byte("PK\x03\x04archive")
+			return []*pb.Attachment{a}
+		}},
+		{"Inline without content id", func() []*pb.Attachment {
+			a := logoAttachment()
+			a.ContentId = ""
+			return []*pb.Attachment{a}
+		}},
+		{"Invalid content id", func() []*pb.Attachment {
+			a := logoAttachment()
+			a.ContentId = "<logo>"
+			return []*pb.Attachment{a}
+		}},
+		{"Duplicated content id", func() []*pb.Attachment {
+			return []*pb.Attachment{logoAttachment(), logoAttachment()}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := policy.validate(tt.attachments())
+
+			// Assert
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Attachments must be rejected as invalid argument")
+		})
+	}
+}
+
+func TestEmailService_SendMailAttachments(t *testing.T) {
+	// Arrange
+	provider := &fakeProvider{name: "fake"}
+	es := NewEmailService(WithProvider(provider), WithAttachmentPolicy(AttachmentPolicy{MaxFileSize: 8}))
+	req := validRequest()
+	req.Attachments = []*pb.Attachment{pdfAttachment()}
+
+	// Act
+	resp, err := es.SendMail(context.Background(), req)
+
+	// Assert
+	assert.Nil(t, resp, "Response must not exist")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Attachment over the limit must be rejected")
+	assert.Empty(t, provider.requests, "Provider must not be called")
+}
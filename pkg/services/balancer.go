@@ -0,0 +1,287 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The strategies of picking the provider by the Balancer
+const (
+	// WeightedRoundRobin spreads the emails in proportion to the weights in a deterministic order
+	WeightedRoundRobin = "weighted_round_robin"
+	// LeastOutstanding picks the provider with the lowest number of in-flight emails per weight
+	LeastOutstanding = "least_outstanding"
+	// WeightedRandom picks the provider randomly in proportion to the weights
+	WeightedRandom = "weighted_random"
+)
+
+// maxThrottleWait is how long the email waits for the provider which exceeded
+// its rate limit, unless the context ends earlier
+const maxThrottleWait = 10 * time.Second
+
+// WeightedProvider is the member of the Balancer
+type WeightedProvider struct {
+	Provider Provider
+	// Weight is the relative share of emails, default is 1
+	Weight int
+	// Breaker configures the circuit breaker of the provider
+	Breaker BreakerConfig
+	// Retry is used by the Dispatcher for emails which failed on the provider,
+	// zero value means the default policy of the Dispatcher
+	Retry RetryPolicy
+	// RateLimit is the number of emails per second sent through the provider,
+	// zero value means no limit
+	RateLimit ratelimit.Limit
+}
+
+type member struct {
+	provider    Provider
+	breaker     *circuitBreaker
+	bucket      *ratelimit.Bucket
+	weight      int
+	current     int
+	outstanding int
+}
+
+// Balancer spreads the emails between the providers. It is a Provider itself,
+// so the EmailService does not need to know how many providers are configured.
+// Every provider is guarded by the circuit breaker and the email which failed
+// on the provider in a retryable way is sent through the next healthy one.
+type Balancer struct {
+	strategy string
+	members  []*member
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewBalancer constructor of Balancer. The default strategy is WeightedRoundRobin.
+func NewBalancer(strategy string, providers ...WeightedProvider) (*Balancer, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("balancer requires at least one provider")
+	}
+	if strategy == "" {
+		strategy = WeightedRoundRobin
+	}
+	switch strategy {
+	case WeightedRoundRobin, LeastOutstanding, WeightedRandom:
+	default:
+		return nil, fmt.Errorf("unknown balancing strategy %q", strategy)
+	}
+
+	b := &Balancer{
+		strategy: strategy,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	names := make(map[string]struct{}, len(providers))
+	for _, p := range providers {
+		if p.Provider == nil {
+			return nil, fmt.Errorf("provider can not be nil")
+		}
+		if _, ok := names[p.Provider.Name()]; ok {
+			return nil, fmt.Errorf("provider %q is registered twice", p.Provider.Name())
+		}
+		names[p.Provider.Name()] = struct{}{}
+		if p.Weight < 0 {
+			return nil, fmt.Errorf("weight of provider %q can not be negative", p.Provider.Name())
+		}
+		if p.Weight == 0 {
+			p.Weight = 1
+		}
+		b.members = append(b.members, &member{
+			provider: p.Provider,
+			breaker:  newCircuitBreaker(p.Provider.Name(), p.Breaker),
+			bucket:   ratelimit.NewBucket(p.RateLimit),
+			weight:   p.Weight,
+		})
+	}
+	return b, nil
+}
+
+// Name of the balancer
+func (b *Balancer) Name() string {
+	return "balancer"
+}
+
+// Send delivers the email through the provider picked by the strategy. When
+// the provider fails in a retryable way the email is sent through the next
+// provider which was not tried yet, until none of them is left. The error of
+// the provider is returned as ProviderError. The providers which exceeded their
+// rate limit are skipped, when all of them did the email waits for the first
+// one which can send it again. The email which can not wait that long is
+// rejected with RESOURCE_EXHAUSTED.
+func (b *Balancer) Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error) {
+	tried := make(map[*member]bool, len(b.members))
+	waitUntil := time.Now().Add(maxThrottleWait)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(waitUntil) {
+		waitUntil = deadline
+	}
+	var lastErr error
+	for {
+		m, wait := b.acquire(tried)
+		if m == nil && wait > 0 {
+			if time.Now().Add(wait).After(waitUntil) {
+				return nil, ratelimit.Exhausted(wait, "sending rate of email providers exceeded, retry after %v",
+					wait.Round(time.Millisecond))
+			}
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+		}
+		if m == nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, status.Error(codes.Unavailable, "no healthy email provider is available")
+		}
+		tried[m] = true
+
+		start := time.Now()
+		receipt, err := m.provider.Send(ctx, req)
+		observeSend(m.provider.Name(), start, err)
+		m.breaker.record(err)
+		b.release(m)
+		if err == nil {
+			return receipt, nil
+		}
+		lastErr = &ProviderError{Provider: m.provider.Name(), Err: err}
+		if !isProviderFailure(err) || ctx.Err() != nil {
+			return nil, lastErr
+		}
+		zap.L().Warn("Email provider failed, trying next one",
+			zap.String("provider", m.provider.Name()),
+			zap.Error(err))
+	}
+}
+
+// acquire picks the member which was not tried yet, whose circuit breaker lets
+// the email through and which did not exceed its rate limit, then marks it as
+// busy. When only the members which exceeded the rate limit are left, the time
+// after which the first of them can send the email is returned.
+func (b *Balancer) acquire(tried map[*member]bool) (*member, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var wait time.Duration
+	eligible := make([]*member, 0, len(b.members))
+	for _, m := range b.members {
+		if tried[m] || !m.breaker.ready() {
+			continue
+		}
+		if delay := m.bucket.Delay(); delay > 0 {
+			throttledSends.WithLabelValues(m.provider.Name()).Inc()
+			if wait == 0 || delay < wait {
+				wait = delay
+			}
+			continue
+		}
+		eligible = append(eligible, m)
+	}
+	for len(eligible) > 0 {
+		var m *member
+		switch b.strategy {
+		case LeastOutstanding:
+			m = b.leastOutstanding(eligible)
+		case WeightedRandom:
+			m = b.weightedRandom(eligible)
+		default:
+			m = b.weightedRoundRobin(eligible)
+		}
+		// The half-open breaker may have been taken by concurrent probe
+		if m.breaker.acquire() {
+			// The bucket is taken only by the balancer, so it still has the token
+			m.bucket.Take()
+			m.outstanding++
+			return m, 0
+		}
+		eligible = without(eligible, m)
+	}
+	return nil, wait
+}
+
+func without(members []*member, m *member) []*member {
+	out := members[:0]
+	for _, c := range members {
+		if c != m {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (b *Balancer) release(m *member) {
+	b.mu.Lock()
+	m.outstanding--
+	b.mu.Unlock()
+}
+
+// weightedRoundRobin is the smooth weighted round-robin used by nginx, which
+// does not send bursts of emails to the provider with the highest weight
+func (b *Balancer) weightedRoundRobin(members []*member) *member {
+	var (
+		best  *member
+		total int
+	)
+	for _, m := range members {
+		m.current += m.weight
+		total += m.weight
+		if best == nil || m.current > best.current {
+			best = m
+		}
+	}
+	if best != nil {
+		best.current -= total
+	}
+	return best
+}
+
+func (b *Balancer) leastOutstanding(members []*member) *member {
+	var best *member
+	for _, m := range members {
+		// Compare outstanding/weight without floating point numbers
+		if best == nil || m.outstanding*best.weight < best.outstanding*m.weight {
+			best = m
+		}
+	}
+	return best
+}
+
+func (b *Balancer) weightedRandom(members []*member) *member {
+	total := 0
+	for _, m := range members {
+		total += m.weight
+	}
+	n := b.rand.Intn(total)
+	for _, m := range members {
+		if n < m.weight {
+			return m
+		}
+		n -= m.weight
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockingProvider holds every email until it is released
+type blockingProvider struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingProvider(name string) *blockingProvider {
+	return &blockingProvider{
+		name:    name,
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingProvider) Name() string {
+	return b.name
+}
+
+func (b *blockingProvider) Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return &Receipt{Provider: b.name, MessageID: "id-" + b.name}, nil
+}
+
+func sendMany(t *testing.T, b *Balancer, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		receipt, err := b.Send(context.Background(), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		counts[receipt.Provider]++
+	}
+	return counts
+}
+
+func TestNewBalancer(t *testing.T) {
+	t.Run("Providers are required", func(t *testing.T) {
+		// Act
+		_, err := NewBalancer(WeightedRoundRobin)
+
+		// Assert
+		assert.Error(t, err, "Balancer without providers must not be created")
+	})
+
+	t.Run("Unknown strategy", func(t *testing.T) {
+		// Act
+		_, err := NewBalancer("fastest", WeightedProvider{Provider: &fakeProvider{name: "a"}})
+
+		// Assert
+		assert.Error(t, err, "Unknown strategy must be rejected")
+	})
+
+	t.Run("Duplicated provider name", func(t *testing.T) {
+		// Act
+		_, err := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: &fakeProvider{name: "a"}},
+			WeightedProvider{Provider: &fakeProvider{name: "a"}})
+
+		// Assert
+		assert.Error(t, err, "Providers must have unique names")
+	})
+
+	t.Run("Negative weight", func(t *testing.T) {
+		// Act
+		_, err := NewBalancer(WeightedRoundRobin, WeightedProvider{Provider: &fakeProvider{name: "a"}, Weight: -1})
+
+		// Assert
+		assert.Error(t, err, "Negative weight must be rejected")
+	})
+}
+
+func TestBalancer_WeightedRoundRobin(t *testing.T) {
+	// Arrange
+	b, err := NewBalancer(WeightedRoundRobin,
+		WeightedProvider{Provider: &fakeProvider{name: "a"}, Weight: 5},
+		WeightedProvider{Provider: &fakeProvider{name: "b"}, Weight: 1},
+		WeightedProvider{Provider: &fakeProvider{name: "c"}, Weight: 1})
+	assert.NoError(t, err, "Balancer should be created")
+
+	// Act
+	var order []string
+	for i := 0; i < 7; i++ {
+		receipt, err := b.Send(context.Background(), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		order = append(order, receipt.Provider)
+	}
+
+	// Assert
+	assert.Equal(t, []string{"a", "a", "b", "a", "c", "a", "a"}, order,
+		"Smooth weighted round-robin must interleave providers")
+}
+
+func TestBalancer_WeightedRandom(t *testing.T) {
+	// Arrange
+	b, err := NewBalancer(WeightedRandom,
+		WeightedProvider{Provider: &fakeProvider{name: "a"}, Weight: 3},
+		WeightedProvider{Provider: &fakeProvider{name: "b"}, Weight: 1})
+	assert.NoError(t, err, "Balancer should be created")
+
+	// Act
+	counts := sendMany(t, b, 4000)
+
+	// Assert
+	assert.InDelta(t, 3000, counts["a"], 300, "Provider a should get around 3/4 of emails")
+	assert.InDelta(t, 1000, counts["b"], 300, "Provider b should get around 1/4 of emails")
+}
+
+func TestBalancer_LeastOutstanding(t *testing.T) {
+	// Arrange
+	slow := newBlockingProvider("slow")
+	fast := &fakeProvider{name: "fast"}
+	b, err := NewBalancer(LeastOutstanding,
+		WeightedProvider{Provider: slow},
+		WeightedProvider{Provider: fast})
+	assert.NoError(t, err, "Balancer should be created")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := b.Send(context.Background(), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+	}()
+	<-slow.started
+
+	// Act
+	counts := sendMany(t, b, 3)
+	close(slow.release)
+	<-done
+
+	// Assert
+	assert.Equal(t, 3, counts["fast"], "Emails must avoid the busy provider")
+}
+
+func TestBalancer_ProviderError(t *testing.T) {
+	// Arrange
+	b, err := NewBalancer(WeightedRoundRobin, WeightedProvider{Provider: &fakeProvider{
+		name: "failing",
+		err:  status.Error(codes.Unavailable, "down"),
+	}})
+	assert.NoError(t, err, "Balancer should be created")
+
+	// Act
+	receipt, err := b.Send(context.Background(), validRequest())
+
+	// Assert
+	assert.Nil(t, receipt, "Receipt must not exist")
+	assert.Equal(t, codes.Unavailable, status.Code(err), "Error of the provider must be returned")
+}
+
+func TestBalancer_Failover(t *testing.T) {
+	t.Run("Retryable error is retried on another provider", func(t *testing.T) {
+		// Arrange
+		failing := &fakeProvider{name: "failing", err: status.Error(codes.Unavailable, "down")}
+		healthy := &fakeProvider{name: "healthy"}
+		b, err := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: failing, Weight: 10},
+			WeightedProvider{Provider: healthy})
+		assert.NoError(t, err, "Balancer should be created")
+
+		// Act
+		receipt, err := b.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "healthy", receipt.Provider, "Email must be sent through healthy provider")
+		assert.Len(t, failing.requests, 1, "Failing provider must be tried once")
+	})
+
+	t.Run("Error of the email is not retried", func(t *testing.T) {
+		// Arrange
+		rejecting := &fakeProvider{name: "rejecting", err: status.Error(codes.InvalidArgument, "bad address")}
+		healthy := &fakeProvider{name: "healthy"}
+		b, err := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: rejecting, Weight: 10},
+			WeightedProvider{Provider: healthy})
+		assert.NoError(t, err, "Balancer should be created")
+
+		// Act
+		_, err = b.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Error of the provider must be returned")
+		assert.Empty(t, healthy.requests, "Email must not be sent twice")
+	})
+
+	t.Run("Last error is returned when all providers fail", func(t *testing.T) {
+		// Arrange
+		b, err := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: &fakeProvider{name: "a", err: status.Error(codes.Unavailable, "a is down")}},
+			WeightedProvider{Provider: &fakeProvider{name: "b", err: status.Error(codes.Unavailable, "b is down")}})
+		assert.NoError(t, err, "Balancer should be created")
+
+		// Act
+		_, err = b.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Unavailable, status.Code(err), "Error of the provider must be returned")
+	})
+
+	t.Run("Provider with open breaker is skipped", func(t *testing.T) {
+		// Arrange
+		failing := &fakeProvider{name: "failing", err: status.Error(codes.Unavailable, "down")}
+		healthy := &fakeProvider{name: "healthy"}
+		b, err := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: failing, Weight: 10, Breaker: BreakerConfig{ConsecutiveFailures: 1}},
+			WeightedProvider{Provider: healthy})
+		assert.NoError(t, err, "Balancer should be created")
+
+		// Act
+		sendMany(t, b, 5)
+
+		// Assert
+		assert.Len(t, failing.requests, 1, "Open breaker must stop emails to failing provider")
+		assert.Len(t, healthy.requests, 5, "All emails must be sent through healthy provider")
+	})
+
+	t.Run("No healthy provider", func(t *testing.T) {
+		// Arrange
+		b, err := NewBalancer(WeightedRoundRobin, WeightedProvider{
+			Provider: &fakeProvider{name: "failing", err: status.Error(codes.Unavailable, "down")},
+			Breaker:  BreakerConfig{ConsecutiveFailures: 1},
+		})
+		assert.NoError(t, err, "Balancer should be created")
+		_, _ = b.Send(context.Background(), validRequest())
+
+		// Act
+		_, err = b.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Unavailable, status.Code(err), "Unavailable must be returned")
+		assert.Contains(t, status.Convert(err).Message(), "no healthy email provider", "Open breakers must be reported")
+	})
+}
+
+func TestBalancer_RateLimit(t *testing.T) {
+	t.Run("Provider which exceeded the rate limit is skipped", func(t *testing.T) {
+		// Arrange
+		b, _ := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: &fakeProvider{name: "limited"}, Weight: 10, RateLimit: ratelimit.Limit{Rate: 0.1, Burst: 2}},
+			WeightedProvider{Provider: &fakeProvider{name: "spare"}, Weight: 1},
+		)
+
+		// Act
+		counts := sendMany(t, b, 10)
+
+		// Assert
+		assert.Equal(t, map[string]int{"limited": 2, "spare": 8}, counts, "Limited provider must send only the burst")
+	})
+
+	t.Run("Email waits for the limited provider", func(t *testing.T) {
+		// Arrange
+		b, _ := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: &fakeProvider{name: "limited"}, RateLimit: ratelimit.Limit{Rate: 20, Burst: 1}},
+		)
+		start := time.Now()
+
+		// Act
+		counts := sendMany(t, b, 3)
+
+		// Assert
+		assert.Equal(t, map[string]int{"limited": 3}, counts, "Every email must be sent")
+		assert.True(t, time.Since(start) >= 90*time.Millisecond, "Emails must be sent at the rate")
+	})
+
+	t.Run("Email which can not wait is rejected", func(t *testing.T) {
+		// Arrange
+		b, _ := NewBalancer(WeightedRoundRobin,
+			WeightedProvider{Provider: &fakeProvider{name: "limited"}, RateLimit: ratelimit.Limit{Rate: 1, Burst: 1}},
+		)
+		sendMany(t, b, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// Act
+		receipt, err := b.Send(ctx, validRequest())
+
+		// Assert
+		assert.Nil(t, receipt, "Email must not be sent")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "Resource exhausted must be returned")
+		retryAfter, ok := ratelimit.RetryAfter(err)
+		assert.True(t, ok, "Time after which the email can be sent must be returned")
+		assert.True(t, retryAfter > 900*time.Millisecond, "Time must be until the next token")
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func batchRequest(addresses ...string) *pb.SendMailBatchRequest {
+	req := &pb.SendMailBatchRequest{Email: validRequest()}
+	req.Email.To = nil
+	for _, a := range addresses {
+		req.Recipients = append(req.Recipients, &pb.BatchRecipient{Address: &pb.Address{Email: a}})
+	}
+	return req
+}
+
+func TestEmailService_SendMailBatch(t *testing.T) {
+	t.Run("Invalid recipient does not fail the batch", func(t *testing.T) {
+		// Arrange
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider))
+
+		// Act
+		resp, err := es.SendMailBatch(context.Background(),
+			batchRequest("first@example.com", "not an address", "third@example.com"))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, []*pb.BatchResult{
+			{MessageId: "id-fake", Provider: "fake"},
+			{Code: int32(codes.InvalidArgument), Error: `to address "not an address" is not valid`},
+			{MessageId: "id-fake", Provider: "fake"},
+		}, resp.Results, "Result must be returned per recipient")
+		if assert.Len(t, provider.requests, 2, "Valid recipients must receive the email") {
+			assert.Equal(t, "first@example.com", provider.requests[0].To[0].Email, "Every recipient must get own email")
+			assert.Equal(t, "third@example.com", provider.requests[1].To[0].Email, "Every recipient must get own email")
+		}
+	})
+
+	t.Run("Template is rendered with recipient variables", func(t *testing.T) {
+		// Arrange
+		templates := newTestTemplates(t)
+		_, err := templates.Create(welcomeTemplate())
+		assert.NoError(t, err, "Template must be created")
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithTemplates(templates))
+		req := batchRequest("alice@example.com", "bob@example.com", "carol@example.com")
+		req.Email.Subject, req.Email.TextBody = "", ""
+		req.Email.TemplateId = "welcome"
+		req.Email.Variables = map[string]string{"code": "SHARED"}
+		req.Recipients[0].Variables = map[string]string{"name": "Alice"}
+		req.Recipients[1].Variables = map[string]string{"name": "Bob", "code": "B0B"}
+
+		// Act
+		resp, err := es.SendMailBatch(context.Background(), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, int32(codes.InvalidArgument), resp.Results[2].Code, "Recipient without variable must fail")
+		if assert.Len(t, provider.requests, 2, "Rendered emails must be sent") {
+			assert.Equal(t, "Hello Alice, your code is SHARED", provider.requests[0].TextBody, "Shared variable must be used")
+			assert.Equal(t, "Hello Bob, your code is B0B", provider.requests[1].TextBody, "Recipient variable must override shared one")
+		}
+		assert.Len(t, req.Email.Variables, 1, "Shared variables must not be modified")
+	})
+
+	t.Run("Retried batch is not sent again", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithIdempotency(idem))
+		req := batchRequest("first@example.com", "second@example.com")
+
+		// Act
+		first, err := es.SendMailBatch(withIdempotencyKey("batch-1"), req)
+		assert.NoError(t, err, "Error should not occur")
+		second, err := es.SendMailBatch(withIdempotencyKey("batch-1"), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, first, second, "Original results must be returned")
+		assert.Len(t, provider.requests, 2, "Every recipient must receive the email once")
+	})
+
+	tests := []struct {
+		name string
+		req  func() *pb.SendMailBatchRequest
+	}{
+		{"Missing email", func() *pb.SendMailBatchRequest {
+			return &pb.SendMailBatchRequest{Recipients: batchRequest("a@example.com").Recipients}
+		}},
+		{"Recipients in email", func() *pb.SendMailBatchRequest {
+			req := batchRequest("a@example.com")
+			req.Email.Cc = []*pb.Address{{Email: "cc@example.com"}}
+			return req
+		}},
+		{"Missing recipients", func() *pb.SendMailBatchRequest {
+			return batchRequest()
+		}},
+		{"Too many recipients", func() *pb.SendMailBatchRequest {
+			req := batchRequest()
+			for i := 0; i <= maxBatchRecipients; i++ {
+				req.Recipients = append(req.Recipients, &pb.BatchRecipient{Address: &pb.Address{Email: "a@example.com"}})
+			}
+			return req
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			provider := &fakeProvider{name: "fake"}
+			es := NewEmailService(WithProvider(provider))
+
+			// Act
+			resp, err := es.SendMailBatch(context.Background(), tt.req())
+
+			// Assert
+			assert.Nil(t, resp, "Response must not exist")
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Batch must be rejected as invalid argument")
+			assert.Empty(t, provider.requests, "Provider must not be called")
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerState is the state of the provider circuit breaker
+type BreakerState int
+
+// The states of circuit breaker. The values are exported as the
+// email_provider_circuit_breaker_state gauge.
+const (
+	// BreakerClosed lets all emails through the provider
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets limited number of emails to probe if the provider recovered
+	BreakerHalfOpen
+	// BreakerOpen rejects all emails until the open timeout elapses
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig configures the circuit breaker of the single provider.
+// Zero values are replaced with defaults.
+type BreakerConfig struct {
+	// ConsecutiveFailures opens the breaker after that many failures in a row, default 5
+	ConsecutiveFailures int `mapstructure:"consecutive_failures"`
+	// ErrorRate opens the breaker when the ratio of failures in the window exceeds it, default 0.5
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// MinRequests is the number of sends in the window required to evaluate the error rate, default 20
+	MinRequests int `mapstructure:"min_requests"`
+	// Window is the period in which the error rate is measured, default 1m
+	Window time.Duration `mapstructure:"window"`
+	// OpenTimeout is the time after which the open breaker becomes half-open, default 30s
+	OpenTimeout time.Duration `mapstructure:"open_timeout"`
+	// HalfOpenRequests is the number of probes allowed at once in half-open state, default 1
+	HalfOpenRequests int `mapstructure:"half_open_requests"`
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.ConsecutiveFailures <= 0 {
+		c.ConsecutiveFailures = 5
+	}
+	if c.ErrorRate <= 0 || c.ErrorRate > 1 {
+		c.ErrorRate = 0.5
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.Window <= 0 {
+		c.Window = time.Minute
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+	return c
+}
+
+// circuitBreaker tracks the health of the single provider
+type circuitBreaker struct {
+	provider string
+	cfg      BreakerConfig
+	now      func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+}
+
+func newCircuitBreaker(provider string, cfg BreakerConfig) *circuitBreaker {
+	cb := &circuitBreaker{
+		provider: provider,
+		cfg:      cfg.withDefaults(),
+		now:      time.Now,
+	}
+	breakerState.WithLabelValues(provider).Set(float64(BreakerClosed))
+	return cb
+}
+
+// State returns the current state of the breaker
+func (cb *circuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// ready reports if the email could be sent through the provider
+// without reserving anything
+func (cb *circuitBreaker) ready() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case BreakerOpen:
+		return !cb.now().Before(cb.openedAt.Add(cb.cfg.OpenTimeout))
+	case BreakerHalfOpen:
+		return cb.probes < cb.cfg.HalfOpenRequests
+	}
+	return true
+}
+
+// acquire reserves the send through the provider. The open breaker which
+// waited long enough becomes half-open and the send is used as a probe.
+func (cb *circuitBreaker) acquire() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case BreakerOpen:
+		if cb.now().Before(cb.openedAt.Add(cb.cfg.OpenTimeout)) {
+			return false
+		}
+		cb.setState(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if cb.probes >= cb.cfg.HalfOpenRequests {
+			return false
+		}
+		cb.probes++
+	}
+	return true
+}
+
+// record updates the breaker with the result of the send
+func (cb *circuitBreaker) record(err error) {
+	failed := isProviderFailure(err)
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == BreakerHalfOpen {
+		cb.probes--
+		if failed {
+			cb.open()
+		} else {
+			cb.setState(BreakerClosed)
+		}
+		return
+	}
+	if cb.state == BreakerOpen {
+		// The send was acquired before another one opened the breaker
+		return
+	}
+
+	now := cb.now()
+	if now.Sub(cb.windowStart) >= cb.cfg.Window {
+		cb.windowStart = now
+		cb.requests = 0
+		cb.failures = 0
+	}
+	cb.requests++
+	if !failed {
+		cb.consecutive = 0
+		return
+	}
+	cb.failures++
+	cb.consecutive++
+	if cb.consecutive >= cb.cfg.ConsecutiveFailures ||
+		(cb.requests >= cb.cfg.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.cfg.ErrorRate) {
+		cb.open()
+	}
+}
+
+func (cb *circuitBreaker) open() {
+	cb.openedAt = cb.now()
+	cb.setState(BreakerOpen)
+}
+
+// setState must be called with the lock held
+func (cb *circuitBreaker) setState(state BreakerState) {
+	if cb.state == state {
+		return
+	}
+	zap.L().Info("Circuit breaker state changed",
+		zap.String("provider", cb.provider),
+		zap.Stringer("from", cb.state),
+		zap.Stringer("to", state),
+		zap.Int("consecutive_failures", cb.consecutive),
+		zap.Int("window_requests", cb.requests),
+		zap.Int("window_failures", cb.failures))
+	cb.state = state
+	cb.consecutive = 0
+	cb.windowStart = cb.now()
+	cb.requests = 0
+	cb.failures = 0
+	breakerState.WithLabelValues(cb.provider).Set(float64(state))
+	breakerTransitions.WithLabelValues(cb.provider, state.String()).Inc()
+}
+
+// isProviderFailure reports if the error says something about the health of
+// the provider rather than about the email itself or the caller
+func isProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsRetryable(err) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestBreaker(cfg BreakerConfig) (*circuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cb := newCircuitBreaker("test", cfg)
+	cb.now = clock.Now
+	cb.windowStart = clock.now
+	return cb, clock
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	t.Run("Consecutive failures open the breaker", func(t *testing.T) {
+		// Arrange
+		cb, _ := newTestBreaker(BreakerConfig{ConsecutiveFailures: 3})
+
+		// Act
+		cb.record(unavailable)
+		cb.record(unavailable)
+		cb.record(nil)
+		cb.record(unavailable)
+		cb.record(unavailable)
+		stillClosed := cb.State()
+		cb.record(unavailable)
+
+		// Assert
+		assert.Equal(t, BreakerClosed, stillClosed, "Success must reset consecutive failures")
+		assert.Equal(t, BreakerOpen, cb.State(), "Breaker must be opened")
+		assert.False(t, cb.ready(), "Open breaker must reject emails")
+	})
+
+	t.Run("Error rate opens the breaker", func(t *testing.T) {
+		// Arrange
+		cb, _ := newTestBreaker(BreakerConfig{ConsecutiveFailures: 100, ErrorRate: 0.5, MinRequests: 4})
+
+		// Act
+		cb.record(nil)
+		cb.record(unavailable)
+		cb.record(nil)
+		cb.record(unavailable)
+
+		// Assert
+		assert.Equal(t, BreakerOpen, cb.State(), "Breaker must be opened when half of sends fail")
+	})
+
+	t.Run("Error rate is measured in the window", func(t *testing.T) {
+		// Arrange
+		cb, clock := newTestBreaker(BreakerConfig{ConsecutiveFailures: 100, MinRequests: 4, Window: time.Minute})
+
+		// Act
+		cb.record(unavailable)
+		cb.record(unavailable)
+		clock.now = clock.now.Add(2 * time.Minute)
+		cb.record(nil)
+		cb.record(nil)
+		cb.record(unavailable)
+		cb.record(nil)
+
+		// Assert
+		assert.Equal(t, BreakerClosed, cb.State(), "Failures from previous window must be forgotten")
+	})
+
+	t.Run("Errors of the email do not open the breaker", func(t *testing.T) {
+		// Arrange
+		cb, _ := newTestBreaker(BreakerConfig{ConsecutiveFailures: 1})
+
+		// Act
+		cb.record(status.Error(codes.InvalidArgument, "bad address"))
+		cb.record(status.Error(codes.FailedPrecondition, "rejected"))
+		cb.record(status.Error(codes.Canceled, "canceled"))
+
+		// Assert
+		assert.Equal(t, BreakerClosed, cb.State(), "Breaker must stay closed")
+	})
+
+	t.Run("Half-open breaker closes after successful probe", func(t *testing.T) {
+		// Arrange
+		cb, clock := newTestBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Minute})
+		cb.record(unavailable)
+		clock.now = clock.now.Add(time.Minute)
+
+		// Act
+		probe := cb.acquire()
+		secondProbe := cb.acquire()
+		state := cb.State()
+		cb.record(nil)
+
+		// Assert
+		assert.True(t, probe, "Probe must be allowed after open timeout")
+		assert.False(t, secondProbe, "Only one probe is allowed at once")
+		assert.Equal(t, BreakerHalfOpen, state, "Breaker must be half-open during the probe")
+		assert.Equal(t, BreakerClosed, cb.State(), "Breaker must be closed")
+	})
+
+	t.Run("Half-open breaker opens again after failed probe", func(t *testing.T) {
+		// Arrange
+		cb, clock := newTestBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Minute})
+		cb.record(unavailable)
+		clock.now = clock.now.Add(time.Minute)
+
+		// Act
+		probe := cb.acquire()
+		cb.record(errors.New("connection reset"))
+
+		// Assert
+		assert.True(t, probe, "Probe must be allowed after open timeout")
+		assert.Equal(t, BreakerOpen, cb.State(), "Breaker must be opened again")
+		assert.False(t, cb.acquire(), "Open timeout must start again")
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CallbackService registers the URLs to which the Notifier posts the events of
+// messages. Every caller manages only its own callbacks, which receive only the
+// events of the messages it sent.
+type CallbackService struct {
+	pb.CallbackServiceServer
+	opts *options
+}
+
+// NewCallbackService constructor of CallbackService
+func NewCallbackService(opts ...Option) *CallbackService {
+	return &CallbackService{
+		opts: evaluateOptions(opts),
+	}
+}
+
+// CreateCallback registers the callback
+func (cs *CallbackService) CreateCallback(ctx context.Context, req *pb.CreateCallbackRequest) (*pb.Callback, error) {
+	callbacks, err := cs.callbacks()
+	if err != nil {
+		return nil, err
+	}
+	return callbacks.Create(ctx, caller(ctx), req.GetCallback())
+}
+
+// GetCallback returns the callback
+func (cs *CallbackService) GetCallback(ctx context.Context, req *pb.GetCallbackRequest) (*pb.Callback, error) {
+	callbacks, err := cs.callbacks()
+	if err != nil {
+		return nil, err
+	}
+	return callbacks.Get(caller(ctx), req.GetId())
+}
+
+// ListCallbacks returns the page of callbacks
+func (cs *CallbackService) ListCallbacks(ctx context.Context, req *pb.ListCallbacksRequest) (*pb.ListCallbacksResponse, error) {
+	callbacks, err := cs.callbacks()
+	if err != nil {
+		return nil, err
+	}
+	list, next, err := callbacks.List(caller(ctx), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListCallbacksResponse{Callbacks: list, NextPageToken: next}, nil
+}
+
+// DeleteCallback removes the callback
+func (cs *CallbackService) DeleteCallback(ctx context.Context, req *pb.DeleteCallbackRequest) (*pb.DeleteCallbackResponse, error) {
+	callbacks, err := cs.callbacks()
+	if err != nil {
+		return nil, err
+	}
+	if err := callbacks.Delete(caller(ctx), req.GetId()); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteCallbackResponse{}, nil
+}
+
+// EnableCallback enables the callback disabled after repeated failures
+func (cs *CallbackService) EnableCallback(ctx context.Context, req *pb.EnableCallbackRequest) (*pb.Callback, error) {
+	callbacks, err := cs.callbacks()
+	if err != nil {
+		return nil, err
+	}
+	return callbacks.Enable(caller(ctx), req.GetId())
+}
+
+// ListCallbackAttempts returns the page of the recent attempts of the callback
+func (cs *CallbackService) ListCallbackAttempts(ctx context.Context,
+	req *pb.ListCallbackAttemptsRequest) (*pb.ListCallbackAttemptsResponse, error) {
+	callbacks, err := cs.callbacks()
+	if err != nil {
+		return nil, err
+	}
+	attempts, next, err := callbacks.Attempts(caller(ctx), req.GetCallbackId(), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListCallbackAttemptsResponse{Attempts: attempts, NextPageToken: next}, nil
+}
+
+func (cs *CallbackService) callbacks() (*Callbacks, error) {
+	if cs.opts.callbacks == nil {
+		return nil, status.Error(codes.FailedPrecondition, "callbacks are not configured")
+	}
+	return cs.opts.callbacks, nil
+}
@@ -0,0 +1,544 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The callbacks, their pending deliveries and attempts share the store, so
+// the keys are prefixed
+const (
+	callbackPrefix = "callback/"
+	deliveryPrefix = "delivery/"
+	attemptPrefix  = "attempt/"
+)
+
+const (
+	// maxCallbackAttempts limits the history of attempts kept for every callback
+	maxCallbackAttempts = 100
+	// minCallbackSecret is the minimal length of the secret given by the client
+	minCallbackSecret = 16
+)
+
+// storedCallback is the callback as it is kept in the store
+type storedCallback struct {
+	ID                  string    `json:"id"`
+	URL                 string    `json:"url"`
+	Secret              string    `json:"secret"`
+	Types               []string  `json:"types,omitempty"`
+	Tags                []string  `json:"tags,omitempty"`
+	From                string    `json:"from,omitempty"`
+	Disabled            bool      `json:"disabled,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	DisabledAt          time.Time `json:"disabled_at"`
+	// Owner is the caller which registered the callback, only the events of
+	// its messages are posted to the callback
+	Owner string `json:"owner,omitempty"`
+}
+
+// filter returns the filter of events which are posted to the callback
+func (c *storedCallback) filter() *pb.WatchMessagesRequest {
+	f := &pb.WatchMessagesRequest{Tags: c.Tags, From: c.From}
+	for _, t := range c.Types {
+		f.Types = append(f.Types, pb.MessageEvent_Type(pb.MessageEvent_Type_value[t]))
+	}
+	return f
+}
+
+// callbackDelivery is the event which waits to be posted to the callback
+type callbackDelivery struct {
+	ID         string `json:"id"`
+	CallbackID string `json:"callback_id"`
+	// Event is the marshalled MessageEvent
+	Event     []byte    `json:"event"`
+	Attempts  int       `json:"attempts"`
+	NotBefore time.Time `json:"not_before"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// storedAttempt is the attempt as it is kept in the store
+type storedAttempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	MessageID  string    `json:"message_id"`
+	EventType  string    `json:"event_type"`
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+}
+
+// Callbacks stores the URLs registered by the client applications together
+// with the events waiting to be posted to them and the recent attempts
+type Callbacks struct {
+	store *storage.Store
+	now   func() time.Time
+	// allowed reports if the events may be posted to the address
+	allowed func(ip net.IP) bool
+
+	// mu serializes updates of callbacks, e.g. the failure counter
+	mu sync.Mutex
+}
+
+// NewCallbacks constructor of Callbacks
+func NewCallbacks(store *storage.Store) *Callbacks {
+	return &Callbacks{
+		store:   store,
+		now:     time.Now,
+		allowed: publicIP,
+	}
+}
+
+// Create registers the callback of the owner, the secret is generated when
+// empty. The URL has to resolve only to the public addresses.
+func (c *Callbacks) Create(ctx context.Context, owner string, cb *pb.Callback) (*pb.Callback, error) {
+	if cb == nil {
+		return nil, status.Error(codes.InvalidArgument, "callback is required")
+	}
+	if err := validateCallback(cb); err != nil {
+		return nil, err
+	}
+	if err := c.checkHost(ctx, cb.GetUrl()); err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not generate callback id: %v", err)
+	}
+	secret := cb.GetSecret()
+	if secret == "" {
+		if secret, err = newCallbackSecret(); err != nil {
+			return nil, status.Errorf(codes.Internal, "can not generate callback secret: %v", err)
+		}
+	}
+	sc := &storedCallback{
+		ID:        id,
+		Owner:     owner,
+		URL:       cb.GetUrl(),
+		Secret:    secret,
+		Tags:      cb.GetTags(),
+		From:      cb.GetFrom(),
+		CreatedAt: c.now(),
+	}
+	for _, t := range cb.GetTypes() {
+		sc.Types = append(sc.Types, t.String())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.save(sc); err != nil {
+		return nil, err
+	}
+	out, err := callbackToProto(sc)
+	if err != nil {
+		return nil, err
+	}
+	out.Secret = sc.Secret
+	return out, nil
+}
+
+// Get returns the callback of the owner without its secret
+func (c *Callbacks) Get(owner, id string) (*pb.Callback, error) {
+	sc, err := c.owned(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	return callbackToProto(sc)
+}
+
+// List returns the page of callbacks of the owner ordered by id, which starts
+// after the page token. The returned token is empty on the last page.
+func (c *Callbacks) List(owner string, pageSize int, pageToken string) ([]*pb.Callback, string, error) {
+	pageSize = normalizePageSize(pageSize)
+	var (
+		callbacks []*pb.Callback
+		next      string
+		err       error
+	)
+	c.store.Range(callbackPrefix, func(key string, value []byte) bool {
+		if strings.TrimPrefix(key, callbackPrefix) <= pageToken {
+			return true
+		}
+		sc := &storedCallback{}
+		if err = json.Unmarshal(value, sc); err != nil {
+			err = status.Errorf(codes.Internal, "can not decode callback %q: %v", key, err)
+			return false
+		}
+		if sc.Owner != owner {
+			return true
+		}
+		if len(callbacks) == pageSize {
+			next = callbacks[len(callbacks)-1].Id
+			return false
+		}
+		var cb *pb.Callback
+		if cb, err = callbackToProto(sc); err != nil {
+			return false
+		}
+		callbacks = append(callbacks, cb)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return callbacks, next, nil
+}
+
+// Delete removes the callback of the owner, the events waiting for it are dropped
+func (c *Callbacks) Delete(owner, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.owned(owner, id); err != nil {
+		return err
+	}
+	if err := c.store.Delete(callbackPrefix + id); err != nil {
+		return status.Errorf(codes.Internal, "can not delete callback %q: %v", id, err)
+	}
+	var keys []string
+	c.store.Range(attemptPrefix+id+"/", func(key string, value []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	for _, key := range keys {
+		if err := c.store.Delete(key); err != nil {
+			return status.Errorf(codes.Internal, "can not delete attempts of callback %q: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// Enable posts the events again to the callback of the owner disabled after
+// repeated failures
+func (c *Callbacks) Enable(owner, id string) (*pb.Callback, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sc, err := c.owned(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	sc.Disabled = false
+	sc.DisabledAt = time.Time{}
+	sc.ConsecutiveFailures = 0
+	if err := c.save(sc); err != nil {
+		return nil, err
+	}
+	return callbackToProto(sc)
+}
+
+// Attempts returns the page of the recent attempts of the callback of the
+// owner ordered by time, which starts after the page token. The returned token
+// is empty on the last page.
+func (c *Callbacks) Attempts(owner, id string, pageSize int, pageToken string) ([]*pb.CallbackAttempt, string, error) {
+	if _, err := c.owned(owner, id); err != nil {
+		return nil, "", err
+	}
+	pageSize = normalizePageSize(pageSize)
+	prefix := attemptPrefix + id + "/"
+	var (
+		attempts []*pb.CallbackAttempt
+		last     string
+		next     string
+		err      error
+	)
+	c.store.Range(prefix, func(key string, value []byte) bool {
+		token := strings.TrimPrefix(key, prefix)
+		if token <= pageToken {
+			return true
+		}
+		if len(attempts) == pageSize {
+			next = last
+			return false
+		}
+		sa := &storedAttempt{}
+		if err = json.Unmarshal(value, sa); err != nil {
+			err = status.Errorf(codes.Internal, "can not decode callback attempt %q: %v", key, err)
+			return false
+		}
+		var a *pb.CallbackAttempt
+		if a, err = attemptToProto(sa); err != nil {
+			return false
+		}
+		attempts = append(attempts, a)
+		last = token
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return attempts, next, nil
+}
+
+// enabled returns the callbacks which the events are posted to
+func (c *Callbacks) enabled() ([]*storedCallback, error) {
+	var (
+		callbacks []*storedCallback
+		err       error
+	)
+	c.store.Range(callbackPrefix, func(key string, value []byte) bool {
+		sc := &storedCallback{}
+		if err = json.Unmarshal(value, sc); err != nil {
+			return false
+		}
+		if !sc.Disabled {
+			callbacks = append(callbacks, sc)
+		}
+		return true
+	})
+	return callbacks, err
+}
+
+// record stores the attempt and counts the consecutive failures of the
+// callback, which is disabled after disableAfter of them. The callback is
+// returned in its new state.
+func (c *Callbacks) record(callbackID string, a *storedAttempt, disableAfter int) (*storedCallback, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sc, err := c.get(callbackID)
+	if err != nil {
+		return nil, err
+	}
+	if a.Success {
+		sc.ConsecutiveFailures = 0
+	} else {
+		sc.ConsecutiveFailures++
+		if !sc.Disabled && sc.ConsecutiveFailures >= disableAfter {
+			sc.Disabled = true
+			sc.DisabledAt = a.Time
+		}
+	}
+	if err := c.save(sc); err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	prefix := attemptPrefix + callbackID + "/"
+	key := fmt.Sprintf("%s%020d-%s-%d", prefix, a.Time.UnixNano(), a.DeliveryID, a.Attempt)
+	if err := c.store.Put(key, value); err != nil {
+		return nil, err
+	}
+	var keys []string
+	c.store.Range(prefix, func(key string, value []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	for i := 0; i < len(keys)-maxCallbackAttempts; i++ {
+		if err := c.store.Delete(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return sc, nil
+}
+
+func (c *Callbacks) pending() ([]*callbackDelivery, error) {
+	var (
+		deliveries []*callbackDelivery
+		err        error
+	)
+	c.store.Range(deliveryPrefix, func(key string, value []byte) bool {
+		d := &callbackDelivery{}
+		if err = json.Unmarshal(value, d); err != nil {
+			return false
+		}
+		deliveries = append(deliveries, d)
+		return true
+	})
+	return deliveries, err
+}
+
+func (c *Callbacks) delivery(id string) (*callbackDelivery, bool, error) {
+	value, ok := c.store.Get(deliveryPrefix + id)
+	if !ok {
+		return nil, false, nil
+	}
+	d := &callbackDelivery{}
+	if err := json.Unmarshal(value, d); err != nil {
+		return nil, false, err
+	}
+	return d, true, nil
+}
+
+func (c *Callbacks) saveDelivery(d *callbackDelivery) error {
+	value, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return c.store.Put(deliveryPrefix+d.ID, value)
+}
+
+// saveDeliveries stores the deliveries with the single sync of the store
+func (c *Callbacks) saveDeliveries(deliveries []*callbackDelivery) error {
+	values := make(map[string][]byte, len(deliveries))
+	for _, d := range deliveries {
+		value, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		values[deliveryPrefix+d.ID] = value
+	}
+	return c.store.PutAll(values)
+}
+
+func (c *Callbacks) removeDelivery(id string) error {
+	return c.store.Delete(deliveryPrefix + id)
+}
+
+func (c *Callbacks) get(id string) (*storedCallback, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "callback id is required")
+	}
+	value, ok := c.store.Get(callbackPrefix + id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "callback %q not found", id)
+	}
+	sc := &storedCallback{}
+	if err := json.Unmarshal(value, sc); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not decode callback %q: %v", id, err)
+	}
+	return sc, nil
+}
+
+// owned returns the callback of the owner, the callbacks of other callers are not found
+func (c *Callbacks) owned(owner, id string) (*storedCallback, error) {
+	sc, err := c.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Owner != owner {
+		return nil, status.Errorf(codes.NotFound, "callback %q not found", id)
+	}
+	return sc, nil
+}
+
+func (c *Callbacks) save(sc *storedCallback) error {
+	value, err := json.Marshal(sc)
+	if err != nil {
+		return status.Errorf(codes.Internal, "can not encode callback %q: %v", sc.ID, err)
+	}
+	if err := c.store.Put(callbackPrefix+sc.ID, value); err != nil {
+		return status.Errorf(codes.Internal, "can not store callback %q: %v", sc.ID, err)
+	}
+	return nil
+}
+
+func validateCallback(cb *pb.Callback) error {
+	u, err := url.Parse(cb.GetUrl())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return status.Errorf(codes.InvalidArgument, "callback url %q must be absolute http or https URL", cb.GetUrl())
+	}
+	if cb.GetSecret() != "" && len(cb.GetSecret()) < minCallbackSecret {
+		return status.Errorf(codes.InvalidArgument, "callback secret must have at least %d characters", minCallbackSecret)
+	}
+	for _, t := range cb.GetTypes() {
+		if _, ok := pb.MessageEvent_Type_name[int32(t)]; !ok || t == pb.MessageEvent_TYPE_UNSPECIFIED {
+			return status.Errorf(codes.InvalidArgument, "unknown event type %d", t)
+		}
+	}
+	for _, tag := range cb.GetTags() {
+		if err := validateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHost rejects the URL whose host resolves to the address which is not allowed
+func (c *Callbacks) checkHost(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "callback url %q must be absolute http or https URL", rawURL)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return status.Errorf(codes.InvalidArgument, "can not resolve host of callback url %q", rawURL)
+	}
+	for _, addr := range addrs {
+		if !c.allowed(addr.IP) {
+			return status.Errorf(codes.InvalidArgument, "callback url %q must not point to the private address", rawURL)
+		}
+	}
+	return nil
+}
+
+// publicIP reports if the address is public. The loopback, private,
+// link-local, e.g. the cloud metadata 169.254.169.254, multicast and
+// unspecified addresses are not.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// newCallbackSecret generates the random secret of the signature
+func newCallbackSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func callbackToProto(sc *storedCallback) (*pb.Callback, error) {
+	out := &pb.Callback{
+		Id:                  sc.ID,
+		Url:                 sc.URL,
+		Tags:                sc.Tags,
+		From:                sc.From,
+		Disabled:            sc.Disabled,
+		ConsecutiveFailures: int32(sc.ConsecutiveFailures),
+	}
+	out.Types = sc.filter().Types
+	var err error
+	if out.CreatedAt, err = timestampProto(sc.CreatedAt); err != nil {
+		return nil, err
+	}
+	if out.DisabledAt, err = timestampProto(sc.DisabledAt); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func attemptToProto(sa *storedAttempt) (*pb.CallbackAttempt, error) {
+	out := &pb.CallbackAttempt{
+		DeliveryId: sa.DeliveryID,
+		MessageId:  sa.MessageID,
+		EventType:  pb.MessageEvent_Type(pb.MessageEvent_Type_value[sa.EventType]),
+		Attempt:    int32(sa.Attempt),
+		StatusCode: int32(sa.StatusCode),
+		Error:      sa.Error,
+		Success:    sa.Success,
+	}
+	var err error
+	if out.Time, err = timestampProto(sa.Time); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
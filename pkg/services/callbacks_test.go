@@ -0,0 +1,491 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestCallbacks(t *testing.T) *Callbacks {
+	callbacks := NewCallbacks(openTestStore(t, "callbacks"))
+	// The receivers of the tests listen on the loopback
+	callbacks.allowed = func(net.IP) bool { return true }
+	return callbacks
+}
+
+// callbackReceiver records the events posted to the callback
+type callbackReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// newCallbackReceiver answers with the statuses in order and then with 200
+func newCallbackReceiver(statuses ...int) *callbackReceiver {
+	r := &callbackReceiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		code := http.StatusOK
+		if len(r.statuses) > 0 {
+			code, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(code)
+	}))
+	return r
+}
+
+func (r *callbackReceiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// waitFor polls the condition for up to a second
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func testNotifierConfig() NotifierConfig {
+	return NotifierConfig{
+		Retry:        RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxAttempts: 3},
+		DisableAfter: 3,
+	}
+}
+
+func TestCallbackService(t *testing.T) {
+	// Arrange
+	callbacks := newTestCallbacks(t)
+	cs := NewCallbackService(WithCallbacks(callbacks))
+	ctx := context.Background()
+	var created *pb.Callback
+
+	t.Run("Callback is created with generated secret", func(t *testing.T) {
+		// Act
+		var err error
+		created, err = cs.CreateCallback(ctx, &pb.CreateCallbackRequest{Callback: &pb.Callback{
+			Url:   "https://203.0.113.10/events",
+			Types: []pb.MessageEvent_Type{pb.MessageEvent_DELIVERED, pb.MessageEvent_BOUNCED},
+			Tags:  []string{"welcome"},
+		}})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.NotEmpty(t, created.Id, "Id must be generated")
+		assert.Len(t, created.Secret, 64, "Secret must be generated")
+		assert.Equal(t, []pb.MessageEvent_Type{pb.MessageEvent_DELIVERED, pb.MessageEvent_BOUNCED}, created.Types,
+			"Types must be kept")
+	})
+
+	t.Run("Secret is returned only on create", func(t *testing.T) {
+		// Act
+		cb, err := cs.GetCallback(ctx, &pb.GetCallbackRequest{Id: created.Id})
+		list, listErr := cs.ListCallbacks(ctx, &pb.ListCallbacksRequest{})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Empty(t, cb.Secret, "Secret must not be returned")
+		assert.Equal(t, "https://203.0.113.10/events", cb.Url, "URL must be returned")
+		assert.NoError(t, listErr, "Error should not occur")
+		if assert.Len(t, list.Callbacks, 1, "Callback must be listed") {
+			assert.Empty(t, list.Callbacks[0].Secret, "Secret must not be listed")
+		}
+	})
+
+	t.Run("Invalid callbacks are rejected", func(t *testing.T) {
+		for _, cb := range []*pb.Callback{
+			nil,
+			{Url: "/events"},
+			{Url: "ftp://example.com/events"},
+			{Url: "https://example.com", Secret: "short"},
+			{Url: "https://example.com", Types: []pb.MessageEvent_Type{pb.MessageEvent_TYPE_UNSPECIFIED}},
+			{Url: "https://example.com", Tags: []string{""}},
+		} {
+			// Act
+			_, err := cs.CreateCallback(ctx, &pb.CreateCallbackRequest{Callback: cb})
+
+			// Assert
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Callback %v must be rejected", cb)
+		}
+	})
+
+	t.Run("Callbacks to private addresses are rejected", func(t *testing.T) {
+		// Arrange
+		private := newTestCallbacks(t)
+		private.allowed = publicIP
+		cs := NewCallbackService(WithCallbacks(private))
+
+		for _, url := range []string{
+			"http://localhost:8080/events",
+			"http://127.0.0.1/events",
+			"http://[::1]/events",
+			"http://10.1.2.3/events",
+			"http://192.168.0.1/events",
+			"http://169.254.169.254/latest/meta-data",
+			"http://0.0.0.0/events",
+		} {
+			// Act
+			_, err := cs.CreateCallback(ctx, &pb.CreateCallbackRequest{Callback: &pb.Callback{Url: url}})
+
+			// Assert
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Callback to %s must be rejected", url)
+		}
+	})
+
+	t.Run("Callback is deleted", func(t *testing.T) {
+		// Act
+		_, err := cs.DeleteCallback(ctx, &pb.DeleteCallbackRequest{Id: created.Id})
+		_, getErr := cs.GetCallback(ctx, &pb.GetCallbackRequest{Id: created.Id})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, codes.NotFound, status.Code(getErr), "Deleted callback must not be found")
+	})
+
+	t.Run("Callbacks of other callers are not visible", func(t *testing.T) {
+		// Arrange
+		alice := auth.NewContext(ctx, &auth.Identity{Method: auth.MethodKey, ID: "alice"})
+		bob := auth.NewContext(ctx, &auth.Identity{Method: auth.MethodKey, ID: "bob"})
+		owned, err := cs.CreateCallback(alice, &pb.CreateCallbackRequest{Callback: &pb.Callback{
+			Url: "https://203.0.113.10/alice",
+		}})
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		_, getErr := cs.GetCallback(bob, &pb.GetCallbackRequest{Id: owned.Id})
+		_, enableErr := cs.EnableCallback(bob, &pb.EnableCallbackRequest{Id: owned.Id})
+		_, attemptsErr := cs.ListCallbackAttempts(bob, &pb.ListCallbackAttemptsRequest{CallbackId: owned.Id})
+		_, deleteErr := cs.DeleteCallback(bob, &pb.DeleteCallbackRequest{Id: owned.Id})
+		bobs, bobErr := cs.ListCallbacks(bob, &pb.ListCallbacksRequest{})
+		alices, aliceErr := cs.ListCallbacks(alice, &pb.ListCallbacksRequest{})
+
+		// Assert
+		assert.Equal(t, codes.NotFound, status.Code(getErr), "Callback of other caller must not be found")
+		assert.Equal(t, codes.NotFound, status.Code(enableErr), "Callback of other caller must not be enabled")
+		assert.Equal(t, codes.NotFound, status.Code(attemptsErr), "Attempts of other caller must not be listed")
+		assert.Equal(t, codes.NotFound, status.Code(deleteErr), "Callback of other caller must not be deleted")
+		assert.NoError(t, bobErr, "Error should not occur")
+		assert.Empty(t, bobs.Callbacks, "Callbacks of other caller must not be listed")
+		assert.NoError(t, aliceErr, "Error should not occur")
+		if assert.Len(t, alices.Callbacks, 1, "Own callback must be listed") {
+			assert.Equal(t, owned.Id, alices.Callbacks[0].Id, "Own callback must be listed")
+		}
+	})
+
+	t.Run("Service without callbacks", func(t *testing.T) {
+		// Act
+		_, err := NewCallbackService().ListCallbacks(ctx, &pb.ListCallbacksRequest{})
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Failed precondition must be returned")
+	})
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("Selected event is posted with signature", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver()
+		defer receiver.Close()
+		cb, err := callbacks.Create(context.Background(), "",
+			&pb.Callback{Url: receiver.URL, Types: []pb.MessageEvent_Type{pb.MessageEvent_DELIVERED}})
+		assert.NoError(t, err, "Callback should be created")
+		events := NewEvents(10)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "queued", Type: pb.MessageEvent_QUEUED})
+		events.Publish(&pb.MessageEvent{MessageId: "delivered", Type: pb.MessageEvent_DELIVERED})
+
+		// Assert
+		assert.True(t, waitFor(func() bool { return receiver.received() == 1 }), "Event must be posted")
+		time.Sleep(20 * time.Millisecond)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		if assert.Len(t, receiver.requests, 1, "Only selected event must be posted") {
+			req, body := receiver.requests[0], receiver.bodies[0]
+			var posted map[string]interface{}
+			assert.NoError(t, json.Unmarshal(body, &posted), "Event must be JSON")
+			assert.Equal(t, "delivered", posted["message_id"], "Event must be posted")
+			assert.Equal(t, "DELIVERED", posted["type"], "Type must be posted")
+			assert.NotEmpty(t, req.Header.Get(CallbackIDHeader), "Delivery id must be sent")
+			assert.Equal(t, SignCallback(cb.Secret, req.Header.Get(CallbackTimestampHeader), body),
+				req.Header.Get(CallbackSignatureHeader), "Event must be signed with the secret")
+		}
+	})
+
+	t.Run("Only the events of the owner are posted", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver()
+		defer receiver.Close()
+		_, err := callbacks.Create(context.Background(), "key:alice", &pb.Callback{Url: receiver.URL})
+		assert.NoError(t, err, "Callback should be created")
+		events := NewEvents(10)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		events.PublishFor("key:bob", &pb.MessageEvent{MessageId: "bob", Type: pb.MessageEvent_SENT})
+		events.Publish(&pb.MessageEvent{MessageId: "anonymous", Type: pb.MessageEvent_SENT})
+		events.PublishFor("key:alice", &pb.MessageEvent{MessageId: "alice", Type: pb.MessageEvent_SENT})
+
+		// Assert
+		assert.True(t, waitFor(func() bool { return receiver.received() == 1 }), "Event of the owner must be posted")
+		time.Sleep(20 * time.Millisecond)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		if assert.Len(t, receiver.bodies, 1, "Only the events of the owner must be posted") {
+			var posted map[string]interface{}
+			assert.NoError(t, json.Unmarshal(receiver.bodies[0], &posted), "Event must be JSON")
+			assert.Equal(t, "alice", posted["message_id"], "Event of the owner must be posted")
+		}
+	})
+
+	t.Run("Every event is posted when more are published than the buffer holds", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver()
+		defer receiver.Close()
+		_, err := callbacks.Create(context.Background(), "", &pb.Callback{Url: receiver.URL})
+		assert.NoError(t, err, "Callback should be created")
+		events := NewEvents(4)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		for i := 0; i < 50; i++ {
+			events.Publish(&pb.MessageEvent{MessageId: fmt.Sprintf("message-%d", i), Type: pb.MessageEvent_QUEUED})
+		}
+
+		// Assert
+		assert.True(t, waitFor(func() bool { return receiver.received() == 50 }), "Every event must be posted")
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		posted := make(map[string]bool)
+		for _, body := range receiver.bodies {
+			var ev map[string]interface{}
+			assert.NoError(t, json.Unmarshal(body, &ev), "Event must be JSON")
+			posted[fmt.Sprint(ev["message_id"])] = true
+		}
+		assert.Len(t, posted, 50, "Every event must be posted once")
+	})
+
+	t.Run("Redirect of the callback is not followed", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		target := newCallbackReceiver()
+		defer target.Close()
+		redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+		defer redirect.Close()
+		cb, _ := callbacks.Create(context.Background(), "", &pb.Callback{Url: redirect.URL})
+		events := NewEvents(10)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "sent", Type: pb.MessageEvent_SENT})
+
+		// Assert
+		var attempts []*pb.CallbackAttempt
+		assert.True(t, waitFor(func() bool {
+			attempts, _, _ = callbacks.Attempts("", cb.Id, 0, "")
+			return len(attempts) > 0
+		}), "Attempt must be recorded")
+		assert.False(t, attempts[0].Success, "Redirect must fail the attempt")
+		assert.Equal(t, int32(http.StatusTemporaryRedirect), attempts[0].StatusCode, "Redirect status must be recorded")
+		assert.Equal(t, 0, target.received(), "Redirect must not be followed")
+	})
+
+	t.Run("Address is checked when the event is posted", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver()
+		defer receiver.Close()
+		cb, _ := callbacks.Create(context.Background(), "", &pb.Callback{Url: receiver.URL})
+		// The host resolves to the loopback after the callback was registered
+		callbacks.allowed = publicIP
+		events := NewEvents(10)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "sent", Type: pb.MessageEvent_SENT})
+
+		// Assert
+		var attempts []*pb.CallbackAttempt
+		assert.True(t, waitFor(func() bool {
+			attempts, _, _ = callbacks.Attempts("", cb.Id, 0, "")
+			return len(attempts) > 0
+		}), "Attempt must be recorded")
+		assert.False(t, attempts[0].Success, "Private address must fail the attempt")
+		assert.Contains(t, attempts[0].Error, "not allowed", "Address must be rejected")
+		assert.Equal(t, 0, receiver.received(), "Private address must not be connected")
+	})
+
+	t.Run("Failed event is retried and attempts are recorded", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver(http.StatusInternalServerError)
+		defer receiver.Close()
+		cb, _ := callbacks.Create(context.Background(), "", &pb.Callback{Url: receiver.URL})
+		events := NewEvents(10)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "bounced", Type: pb.MessageEvent_BOUNCED})
+
+		// Assert
+		assert.True(t, waitFor(func() bool { return receiver.received() == 2 }), "Event must be retried")
+		var attempts []*pb.CallbackAttempt
+		assert.True(t, waitFor(func() bool {
+			attempts, _, _ = callbacks.Attempts("", cb.Id, 0, "")
+			return len(attempts) == 2
+		}), "Attempts must be recorded")
+		assert.False(t, attempts[0].Success, "First attempt must fail")
+		assert.Equal(t, int32(http.StatusInternalServerError), attempts[0].StatusCode, "Status must be recorded")
+		assert.True(t, attempts[1].Success, "Second attempt must succeed")
+		assert.Equal(t, int32(2), attempts[1].Attempt, "Attempt must be counted")
+		assert.Equal(t, attempts[0].DeliveryId, attempts[1].DeliveryId, "Retry must keep the delivery id")
+		assert.Equal(t, pb.MessageEvent_BOUNCED, attempts[1].EventType, "Event type must be recorded")
+		stored, _ := callbacks.Get("", cb.Id)
+		assert.Equal(t, int32(0), stored.ConsecutiveFailures, "Success must reset failures")
+	})
+
+	t.Run("Callback is disabled after repeated failures", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		defer receiver.Close()
+		cb, _ := callbacks.Create(context.Background(), "", &pb.Callback{Url: receiver.URL})
+		events := NewEvents(10)
+		cfg := testNotifierConfig()
+		cfg.Retry.MaxAttempts = 5
+		notifier := NewNotifier(callbacks, events, cfg)
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "sent", Type: pb.MessageEvent_SENT})
+
+		// Assert
+		var stored *pb.Callback
+		assert.True(t, waitFor(func() bool {
+			stored, _ = callbacks.Get("", cb.Id)
+			return stored.Disabled
+		}), "Callback must be disabled")
+		assert.NotNil(t, stored.DisabledAt, "Time of disabling must be set")
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, 3, receiver.received(), "Disabled callback must not receive events")
+		pending, _ := callbacks.pending()
+		assert.Empty(t, pending, "Deliveries of disabled callback must be dropped")
+
+		enabled, err := callbacks.Enable("", cb.Id)
+		assert.NoError(t, err, "Error should not occur")
+		assert.False(t, enabled.Disabled, "Callback must be enabled")
+		assert.Equal(t, int32(0), enabled.ConsecutiveFailures, "Failures must be reset")
+	})
+
+	t.Run("Post aborted by stop is not recorded", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		posted, release := make(chan struct{}, 1), make(chan struct{})
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			posted <- struct{}{}
+			<-release
+		}))
+		defer receiver.Close()
+		defer close(release)
+		cb, _ := callbacks.Create(context.Background(), "", &pb.Callback{Url: receiver.URL})
+		events := NewEvents(10)
+		notifier := NewNotifier(callbacks, events, testNotifierConfig())
+		notifier.Start()
+		events.Publish(&pb.MessageEvent{MessageId: "sent", Type: pb.MessageEvent_SENT})
+		<-posted
+
+		// Act
+		notifier.Stop()
+
+		// Assert
+		attempts, _, err := callbacks.Attempts("", cb.Id, 0, "")
+		assert.NoError(t, err, "Error should not occur")
+		assert.Empty(t, attempts, "Aborted post must not be recorded")
+		stored, _ := callbacks.Get("", cb.Id)
+		assert.Equal(t, int32(0), stored.ConsecutiveFailures, "Aborted post must not be counted as the failure")
+		pending, _ := callbacks.pending()
+		assert.Len(t, pending, 1, "Delivery must be kept for the restart")
+	})
+
+	t.Run("Pending delivery is resumed", func(t *testing.T) {
+		// Arrange
+		callbacks := newTestCallbacks(t)
+		receiver := newCallbackReceiver()
+		defer receiver.Close()
+		cb, _ := callbacks.Create(context.Background(), "", &pb.Callback{Url: receiver.URL})
+		event := []byte{}
+		assert.NoError(t, callbacks.saveDelivery(&callbackDelivery{
+			ID:         "pending",
+			CallbackID: cb.Id,
+			Event:      event,
+			NotBefore:  time.Now(),
+			CreatedAt:  time.Now(),
+		}), "Delivery should be stored")
+		notifier := NewNotifier(callbacks, NewEvents(10), testNotifierConfig())
+
+		// Act
+		notifier.Start()
+		defer notifier.Stop()
+
+		// Assert
+		assert.True(t, waitFor(func() bool { return receiver.received() == 1 }), "Pending delivery must be posted")
+		assert.True(t, waitFor(func() bool {
+			pending, _ := callbacks.pending()
+			return len(pending) == 0
+		}), "Posted delivery must be removed")
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// DeadLetters stores the messages which ran out of retries together with
+// their content, so they can be inspected and requeued
+type DeadLetters struct {
+	store *storage.Store
+}
+
+// NewDeadLetters constructor of DeadLetters
+func NewDeadLetters(store *storage.Store) *DeadLetters {
+	return &DeadLetters{store: store}
+}
+
+// Get returns the dead-lettered message by its id
+func (d *DeadLetters) Get(id string) (*Message, error) {
+	value, ok := d.store.Get(id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "dead letter %q not found", id)
+	}
+	msg := &Message{}
+	if err := json.Unmarshal(value, msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not decode dead letter %q: %v", id, err)
+	}
+	return msg, nil
+}
+
+// List returns the page of messages ordered by id, which starts after the
+// page token. The returned token is empty on the last page.
+func (d *DeadLetters) List(pageSize int, pageToken string) ([]*Message, string, error) {
+	pageSize = normalizePageSize(pageSize)
+	var (
+		messages []*Message
+		next     string
+		err      error
+	)
+	d.store.Range("", func(key string, value []byte) bool {
+		if key <= pageToken {
+			return true
+		}
+		if len(messages) == pageSize {
+			next = messages[len(messages)-1].ID
+			return false
+		}
+		msg := &Message{}
+		if err = json.Unmarshal(value, msg); err != nil {
+			err = status.Errorf(codes.Internal, "can not decode dead letter %q: %v", key, err)
+			return false
+		}
+		messages = append(messages, msg)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return messages, next, nil
+}
+
+// Purge removes the messages with given ids, or all of them which failed
+// before the given time when no ids are given. It returns the number of
+// removed messages.
+func (d *DeadLetters) Purge(ids []string, before time.Time) (int, error) {
+	if len(ids) == 0 {
+		d.store.Range("", func(key string, value []byte) bool {
+			msg := &Message{}
+			if err := json.Unmarshal(value, msg); err != nil || before.IsZero() || msg.UpdatedAt.Before(before) {
+				ids = append(ids, key)
+			}
+			return true
+		})
+	}
+	purged := 0
+	for _, id := range ids {
+		if _, ok := d.store.Get(id); !ok {
+			continue
+		}
+		if err := d.store.Delete(id); err != nil {
+			return purged, status.Errorf(codes.Internal, "can not remove dead letter %q: %v", id, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (d *DeadLetters) add(msg *Message) error {
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return d.store.Put(msg.ID, value)
+}
+
+func (d *DeadLetters) remove(id string) error {
+	return d.store.Delete(id)
+}
+
+func normalizePageSize(size int) int {
+	if size <= 0 {
+		return defaultPageSize
+	}
+	if size > maxPageSize {
+		return maxPageSize
+	}
+	return size
+}
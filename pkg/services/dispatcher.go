@@ -0,0 +1,162 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DispatcherConfig configures the Dispatcher
+type DispatcherConfig struct {
+	// Workers is the number of emails sent at once, default 1
+	Workers int
+	// Retry is the policy used when the provider has none
+	Retry RetryPolicy
+	// ProviderRetry holds the retry policies by the name of the provider which failed
+	ProviderRetry map[string]RetryPolicy
+}
+
+// Dispatcher is the pool of workers which deliver emails from the queue through
+// the provider. The email which failed temporarily is deferred according to the
+// retry policy of the provider, the one which failed permanently or ran out of
+// retries is dead-lettered.
+type Dispatcher struct {
+	queue         *Queue
+	provider      Provider
+	workers       int
+	retry         RetryPolicy
+	providerRetry map[string]RetryPolicy
+
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher constructor of Dispatcher
+func NewDispatcher(queue *Queue, provider Provider, cfg DispatcherConfig) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	providerRetry := make(map[string]RetryPolicy, len(cfg.ProviderRetry))
+	for name, p := range cfg.ProviderRetry {
+		providerRetry[name] = p.withDefaults()
+	}
+	return &Dispatcher{
+		queue:         queue,
+		provider:      provider,
+		workers:       cfg.Workers,
+		retry:         cfg.Retry.withDefaults(),
+		providerRetry: providerRetry,
+		rnd:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start launches the workers
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work(ctx)
+	}
+}
+
+// Stop waits until the workers finish the emails they are sending
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	defer d.wg.Done()
+	for {
+		msg, err := d.queue.Next(ctx)
+		if err != nil {
+			return
+		}
+		d.deliver(msg)
+	}
+}
+
+func (d *Dispatcher) deliver(msg *Message) {
+	req, err := msg.EmailRequest()
+	if err != nil {
+		d.fail(msg, status.Errorf(codes.Internal, "can not unmarshal email: %v", err))
+		return
+	}
+	// The email is sent to the end even when the dispatcher is stopped,
+	// the provider timeouts bound how long it takes
+	receipt, err := d.provider.Send(context.Background(), req)
+	if err != nil {
+		d.retryOrFail(msg, err)
+		return
+	}
+
+	zap.L().Debug("Email delivered",
+		zap.String("message_id", msg.ID),
+		zap.String("provider", receipt.Provider),
+		zap.String("provider_message_id", receipt.MessageID))
+	if err := d.queue.Complete(msg, receipt); err != nil {
+		zap.L().Error("Can not store delivery result", zap.String("message_id", msg.ID), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) retryOrFail(msg *Message, sendErr error) {
+	if !IsRetryable(sendErr) {
+		d.fail(msg, sendErr)
+		return
+	}
+	policy, ok := d.providerRetry[failedProvider(sendErr)]
+	if !ok {
+		policy = d.retry
+	}
+	d.rndMu.Lock()
+	next, ok := policy.nextAttempt(msg, d.queue.now(), d.rnd)
+	d.rndMu.Unlock()
+	if !ok {
+		d.fail(msg, sendErr)
+		return
+	}
+
+	zap.L().Info("Email delivery deferred",
+		zap.String("message_id", msg.ID),
+		zap.Int("attempt", msg.Attempts),
+		zap.Time("next_attempt_at", next),
+		zap.Error(sendErr))
+	if err := d.queue.Defer(msg, sendErr, next); err != nil {
+		zap.L().Error("Can not defer email", zap.String("message_id", msg.ID), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) fail(msg *Message, sendErr error) {
+	zap.L().Warn("Email delivery failed",
+		zap.String("message_id", msg.ID),
+		zap.Int("attempt", msg.Attempts),
+		zap.Error(sendErr))
+	deadLetteredMessages.Inc()
+	if err := d.queue.Fail(msg, sendErr); err != nil {
+		zap.L().Error("Can not dead-letter email", zap.String("message_id", msg.ID), zap.Error(err))
+	}
+}
@@ -15,16 +15,325 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// maxBatchRecipients limits the size of SendMailBatch request
+const maxBatchRecipients = 1000
+
 type EmailService struct {
 	pb.EmailServiceServer
+	opts *options
+}
+
+// NewEmailService constructor of EmailService
+func NewEmailService(opts ...Option) *EmailService {
+	return &EmailService{
+		opts: evaluateOptions(opts),
+	}
+}
+
+// SendMail validates the email envelope and puts it into the queue. The id of
+// the queued message is returned at once, the provider is known only after the
+// delivery. Without the queue the email is delivered through the provider
+// before returning. The suppressed recipients are dropped and reported in the
+// response. The repeated request with the same idempotency key returns the
+// original response without sending the email again.
+func (es *EmailService) SendMail(ctx context.Context, req *pb.EmailRequest) (*pb.EmailResponse, error) {
+	email, err := es.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return es.sendOnce(ctx, key, req, email)
+}
+
+// SendMailBatch sends the shared email to every recipient as the separate
+// message. The email which can not be sent to the recipient, e.g. because of
+// the invalid address, is reported in its result and the rest of the batch is
+// sent anyway. The idempotency key of the batch is applied to every recipient,
+// so the retried batch does not send the email twice to anyone.
+func (es *EmailService) SendMailBatch(ctx context.Context, req *pb.SendMailBatchRequest) (*pb.SendMailBatchResponse, error) {
+	shared := req.GetEmail()
+	if shared == nil {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	if len(shared.GetTo())+len(shared.GetCc())+len(shared.GetBcc()) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "recipients of the batch must be given in recipients instead of to, cc and bcc")
+	}
+	if len(req.GetRecipients()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one recipient is required")
+	}
+	if len(req.GetRecipients()) > maxBatchRecipients {
+		return nil, status.Errorf(codes.InvalidArgument, "batch can have at most %d recipients", maxBatchRecipients)
+	}
+	key, err := idempotencyKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*pb.BatchResult, 0, len(req.GetRecipients()))
+	for i, rcpt := range req.GetRecipients() {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		resp, err := es.sendToRecipient(ctx, key, i, shared, rcpt)
+		if err != nil {
+			st := status.Convert(err)
+			results = append(results, &pb.BatchResult{Code: int32(st.Code()), Error: st.Message()})
+			continue
+		}
+		result := &pb.BatchResult{MessageId: resp.MessageId, Provider: resp.Provider}
+		if len(resp.Suppressed) > 0 {
+			result.Suppressed = resp.Suppressed[0]
+		}
+		results = append(results, result)
+	}
+	return &pb.SendMailBatchResponse{Results: results}, nil
+}
+
+// sendToRecipient sends the copy of the shared email to the recipient of the batch
+func (es *EmailService) sendToRecipient(ctx context.Context, key string, i int, shared *pb.EmailRequest,
+	rcpt *pb.BatchRecipient) (*pb.EmailResponse, error) {
+	req := proto.Clone(shared).(*pb.EmailRequest)
+	req.To = []*pb.Address{rcpt.GetAddress()}
+	if len(rcpt.GetVariables()) > 0 {
+		variables := make(map[string]string, len(shared.GetVariables())+len(rcpt.GetVariables()))
+		for k, v := range shared.GetVariables() {
+			variables[k] = v
+		}
+		for k, v := range rcpt.GetVariables() {
+			variables[k] = v
+		}
+		req.Variables = variables
+	}
+
+	email, err := es.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		key = fmt.Sprintf("%s/%d", key, i)
+	}
+	return es.sendOnce(ctx, key, req, email)
+}
+
+// sendOnce sends the prepared email unless the response for the idempotency
+// key is already known
+func (es *EmailService) sendOnce(ctx context.Context, key string, req, email *pb.EmailRequest) (*pb.EmailResponse, error) {
+	if key == "" || es.opts.idempotency == nil {
+		return es.send(ctx, email)
+	}
+
+	// The hash of the request as the client sent it, so the retry matches
+	// even when the template was changed in the meantime
+	hash, err := requestHash(req)
+	if err != nil {
+		return nil, err
+	}
+	key = callerKey(ctx, key)
+	resp, err := es.opts.idempotency.begin(key, hash)
+	if err != nil || resp != nil {
+		return resp, err
+	}
+	resp, err = es.send(ctx, email)
+	es.opts.idempotency.finish(key, hash, resp)
+	return resp, err
+}
+
+// prepare renders the template of the request and validates the email
+func (es *EmailService) prepare(ctx context.Context, req *pb.EmailRequest) (*pb.EmailRequest, error) {
+	if req.GetTemplateId() == "" {
+		if len(req.GetVariables()) > 0 {
+			return nil, status.Error(codes.InvalidArgument, "variables can be used only with template_id")
+		}
+		return es.validate(ctx, req)
+	}
+	if req.GetSubject() != "" || req.GetTextBody() != "" || req.GetHtmlBody() != "" {
+		return nil, status.Error(codes.InvalidArgument, "subject and bodies can not be used together with template_id")
+	}
+	if es.opts.templates == nil {
+		return nil, status.Error(codes.FailedPrecondition, "templates are not configured")
+	}
+	content, err := es.opts.templates.render(req.GetTemplateId(), req.GetVariables())
+	if err != nil {
+		return nil, err
+	}
+
+	email := proto.Clone(req).(*pb.EmailRequest)
+	email.TemplateId = ""
+	email.Variables = nil
+	email.Subject = content.Subject
+	email.TextBody = content.TextBody
+	email.HtmlBody = content.HTMLBody
+	return es.validate(ctx, email)
 }
 
-func (es EmailService) SendMail(ctx context.Context, req *pb.EmailRequest) (*pb.EmailResponse, error) {
+// validate checks the email, its attachments and recipients before anything
+// goes to the provider. The returned email has the domains in punycode.
+func (es *EmailService) validate(ctx context.Context, email *pb.EmailRequest) (*pb.EmailRequest, error) {
+	if err := validateRequest(email); err != nil {
+		return nil, err
+	}
+	if err := es.opts.attachments.validate(email.GetAttachments()); err != nil {
+		return nil, err
+	}
+	return es.opts.addresses.check(ctx, email)
+}
+
+// send drops the suppressed recipients and delivers the email to the rest of them
+func (es *EmailService) send(ctx context.Context, req *pb.EmailRequest) (*pb.EmailResponse, error) {
+	var suppressed []*pb.Suppression
+	if es.opts.suppressions != nil {
+		var err error
+		if req, suppressed, err = es.opts.suppressions.filter(req); err != nil {
+			return nil, err
+		}
+		if len(req.GetTo())+len(req.GetCc())+len(req.GetBcc()) == 0 {
+			return &pb.EmailResponse{Suppressed: suppressed}, nil
+		}
+	}
+	resp, err := es.deliver(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Suppressed = suppressed
+	return resp, nil
+}
+
+func (es *EmailService) deliver(ctx context.Context, req *pb.EmailRequest) (*pb.EmailResponse, error) {
+	if es.opts.queue != nil {
+		msg, err := es.opts.queue.EnqueueFor(caller(ctx), req)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.EmailResponse{
+			MessageId: msg.ID,
+		}, nil
+	}
+	if req.GetSendAt() != nil {
+		return nil, status.Error(codes.FailedPrecondition, "scheduled delivery is not available without the queue")
+	}
+	if es.opts.provider == nil {
+		return nil, status.Error(codes.Unavailable, "no email provider is configured")
+	}
+
+	receipt, err := es.opts.provider.Send(ctx, req)
+	if err != nil {
+		zap.L().Warn("Provider failed to send email",
+			zap.String("provider", es.opts.provider.Name()), zap.Error(err))
+		return nil, err
+	}
 	return &pb.EmailResponse{
-		Error: req.Message,
+		MessageId: receipt.MessageID,
+		Provider:  receipt.Provider,
 	}, nil
 }
+
+// CancelMessage stops the delivery of the message which waits in the queue,
+// e.g. the scheduled one. The message which is being sent or was already
+// delivered is rejected with FAILED_PRECONDITION.
+func (es *EmailService) CancelMessage(ctx context.Context, req *pb.CancelMessageRequest) (*pb.Message, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message id is required")
+	}
+	if es.opts.queue == nil {
+		return nil, status.Errorf(codes.NotFound, "message %q not found", req.GetId())
+	}
+	msg, err := es.opts.queue.Cancel(caller(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return messageToProto(msg)
+}
+
+// RescheduleMessage changes the time of the delivery of the message which
+// waits in the queue
+func (es *EmailService) RescheduleMessage(ctx context.Context, req *pb.RescheduleMessageRequest) (*pb.Message, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message id is required")
+	}
+	if req.GetSendAt() == nil {
+		return nil, status.Error(codes.InvalidArgument, "send_at is required")
+	}
+	sendAt, err := scheduledTime(req.GetSendAt(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if es.opts.queue == nil {
+		return nil, status.Errorf(codes.NotFound, "message %q not found", req.GetId())
+	}
+	msg, err := es.opts.queue.Reschedule(caller(ctx), req.GetId(), sendAt)
+	if err != nil {
+		return nil, err
+	}
+	return messageToProto(msg)
+}
+
+// WatchMessages streams the events of messages selected by the request until
+// the client cancels the call. The client which does not keep up with the
+// events is disconnected with RESOURCE_EXHAUSTED, then it should check the
+// state with GetMessage and watch again.
+func (es *EmailService) WatchMessages(req *pb.WatchMessagesRequest, stream pb.EmailService_WatchMessagesServer) error {
+	if es.opts.queue == nil {
+		return status.Error(codes.FailedPrecondition, "message events are not available without the queue")
+	}
+	sub := es.opts.queue.Events().Subscribe(req)
+	defer sub.Close()
+
+	// The headers let the client know that it is subscribed before the first event
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case ev, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "events were dropped because the client did not keep up")
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ValidateAddress checks the address as SendMail checks the recipients. The
+// address which is not valid is not an error, the response tells why.
+func (es *EmailService) ValidateAddress(ctx context.Context, req *pb.ValidateAddressRequest) (*pb.ValidateAddressResponse, error) {
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	return es.opts.addresses.Validate(ctx, req.GetEmail()), nil
+}
+
+// GetMessage returns the delivery state of the message. Only queued messages
+// are tracked, so without the queue every message is reported as not found.
+// The messages sent by other callers are not found either.
+func (es *EmailService) GetMessage(ctx context.Context, req *pb.GetMessageRequest) (*pb.Message, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message id is required")
+	}
+	if es.opts.queue == nil {
+		return nil, status.Errorf(codes.NotFound, "message %q not found", req.GetId())
+	}
+	msg, err := es.opts.queue.GetFor(caller(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return messageToProto(msg)
+}
@@ -13,8 +13,212 @@
 // limitations under the License.
 package services
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func validRequest() *pb.EmailRequest {
+	return &pb.EmailRequest{
+		From:     &pb.Address{Email: "sender@example.com", Name: "Sender"},
+		To:       []*pb.Address{{Email: "recipient@example.com", Name: "Recipient"}},
+		Subject:  "Hello",
+		TextBody: "Hello world",
+		Headers:  map[string]string{"X-Campaign-Id": "42"},
+	}
+}
+
+type fakeProvider struct {
+	name     string
+	err      error
+	requests []*pb.EmailRequest
+}
+
+func (f *fakeProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeProvider) Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Receipt{Provider: f.name, MessageID: "id-" + f.name}, nil
+}
 
 func TestEmailService_SendMail(t *testing.T) {
+	// Arrange
+	provider := &fakeProvider{name: "fake"}
+	es := NewEmailService(WithProvider(provider))
+
+	t.Run("Valid request is delivered through provider", func(t *testing.T) {
+		// Act
+		resp, err := es.SendMail(context.Background(), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "id-fake", resp.MessageId, "Message id of the provider must be returned")
+		assert.Equal(t, "fake", resp.Provider, "Name of the provider must be returned")
+		assert.Len(t, provider.requests, 1, "Provider must receive the request")
+	})
+
+	t.Run("Email is queued when queue is configured", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued := NewEmailService(WithProvider(provider), WithQueue(q))
+		sent := len(provider.requests)
+
+		ctx := auth.NewContext(context.Background(), &auth.Identity{Method: auth.MethodKey, ID: "app"})
+
+		// Act
+		resp, err := queued.SendMail(ctx, validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Empty(t, resp.Provider, "Provider is not known before delivery")
+		msg, err := q.Get(resp.MessageId)
+		assert.NoError(t, err, "Message must be stored in the queue")
+		assert.Equal(t, MessageQueued, msg.State, "Message must wait for delivery")
+		assert.Equal(t, "key:app", msg.Owner, "Caller must own the message")
+		assert.Len(t, provider.requests, sent, "Provider must not be called")
+	})
+
+	t.Run("Provider error is returned", func(t *testing.T) {
+		// Arrange
+		failing := NewEmailService(WithProvider(&fakeProvider{
+			name: "failing",
+			err:  status.Error(codes.Unavailable, "down"),
+		}))
+
+		// Act
+		resp, err := failing.SendMail(context.Background(), validRequest())
+
+		// Assert
+		assert.Nil(t, resp, "Response must not exist")
+		assert.Equal(t, codes.Unavailable, status.Code(err), "Provider error must be passed")
+	})
+
+	t.Run("Missing provider", func(t *testing.T) {
+		// Act
+		resp, err := NewEmailService().SendMail(context.Background(), validRequest())
+
+		// Assert
+		assert.Nil(t, resp, "Response must not exist")
+		assert.Equal(t, codes.Unavailable, status.Code(err), "Service must be unavailable")
+	})
+
+	tests := []struct {
+		name   string
+		modify func(r *pb.EmailRequest)
+	}{
+		{"Missing sender", func(r *pb.EmailRequest) { r.From = nil }},
+		{"Invalid sender", func(r *pb.EmailRequest) { r.From.Email = "not an address" }},
+		{"Sender with display name in email field", func(r *pb.EmailRequest) { r.From.Email = "Sender <sender@example.com>" }},
+		{"Invalid reply-to", func(r *pb.EmailRequest) { r.ReplyTo = &pb.Address{} }},
+		{"Missing recipients", func(r *pb.EmailRequest) { r.To = nil }},
+		{"Invalid cc", func(r *pb.EmailRequest) { r.Cc = []*pb.Address{{Email: "@example.com"}} }},
+		{"Recipient with domain literal", func(r *pb.EmailRequest) { r.To[0].Email = "recipient@[192.0.2.1]" }},
+		{"Recipient without top level domain", func(r *pb.EmailRequest) { r.To[0].Email = "recipient@localhost" }},
+		{"Recipient with double dot", func(r *pb.EmailRequest) { r.To[0].Email = "john..doe@example.com" }},
+		{"Recipient with non ASCII local part", func(r *pb.EmailRequest) { r.To[0].Email = "józef@example.com" }},
+		{"Nil bcc", func(r *pb.EmailRequest) { r.Bcc = []*pb.Address{nil} }},
+		{"New line in display name", func(r *pb.EmailRequest) { r.To[0].Name = "Recipient\r\nBcc: x@example.com" }},
+		{"Missing subject", func(r *pb.EmailRequest) { r.Subject = " " }},
+		{"New line in subject", func(r *pb.EmailRequest) { r.Subject = "Hello\nBcc: x@example.com" }},
+		{"Missing body", func(r *pb.EmailRequest) { r.TextBody = "" }},
+		{"Reserved header", func(r *pb.EmailRequest) { r.Headers["subject"] = "Other" }},
+		{"Invalid header name", func(r *pb.EmailRequest) { r.Headers["X Bad"] = "value" }},
+		{"New line in header value", func(r *pb.EmailRequest) { r.Headers["X-Bad"] = "a\r\nb" }},
+		{"Empty tag", func(r *pb.EmailRequest) { r.Tags = []string{""} }},
+		{"Non ASCII tag", func(r *pb.EmailRequest) { r.Tags = []string{"powitanie-ż"} }},
+		{"Too many tags", func(r *pb.EmailRequest) { r.Tags = strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := validRequest()
+			tt.modify(req)
+
+			// Act
+			resp, err := es.SendMail(context.Background(), req)
+
+			// Assert
+			assert.Nil(t, resp, "Response must not exist")
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Request must be rejected as invalid argument")
+		})
+	}
+
+	t.Run("Body can be HTML only", func(t *testing.T) {
+		// Arrange
+		req := validRequest()
+		req.TextBody = ""
+		req.HtmlBody = "<p>Hello world</p>"
+
+		// Act
+		_, err := es.SendMail(context.Background(), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+	})
+}
+
+func TestEmailService_GetMessage(t *testing.T) {
+	// Arrange
+	q := newTestQueue(t)
+	es := NewEmailService(WithQueue(q))
+	resp, err := es.SendMail(context.Background(), validRequest())
+	assert.NoError(t, err, "Email should be queued")
+
+	t.Run("Queued message", func(t *testing.T) {
+		// Act
+		msg, err := es.GetMessage(context.Background(), &pb.GetMessageRequest{Id: resp.MessageId})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, resp.MessageId, msg.Id, "Id must be returned")
+		assert.Equal(t, pb.Message_QUEUED, msg.State, "Message must be queued")
+		assert.NotNil(t, msg.CreatedAt, "Creation time must be returned")
+		assert.NotNil(t, msg.NextAttemptAt, "Next attempt time must be returned")
+	})
+
+	t.Run("Sent message", func(t *testing.T) {
+		// Arrange
+		sending, err := q.Next(context.Background())
+		assert.NoError(t, err, "Message should be taken from the queue")
+		assert.NoError(t, q.Complete(sending, &Receipt{Provider: "fake", MessageID: "id-fake"}), "Complete should succeed")
+
+		// Act
+		msg, err := es.GetMessage(context.Background(), &pb.GetMessageRequest{Id: resp.MessageId})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, pb.Message_SENT, msg.State, "Message must be sent")
+		assert.Equal(t, "fake", msg.Provider, "Provider must be returned")
+		assert.Equal(t, "id-fake", msg.ProviderMessageId, "Provider message id must be returned")
+		assert.Equal(t, int32(1), msg.Attempts, "Attempts must be returned")
+		assert.Nil(t, msg.NextAttemptAt, "Sent message has no next attempt")
+	})
+
+	t.Run("Unknown message", func(t *testing.T) {
+		// Act
+		_, err := es.GetMessage(context.Background(), &pb.GetMessageRequest{Id: "unknown"})
+
+		// Assert
+		assert.Equal(t, codes.NotFound, status.Code(err), "Not found must be returned")
+	})
+
+	t.Run("Missing id", func(t *testing.T) {
+		// Act
+		_, err := es.GetMessage(context.Background(), &pb.GetMessageRequest{})
 
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+	})
 }
@@ -0,0 +1,213 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"strings"
+	"sync"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// defaultEventBuffer is the number of events which wait for the slow subscriber
+const defaultEventBuffer = 256
+
+// Events broadcasts the lifecycle events of messages to the subscribers.
+// Publishing never blocks the delivery, the subscriber which does not keep up
+// and fills its buffer is dropped and has to subscribe again. The backlogs
+// are never dropped, they keep every event until it is taken.
+type Events struct {
+	buffer int
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	backlogs    map[*Backlog]struct{}
+}
+
+// NewEvents constructor of Events, the buffer is the number of events kept
+// for every subscriber, default is 256
+func NewEvents(buffer int) *Events {
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	return &Events{
+		buffer:      buffer,
+		subscribers: make(map[*Subscription]struct{}),
+		backlogs:    make(map[*Backlog]struct{}),
+	}
+}
+
+// Subscription receives the events selected by the filter
+type Subscription struct {
+	events *Events
+	filter *pb.WatchMessagesRequest
+	// C is closed when the subscriber was dropped or closed
+	C chan *pb.MessageEvent
+}
+
+// Subscribe starts receiving the events selected by the filter
+func (e *Events) Subscribe(filter *pb.WatchMessagesRequest) *Subscription {
+	s := &Subscription{
+		events: e,
+		filter: filter,
+		C:      make(chan *pb.MessageEvent, e.buffer),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscribers[s] = struct{}{}
+	watchers.Inc()
+	return s
+}
+
+// Close stops receiving the events
+func (s *Subscription) Close() {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	s.events.remove(s)
+}
+
+// Backlog keeps every published event until the subscriber takes it, so no
+// event is lost however slow the subscriber is
+type Backlog struct {
+	events *Events
+
+	mu      sync.Mutex
+	pending []OwnedEvent
+	ready   chan struct{}
+}
+
+// OwnedEvent is the event of the message together with the caller which sent it
+type OwnedEvent struct {
+	Owner string
+	Event *pb.MessageEvent
+}
+
+// SubscribeBacklog starts keeping every event for the subscriber
+func (e *Events) SubscribeBacklog() *Backlog {
+	b := &Backlog{
+		events: e,
+		ready:  make(chan struct{}, 1),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.backlogs[b] = struct{}{}
+	return b
+}
+
+// Ready receives a value when there are events to take
+func (b *Backlog) Ready() <-chan struct{} {
+	return b.ready
+}
+
+// Take returns the events published since the previous call, oldest first
+func (b *Backlog) Take() []OwnedEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending := b.pending
+	b.pending = nil
+	return pending
+}
+
+// Close stops keeping the events, the ones not taken yet are still returned by Take
+func (b *Backlog) Close() {
+	b.events.mu.Lock()
+	defer b.events.mu.Unlock()
+	delete(b.events.backlogs, b)
+}
+
+func (b *Backlog) add(ev OwnedEvent) {
+	b.mu.Lock()
+	b.pending = append(b.pending, ev)
+	b.mu.Unlock()
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Publish delivers the event of the message without the owner, see PublishFor
+func (e *Events) Publish(ev *pb.MessageEvent) {
+	e.PublishFor("", ev)
+}
+
+// PublishFor delivers the event of the message sent by the owner to every
+// subscriber whose filter selects it and to every backlog
+func (e *Events) PublishFor(owner string, ev *pb.MessageEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for b := range e.backlogs {
+		b.add(OwnedEvent{Owner: owner, Event: ev})
+	}
+	for s := range e.subscribers {
+		if !selects(s.filter, ev) {
+			continue
+		}
+		select {
+		case s.C <- ev:
+		default:
+			zap.L().Warn("Dropped slow subscriber of message events", zap.Int("buffer", e.buffer))
+			droppedWatchers.Inc()
+			e.remove(s)
+		}
+	}
+}
+
+// remove must be called with the lock held
+func (e *Events) remove(s *Subscription) {
+	if _, ok := e.subscribers[s]; !ok {
+		return
+	}
+	delete(e.subscribers, s)
+	close(s.C)
+	watchers.Dec()
+}
+
+// selects checks if the event matches every filter of the request
+func selects(f *pb.WatchMessagesRequest, ev *pb.MessageEvent) bool {
+	if len(f.GetMessageIds()) > 0 && !containsString(f.GetMessageIds(), ev.GetMessageId()) {
+		return false
+	}
+	if f.GetFrom() != "" && !strings.EqualFold(f.GetFrom(), ev.GetFrom()) {
+		return false
+	}
+	if len(f.GetTypes()) > 0 {
+		found := false
+		for _, t := range f.GetTypes() {
+			found = found || t == ev.GetType()
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.GetTags()) > 0 {
+		found := false
+		for _, tag := range ev.GetTags() {
+			found = found || containsString(f.GetTags(), tag)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
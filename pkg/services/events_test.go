@@ -0,0 +1,164 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *pb.MessageEvent
+}
+
+func (s *fakeWatchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *fakeWatchStream) Send(ev *pb.MessageEvent) error {
+	s.events <- ev
+	return nil
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("Subscriber receives only selected events", func(t *testing.T) {
+		// Arrange
+		events := NewEvents(10)
+		sub := events.Subscribe(&pb.WatchMessagesRequest{
+			Tags:  []string{"welcome", "reset"},
+			Types: []pb.MessageEvent_Type{pb.MessageEvent_SENT, pb.MessageEvent_BOUNCED},
+		})
+		defer sub.Close()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "1", Type: pb.MessageEvent_SENT, Tags: []string{"welcome"}})
+		events.Publish(&pb.MessageEvent{MessageId: "2", Type: pb.MessageEvent_QUEUED, Tags: []string{"welcome"}})
+		events.Publish(&pb.MessageEvent{MessageId: "3", Type: pb.MessageEvent_SENT, Tags: []string{"newsletter"}})
+		events.Publish(&pb.MessageEvent{MessageId: "4", Type: pb.MessageEvent_BOUNCED, Tags: []string{"reset"}})
+
+		// Assert
+		assert.Len(t, sub.C, 2, "Only selected events must be delivered")
+		assert.Equal(t, "1", (<-sub.C).MessageId, "Sent welcome message must be delivered")
+		assert.Equal(t, "4", (<-sub.C).MessageId, "Bounced reset message must be delivered")
+	})
+
+	t.Run("Sender is compared case insensitive", func(t *testing.T) {
+		// Arrange
+		events := NewEvents(10)
+		sub := events.Subscribe(&pb.WatchMessagesRequest{From: "Sender@Example.com"})
+		defer sub.Close()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "1", From: "sender@example.com"})
+		events.Publish(&pb.MessageEvent{MessageId: "2", From: "other@example.com"})
+
+		// Assert
+		assert.Len(t, sub.C, 1, "Only events of the sender must be delivered")
+	})
+
+	t.Run("Slow subscriber is dropped without blocking the publisher", func(t *testing.T) {
+		// Arrange
+		events := NewEvents(1)
+		slow := events.Subscribe(&pb.WatchMessagesRequest{})
+		fast := events.Subscribe(&pb.WatchMessagesRequest{MessageIds: []string{"2"}})
+		defer fast.Close()
+
+		// Act
+		events.Publish(&pb.MessageEvent{MessageId: "1"})
+		events.Publish(&pb.MessageEvent{MessageId: "2"})
+
+		// Assert
+		ev, ok := <-slow.C
+		assert.True(t, ok, "Buffered event must be delivered")
+		assert.Equal(t, "1", ev.MessageId, "First event must be delivered")
+		_, ok = <-slow.C
+		assert.False(t, ok, "Channel of the slow subscriber must be closed")
+		assert.Len(t, fast.C, 1, "Other subscribers must receive the event")
+		slow.Close()
+	})
+
+	t.Run("Queue publishes the lifecycle of the message", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		sub := q.Events().Subscribe(&pb.WatchMessagesRequest{})
+		defer sub.Close()
+		req := validRequest()
+		req.Tags = []string{"welcome"}
+
+		// Act
+		queued, err := q.Enqueue(req)
+		assert.NoError(t, err, "Error should not occur")
+		msg, _ := q.Next(context.Background())
+		assert.NoError(t, q.Complete(msg, &Receipt{Provider: "fake", MessageID: "id"}), "Complete should succeed")
+
+		// Assert
+		for _, want := range []pb.MessageEvent_Type{pb.MessageEvent_QUEUED, pb.MessageEvent_SENDING, pb.MessageEvent_SENT} {
+			ev := <-sub.C
+			assert.Equal(t, want, ev.Type, "Events must follow the lifecycle")
+			assert.Equal(t, queued.ID, ev.MessageId, "Event must identify the message")
+			assert.Equal(t, "sender@example.com", ev.From, "Event must carry the sender")
+			assert.Equal(t, []string{"welcome"}, ev.Tags, "Event must carry the tags")
+		}
+	})
+}
+
+func TestEmailService_WatchMessages(t *testing.T) {
+	t.Run("Watching without the queue is rejected", func(t *testing.T) {
+		// Act
+		err := NewEmailService().WatchMessages(&pb.WatchMessagesRequest{}, &fakeWatchStream{ctx: context.Background()})
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Events require the queue")
+	})
+
+	t.Run("Selected events are streamed until the client cancels", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		es := NewEmailService(WithQueue(q))
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &fakeWatchStream{ctx: ctx, events: make(chan *pb.MessageEvent, 10)}
+		done := make(chan error)
+		req := &pb.WatchMessagesRequest{Types: []pb.MessageEvent_Type{pb.MessageEvent_QUEUED}}
+
+		// Act
+		go func() { done <- es.WatchMessages(req, stream) }()
+		var ev *pb.MessageEvent
+		for ev == nil {
+			_, _ = q.Enqueue(validRequest())
+			select {
+			case ev = <-stream.events:
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		cancel()
+
+		// Assert
+		assert.Equal(t, pb.MessageEvent_QUEUED, ev.Type, "Selected event must be streamed")
+		assert.Equal(t, codes.Canceled, status.Code(<-done), "Watch must end when the client cancels")
+	})
+}
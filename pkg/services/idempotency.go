@@ -0,0 +1,245 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// IdempotencyKeyMetadata is the gRPC metadata key holding the idempotency key
+	IdempotencyKeyMetadata = "idempotency-key"
+	// IdempotencyKeyHeader is the HTTP header passed by the gateway as IdempotencyKeyMetadata
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+)
+
+type idempotencyRecord struct {
+	// Hash identifies the payload of the request
+	Hash string `json:"hash"`
+	// Response is the marshalled EmailResponse returned for the request
+	Response  []byte    `json:"response"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Idempotency remembers the responses of SendMail by the idempotency key, so
+// the client can safely retry the request without sending the email twice
+type Idempotency struct {
+	store  *storage.Store
+	window time.Duration
+	now    func() time.Time
+
+	// mu guards inFlight
+	mu       sync.Mutex
+	inFlight map[string]bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewIdempotency constructor of Idempotency. The keys are remembered for the
+// window, the default is 24 hours.
+func NewIdempotency(store *storage.Store, window time.Duration) *Idempotency {
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &Idempotency{
+		store:    store,
+		window:   window,
+		now:      time.Now,
+		inFlight: make(map[string]bool),
+	}
+}
+
+// Start removes the expired keys periodically
+func (i *Idempotency) Start() {
+	i.stop = make(chan struct{})
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+		ticker := time.NewTicker(i.window / 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-i.stop:
+				return
+			case <-ticker.C:
+				i.sweep()
+			}
+		}
+	}()
+}
+
+// Stop stops removing the expired keys
+func (i *Idempotency) Stop() {
+	if i.stop != nil {
+		close(i.stop)
+		i.wg.Wait()
+	}
+}
+
+// begin returns the response remembered for the key. When there is none the key
+// is reserved until finish is called, so concurrent retries are rejected.
+func (i *Idempotency) begin(key, hash string) (*pb.EmailResponse, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if rec, ok := i.lookup(key); ok {
+		if rec.Hash != hash {
+			return nil, status.Error(codes.AlreadyExists, "idempotency key was already used with different email")
+		}
+		resp := &pb.EmailResponse{}
+		if err := proto.Unmarshal(rec.Response, resp); err != nil {
+			return nil, status.Errorf(codes.Internal, "can not decode response of idempotency key: %v", err)
+		}
+		return resp, nil
+	}
+	if i.inFlight[key] {
+		return nil, status.Error(codes.Aborted, "request with the idempotency key is in progress")
+	}
+	i.inFlight[key] = true
+	return nil, nil
+}
+
+// finish remembers the successful response and releases the key. The key is
+// reserved until the response is stored, so it is stored without the lock.
+func (i *Idempotency) finish(key, hash string, resp *pb.EmailResponse) {
+	if resp != nil {
+		i.remember(key, hash, resp)
+	}
+	i.mu.Lock()
+	delete(i.inFlight, key)
+	i.mu.Unlock()
+}
+
+func (i *Idempotency) remember(key, hash string, resp *pb.EmailResponse) {
+	response, err := proto.Marshal(resp)
+	if err != nil {
+		zap.L().Error("Can not encode response of idempotency key", zap.String("key", key), zap.Error(err))
+		return
+	}
+	value, err := json.Marshal(idempotencyRecord{
+		Hash:      hash,
+		Response:  response,
+		CreatedAt: i.now(),
+	})
+	if err == nil {
+		err = i.store.Put(key, value)
+	}
+	if err != nil {
+		zap.L().Error("Can not store idempotency key", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// lookup returns the record of the key which did not expire
+func (i *Idempotency) lookup(key string) (*idempotencyRecord, bool) {
+	value, ok := i.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	rec := &idempotencyRecord{}
+	if err := json.Unmarshal(value, rec); err != nil {
+		zap.L().Error("Can not decode idempotency key", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	if i.now().Sub(rec.CreatedAt) >= i.window {
+		return nil, false
+	}
+	return rec, true
+}
+
+// sweep removes the expired keys
+func (i *Idempotency) sweep() {
+	now := i.now()
+	var expired []string
+	i.store.Range("", func(key string, value []byte) bool {
+		rec := &idempotencyRecord{}
+		if err := json.Unmarshal(value, rec); err != nil || now.Sub(rec.CreatedAt) >= i.window {
+			expired = append(expired, key)
+		}
+		return true
+	})
+	for _, key := range expired {
+		if err := i.store.Delete(key); err != nil {
+			zap.L().Error("Can not remove idempotency key", zap.String("key", key), zap.Error(err))
+			return
+		}
+	}
+}
+
+// callerKey scopes the idempotency key to the caller, so the callers can not
+// get the responses of each other. The anonymous callers share the keys.
+func callerKey(ctx context.Context, key string) string {
+	sum := sha256.Sum256([]byte(caller(ctx)))
+	return hex.EncodeToString(sum[:8]) + "/" + key
+}
+
+// caller returns the identity of the authenticated caller, it is empty when
+// the authentication is disabled
+func caller(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		return id.String()
+	}
+	return ""
+}
+
+// idempotencyKey reads the key from the incoming metadata
+func idempotencyKey(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	values := md.Get(IdempotencyKeyMetadata)
+	if len(values) == 0 {
+		return "", nil
+	}
+	key := values[0]
+	if len(key) > maxIdempotencyKeyLength {
+		return "", status.Errorf(codes.InvalidArgument,
+			"idempotency key can not be longer than %d characters", maxIdempotencyKeyLength)
+	}
+	for _, r := range key {
+		if r < ' ' || r > '~' {
+			return "", status.Error(codes.InvalidArgument, "idempotency key must contain only printable ASCII characters")
+		}
+	}
+	return key, nil
+}
+
+// requestHash identifies the payload of the request. The deterministic
+// marshalling keeps the order of headers map stable.
+func requestHash(req *pb.EmailRequest) (string, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(req); err != nil {
+		return "", status.Errorf(codes.Internal, "can not marshal email: %v", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,206 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestIdempotency(t *testing.T, window time.Duration) *Idempotency {
+	return NewIdempotency(openTestStore(t, "idempotency"), window)
+}
+
+func withIdempotencyKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(IdempotencyKeyMetadata, key))
+}
+
+func TestEmailService_SendMailIdempotency(t *testing.T) {
+	t.Run("Repeated request returns original response", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithIdempotency(idem))
+
+		// Act
+		first, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		second, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.True(t, proto.Equal(first, second), "Original response must be returned")
+		assert.Len(t, provider.requests, 1, "Email must be sent once")
+	})
+
+	t.Run("Suppressed recipients are returned again", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		suppressions := newTestSuppressions(t)
+		_, err := suppressions.add("bounced@example.com", pb.Suppression_BOUNCE, "")
+		assert.NoError(t, err, "Recipient should be suppressed")
+		es := NewEmailService(WithProvider(&fakeProvider{name: "fake"}), WithIdempotency(idem),
+			WithSuppressions(suppressions))
+		req := validRequest()
+		req.Cc = []*pb.Address{{Email: "bounced@example.com"}}
+		first, err := es.SendMail(withIdempotencyKey("key-1"), req)
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		second, err := es.SendMail(withIdempotencyKey("key-1"), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Len(t, second.Suppressed, 1, "Suppressed recipient must be returned")
+		assert.True(t, proto.Equal(first, second), "Original response must be returned")
+	})
+
+	t.Run("Keys of callers are separate", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithIdempotency(idem))
+		caller := func(id string) context.Context {
+			return auth.NewContext(withIdempotencyKey("key-1"), &auth.Identity{Method: auth.MethodKey, ID: id})
+		}
+		_, err := es.SendMail(caller("billing"), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		other := validRequest()
+		other.Subject = "Other"
+
+		// Act
+		_, err = es.SendMail(caller("newsletter"), other)
+
+		// Assert
+		assert.NoError(t, err, "Key of other caller must not be reused")
+		assert.Len(t, provider.requests, 2, "Email of every caller must be sent")
+	})
+
+	t.Run("Key reused with different email", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		es := NewEmailService(WithProvider(&fakeProvider{name: "fake"}), WithIdempotency(idem))
+		_, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		other := validRequest()
+		other.Subject = "Other"
+
+		// Act
+		_, err = es.SendMail(withIdempotencyKey("key-1"), other)
+
+		// Assert
+		assert.Equal(t, codes.AlreadyExists, status.Code(err), "Already exists must be returned")
+	})
+
+	t.Run("Key expires after window", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithIdempotency(idem))
+		_, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		idem.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		// Act
+		_, err = es.SendMail(withIdempotencyKey("key-1"), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Len(t, provider.requests, 2, "Email must be sent again")
+	})
+
+	t.Run("Failed request is not remembered", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		provider := &fakeProvider{name: "fake", err: status.Error(codes.Unavailable, "down")}
+		es := NewEmailService(WithProvider(provider), WithIdempotency(idem))
+		_, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+		assert.Error(t, err, "Error should occur")
+		provider.err = nil
+
+		// Act
+		resp, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Retry should succeed")
+		assert.Equal(t, "id-fake", resp.MessageId, "Email must be sent")
+	})
+
+	t.Run("Request in progress", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		_, err := idem.begin("key-1", "hash")
+		assert.NoError(t, err, "Key should be reserved")
+
+		// Act
+		_, err = idem.begin("key-1", "hash")
+
+		// Assert
+		assert.Equal(t, codes.Aborted, status.Code(err), "Aborted must be returned")
+	})
+
+	t.Run("Expired keys are removed", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		es := NewEmailService(WithProvider(&fakeProvider{name: "fake"}), WithIdempotency(idem))
+		_, err := es.SendMail(withIdempotencyKey("key-1"), validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		idem.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		// Act
+		idem.sweep()
+
+		// Assert
+		assert.Zero(t, idem.store.Len(), "Expired key must be removed")
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		// Arrange
+		idem := newTestIdempotency(t, time.Hour)
+		es := NewEmailService(WithProvider(&fakeProvider{name: "fake"}), WithIdempotency(idem))
+
+		// Act
+		_, err := es.SendMail(withIdempotencyKey(strings.Repeat("k", 256)), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+	})
+}
+
+func TestRequestHash(t *testing.T) {
+	// Arrange
+	a := validRequest()
+	a.Headers = map[string]string{"X-A": "1", "X-B": "2", "X-C": "3"}
+	b := validRequest()
+	b.Headers = map[string]string{"X-C": "3", "X-B": "2", "X-A": "1"}
+
+	// Act
+	hashA, errA := requestHash(a)
+	hashB, errB := requestHash(b)
+
+	// Assert
+	assert.NoError(t, errA, "Error should not occur")
+	assert.NoError(t, errB, "Error should not occur")
+	assert.Equal(t, hashA, hashB, "Hash must not depend on the order of headers")
+}
@@ -0,0 +1,99 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var messageStates = map[MessageState]pb.Message_State{
+	MessageQueued:    pb.Message_QUEUED,
+	MessageSending:   pb.Message_SENDING,
+	MessageSent:      pb.Message_SENT,
+	MessageDelivered: pb.Message_DELIVERED,
+	MessageOpened:    pb.Message_OPENED,
+	MessageDeferred:  pb.Message_DEFERRED,
+	MessageBounced:   pb.Message_BOUNCED,
+	MessageFailed:    pb.Message_FAILED,
+	MessageCanceled:  pb.Message_CANCELED,
+}
+
+var messageEventTypes = map[MessageState]pb.MessageEvent_Type{
+	MessageQueued:    pb.MessageEvent_QUEUED,
+	MessageSending:   pb.MessageEvent_SENDING,
+	MessageSent:      pb.MessageEvent_SENT,
+	MessageDelivered: pb.MessageEvent_DELIVERED,
+	MessageOpened:    pb.MessageEvent_OPENED,
+	MessageDeferred:  pb.MessageEvent_DEFERRED,
+	MessageBounced:   pb.MessageEvent_BOUNCED,
+	MessageFailed:    pb.MessageEvent_FAILED,
+	MessageCanceled:  pb.MessageEvent_CANCELED,
+}
+
+// messageEvent describes the change of the message which happened at its UpdatedAt
+func messageEvent(msg *Message, eventType pb.MessageEvent_Type) *pb.MessageEvent {
+	ev := &pb.MessageEvent{
+		MessageId: msg.ID,
+		Type:      eventType,
+		From:      msg.From,
+		Tags:      msg.Tags,
+		Provider:  msg.Provider,
+		Attempts:  int32(msg.Attempts),
+		Error:     msg.LastError,
+	}
+	ev.Time, _ = timestampProto(msg.UpdatedAt)
+	return ev
+}
+
+// messageToProto converts the queued message to its API representation
+func messageToProto(msg *Message) (*pb.Message, error) {
+	out := &pb.Message{
+		Id:                msg.ID,
+		State:             messageStates[msg.State],
+		Provider:          msg.Provider,
+		ProviderMessageId: msg.ProviderMessageID,
+		Attempts:          int32(msg.Attempts),
+		LastError:         msg.LastError,
+	}
+	var err error
+	if out.CreatedAt, err = timestampProto(msg.CreatedAt); err != nil {
+		return nil, err
+	}
+	if out.UpdatedAt, err = timestampProto(msg.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if msg.State == MessageQueued || msg.State == MessageDeferred {
+		if out.NextAttemptAt, err = timestampProto(msg.NotBefore); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func timestampProto(t time.Time) (*timestamp.Timestamp, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid timestamp: %v", err)
+	}
+	return ts, nil
+}
@@ -0,0 +1,101 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	providerSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_provider_sends_total",
+		Help: "Total number of emails sent through the provider partitioned by the gRPC code of the result.",
+	}, []string{"provider", "code"})
+
+	providerSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_provider_send_duration_seconds",
+		Help:    "Time spent on sending the email through the provider.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	breakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "email_provider_circuit_breaker_state",
+		Help: "State of the provider circuit breaker: 0 closed, 1 half-open, 2 open.",
+	}, []string{"provider"})
+
+	breakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_provider_circuit_breaker_transitions_total",
+		Help: "Total number of the provider circuit breaker transitions partitioned by the new state.",
+	}, []string{"provider", "state"})
+
+	queuedMessages = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "email_queue_messages",
+		Help: "Number of emails waiting in the queue for the delivery.",
+	})
+
+	deadLetteredMessages = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "email_dead_lettered_messages_total",
+		Help: "Total number of emails which failed permanently or ran out of retries.",
+	})
+
+	watchers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "email_event_watchers",
+		Help: "Number of subscribers of the message events.",
+	})
+
+	droppedWatchers = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "email_event_watchers_dropped_total",
+		Help: "Total number of subscribers of the message events dropped because they did not keep up.",
+	})
+
+	suppressedRecipients = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_suppressed_recipients_total",
+		Help: "Total number of recipients dropped from emails because they are suppressed partitioned by the reason.",
+	}, []string{"reason"})
+
+	throttledSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_provider_throttled_total",
+		Help: "Total number of times the provider was skipped because it exceeded its rate limit.",
+	}, []string{"provider"})
+
+	rejectedAddresses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_rejected_addresses_total",
+		Help: "Total number of recipients rejected by the address validation partitioned by the result.",
+	}, []string{"result"})
+
+	providerEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_provider_events_total",
+		Help: "Total number of events received from the provider webhooks partitioned by the type of the event.",
+	}, []string{"provider", "type"})
+
+	callbackAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_callback_attempts_total",
+		Help: "Total number of attempts to post the message events to the callbacks partitioned by the result.",
+	}, []string{"success"})
+)
+
+func init() {
+	prometheus.MustRegister(providerSends, providerSendDuration, breakerState, breakerTransitions, queuedMessages,
+		deadLetteredMessages, watchers, droppedWatchers, suppressedRecipients,
+		rejectedAddresses, throttledSends, providerEvents, callbackAttempts)
+}
+
+// observeSend records the result of the single call to the provider
+func observeSend(provider string, start time.Time, err error) {
+	providerSends.WithLabelValues(provider, status.Code(err).String()).Inc()
+	providerSendDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,366 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+// Headers of the events posted to the callbacks
+const (
+	CallbackIDHeader        = "X-Webhook-Id"
+	CallbackTimestampHeader = "X-Webhook-Timestamp"
+	CallbackSignatureHeader = "X-Webhook-Signature"
+)
+
+// NotifierConfig configures the Notifier. Zero values are replaced with defaults.
+type NotifierConfig struct {
+	// Workers is the number of events posted at once, default 2
+	Workers int `mapstructure:"workers"`
+	// Retry is the policy of posting the single event
+	Retry RetryPolicy `mapstructure:"retry"`
+	// DisableAfter is the number of consecutive failed attempts after which
+	// the callback is disabled, default 10
+	DisableAfter int `mapstructure:"disable_after"`
+	// Timeout of the single attempt, default 10s
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func (c NotifierConfig) withDefaults() NotifierConfig {
+	if c.Workers <= 0 {
+		c.Workers = 2
+	}
+	c.Retry = c.Retry.withDefaults()
+	if c.DisableAfter <= 0 {
+		c.DisableAfter = 10
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return c
+}
+
+// Notifier posts the events of messages to the callbacks which select them.
+// Every event is stored before it is posted, so it survives the restart, and
+// it is retried with the exponential backoff until the callback accepts it.
+// The callback which fails repeatedly is disabled.
+type Notifier struct {
+	callbacks *Callbacks
+	events    *Events
+	cfg       NotifierConfig
+	client    *http.Client
+
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+
+	work   chan string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNotifier constructor of Notifier, the events are usually the ones of the queue
+func NewNotifier(callbacks *Callbacks, events *Events, cfg NotifierConfig) *Notifier {
+	cfg = cfg.withDefaults()
+	return &Notifier{
+		callbacks: callbacks,
+		events:    events,
+		cfg:       cfg,
+		client:    newCallbackClient(callbacks, cfg.Timeout),
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		work:      make(chan string),
+	}
+}
+
+// newCallbackClient returns the client which connects only to the addresses
+// allowed by the callbacks. The address is checked when the connection is
+// made, so the host which resolves to other address after the callback was
+// registered is rejected too. The redirects are not followed.
+func newCallbackClient(callbacks *Callbacks, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !callbacks.allowed(ip) {
+				return fmt.Errorf("callback address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Start resumes the pending deliveries and launches the workers
+func (n *Notifier) Start() {
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	pending, err := n.callbacks.pending()
+	if err != nil {
+		zap.L().Error("Can not resume callback deliveries", zap.Error(err))
+	}
+	for _, d := range pending {
+		n.schedule(d)
+	}
+	if len(pending) > 0 {
+		zap.L().Info("Resumed callback deliveries", zap.Int("count", len(pending)))
+	}
+
+	backlog := n.events.SubscribeBacklog()
+	n.wg.Add(1)
+	go n.route(backlog)
+	for i := 0; i < n.cfg.Workers; i++ {
+		n.wg.Add(1)
+		go n.deliver()
+	}
+}
+
+// Stop aborts the posts in flight and waits until the workers finish. The
+// aborted posts are not counted as the failures of the callbacks. The events
+// published before Stop are stored, so they are posted after the restart.
+func (n *Notifier) Stop() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	n.wg.Wait()
+}
+
+// route stores the deliveries of the events for every callback of their owner
+// which selects them. The events come from the backlog, so none of them is lost when the
+// callbacks do not keep up.
+func (n *Notifier) route(backlog *Backlog) {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			backlog.Close()
+			n.enqueue(backlog.Take())
+			return
+		case <-backlog.Ready():
+			n.enqueue(backlog.Take())
+		}
+	}
+}
+
+// enqueue stores the deliveries of the events at once and schedules them
+func (n *Notifier) enqueue(events []OwnedEvent) {
+	if len(events) == 0 {
+		return
+	}
+	deliveries, err := n.deliveries(events)
+	if err == nil && len(deliveries) > 0 {
+		err = n.callbacks.saveDeliveries(deliveries)
+	}
+	if err != nil {
+		zap.L().Error("Can not store callback deliveries", zap.Int("events", len(events)), zap.Error(err))
+		return
+	}
+	for _, d := range deliveries {
+		n.schedule(d)
+	}
+}
+
+// deliveries returns the delivery of every event for every callback of its
+// owner which selects it
+func (n *Notifier) deliveries(events []OwnedEvent) ([]*callbackDelivery, error) {
+	callbacks, err := n.callbacks.enabled()
+	if err != nil || len(callbacks) == 0 {
+		return nil, err
+	}
+	now := n.callbacks.now()
+	var deliveries []*callbackDelivery
+	for _, ev := range events {
+		var event []byte
+		for _, cb := range callbacks {
+			if cb.Owner != ev.Owner || !selects(cb.filter(), ev.Event) {
+				continue
+			}
+			if event == nil {
+				if event, err = proto.Marshal(ev.Event); err != nil {
+					return nil, err
+				}
+			}
+			id, err := newID()
+			if err != nil {
+				return nil, err
+			}
+			deliveries = append(deliveries, &callbackDelivery{
+				ID:         id,
+				CallbackID: cb.ID,
+				Event:      event,
+				NotBefore:  now,
+				CreatedAt:  now,
+			})
+		}
+	}
+	return deliveries, nil
+}
+
+// schedule hands the delivery to the workers when it is due
+func (n *Notifier) schedule(d *callbackDelivery) {
+	id := d.ID
+	time.AfterFunc(d.NotBefore.Sub(n.callbacks.now()), func() {
+		select {
+		case n.work <- id:
+		case <-n.ctx.Done():
+		}
+	})
+}
+
+func (n *Notifier) deliver() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case id := <-n.work:
+			if err := n.attempt(id); err != nil {
+				zap.L().Error("Can not post event to callback", zap.String("delivery_id", id), zap.Error(err))
+			}
+		}
+	}
+}
+
+// attempt posts the event once and decides if it is retried
+func (n *Notifier) attempt(id string) error {
+	d, ok, err := n.callbacks.delivery(id)
+	if err != nil || !ok {
+		return err
+	}
+	cb, err := n.callbacks.get(d.CallbackID)
+	if err != nil || cb.Disabled {
+		// The callback was deleted or disabled in the meantime
+		return n.callbacks.removeDelivery(id)
+	}
+	ev := &pb.MessageEvent{}
+	if err := proto.Unmarshal(d.Event, ev); err != nil {
+		_ = n.callbacks.removeDelivery(id)
+		return err
+	}
+
+	d.Attempts++
+	a := &storedAttempt{
+		DeliveryID: d.ID,
+		MessageID:  ev.GetMessageId(),
+		EventType:  ev.GetType().String(),
+		Attempt:    d.Attempts,
+		Time:       n.callbacks.now(),
+	}
+	a.StatusCode, err = n.post(cb, d.ID, ev)
+	if err != nil && n.ctx.Err() != nil {
+		// The post was aborted by Stop, the delivery is resumed after the restart
+		return nil
+	}
+	a.Success = err == nil
+	if err != nil {
+		a.Error = err.Error()
+	}
+	callbackAttempts.WithLabelValues(strconv.FormatBool(a.Success)).Inc()
+	if cb, err = n.callbacks.record(cb.ID, a, n.cfg.DisableAfter); err != nil {
+		return err
+	}
+	if a.Success {
+		return n.callbacks.removeDelivery(id)
+	}
+	if cb.Disabled {
+		zap.L().Warn("Callback disabled after repeated failures",
+			zap.String("callback_id", cb.ID), zap.Int("failures", cb.ConsecutiveFailures))
+		return n.callbacks.removeDelivery(id)
+	}
+
+	n.rndMu.Lock()
+	next := a.Time.Add(n.cfg.Retry.backoff(d.Attempts, n.rnd))
+	n.rndMu.Unlock()
+	if d.Attempts >= n.cfg.Retry.MaxAttempts || next.Sub(d.CreatedAt) > n.cfg.Retry.MaxAge {
+		zap.L().Warn("Event was not posted to callback",
+			zap.String("callback_id", cb.ID), zap.String("delivery_id", d.ID), zap.Int("attempts", d.Attempts))
+		return n.callbacks.removeDelivery(id)
+	}
+	d.NotBefore = next
+	if err := n.callbacks.saveDelivery(d); err != nil {
+		return err
+	}
+	n.schedule(d)
+	return nil
+}
+
+// post sends the signed event and returns the status code of the response
+func (n *Notifier) post(cb *storedCallback, deliveryID string, ev *pb.MessageEvent) (int, error) {
+	var body bytes.Buffer
+	if err := (&jsonpb.Marshaler{OrigName: true}).Marshal(&body, ev); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, cb.URL, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(n.callbacks.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CallbackIDHeader, deliveryID)
+	req.Header.Set(CallbackTimestampHeader, timestamp)
+	req.Header.Set(CallbackSignatureHeader, SignCallback(cb.Secret, timestamp, body.Bytes()))
+
+	resp, err := n.client.Do(req.WithContext(n.ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("callback responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignCallback returns the value of the X-Webhook-Signature header, i.e. v1=
+// followed by the hex HMAC-SHA256 of the timestamp, a dot and the body. The
+// client computes it with its secret and compares with the header, it should
+// also reject the old timestamps to prevent replays.
+func SignCallback(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,126 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/RafalKorepta/coding-challenge/pkg/dkim"
+)
+
+var (
+	defaultOptions = &options{
+		attachments: AttachmentPolicy{}.withDefaults(),
+		addresses:   newAddressValidator(AddressValidation{}, nil),
+	}
+)
+
+type options struct {
+	provider     Provider
+	queue        *Queue
+	idempotency  *Idempotency
+	templates    *Templates
+	suppressions *Suppressions
+	callbacks    *Callbacks
+	attachments  AttachmentPolicy
+	addresses    *AddressValidator
+	dkim         *dkim.Keyring
+	apiKeys      *auth.Keys
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithProvider setup the provider which delivers the emails
+func WithProvider(p Provider) Option {
+	return func(o *options) {
+		o.provider = p
+	}
+}
+
+// WithQueue setup the queue, with it SendMail only stores the email
+// and the Dispatcher delivers it later
+func WithQueue(q *Queue) Option {
+	return func(o *options) {
+		o.queue = q
+	}
+}
+
+// WithIdempotency setup where the responses for idempotency keys are remembered
+func WithIdempotency(i *Idempotency) Option {
+	return func(o *options) {
+		o.idempotency = i
+	}
+}
+
+// WithTemplates setup where the email templates are stored
+func WithTemplates(t *Templates) Option {
+	return func(o *options) {
+		o.templates = t
+	}
+}
+
+// WithSuppressions setup where the addresses to which emails are not sent are stored
+func WithSuppressions(s *Suppressions) Option {
+	return func(o *options) {
+		o.suppressions = s
+	}
+}
+
+// WithCallbacks setup where the URLs which receive the events of messages are stored
+func WithCallbacks(c *Callbacks) Option {
+	return func(o *options) {
+		o.callbacks = c
+	}
+}
+
+// WithAttachmentPolicy setup the limits of attachments accepted by SendMail
+func WithAttachmentPolicy(p AttachmentPolicy) Option {
+	return func(o *options) {
+		o.attachments = p.withDefaults()
+	}
+}
+
+// WithAddressValidator setup the checks of recipient addresses done by SendMail,
+// by default only the syntax is checked
+func WithAddressValidator(v *AddressValidator) Option {
+	return func(o *options) {
+		if v != nil {
+			o.addresses = v
+		}
+	}
+}
+
+// WithDKIM setup the keys which sign the messages built by the providers
+// that accept raw emails
+func WithDKIM(k *dkim.Keyring) Option {
+	return func(o *options) {
+		o.dkim = k
+	}
+}
+
+// WithAPIKeys setup the API keys managed by APIKeyService, they should be set
+// only when the callers are authenticated
+func WithAPIKeys(k *auth.Keys) Option {
+	return func(o *options) {
+		o.apiKeys = k
+	}
+}
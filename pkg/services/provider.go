@@ -0,0 +1,75 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Receipt is returned by the Provider when the email was accepted for the delivery
+type Receipt struct {
+	// Provider is the name of the provider that accepted the email
+	Provider string
+	// MessageID is the identifier assigned to the email by the provider
+	MessageID string
+}
+
+// Provider delivers emails through an external service e.g. SendGrid.
+//
+// The errors returned by Send must be gRPC statuses, so they can be passed
+// to the caller without any translation.
+type Provider interface {
+	// Name identifies the provider in logs, metrics and responses
+	Name() string
+	// Send delivers already validated request
+	Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error)
+}
+
+// IsRetryable reports if the failed delivery is temporary, so it can be attempted again.
+// Other failures are permanent e.g. the provider rejected the message.
+func IsRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// ProviderError is the error of the provider which failed to send the email.
+// It keeps the gRPC status of the original error.
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+// GRPCStatus returns the status of the original error
+func (e *ProviderError) GRPCStatus() *status.Status {
+	return status.Convert(e.Err)
+}
+
+// failedProvider returns the name of the provider which returned the error
+func failedProvider(err error) string {
+	if pe, ok := err.(*ProviderError); ok {
+		return pe.Provider
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"fmt"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"github.com/mitchellh/mapstructure"
+)
+
+// The types of providers which can be used in the configuration
+const (
+	SendGridType = "sendgrid"
+	SESType      = "ses"
+	SMTPType     = "smtp"
+)
+
+// providerEntry holds the keys common to every entry of the providers list
+type providerEntry struct {
+	Type    string        `mapstructure:"type"`
+	Weight  int           `mapstructure:"weight"`
+	Breaker BreakerConfig `mapstructure:"circuit_breaker"`
+	Retry   RetryPolicy   `mapstructure:"retry"`
+	// RateLimit is the outbound rate of the provider
+	RateLimit ratelimit.Limit `mapstructure:"rate_limit"`
+}
+
+// NewWeightedProvider creates the balancer member from the single entry of the
+// providers list in the configuration. Apart from the type and weight the entry
+// holds the keys of SendGridConfig, SESConfig or SMTPConfig, optional
+// circuit_breaker with the keys of BreakerConfig, optional retry with the
+// keys of RetryPolicy and optional rate_limit of emails per second, e.g.:
+//
+//	providers:
+//	  - type: sendgrid
+//	    weight: 3
+//	    api_key: SG.xxx
+//	    rate_limit:
+//	      rate: 100
+//	      burst: 200
+//	  - type: ses
+//	    weight: 1
+//	    region: eu-west-1
+//	    circuit_breaker:
+//	      consecutive_failures: 3
+//	      open_timeout: 1m
+//	    retry:
+//	      max_attempts: 10
+//
+// The options given with WithDKIM sign the messages of SES and SMTP providers.
+func NewWeightedProvider(settings map[string]interface{}, opts ...Option) (WeightedProvider, error) {
+	o := evaluateOptions(opts)
+	var entry providerEntry
+	if err := decodeSettings(settings, &entry); err != nil {
+		return WeightedProvider{}, err
+	}
+
+	var (
+		provider Provider
+		err      error
+	)
+	switch entry.Type {
+	case SendGridType:
+		var cfg SendGridConfig
+		if err = decodeSettings(settings, &cfg); err == nil {
+			provider, err = NewSendGrid(cfg)
+		}
+	case SESType:
+		cfg := SESConfig{DKIM: o.dkim}
+		if err = decodeSettings(settings, &cfg); err == nil {
+			provider, err = NewSES(cfg)
+		}
+	case SMTPType:
+		cfg := SMTPConfig{DKIM: o.dkim}
+		if err = decodeSettings(settings, &cfg); err == nil {
+			provider, err = NewSMTP(cfg)
+		}
+	default:
+		return WeightedProvider{}, fmt.Errorf("unknown provider type %q", entry.Type)
+	}
+	if err != nil {
+		return WeightedProvider{}, fmt.Errorf("%s provider: %v", entry.Type, err)
+	}
+	return WeightedProvider{
+		Provider:  provider,
+		Weight:    entry.Weight,
+		Breaker:   entry.Breaker,
+		Retry:     entry.Retry,
+		RateLimit: entry.RateLimit,
+	}, nil
+}
+
+// decodeSettings decodes the configuration the same way as viper does
+func decodeSettings(settings map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(settings)
+}
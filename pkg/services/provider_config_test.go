@@ -0,0 +1,97 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RafalKorepta/coding-challenge/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWeightedProvider(t *testing.T) {
+	t.Run("SendGrid entry", func(t *testing.T) {
+		// Act
+		wp, err := NewWeightedProvider(map[string]interface{}{
+			"type":    "sendgrid",
+			"name":    "sendgrid-eu",
+			"weight":  "3",
+			"api_key": "SG.key",
+			"timeout": "5s",
+			"circuit_breaker": map[interface{}]interface{}{
+				"consecutive_failures": 3,
+				"open_timeout":         "1m",
+			},
+			"rate_limit": map[interface{}]interface{}{
+				"rate":  "100",
+				"burst": 200,
+			},
+		})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, 3, wp.Weight, "Weight must be decoded")
+		sg, ok := wp.Provider.(*SendGrid)
+		assert.True(t, ok, "SendGrid provider must be created")
+		assert.Equal(t, "sendgrid-eu", sg.Name(), "Name must be decoded")
+		assert.Equal(t, 5*time.Second, sg.client.Timeout, "Timeout must be decoded")
+		assert.Equal(t, 3, wp.Breaker.ConsecutiveFailures, "Circuit breaker must be decoded")
+		assert.Equal(t, time.Minute, wp.Breaker.OpenTimeout, "Circuit breaker must be decoded")
+		assert.Equal(t, ratelimit.Limit{Rate: 100, Burst: 200}, wp.RateLimit, "Rate limit must be decoded")
+	})
+
+	t.Run("SES entry", func(t *testing.T) {
+		// Act
+		wp, err := NewWeightedProvider(map[string]interface{}{
+			"type":              "ses",
+			"region":            "eu-west-1",
+			"access_key_id":     "AKID",
+			"secret_access_key": "secret",
+		})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "ses", wp.Provider.Name(), "SES provider must be created")
+	})
+
+	t.Run("SMTP entry", func(t *testing.T) {
+		// Act
+		wp, err := NewWeightedProvider(map[string]interface{}{
+			"type": "smtp",
+			"host": "smtp.example.com",
+			"port": 2525,
+		})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "smtp", wp.Provider.Name(), "SMTP provider must be created")
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		// Act
+		_, err := NewWeightedProvider(map[string]interface{}{"type": "mailgun"})
+
+		// Assert
+		assert.Error(t, err, "Unknown provider type must be rejected")
+	})
+
+	t.Run("Invalid provider settings", func(t *testing.T) {
+		// Act
+		_, err := NewWeightedProvider(map[string]interface{}{"type": "sendgrid"})
+
+		// Assert
+		assert.Error(t, err, "SendGrid without API key must be rejected")
+	})
+}
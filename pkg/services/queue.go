@@ -0,0 +1,713 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MessageState is the state of the accepted email in its lifecycle
+type MessageState string
+
+// The states of the accepted email
+const (
+	// MessageQueued waits in the queue for the delivery
+	MessageQueued MessageState = "queued"
+	// MessageSending is being delivered by the worker
+	MessageSending MessageState = "sending"
+	// MessageSent was accepted by the provider
+	MessageSent MessageState = "sent"
+	// MessageDeferred failed temporarily and waits for the next attempt
+	MessageDeferred MessageState = "deferred"
+	// MessageDelivered was accepted by the recipient mail server
+	MessageDelivered MessageState = "delivered"
+	// MessageOpened was opened by the recipient
+	MessageOpened MessageState = "opened"
+	// MessageBounced was rejected by the recipient mail server
+	MessageBounced MessageState = "bounced"
+	// MessageFailed could not be delivered
+	MessageFailed MessageState = "failed"
+	// MessageCanceled was canceled before the delivery
+	MessageCanceled MessageState = "canceled"
+)
+
+// errQueueClosed is returned from Next when the queue was closed
+var errQueueClosed = errors.New("queue is closed")
+
+// providerIndexPrefix prefixes the keys which map the id assigned by the
+// provider to the id of the message, the message ids are hex, so they never
+// start with it
+const providerIndexPrefix = "provider/"
+
+// trackedStates orders the states reported by the provider after the delivery,
+// the events can come out of order, so the state never goes back
+var trackedStates = map[MessageState]int{
+	MessageSent:      1,
+	MessageDelivered: 2,
+	MessageBounced:   3,
+	MessageOpened:    3,
+}
+
+// finishedStates are the states of messages which left the queue for good,
+// they are removed after the retention period
+var finishedStates = map[MessageState]bool{
+	MessageSent:      true,
+	MessageDelivered: true,
+	MessageOpened:    true,
+	MessageBounced:   true,
+	MessageFailed:    true,
+	MessageCanceled:  true,
+}
+
+// Message is the email accepted by the service together with its delivery state
+type Message struct {
+	ID string `json:"id"`
+	// Request is the marshalled EmailRequest, it is dropped after the delivery
+	Request           []byte       `json:"request,omitempty"`
+	State             MessageState `json:"state"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	NotBefore         time.Time    `json:"not_before"`
+	Attempts          int          `json:"attempts"`
+	Provider          string       `json:"provider,omitempty"`
+	ProviderMessageID string       `json:"provider_message_id,omitempty"`
+	LastError         string       `json:"last_error,omitempty"`
+	// From and Tags are kept after the delivery to filter the events
+	From string   `json:"from,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// Owner is the caller which sent the message, its callbacks receive the events
+	Owner string `json:"owner,omitempty"`
+	// FirstAttemptAt is the start of the first delivery attempt, the age of
+	// the message is measured from it, so the scheduled message is retried
+	// as long as the one sent at once
+	FirstAttemptAt time.Time `json:"first_attempt_at"`
+}
+
+// withoutRequest returns the copy of the message without the content of the email
+func (m *Message) withoutRequest() *Message {
+	c := *m
+	c.Request = nil
+	return &c
+}
+
+// retriedSince returns the time from which the age of the message is measured
+func (m *Message) retriedSince() time.Time {
+	if m.FirstAttemptAt.IsZero() {
+		// The message was stored before the first attempt was recorded
+		return m.CreatedAt
+	}
+	return m.FirstAttemptAt
+}
+
+// EmailRequest unmarshals the request of the message
+func (m *Message) EmailRequest() (*pb.EmailRequest, error) {
+	req := &pb.EmailRequest{}
+	if err := proto.Unmarshal(m.Request, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Queue is the durable queue of emails waiting for the delivery. Every message
+// is written to the store before Enqueue returns, so it survives the crash.
+// Messages which were queued or being sent when the process stopped are
+// queued again by NewQueue.
+type Queue struct {
+	store       *storage.Store
+	deadLetters *DeadLetters
+	events      *Events
+	now         func() time.Time
+
+	mu      sync.Mutex
+	due     dueHeap
+	changed chan struct{}
+	closed  bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewQueue constructor of Queue which loads pending messages from the store.
+// The messages which ran out of retries are moved to the dead letters, without
+// them such messages are only marked as failed.
+func NewQueue(store *storage.Store, deadLetters *DeadLetters) (*Queue, error) {
+	q := &Queue{
+		store:       store,
+		deadLetters: deadLetters,
+		events:      NewEvents(defaultEventBuffer),
+		now:         time.Now,
+		changed:     make(chan struct{}),
+	}
+
+	var pending []*Message
+	var err error
+	store.Range("", func(key string, value []byte) bool {
+		if strings.HasPrefix(key, providerIndexPrefix) {
+			return true
+		}
+		msg := &Message{}
+		if err = json.Unmarshal(value, msg); err != nil {
+			return false
+		}
+		if msg.State == MessageQueued || msg.State == MessageSending || msg.State == MessageDeferred {
+			pending = append(pending, msg)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range pending {
+		if msg.State == MessageSending {
+			// The process stopped in the middle of the delivery, the provider
+			// could have accepted the email, but it is better to send it twice
+			// than to lose it
+			msg.State = MessageQueued
+			if err := q.save(msg); err != nil {
+				return nil, err
+			}
+		}
+		heap.Push(&q.due, dueEntry{id: msg.ID, notBefore: msg.NotBefore, createdAt: msg.CreatedAt})
+	}
+	queuedMessages.Set(float64(len(q.due)))
+	if len(pending) > 0 {
+		zap.L().Info("Resumed pending messages", zap.Int("count", len(pending)))
+	}
+	return q, nil
+}
+
+// Enqueue stores the email and schedules it for the delivery at its send_at,
+// or immediately when it is not set
+func (q *Queue) Enqueue(req *pb.EmailRequest) (*Message, error) {
+	return q.EnqueueFor("", req)
+}
+
+// EnqueueFor stores the email sent by the owner, see Enqueue
+func (q *Queue) EnqueueFor(owner string, req *pb.EmailRequest) (*Message, error) {
+	now := q.now()
+	sendAt, err := scheduledTime(req.GetSendAt(), now)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := proto.Marshal(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not marshal email: %v", err)
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not generate message id: %v", err)
+	}
+	msg := &Message{
+		ID:        id,
+		Request:   raw,
+		State:     MessageQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+		NotBefore: now,
+		From:      req.GetFrom().GetEmail(),
+		Tags:      req.GetTags(),
+		Owner:     owner,
+	}
+	if !sendAt.IsZero() {
+		msg.NotBefore = sendAt
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, status.Error(codes.Unavailable, "email queue is closed")
+	}
+	if err := q.save(msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not store email: %v", err)
+	}
+	q.push(msg)
+	q.publish(msg)
+	return msg, nil
+}
+
+// Get returns the message by its id
+func (q *Queue) Get(id string) (*Message, error) {
+	value, ok := q.store.Get(id)
+	if !ok || strings.HasPrefix(id, providerIndexPrefix) {
+		return nil, status.Errorf(codes.NotFound, "message %q not found", id)
+	}
+	msg := &Message{}
+	if err := json.Unmarshal(value, msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not decode message %q: %v", id, err)
+	}
+	return msg, nil
+}
+
+// GetFor returns the message sent by the owner, the messages of other callers
+// are not found
+func (q *Queue) GetFor(owner, id string) (*Message, error) {
+	msg, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Owner != owner {
+		return nil, status.Errorf(codes.NotFound, "message %q not found", id)
+	}
+	return msg, nil
+}
+
+// Next waits for the message which is due for the delivery and marks it as being sent
+func (q *Queue) Next(ctx context.Context) (*Message, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, errQueueClosed
+		}
+		changed := q.changed
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if len(q.due) > 0 {
+			top := q.due[0]
+			if delay := top.notBefore.Sub(q.now()); delay > 0 {
+				timer = time.NewTimer(delay)
+				wait = timer.C
+			} else {
+				heap.Pop(&q.due)
+				queuedMessages.Set(float64(len(q.due)))
+				msg, err := q.start(top.id)
+				q.mu.Unlock()
+				if err != nil {
+					zap.L().Error("Can not start delivery of message", zap.String("message_id", top.id), zap.Error(err))
+					continue
+				}
+				return msg, nil
+			}
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, ctx.Err()
+		case <-changed:
+		case <-wait:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// Complete records the successful delivery. The content of the email is not needed anymore.
+func (q *Queue) Complete(msg *Message, receipt *Receipt) error {
+	msg.State = MessageSent
+	msg.UpdatedAt = q.now()
+	msg.LastError = ""
+	msg.Request = nil
+	msg.Provider = receipt.Provider
+	msg.ProviderMessageID = receipt.MessageID
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.save(msg); err != nil {
+		return err
+	}
+	if msg.ProviderMessageID != "" {
+		// The events of the provider identify the message by its own id
+		if err := q.store.Put(providerIndexPrefix+msg.ProviderMessageID, []byte(msg.ID)); err != nil {
+			return err
+		}
+	}
+	q.publish(msg)
+	return nil
+}
+
+// Track records the state of the sent message reported by the provider, which
+// identifies the message by the id it assigned. The state which is not newer
+// than the current one is ignored.
+func (q *Queue) Track(providerMessageID string, state MessageState, detail string) (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	id, ok := q.store.Get(providerIndexPrefix + providerMessageID)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "message sent as %q not found", providerMessageID)
+	}
+	msg, err := q.Get(string(id))
+	if err != nil {
+		return nil, err
+	}
+	current, ok := trackedStates[msg.State]
+	if !ok || trackedStates[state] <= current {
+		return msg, nil
+	}
+	msg.State = state
+	msg.UpdatedAt = q.now()
+	msg.LastError = detail
+	if err := q.save(msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not store email: %v", err)
+	}
+	q.publish(msg)
+	return msg, nil
+}
+
+// Defer puts the message which failed temporarily back into the queue
+func (q *Queue) Defer(msg *Message, sendErr error, notBefore time.Time) error {
+	msg.State = MessageDeferred
+	msg.UpdatedAt = q.now()
+	msg.LastError = sendErr.Error()
+	msg.Provider = failedProvider(sendErr)
+	msg.NotBefore = notBefore
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.save(msg); err != nil {
+		return err
+	}
+	q.push(msg)
+	q.publish(msg)
+	return nil
+}
+
+// Fail records that the message could not be delivered and moves it to the
+// dead letters, from which it can be requeued
+func (q *Queue) Fail(msg *Message, sendErr error) error {
+	msg.State = MessageFailed
+	msg.UpdatedAt = q.now()
+	msg.LastError = sendErr.Error()
+	msg.Provider = failedProvider(sendErr)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.deadLetters != nil {
+		if err := q.deadLetters.add(msg); err != nil {
+			return err
+		}
+	}
+	if err := q.save(msg.withoutRequest()); err != nil {
+		return err
+	}
+	q.publish(msg)
+	return nil
+}
+
+// Requeue moves the dead-lettered message back to the queue. The attempts
+// and the age start from zero, so the message gets the full retry policy again.
+func (q *Queue) Requeue(id string) (*Message, error) {
+	if q.deadLetters == nil {
+		return nil, status.Errorf(codes.NotFound, "dead letter %q not found", id)
+	}
+	msg, err := q.deadLetters.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := q.now()
+	msg.State = MessageQueued
+	msg.UpdatedAt = now
+	msg.NotBefore = now
+	msg.Attempts = 0
+	msg.FirstAttemptAt = time.Time{}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, status.Error(codes.Unavailable, "email queue is closed")
+	}
+	if err := q.save(msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not store email: %v", err)
+	}
+	if err := q.deadLetters.remove(id); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not remove dead letter: %v", err)
+	}
+	q.push(msg)
+	q.publish(msg)
+	return msg, nil
+}
+
+// Cancel stops the delivery of the message which waits in the queue. The
+// message which is being sent or was already delivered can not be canceled.
+func (q *Queue) Cancel(owner, id string) (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msg, err := q.waiting(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	msg.State = MessageCanceled
+	msg.UpdatedAt = q.now()
+	msg.Request = nil
+	if err := q.save(msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not store email: %v", err)
+	}
+	q.due.remove(id)
+	queuedMessages.Set(float64(len(q.due)))
+	q.publish(msg)
+	return msg, nil
+}
+
+// Reschedule changes the time of the delivery of the message which waits in
+// the queue. The message is sent immediately when the time already passed.
+func (q *Queue) Reschedule(owner, id string, sendAt time.Time) (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msg, err := q.waiting(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	now := q.now()
+	msg.UpdatedAt = now
+	msg.NotBefore = now
+	if sendAt.After(now) {
+		msg.NotBefore = sendAt
+	}
+	if err := q.save(msg); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not store email: %v", err)
+	}
+	q.due.remove(id)
+	q.push(msg)
+	q.publish(msg)
+	return msg, nil
+}
+
+// Events returns the broadcaster of the lifecycle events of messages
+func (q *Queue) Events() *Events {
+	return q.events
+}
+
+// DeadLetters returns the store of messages which ran out of retries
+func (q *Queue) DeadLetters() *DeadLetters {
+	return q.deadLetters
+}
+
+// Close wakes up all workers waiting for messages and stops removing the
+// finished messages
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.changed)
+	stop := q.stop
+	q.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		q.wg.Wait()
+	}
+}
+
+// StartRetention removes the messages which finished longer than the
+// retention ago, together with their provider index keys. The dead letters
+// are kept until they are requeued or removed.
+func (q *Queue) StartRetention(retention time.Duration) {
+	interval := retention / 10
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	q.mu.Lock()
+	q.stop = make(chan struct{})
+	stop := q.stop
+	q.mu.Unlock()
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				removed, err := q.expire(q.now().Add(-retention))
+				if err != nil {
+					zap.L().Error("Can not remove finished messages", zap.Error(err))
+				}
+				if removed > 0 {
+					zap.L().Info("Removed finished messages", zap.Int("count", removed))
+				}
+			}
+		}
+	}()
+}
+
+// expire removes the messages which finished before the time
+func (q *Queue) expire(before time.Time) (int, error) {
+	var finished []string
+	q.store.Range("", func(key string, value []byte) bool {
+		if strings.HasPrefix(key, providerIndexPrefix) {
+			return true
+		}
+		msg := &Message{}
+		if err := json.Unmarshal(value, msg); err == nil && finishedStates[msg.State] && msg.UpdatedAt.Before(before) {
+			finished = append(finished, msg.ID)
+		}
+		return true
+	})
+
+	removed := 0
+	for _, id := range finished {
+		ok, err := q.remove(id, before)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// remove deletes the message unless it changed since it was found, e.g. it
+// was requeued from the dead letters
+func (q *Queue) remove(id string, before time.Time) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msg, err := q.Get(id)
+	if err != nil {
+		return false, nil
+	}
+	if !finishedStates[msg.State] || !msg.UpdatedAt.Before(before) {
+		return false, nil
+	}
+	if msg.ProviderMessageID != "" {
+		key := providerIndexPrefix + msg.ProviderMessageID
+		if indexed, ok := q.store.Get(key); ok && string(indexed) == id {
+			if err := q.store.Delete(key); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, q.store.Delete(id)
+}
+
+// start must be called with the lock held
+func (q *Queue) start(id string) (*Message, error) {
+	msg, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	msg.State = MessageSending
+	msg.Attempts++
+	msg.UpdatedAt = q.now()
+	if msg.FirstAttemptAt.IsZero() {
+		msg.FirstAttemptAt = msg.UpdatedAt
+	}
+	if err := q.save(msg); err != nil {
+		return nil, err
+	}
+	q.publish(msg)
+	return msg, nil
+}
+
+// waiting returns the message of the owner which did not leave the queue yet,
+// it must be called with the lock held
+func (q *Queue) waiting(owner, id string) (*Message, error) {
+	if q.closed {
+		return nil, status.Error(codes.Unavailable, "email queue is closed")
+	}
+	msg, err := q.GetFor(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg.State != MessageQueued && msg.State != MessageDeferred {
+		return nil, status.Errorf(codes.FailedPrecondition, "message %q is %s and does not wait in the queue", id, msg.State)
+	}
+	return msg, nil
+}
+
+// push must be called with the lock held
+func (q *Queue) push(msg *Message) {
+	heap.Push(&q.due, dueEntry{id: msg.ID, notBefore: msg.NotBefore, createdAt: msg.CreatedAt})
+	queuedMessages.Set(float64(len(q.due)))
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// publish announces the current state of the message
+func (q *Queue) publish(msg *Message) {
+	q.events.PublishFor(msg.Owner, messageEvent(msg, messageEventTypes[msg.State]))
+}
+
+func (q *Queue) save(msg *Message) error {
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return q.store.Put(msg.ID, value)
+}
+
+// newID generates the random message id
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type dueEntry struct {
+	id        string
+	notBefore time.Time
+	createdAt time.Time
+}
+
+// dueHeap orders messages by the time they are due and then by the time they were accepted
+type dueHeap []dueEntry
+
+func (h dueHeap) Len() int { return len(h) }
+
+func (h dueHeap) Less(i, j int) bool {
+	if !h[i].notBefore.Equal(h[j].notBefore) {
+		return h[i].notBefore.Before(h[j].notBefore)
+	}
+	return h[i].createdAt.Before(h[j].createdAt)
+}
+
+func (h dueHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *dueHeap) Push(x interface{}) { *h = append(*h, x.(dueEntry)) }
+
+func (h *dueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// remove drops the entry of the message, the scheduled messages are rarely
+// canceled, so the linear search is good enough
+func (h *dueHeap) remove(id string) {
+	for i, e := range *h {
+		if e.id == id {
+			heap.Remove(h, i)
+			return
+		}
+	}
+}
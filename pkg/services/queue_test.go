@@ -0,0 +1,373 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestQueue(t *testing.T) *Queue {
+	return openTestQueue(t, testDir(t, "queue"))
+}
+
+// openTestQueue opens the queue kept in the directory, e.g. again after the restart
+func openTestQueue(t *testing.T, dir string) *Queue {
+	store := openTestStoreAt(t, filepath.Join(dir, "queue"))
+	deadLetterStore := openTestStoreAt(t, filepath.Join(dir, "deadletters"))
+	q, err := NewQueue(store, NewDeadLetters(deadLetterStore))
+	if err != nil {
+		t.Fatalf("Queue should be created: %v", err)
+	}
+	return q
+}
+
+// waitForState polls the message until it reaches the state
+func waitForState(t *testing.T, q *Queue, id string, state MessageState) *Message {
+	var msg *Message
+	for i := 0; i < 100; i++ {
+		msg, _ = q.Get(id)
+		if msg.State == state {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return msg
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Enqueued message is returned by Next", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+
+		// Act
+		queued, err := q.Enqueue(validRequest())
+		assert.NoError(t, err, "Error should not occur")
+		msg, err := q.Next(context.Background())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, queued.ID, msg.ID, "Queued message must be returned")
+		assert.Equal(t, MessageSending, msg.State, "Message must be marked as being sent")
+		assert.Equal(t, 1, msg.Attempts, "Attempt must be counted")
+		req, err := msg.EmailRequest()
+		assert.NoError(t, err, "Request should be unmarshalled")
+		assert.Equal(t, validRequest().Subject, req.Subject, "Request must be stored")
+	})
+
+	t.Run("Messages are returned in order of acceptance", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		first, _ := q.Enqueue(validRequest())
+		second, _ := q.Enqueue(validRequest())
+
+		// Act
+		a, _ := q.Next(context.Background())
+		b, _ := q.Next(context.Background())
+
+		// Assert
+		assert.Equal(t, first.ID, a.ID, "First message must be returned first")
+		assert.Equal(t, second.ID, b.ID, "Second message must be returned second")
+	})
+
+	t.Run("Next waits for the message", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// Act
+		_, err := q.Next(ctx)
+
+		// Assert
+		assert.Equal(t, context.DeadlineExceeded, err, "Next must wait until context is done")
+	})
+
+	t.Run("Pending messages are resumed after restart", func(t *testing.T) {
+		// Arrange
+		dir := testDir(t, "queue")
+		q := openTestQueue(t, dir)
+		interrupted, _ := q.Enqueue(validRequest())
+		waiting, _ := q.Enqueue(validRequest())
+		sent, _ := q.Enqueue(validRequest())
+		_, _ = q.Next(context.Background())
+		_, _ = q.Next(context.Background())
+		last, _ := q.Next(context.Background())
+		assert.NoError(t, q.Complete(last, &Receipt{Provider: "fake", MessageID: "id"}), "Complete should succeed")
+		// interrupted stays in sending state, waiting is moved back to queued state
+		waitingMsg, _ := q.Get(waiting.ID)
+		waitingMsg.State = MessageQueued
+		assert.NoError(t, q.save(waitingMsg), "Save should succeed")
+		assert.NoError(t, q.store.Close(), "Store should be closed")
+		assert.NoError(t, q.deadLetters.store.Close(), "Store should be closed")
+
+		// Act
+		q = openTestQueue(t, dir)
+		a, _ := q.Next(context.Background())
+		b, _ := q.Next(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := q.Next(ctx)
+
+		// Assert
+		assert.ElementsMatch(t, []string{interrupted.ID, waiting.ID}, []string{a.ID, b.ID}, "Pending messages must be resumed")
+		assert.Equal(t, context.DeadlineExceeded, err, "Sent message must not be resumed")
+		msg, err := q.Get(sent.ID)
+		assert.NoError(t, err, "Sent message must be kept")
+		assert.Equal(t, MessageSent, msg.State, "Sent message must keep its state")
+		assert.Equal(t, "fake", msg.Provider, "Provider must be stored")
+		assert.Empty(t, msg.Request, "Request must be dropped after delivery")
+	})
+
+	t.Run("Failed message is dead-lettered", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(validRequest())
+		msg, _ := q.Next(context.Background())
+
+		// Act
+		err := q.Fail(msg, &ProviderError{Provider: "fake", Err: status.Error(codes.InvalidArgument, "bad address")})
+
+		// Assert
+		assert.NoError(t, err, "Fail should succeed")
+		stored, _ := q.Get(queued.ID)
+		assert.Equal(t, MessageFailed, stored.State, "Message must be failed")
+		assert.Contains(t, stored.LastError, "bad address", "Error must be stored")
+		assert.Equal(t, "fake", stored.Provider, "Failed provider must be stored")
+		assert.Empty(t, stored.Request, "Content must be kept only in dead letters")
+		deadLetter, err := q.DeadLetters().Get(queued.ID)
+		assert.NoError(t, err, "Message must be dead-lettered")
+		assert.NotEmpty(t, deadLetter.Request, "Content must be kept in dead letters")
+	})
+
+	t.Run("Deferred message is returned when due", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(validRequest())
+		msg, _ := q.Next(context.Background())
+		due := time.Now().Add(50 * time.Millisecond)
+
+		// Act
+		err := q.Defer(msg, status.Error(codes.Unavailable, "down"), due)
+		deferred, _ := q.Get(queued.ID)
+		next, nextErr := q.Next(context.Background())
+
+		// Assert
+		assert.NoError(t, err, "Defer should succeed")
+		assert.Equal(t, MessageDeferred, deferred.State, "Message must be deferred")
+		assert.NoError(t, nextErr, "Error should not occur")
+		assert.Equal(t, queued.ID, next.ID, "Deferred message must be returned")
+		assert.False(t, time.Now().Before(due), "Deferred message must not be returned before it is due")
+		assert.Equal(t, 2, next.Attempts, "Second attempt must be counted")
+	})
+
+	t.Run("Dead-lettered message is requeued", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(validRequest())
+		msg, _ := q.Next(context.Background())
+		assert.NoError(t, q.Fail(msg, status.Error(codes.Unavailable, "down")), "Fail should succeed")
+
+		// Act
+		requeued, err := q.Requeue(queued.ID)
+		next, nextErr := q.Next(context.Background())
+
+		// Assert
+		assert.NoError(t, err, "Requeue should succeed")
+		assert.Equal(t, MessageQueued, requeued.State, "Message must be queued")
+		assert.NoError(t, nextErr, "Error should not occur")
+		assert.Equal(t, queued.ID, next.ID, "Requeued message must be returned")
+		assert.Equal(t, 1, next.Attempts, "Attempts must start from zero")
+		_, err = q.DeadLetters().Get(queued.ID)
+		assert.Equal(t, codes.NotFound, status.Code(err), "Message must be removed from dead letters")
+	})
+
+	t.Run("Requeued message older than the max age is retried", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(validRequest())
+		msg, _ := q.Next(context.Background())
+		assert.NoError(t, q.Fail(msg, status.Error(codes.Unavailable, "down")), "Fail should succeed")
+		q.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+		policy := RetryPolicy{}.withDefaults()
+
+		// Act
+		_, err := q.Requeue(queued.ID)
+		next, nextErr := q.Next(context.Background())
+		_, retried := policy.nextAttempt(next, q.now(), rand.New(rand.NewSource(1)))
+
+		// Assert
+		assert.NoError(t, err, "Requeue should succeed")
+		assert.NoError(t, nextErr, "Error should not occur")
+		assert.True(t, retried, "Requeued message must get the full retry policy again")
+	})
+
+	t.Run("Finished messages are removed after the retention", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		now := time.Now()
+		q.now = func() time.Time { return now }
+		sent := sentTestMessage(t, q, "provider-id")
+		canceled, err := q.Enqueue(validRequest())
+		assert.NoError(t, err, "Message should be queued")
+		_, err = q.Cancel("", canceled.ID)
+		assert.NoError(t, err, "Message should be canceled")
+		now = now.Add(2 * time.Hour)
+		recent := sentTestMessage(t, q, "recent-id")
+		pending, err := q.Enqueue(validRequest())
+		assert.NoError(t, err, "Message should be queued")
+
+		// Act
+		removed, err := q.expire(now.Add(-time.Hour))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, 2, removed, "Finished messages must be removed")
+		for _, id := range []string{sent.ID, canceled.ID} {
+			_, err = q.Get(id)
+			assert.Equal(t, codes.NotFound, status.Code(err), "Message %s must be removed", id)
+		}
+		_, err = q.Track("provider-id", MessageDelivered, "")
+		assert.Equal(t, codes.NotFound, status.Code(err), "Provider index must be removed")
+		for _, id := range []string{pending.ID, recent.ID} {
+			_, err = q.Get(id)
+			assert.NoError(t, err, "Message %s must be kept", id)
+		}
+		_, err = q.Track("recent-id", MessageDelivered, "")
+		assert.NoError(t, err, "Provider index of the kept message must be kept")
+	})
+
+	t.Run("Unknown message", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+
+		// Act
+		_, err := q.Get("missing")
+
+		// Assert
+		assert.Equal(t, codes.NotFound, status.Code(err), "Not found must be returned")
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("Email is delivered", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		provider := &fakeProvider{name: "fake"}
+		d := NewDispatcher(q, provider, DispatcherConfig{})
+		queued, err := q.Enqueue(validRequest())
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		d.Start()
+		msg := waitForState(t, q, queued.ID, MessageSent)
+		d.Stop()
+
+		// Assert
+		assert.Equal(t, MessageSent, msg.State, "Message must be delivered")
+		assert.Equal(t, "fake", msg.Provider, "Provider must be stored")
+		assert.Equal(t, "id-fake", msg.ProviderMessageID, "Provider message id must be stored")
+		assert.Len(t, provider.requests, 1, "Provider must receive the email")
+	})
+
+	t.Run("Temporary failure is deferred by provider policy", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		b, err := NewBalancer(WeightedRoundRobin, WeightedProvider{
+			Provider: &fakeProvider{name: "fake", err: status.Error(codes.Unavailable, "down")},
+		})
+		assert.NoError(t, err, "Balancer should be created")
+		d := NewDispatcher(q, b, DispatcherConfig{
+			Retry:         RetryPolicy{InitialBackoff: time.Millisecond},
+			ProviderRetry: map[string]RetryPolicy{"fake": {InitialBackoff: time.Hour}},
+		})
+		queued, _ := q.Enqueue(validRequest())
+
+		// Act
+		d.Start()
+		msg := waitForState(t, q, queued.ID, MessageDeferred)
+		d.Stop()
+
+		// Assert
+		assert.Equal(t, MessageDeferred, msg.State, "Message must be deferred")
+		assert.Equal(t, "fake", msg.Provider, "Failed provider must be stored")
+		assert.True(t, msg.NotBefore.After(time.Now().Add(30*time.Minute)), "Provider policy must be used")
+	})
+
+	t.Run("Permanent failure is dead-lettered", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		provider := &fakeProvider{name: "fake", err: status.Error(codes.InvalidArgument, "bad address")}
+		d := NewDispatcher(q, provider, DispatcherConfig{})
+		queued, _ := q.Enqueue(validRequest())
+
+		// Act
+		d.Start()
+		msg := waitForState(t, q, queued.ID, MessageFailed)
+		d.Stop()
+
+		// Assert
+		assert.Equal(t, MessageFailed, msg.State, "Message must be failed")
+		assert.Equal(t, 1, msg.Attempts, "Message must not be retried")
+		_, err := q.DeadLetters().Get(queued.ID)
+		assert.NoError(t, err, "Message must be dead-lettered")
+	})
+
+	t.Run("Message runs out of attempts", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		provider := &fakeProvider{name: "fake", err: status.Error(codes.Unavailable, "down")}
+		d := NewDispatcher(q, provider, DispatcherConfig{
+			Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		})
+		queued, _ := q.Enqueue(validRequest())
+
+		// Act
+		d.Start()
+		msg := waitForState(t, q, queued.ID, MessageFailed)
+		d.Stop()
+
+		// Assert
+		assert.Equal(t, MessageFailed, msg.State, "Message must be failed")
+		assert.Equal(t, 3, msg.Attempts, "All attempts must be used")
+		assert.Len(t, provider.requests, 3, "Provider must be called on every attempt")
+	})
+
+	t.Run("Message scheduled beyond the max age is retried", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		provider := &fakeProvider{name: "fake", err: status.Error(codes.Unavailable, "down")}
+		d := NewDispatcher(q, provider, DispatcherConfig{})
+		queued, err := q.Enqueue(scheduledRequest(time.Now().Add(48 * time.Hour)))
+		assert.NoError(t, err, "Error should not occur")
+		q.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+
+		// Act
+		d.Start()
+		msg := waitForState(t, q, queued.ID, MessageDeferred)
+		d.Stop()
+
+		// Assert
+		assert.Equal(t, MessageDeferred, msg.State, "Message must be deferred, not dead-lettered")
+		assert.Equal(t, 1, msg.Attempts, "First attempt must be counted")
+		assert.False(t, msg.FirstAttemptAt.IsZero(), "First attempt must be recorded")
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/dkim"
+	"github.com/RafalKorepta/coding-challenge/pkg/mime"
+)
+
+// buildRawMessage creates the MIME message for providers that accept raw emails.
+// The Bcc recipients are not part of the message, they must be passed to the
+// provider as envelope recipients returned by the message Recipients. The raw
+// message is signed when the keyring has the key of the sender domain.
+func buildRawMessage(req *pb.EmailRequest, now time.Time, keyring *dkim.Keyring) (*mime.Message, []byte, error) {
+	messageID, err := mime.NewMessageID(mime.Domain(req.GetFrom().GetEmail()))
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &mime.Message{
+		From:      mimeAddress(req.GetFrom()),
+		To:        mimeAddressList(req.GetTo()),
+		Cc:        mimeAddressList(req.GetCc()),
+		Bcc:       mimeAddressList(req.GetBcc()),
+		Subject:   req.GetSubject(),
+		Header:    req.GetHeaders(),
+		Text:      req.GetTextBody(),
+		HTML:      req.GetHtmlBody(),
+		Date:      now,
+		MessageID: messageID,
+	}
+	for _, a := range req.GetAttachments() {
+		msg.Attachments = append(msg.Attachments, mime.Attachment{
+			Filename:    a.GetFilename(),
+			ContentType: attachmentContentType(a),
+			Content:     a.GetContent(),
+			Inline:      a.GetDisposition() == pb.Attachment_INLINE,
+			ContentID:   a.GetContentId(),
+		})
+	}
+	if req.GetReplyTo() != nil {
+		replyTo := mimeAddress(req.GetReplyTo())
+		msg.ReplyTo = &replyTo
+	}
+	raw, err := msg.Bytes()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to build raw message: %v", err)
+	}
+	if keyring != nil {
+		if raw, err = keyring.Sign(mime.Domain(msg.From.Email), raw); err != nil {
+			return nil, nil, err
+		}
+	}
+	return msg, raw, nil
+}
+
+func mimeAddress(a *pb.Address) mime.Address {
+	return mime.Address{Name: a.GetName(), Email: a.GetEmail()}
+}
+
+func mimeAddressList(addrs []*pb.Address) []mime.Address {
+	list := make([]mime.Address, 0, len(addrs))
+	for _, a := range addrs {
+		list = append(list, mimeAddress(a))
+	}
+	return list
+}
@@ -0,0 +1,83 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides when the email which failed temporarily is sent again.
+// Zero values are replaced with defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the number of deliveries after which the email is dead-lettered, default 5
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// InitialBackoff is the delay before the second attempt, default 30s
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	// MaxBackoff caps the delay between attempts, default 1h
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+	// Multiplier grows the delay after every attempt, default 2
+	Multiplier float64 `mapstructure:"multiplier"`
+	// Jitter is the fraction of the delay randomly added or subtracted, default 0.2
+	Jitter float64 `mapstructure:"jitter"`
+	// MaxAge is the time since the first delivery attempt after which the email
+	// is dead-lettered, default 24h
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 30 * time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Hour
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter <= 0 || p.Jitter > 1 {
+		p.Jitter = 0.2
+	}
+	if p.MaxAge <= 0 {
+		p.MaxAge = 24 * time.Hour
+	}
+	return p
+}
+
+// backoff returns the delay after the given attempt, the first attempt is 1
+func (p RetryPolicy) backoff(attempt int, rnd *rand.Rand) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	delay *= 1 + p.Jitter*(2*rnd.Float64()-1)
+	return time.Duration(delay)
+}
+
+// nextAttempt returns when the message should be sent again, false means the
+// message ran out of retries
+func (p RetryPolicy) nextAttempt(msg *Message, now time.Time, rnd *rand.Rand) (time.Time, bool) {
+	if msg.Attempts >= p.MaxAttempts {
+		return time.Time{}, false
+	}
+	next := now.Add(p.backoff(msg.Attempts, rnd))
+	if next.Sub(msg.retriedSince()) > p.MaxAge {
+		return time.Time{}, false
+	}
+	return next, true
+}
@@ -0,0 +1,93 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	// Arrange
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.1,
+	}.withDefaults()
+	rnd := rand.New(rand.NewSource(1))
+
+	for attempt, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		// Act
+		delay := policy.backoff(attempt, rnd)
+
+		// Assert
+		assert.InDelta(t, float64(expected), float64(delay), float64(expected)/10,
+			"Delay after attempt %d must be around %v", attempt, expected)
+	}
+}
+
+func TestRetryPolicy_NextAttempt(t *testing.T) {
+	// Arrange
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxAge: time.Hour}.withDefaults()
+	rnd := rand.New(rand.NewSource(1))
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Attempt is left", func(t *testing.T) {
+		// Act
+		next, ok := policy.nextAttempt(&Message{Attempts: 1, CreatedAt: now}, now, rnd)
+
+		// Assert
+		assert.True(t, ok, "Message must be retried")
+		assert.True(t, next.After(now), "Next attempt must be in the future")
+	})
+
+	t.Run("Attempts are used", func(t *testing.T) {
+		// Act
+		_, ok := policy.nextAttempt(&Message{Attempts: 3, CreatedAt: now}, now, rnd)
+
+		// Assert
+		assert.False(t, ok, "Message must not be retried")
+	})
+
+	t.Run("Message is too old", func(t *testing.T) {
+		// Act
+		_, ok := policy.nextAttempt(&Message{Attempts: 1, CreatedAt: now.Add(-time.Hour)}, now, rnd)
+
+		// Assert
+		assert.False(t, ok, "Message must not be retried")
+	})
+
+	t.Run("Age is measured from the first attempt", func(t *testing.T) {
+		// Act
+		_, ok := policy.nextAttempt(&Message{
+			Attempts:       1,
+			CreatedAt:      now.Add(-48 * time.Hour),
+			FirstAttemptAt: now,
+		}, now, rnd)
+
+		// Assert
+		assert.True(t, ok, "Message scheduled long ago must be retried")
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxScheduleAhead limits how far in the future the email can be scheduled,
+// so the queue does not keep forgotten messages forever
+const maxScheduleAhead = maxScheduleDays * 24 * time.Hour
+
+const maxScheduleDays = 30
+
+// scheduledTime converts the send_at of the request. The zero time is returned
+// when the email should be sent immediately.
+func scheduledTime(sendAt *timestamp.Timestamp, now time.Time) (time.Time, error) {
+	if sendAt == nil {
+		return time.Time{}, nil
+	}
+	t, err := ptypes.Timestamp(sendAt)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid send_at: %v", err)
+	}
+	if t.After(now.Add(maxScheduleAhead)) {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "send_at can be at most %d days ahead", maxScheduleDays)
+	}
+	if !t.After(now) {
+		return time.Time{}, nil
+	}
+	return t, nil
+}
@@ -0,0 +1,227 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/auth"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func scheduledRequest(sendAt time.Time) *pb.EmailRequest {
+	req := validRequest()
+	req.SendAt, _ = ptypes.TimestampProto(sendAt)
+	return req
+}
+
+// nextWithin returns the message which is due within the timeout
+func nextWithin(q *Queue, timeout time.Duration) (*Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.Next(ctx)
+}
+
+func TestQueue_Schedule(t *testing.T) {
+	t.Run("Scheduled message is not returned before send_at", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		sendAt := time.Now().Add(100 * time.Millisecond)
+		later, _ := q.Enqueue(scheduledRequest(sendAt))
+		now, _ := q.Enqueue(validRequest())
+
+		// Act
+		first, _ := nextWithin(q, time.Second)
+		second, _ := nextWithin(q, time.Second)
+
+		// Assert
+		assert.Equal(t, now.ID, first.ID, "Message without send_at must be sent first")
+		assert.Equal(t, later.ID, second.ID, "Scheduled message must be sent")
+		assert.False(t, time.Now().Before(sendAt), "Scheduled message must not be sent before send_at")
+	})
+
+	t.Run("Scheduled message survives restart", func(t *testing.T) {
+		// Arrange
+		dir := testDir(t, "queue")
+		q := openTestQueue(t, dir)
+		sendAt := time.Now().Add(time.Hour)
+		queued, _ := q.Enqueue(scheduledRequest(sendAt))
+		assert.NoError(t, q.store.Close(), "Store should be closed")
+		assert.NoError(t, q.deadLetters.store.Close(), "Store should be closed")
+
+		// Act
+		q = openTestQueue(t, dir)
+		_, err := nextWithin(q, 50*time.Millisecond)
+
+		// Assert
+		assert.Equal(t, context.DeadlineExceeded, err, "Scheduled message must wait after restart")
+		msg, _ := q.Get(queued.ID)
+		assert.True(t, msg.NotBefore.Equal(sendAt), "Time of the delivery must be kept")
+		assert.Len(t, q.due, 1, "Scheduled message must be resumed")
+	})
+
+	t.Run("Too distant send_at is rejected", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+
+		// Act
+		_, err := q.Enqueue(scheduledRequest(time.Now().Add(31 * 24 * time.Hour)))
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Message must be rejected")
+	})
+
+	t.Run("Canceled message is not sent", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(scheduledRequest(time.Now().Add(50 * time.Millisecond)))
+
+		// Act
+		canceled, err := q.Cancel("", queued.ID)
+		_, nextErr := nextWithin(q, 100*time.Millisecond)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, MessageCanceled, canceled.State, "Message must be canceled")
+		assert.Equal(t, context.DeadlineExceeded, nextErr, "Canceled message must not be sent")
+		stored, _ := q.Get(queued.ID)
+		assert.Equal(t, MessageCanceled, stored.State, "Cancellation must be stored")
+		assert.Empty(t, stored.Request, "Content of canceled message must be dropped")
+	})
+
+	t.Run("Message which left the queue can not be canceled", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(validRequest())
+		_, _ = q.Next(context.Background())
+
+		// Act
+		_, err := q.Cancel("", queued.ID)
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Message being sent must not be canceled")
+	})
+
+	t.Run("Rescheduled message is sent at the new time", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		queued, _ := q.Enqueue(scheduledRequest(time.Now().Add(time.Hour)))
+
+		// Act
+		rescheduled, err := q.Reschedule("", queued.ID, time.Now().Add(50*time.Millisecond))
+		next, nextErr := nextWithin(q, time.Second)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, MessageQueued, rescheduled.State, "Message must stay queued")
+		assert.NoError(t, nextErr, "Rescheduled message must be sent")
+		assert.Equal(t, queued.ID, next.ID, "Rescheduled message must be sent")
+		assert.Len(t, q.due, 0, "Previous schedule must be removed")
+	})
+}
+
+func TestEmailService_Schedule(t *testing.T) {
+	t.Run("Scheduled email requires the queue", func(t *testing.T) {
+		// Arrange
+		es := NewEmailService(WithProvider(&fakeProvider{name: "fake"}))
+
+		// Act
+		_, err := es.SendMail(context.Background(), scheduledRequest(time.Now().Add(time.Hour)))
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Scheduled delivery must be rejected")
+	})
+
+	t.Run("Scheduled email is canceled", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		es := NewEmailService(WithQueue(q))
+		resp, err := es.SendMail(context.Background(), scheduledRequest(time.Now().Add(time.Hour)))
+		assert.NoError(t, err, "Email should be queued")
+
+		// Act
+		msg, err := es.CancelMessage(context.Background(), &pb.CancelMessageRequest{Id: resp.MessageId})
+		_, againErr := es.CancelMessage(context.Background(), &pb.CancelMessageRequest{Id: resp.MessageId})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, pb.Message_CANCELED, msg.State, "Message must be canceled")
+		assert.Nil(t, msg.NextAttemptAt, "Canceled message has no next attempt")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(againErr), "Canceled message must not be canceled again")
+	})
+
+	t.Run("Scheduled email is rescheduled", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		es := NewEmailService(WithQueue(q))
+		resp, err := es.SendMail(context.Background(), scheduledRequest(time.Now().Add(time.Hour)))
+		assert.NoError(t, err, "Email should be queued")
+		sendAt, _ := ptypes.TimestampProto(time.Now().Add(2 * time.Hour).Truncate(time.Second))
+
+		// Act
+		msg, err := es.RescheduleMessage(context.Background(), &pb.RescheduleMessageRequest{Id: resp.MessageId, SendAt: sendAt})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, sendAt, msg.NextAttemptAt, "New time must be returned")
+	})
+
+	t.Run("Messages of other callers are not found", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		es := NewEmailService(WithQueue(q))
+		alice := auth.NewContext(context.Background(), &auth.Identity{Method: auth.MethodKey, ID: "alice"})
+		bob := auth.NewContext(context.Background(), &auth.Identity{Method: auth.MethodKey, ID: "bob"})
+		resp, err := es.SendMail(alice, scheduledRequest(time.Now().Add(time.Hour)))
+		assert.NoError(t, err, "Email should be queued")
+		sendAt, _ := ptypes.TimestampProto(time.Now().Add(2 * time.Hour))
+
+		// Act
+		_, getErr := es.GetMessage(bob, &pb.GetMessageRequest{Id: resp.MessageId})
+		_, cancelErr := es.CancelMessage(bob, &pb.CancelMessageRequest{Id: resp.MessageId})
+		_, rescheduleErr := es.RescheduleMessage(bob, &pb.RescheduleMessageRequest{Id: resp.MessageId, SendAt: sendAt})
+		_, anonymousErr := es.GetMessage(context.Background(), &pb.GetMessageRequest{Id: resp.MessageId})
+		msg, err := es.GetMessage(alice, &pb.GetMessageRequest{Id: resp.MessageId})
+
+		// Assert
+		assert.Equal(t, codes.NotFound, status.Code(getErr), "Message of other caller must not be found")
+		assert.Equal(t, codes.NotFound, status.Code(cancelErr), "Message of other caller must not be canceled")
+		assert.Equal(t, codes.NotFound, status.Code(rescheduleErr), "Message of other caller must not be rescheduled")
+		assert.Equal(t, codes.NotFound, status.Code(anonymousErr), "Message must not be found without the identity")
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, pb.Message_QUEUED, msg.State, "Message of the owner must be left in the queue")
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		// Arrange
+		es := NewEmailService()
+		sendAt, _ := ptypes.TimestampProto(time.Now().Add(time.Hour))
+
+		// Act
+		_, cancelErr := es.CancelMessage(context.Background(), &pb.CancelMessageRequest{})
+		_, missingErr := es.RescheduleMessage(context.Background(), &pb.RescheduleMessageRequest{Id: "id"})
+		_, unknownErr := es.RescheduleMessage(context.Background(), &pb.RescheduleMessageRequest{Id: "id", SendAt: sendAt})
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(cancelErr), "Id must be required")
+		assert.Equal(t, codes.InvalidArgument, status.Code(missingErr), "Time must be required")
+		assert.Equal(t, codes.NotFound, status.Code(unknownErr), "Message must not be found without the queue")
+	})
+}
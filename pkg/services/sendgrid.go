@@ -0,0 +1,262 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sendGridDefaultEndpoint = "https://api.sendgrid.com"
+	sendGridSendPath        = "/v3/mail/send"
+	sendGridDefaultTimeout  = 10 * time.Second
+	// sendGridMaxErrorBody limits how much of the error response is read
+	sendGridMaxErrorBody = 64 << 10
+)
+
+// SendGridConfig configures the SendGrid provider
+type SendGridConfig struct {
+	// Name of the provider, default is sendgrid
+	Name string `mapstructure:"name"`
+	// APIKey is the SendGrid API key with Mail Send permission
+	APIKey string `mapstructure:"api_key"`
+	// Endpoint is the base URL of SendGrid API, default is https://api.sendgrid.com
+	Endpoint string `mapstructure:"endpoint"`
+	// Timeout of the single HTTP request, default is 10 seconds
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// SendGrid delivers emails through SendGrid v3 Web API
+type SendGrid struct {
+	name     string
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewSendGrid constructor of SendGrid provider
+func NewSendGrid(cfg SendGridConfig) (*SendGrid, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("sendgrid api key can not be empty")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "sendgrid"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = sendGridDefaultEndpoint
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = sendGridDefaultTimeout
+	}
+	return &SendGrid{
+		name:     cfg.Name,
+		apiKey:   cfg.APIKey,
+		endpoint: strings.TrimSuffix(cfg.Endpoint, "/"),
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Name of the provider
+func (s *SendGrid) Name() string {
+	return s.name
+}
+
+// Send posts the email to the /v3/mail/send endpoint
+func (s *SendGrid) Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error) {
+	payload, err := json.Marshal(newSendGridMail(req))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to marshal sendgrid request: %v", err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, s.endpoint+sendGridSendPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to create sendgrid request: %v", err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Authorization", "Bearer "+s.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, transportError(ctx, s.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return &Receipt{
+			Provider:  s.name,
+			MessageID: resp.Header.Get("X-Message-Id"),
+		}, nil
+	}
+	return nil, sendGridError(resp)
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to,omitempty"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type,omitempty"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition,omitempty"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Categories       []string                  `json:"categories,omitempty"`
+}
+
+type sendGridErrors struct {
+	Errors []struct {
+		Message string `json:"message"`
+		Field   string `json:"field"`
+	} `json:"errors"`
+}
+
+func newSendGridMail(req *pb.EmailRequest) *sendGridMail {
+	m := &sendGridMail{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(req.GetTo()),
+			Cc:  sendGridAddresses(req.GetCc()),
+			Bcc: sendGridAddresses(req.GetBcc()),
+		}},
+		From:       sendGridAddress{Email: req.GetFrom().GetEmail(), Name: req.GetFrom().GetName()},
+		Subject:    req.GetSubject(),
+		Headers:    req.GetHeaders(),
+		Categories: req.GetTags(),
+	}
+	if req.GetReplyTo() != nil {
+		m.ReplyTo = &sendGridAddress{Email: req.GetReplyTo().GetEmail(), Name: req.GetReplyTo().GetName()}
+	}
+	// SendGrid requires text/plain to be the first content
+	if req.GetTextBody() != "" {
+		m.Content = append(m.Content, sendGridContent{Type: "text/plain", Value: req.GetTextBody()})
+	}
+	if req.GetHtmlBody() != "" {
+		m.Content = append(m.Content, sendGridContent{Type: "text/html", Value: req.GetHtmlBody()})
+	}
+	for _, a := range req.GetAttachments() {
+		disposition := "attachment"
+		if a.GetDisposition() == pb.Attachment_INLINE {
+			disposition = "inline"
+		}
+		// SendGrid requires the filename also for inline attachments
+		filename := a.GetFilename()
+		if filename == "" {
+			filename = a.GetContentId()
+		}
+		m.Attachments = append(m.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.GetContent()),
+			Type:        attachmentContentType(a),
+			Filename:    filename,
+			Disposition: disposition,
+			ContentID:   a.GetContentId(),
+		})
+	}
+	return m
+}
+
+func sendGridAddresses(addrs []*pb.Address) []sendGridAddress {
+	var out []sendGridAddress
+	for _, a := range addrs {
+		out = append(out, sendGridAddress{Email: a.GetEmail(), Name: a.GetName()})
+	}
+	return out
+}
+
+// sendGridError translates SendGrid error response into gRPC status
+func sendGridError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, sendGridMaxErrorBody))
+
+	msg := strings.TrimSpace(string(body))
+	var errs sendGridErrors
+	if err := json.Unmarshal(body, &errs); err == nil && len(errs.Errors) > 0 {
+		var parts []string
+		for _, e := range errs.Errors {
+			if e.Field != "" {
+				parts = append(parts, fmt.Sprintf("%s: %s", e.Field, e.Message))
+			} else {
+				parts = append(parts, e.Message)
+			}
+		}
+		msg = strings.Join(parts, "; ")
+	}
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return status.Errorf(httpStatusToCode(resp.StatusCode), "sendgrid responded with %d: %s", resp.StatusCode, msg)
+}
+
+// httpStatusToCode maps HTTP status of the provider response to the gRPC code
+func httpStatusToCode(httpStatus int) codes.Code {
+	switch {
+	case httpStatus == http.StatusBadRequest, httpStatus == http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case httpStatus == http.StatusUnauthorized, httpStatus == http.StatusForbidden:
+		return codes.PermissionDenied
+	case httpStatus == http.StatusNotFound:
+		return codes.NotFound
+	case httpStatus == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case httpStatus >= 500:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// transportError translates error of the HTTP client into gRPC status
+func transportError(ctx context.Context, provider string, err error) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return status.Errorf(codes.Canceled, "%s request canceled: %v", provider, err)
+	case context.DeadlineExceeded:
+		return status.Errorf(codes.DeadlineExceeded, "%s request timed out: %v", provider, err)
+	}
+	return status.Errorf(codes.Unavailable, "%s request failed: %v", provider, err)
+}
@@ -0,0 +1,165 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const sendGridTestKey = "SG.test-key"
+
+func TestNewSendGrid(t *testing.T) {
+	t.Run("API key is required", func(t *testing.T) {
+		// Act
+		sg, err := NewSendGrid(SendGridConfig{})
+
+		// Assert
+		assert.Error(t, err, "Error must occur")
+		assert.Nil(t, sg, "Provider must not exist")
+	})
+
+	t.Run("Defaults are applied", func(t *testing.T) {
+		// Act
+		sg, err := NewSendGrid(SendGridConfig{APIKey: sendGridTestKey})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "sendgrid", sg.Name(), "Default name must be used")
+		assert.Equal(t, sendGridDefaultEndpoint, sg.endpoint, "Default endpoint must be used")
+		assert.Equal(t, sendGridDefaultTimeout, sg.client.Timeout, "Default timeout must be used")
+	})
+}
+
+func TestSendGrid_Send(t *testing.T) {
+	t.Run("Email is posted to the mail send endpoint", func(t *testing.T) {
+		// Arrange
+		var (
+			got        sendGridMail
+			authHeader string
+			path       string
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			authHeader = r.Header.Get("Authorization")
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &got)
+			w.Header().Set("X-Message-Id", "sg-message-id")
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer srv.Close()
+		sg, err := NewSendGrid(SendGridConfig{APIKey: sendGridTestKey, Endpoint: srv.URL})
+		assert.NoError(t, err, "Error should not occur")
+		req := validRequest()
+		req.HtmlBody = `<p>Hello world</p><img src="cid:logo">`
+		req.Attachments = []*pb.Attachment{pdfAttachment(), logoAttachment()}
+
+		// Act
+		receipt, err := sg.Send(context.Background(), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, &Receipt{Provider: "sendgrid", MessageID: "sg-message-id"}, receipt, "Receipt must be returned")
+		assert.Equal(t, sendGridSendPath, path, "Email must be send to mail send endpoint")
+		assert.Equal(t, "Bearer "+sendGridTestKey, authHeader, "API key must be used as bearer token")
+		assert.Equal(t, "sender@example.com", got.From.Email, "Sender must be set")
+		assert.Equal(t, []sendGridAddress{{Email: "recipient@example.com", Name: "Recipient"}},
+			got.Personalizations[0].To, "Recipients must be set")
+		assert.Equal(t, []sendGridContent{
+			{Type: "text/plain", Value: "Hello world"},
+			{Type: "text/html", Value: `<p>Hello world</p><img src="cid:logo">`},
+		}, got.Content, "Text content must be the first one")
+		assert.Equal(t, "42", got.Headers["X-Campaign-Id"], "Custom headers must be passed")
+		assert.Equal(t, []sendGridAttachment{
+			{Content: "JVBERi0xLjQgaW52b2ljZQ==", Type: "application/pdf", Filename: "invoice.pdf", Disposition: "attachment"},
+			{Content: "iVBORw0KGgpsb2dv", Type: "image/png", Filename: "logo", Disposition: "inline", ContentID: "logo"},
+		}, got.Attachments, "Attachments must be passed")
+	})
+
+	tests := []struct {
+		name       string
+		httpStatus int
+		body       string
+		code       codes.Code
+		contains   string
+	}{
+		{"Bad request", http.StatusBadRequest,
+			`{"errors":[{"message":"Invalid email","field":"from.email"}]}`, codes.InvalidArgument, "from.email: Invalid email"},
+		{"Unauthorized", http.StatusUnauthorized,
+			`{"errors":[{"message":"The provided authorization grant is invalid"}]}`, codes.PermissionDenied, "authorization grant"},
+		{"Payload too large", http.StatusRequestEntityTooLarge, "", codes.InvalidArgument, "Request Entity Too Large"},
+		{"Rate limited", http.StatusTooManyRequests, "", codes.ResourceExhausted, "429"},
+		{"Server error", http.StatusInternalServerError, "oops", codes.Unavailable, "oops"},
+		{"Unexpected status", http.StatusConflict, "", codes.Unknown, "409"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.httpStatus)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			sg, err := NewSendGrid(SendGridConfig{APIKey: sendGridTestKey, Endpoint: srv.URL})
+			assert.NoError(t, err, "Error should not occur")
+
+			// Act
+			receipt, err := sg.Send(context.Background(), validRequest())
+
+			// Assert
+			assert.Nil(t, receipt, "Receipt must not exist")
+			assert.Equal(t, tt.code, status.Code(err), "Error must be translated to gRPC code")
+			assert.Contains(t, status.Convert(err).Message(), tt.contains, "Error message must be passed")
+		})
+	}
+
+	t.Run("Unreachable endpoint", func(t *testing.T) {
+		// Arrange
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		sg, err := NewSendGrid(SendGridConfig{APIKey: sendGridTestKey, Endpoint: srv.URL})
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		_, err = sg.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Unavailable, status.Code(err), "Transport error must be unavailable")
+	})
+
+	t.Run("Canceled context", func(t *testing.T) {
+		// Arrange
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+		sg, err := NewSendGrid(SendGridConfig{APIKey: sendGridTestKey, Endpoint: srv.URL})
+		assert.NoError(t, err, "Error should not occur")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		_, err = sg.Send(ctx, validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Canceled, status.Code(err), "Canceled request must be reported")
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+)
+
+// Headers of the SendGrid Signed Event Webhook
+const (
+	sendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	sendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// sendGridEvent is the single event of the SendGrid Event Webhook batch
+type sendGridEvent struct {
+	Email string `json:"email"`
+	Event string `json:"event"`
+	// Type distinguishes the bounce from the blocked, i.e. soft bounced, email
+	Type        string `json:"type"`
+	Reason      string `json:"reason"`
+	SGMessageID string `json:"sg_message_id"`
+}
+
+func (w *Webhooks) handleSendGrid(r *http.Request, body []byte) error {
+	if !verifySendGrid(w.sendGridPK, r.Header, body) {
+		return webhookErrorf(http.StatusForbidden, "invalid signature of SendGrid events")
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(sendGridTimestampHeader), 10, 64)
+	if err != nil || !w.fresh(time.Unix(timestamp, 0)) {
+		return webhookErrorf(http.StatusForbidden, "SendGrid events are too old")
+	}
+	var events []sendGridEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return webhookErrorf(http.StatusBadRequest, "invalid SendGrid events: %v", err)
+	}
+	for _, e := range events {
+		if err := w.apply(sendGridProviderEvent(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendGridProviderEvent translates the event. The sg_message_id starts with
+// the X-Message-Id returned when the email was sent.
+func sendGridProviderEvent(e sendGridEvent) providerEvent {
+	ev := providerEvent{
+		provider:          SendGridType,
+		kind:              e.Event,
+		providerMessageID: strings.SplitN(e.SGMessageID, ".", 2)[0],
+		recipient:         e.Email,
+		description:       e.Reason,
+	}
+	switch e.Event {
+	case "delivered":
+		ev.state = MessageDelivered
+	case "open":
+		ev.state = MessageOpened
+	case "bounce":
+		ev.state = MessageBounced
+		if e.Type != "blocked" {
+			ev.suppress = pb.Suppression_BOUNCE
+		}
+	case "spamreport":
+		ev.suppress = pb.Suppression_COMPLAINT
+	case "unsubscribe", "group_unsubscribe":
+		ev.suppress = pb.Suppression_UNSUBSCRIBE
+	case "processed", "deferred", "dropped", "click", "group_resubscribe":
+	default:
+		// The kind is the label of the metric, so it is not taken from the unknown events
+		ev.kind = otherEventKind
+	}
+	return ev
+}
+
+// verifySendGrid checks the ECDSA signature of the timestamp and the body
+func verifySendGrid(pk *ecdsa.PublicKey, header http.Header, body []byte) bool {
+	sig, err := base64.StdEncoding.DecodeString(header.Get(sendGridSignatureHeader))
+	if err != nil {
+		return false
+	}
+	var rs struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+		return false
+	}
+	h := sha256.New()
+	h.Write([]byte(header.Get(sendGridTimestampHeader)))
+	h.Write(body)
+	return ecdsa.Verify(pk, h.Sum(nil), rs.R, rs.S)
+}
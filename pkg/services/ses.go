@@ -0,0 +1,217 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/dkim"
+	"github.com/RafalKorepta/coding-challenge/pkg/mime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sesAPIVersion     = "2010-12-01"
+	sesSigningService = "ses"
+	sesDefaultTimeout = 10 * time.Second
+	// sesMaxResponseBody limits how much of the response is read
+	sesMaxResponseBody = 64 << 10
+)
+
+// SESConfig configures the Amazon SES provider
+type SESConfig struct {
+	// Name of the provider, default is ses
+	Name string `mapstructure:"name"`
+	// Region of SES endpoint e.g. eu-west-1
+	Region string `mapstructure:"region"`
+	// AccessKeyID of the IAM user allowed to call ses:SendRawEmail
+	AccessKeyID string `mapstructure:"access_key_id"`
+	// SecretAccessKey of the IAM user
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	// SessionToken is required only for temporary credentials
+	SessionToken string `mapstructure:"session_token"`
+	// ConfigurationSet is optional SES configuration set used for event publishing
+	ConfigurationSet string `mapstructure:"configuration_set"`
+	// Endpoint overrides https://email.<region>.amazonaws.com
+	Endpoint string `mapstructure:"endpoint"`
+	// Timeout of the single HTTP request, default is 10 seconds
+	Timeout time.Duration `mapstructure:"timeout"`
+	// DKIM signs the messages of the sender domains which have the key
+	DKIM *dkim.Keyring `mapstructure:"-"`
+}
+
+// SES delivers emails through Amazon Simple Email Service SendRawEmail Query API
+type SES struct {
+	name             string
+	endpoint         string
+	configurationSet string
+	dkim             *dkim.Keyring
+	signer           sigV4Signer
+	client           *http.Client
+	now              func() time.Time
+}
+
+// NewSES constructor of SES provider
+func NewSES(cfg SESConfig) (*SES, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("ses region can not be empty")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("ses access key id and secret access key can not be empty")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "ses"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", cfg.Region)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = sesDefaultTimeout
+	}
+	return &SES{
+		name:             cfg.Name,
+		endpoint:         strings.TrimSuffix(cfg.Endpoint, "/") + "/",
+		configurationSet: cfg.ConfigurationSet,
+		dkim:             cfg.DKIM,
+		signer: sigV4Signer{
+			credentials: awsCredentials{
+				AccessKeyID:     cfg.AccessKeyID,
+				SecretAccessKey: cfg.SecretAccessKey,
+				SessionToken:    cfg.SessionToken,
+			},
+			region:  cfg.Region,
+			service: sesSigningService,
+		},
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}, nil
+}
+
+// Name of the provider
+func (s *SES) Name() string {
+	return s.name
+}
+
+// Send builds the MIME message and calls SendRawEmail action
+func (s *SES) Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error) {
+	msg, raw, err := buildRawMessage(req, s.now(), s.dkim)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to build raw message: %v", err)
+	}
+
+	form := url.Values{}
+	form.Set("Action", "SendRawEmail")
+	form.Set("Version", sesAPIVersion)
+	form.Set("Source", mime.FormatAddress(msg.From))
+	form.Set("RawMessage.Data", base64.StdEncoding.EncodeToString(raw))
+	for i, rcpt := range msg.Recipients() {
+		form.Set("Destinations.member."+strconv.Itoa(i+1), rcpt)
+	}
+	if s.configurationSet != "" {
+		form.Set("ConfigurationSetName", s.configurationSet)
+	}
+	payload := []byte(form.Encode())
+
+	httpReq, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to create ses request: %v", err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	s.signer.Sign(httpReq, payload, s.now())
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, transportError(ctx, s.name, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, sesMaxResponseBody))
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "unable to read ses response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, sesError(resp.StatusCode, body)
+	}
+	var result sesSendRawEmailResponse
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, status.Errorf(codes.Unknown, "unable to parse ses response: %v", err)
+	}
+	return &Receipt{
+		Provider:  s.name,
+		MessageID: result.MessageID,
+	}, nil
+}
+
+type sesSendRawEmailResponse struct {
+	MessageID string `xml:"SendRawEmailResult>MessageId"`
+}
+
+type sesErrorResponse struct {
+	Type    string `xml:"Error>Type"`
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// sesError translates SES error response into gRPC status. Throttling and
+// server side failures are mapped to the codes that can be retried.
+func sesError(httpStatus int, body []byte) error {
+	var errResp sesErrorResponse
+	if err := xml.Unmarshal(body, &errResp); err != nil || errResp.Code == "" {
+		msg := strings.TrimSpace(string(body))
+		if msg == "" {
+			msg = http.StatusText(httpStatus)
+		}
+		return status.Errorf(httpStatusToCode(httpStatus), "ses responded with %d: %s", httpStatus, msg)
+	}
+	return status.Errorf(sesErrorCode(httpStatus, errResp), "ses responded with %s: %s", errResp.Code, errResp.Message)
+}
+
+// sesErrorCode maps SES error code to the gRPC code.
+// See https://docs.aws.amazon.com/ses/latest/APIReference/CommonErrors.html
+func sesErrorCode(httpStatus int, errResp sesErrorResponse) codes.Code {
+	switch errResp.Code {
+	case "Throttling", "ThrottlingException", "LimitExceeded", "LimitExceededException":
+		// Covers both "Maximum sending rate exceeded" and "Daily message quota exceeded"
+		return codes.ResourceExhausted
+	case "ServiceUnavailable", "InternalFailure", "RequestExpired":
+		return codes.Unavailable
+	case "MessageRejected", "MailFromDomainNotVerified", "MailFromDomainNotVerifiedException",
+		"AccountSendingPausedException", "ConfigurationSetDoesNotExist", "ConfigurationSetSendingPausedException":
+		return codes.FailedPrecondition
+	case "InvalidParameterValue", "InvalidParameterCombination", "MissingParameter", "ValidationError",
+		"MalformedQueryString", "InvalidQueryParameter":
+		return codes.InvalidArgument
+	case "AccessDenied", "AccessDeniedException", "InvalidClientTokenId", "SignatureDoesNotMatch",
+		"IncompleteSignature", "MissingAuthenticationToken", "NotAuthorized", "OptInRequired":
+		return codes.PermissionDenied
+	}
+	if errResp.Type == "Receiver" {
+		return codes.Unavailable
+	}
+	return httpStatusToCode(httpStatus)
+}
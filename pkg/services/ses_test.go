@@ -0,0 +1,263 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sesTestAccessKey = "AKIDEXAMPLE"
+	sesTestSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	sesTestRegion    = "eu-west-1"
+)
+
+var sesTestNow = time.Date(2018, 7, 30, 10, 0, 0, 0, time.UTC)
+
+// fakeSES is a local stand-in for SES endpoint that checks the request signature
+type fakeSES struct {
+	form      url.Values
+	sigErr    error
+	status    int
+	response  string
+	callCount int
+}
+
+func (f *fakeSES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.callCount++
+	body, _ := ioutil.ReadAll(r.Body)
+	f.sigErr = verifySigV4(r, body, sesTestSecretKey)
+	if f.sigErr != nil {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>SignatureDoesNotMatch</Code>`+
+			`<Message>%s</Message></Error></ErrorResponse>`, f.sigErr)
+		return
+	}
+	f.form, _ = url.ParseQuery(string(body))
+	w.WriteHeader(f.status)
+	_, _ = w.Write([]byte(f.response))
+}
+
+// verifySigV4 recomputes the signature from the signed headers of received request
+func verifySigV4(r *http.Request, body []byte, secret string) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		return fmt.Errorf("unexpected algorithm in %q", auth)
+	}
+	fields := map[string]string{}
+	for _, f := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ", ") {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("malformed authorization field %q", f)
+		}
+		fields[kv[0]] = kv[1]
+	}
+	scope := strings.Split(fields["Credential"], "/")
+	if len(scope) != 5 || scope[4] != "aws4_request" {
+		return fmt.Errorf("malformed credential scope %q", fields["Credential"])
+	}
+	now, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return fmt.Errorf("malformed date: %v", err)
+	}
+	if now.Format(sigV4DateFormat) != scope[1] {
+		return fmt.Errorf("date %s does not match scope %s", now, scope[1])
+	}
+
+	signed, err := http.NewRequest(r.Method, r.URL.String(), nil)
+	if err != nil {
+		return err
+	}
+	signed.Host = r.Host
+	for _, h := range strings.Split(fields["SignedHeaders"], ";") {
+		if h != "host" {
+			signed.Header[http.CanonicalHeaderKey(h)] = r.Header[http.CanonicalHeaderKey(h)]
+		}
+	}
+	signer := sigV4Signer{
+		credentials: awsCredentials{AccessKeyID: scope[0], SecretAccessKey: secret},
+		region:      scope[2],
+		service:     scope[3],
+	}
+	signedHeaders, signature := signer.signature(signed, body, now)
+	if signedHeaders != fields["SignedHeaders"] || signature != fields["Signature"] {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+func newTestSES(t *testing.T, endpoint, secret string) *SES {
+	ses, err := NewSES(SESConfig{
+		Region:           sesTestRegion,
+		AccessKeyID:      sesTestAccessKey,
+		SecretAccessKey:  secret,
+		ConfigurationSet: "tracking",
+		Endpoint:         endpoint,
+	})
+	assert.NoError(t, err, "Error should not occur")
+	ses.now = func() time.Time { return sesTestNow }
+	return ses
+}
+
+func TestNewSES(t *testing.T) {
+	t.Run("Region is required", func(t *testing.T) {
+		// Act
+		ses, err := NewSES(SESConfig{AccessKeyID: sesTestAccessKey, SecretAccessKey: sesTestSecretKey})
+
+		// Assert
+		assert.Error(t, err, "Error must occur")
+		assert.Nil(t, ses, "Provider must not exist")
+	})
+
+	t.Run("Credentials are required", func(t *testing.T) {
+		// Act
+		ses, err := NewSES(SESConfig{Region: sesTestRegion})
+
+		// Assert
+		assert.Error(t, err, "Error must occur")
+		assert.Nil(t, ses, "Provider must not exist")
+	})
+
+	t.Run("Regional endpoint is used by default", func(t *testing.T) {
+		// Act
+		ses, err := NewSES(SESConfig{Region: sesTestRegion, AccessKeyID: sesTestAccessKey, SecretAccessKey: sesTestSecretKey})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "ses", ses.Name(), "Default name must be used")
+		assert.Equal(t, "https://email.eu-west-1.amazonaws.com/", ses.endpoint, "Regional endpoint must be used")
+	})
+}
+
+func TestSES_Send(t *testing.T) {
+	t.Run("Signed raw email is sent", func(t *testing.T) {
+		// Arrange
+		fake := &fakeSES{
+			status: http.StatusOK,
+			response: `<SendRawEmailResponse xmlns="http://ses.amazonaws.com/doc/2010-12-01/">` +
+				`<SendRawEmailResult><MessageId>ses-message-id</MessageId></SendRawEmailResult>` +
+				`</SendRawEmailResponse>`,
+		}
+		srv := httptest.NewServer(fake)
+		defer srv.Close()
+		ses := newTestSES(t, srv.URL, sesTestSecretKey)
+		req := validRequest()
+		req.Bcc = []*pb.Address{{Email: "hidden@example.com"}}
+		req.Attachments = []*pb.Attachment{pdfAttachment()}
+
+		// Act
+		receipt, err := ses.Send(context.Background(), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.NoError(t, fake.sigErr, "Signature must be valid")
+		assert.Equal(t, &Receipt{Provider: "ses", MessageID: "ses-message-id"}, receipt, "Receipt must be returned")
+		assert.Equal(t, "SendRawEmail", fake.form.Get("Action"), "SendRawEmail action must be called")
+		assert.Equal(t, sesAPIVersion, fake.form.Get("Version"), "API version must be set")
+		assert.Equal(t, "tracking", fake.form.Get("ConfigurationSetName"), "Configuration set must be set")
+		assert.Equal(t, "recipient@example.com", fake.form.Get("Destinations.member.1"), "Recipient must be destination")
+		assert.Equal(t, "hidden@example.com", fake.form.Get("Destinations.member.2"), "Bcc must be destination")
+		raw, err := base64.StdEncoding.DecodeString(fake.form.Get("RawMessage.Data"))
+		assert.NoError(t, err, "Raw message must be base64 encoded")
+		assert.Contains(t, string(raw), "Subject: Hello\r\n", "Raw message must contain subject")
+		assert.NotContains(t, string(raw), "hidden@example.com", "Bcc must not be part of message")
+		assert.Contains(t, string(raw), "Content-Type: multipart/mixed;", "Attachment must be part of message")
+		assert.Contains(t, string(raw), "Content-Disposition: attachment; filename=invoice.pdf\r\n",
+			"Attachment must have filename")
+	})
+
+	t.Run("Wrong secret is rejected by signature check", func(t *testing.T) {
+		// Arrange
+		fake := &fakeSES{}
+		srv := httptest.NewServer(fake)
+		defer srv.Close()
+		ses := newTestSES(t, srv.URL, "wrong-secret")
+
+		// Act
+		receipt, err := ses.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Nil(t, receipt, "Receipt must not exist")
+		assert.Error(t, fake.sigErr, "Signature must be invalid")
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "Signature error must be permission denied")
+		assert.False(t, IsRetryable(err), "Signature error must be permanent")
+	})
+
+	tests := []struct {
+		name       string
+		httpStatus int
+		errType    string
+		errCode    string
+		code       codes.Code
+		retryable  bool
+	}{
+		{"Sending rate exceeded", http.StatusBadRequest, "Sender", "Throttling", codes.ResourceExhausted, true},
+		{"Service unavailable", http.StatusServiceUnavailable, "Receiver", "ServiceUnavailable", codes.Unavailable, true},
+		{"Unknown receiver error", http.StatusInternalServerError, "Receiver", "SomethingNew", codes.Unavailable, true},
+		{"Message rejected", http.StatusBadRequest, "Sender", "MessageRejected", codes.FailedPrecondition, false},
+		{"Invalid parameter", http.StatusBadRequest, "Sender", "InvalidParameterValue", codes.InvalidArgument, false},
+		{"Access denied", http.StatusForbidden, "Sender", "AccessDenied", codes.PermissionDenied, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fake := &fakeSES{
+				status: tt.httpStatus,
+				response: fmt.Sprintf(`<ErrorResponse><Error><Type>%s</Type><Code>%s</Code>`+
+					`<Message>failure</Message></Error></ErrorResponse>`, tt.errType, tt.errCode),
+			}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+			ses := newTestSES(t, srv.URL, sesTestSecretKey)
+
+			// Act
+			receipt, err := ses.Send(context.Background(), validRequest())
+
+			// Assert
+			assert.Nil(t, receipt, "Receipt must not exist")
+			assert.Equal(t, tt.code, status.Code(err), "Error must be translated to gRPC code")
+			assert.Equal(t, tt.retryable, IsRetryable(err), "Error must be classified as retryable or permanent")
+			assert.Contains(t, status.Convert(err).Message(), tt.errCode, "Error code must be passed")
+		})
+	}
+
+	t.Run("Error response which is not XML", func(t *testing.T) {
+		// Arrange
+		fake := &fakeSES{status: http.StatusBadGateway, response: "bad gateway"}
+		srv := httptest.NewServer(fake)
+		defer srv.Close()
+		ses := newTestSES(t, srv.URL, sesTestSecretKey)
+
+		// Act
+		_, err := ses.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Unavailable, status.Code(err), "HTTP status must be translated to gRPC code")
+		assert.True(t, IsRetryable(err), "Gateway error must be retryable")
+	})
+}
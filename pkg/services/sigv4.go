@@ -0,0 +1,163 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+)
+
+// awsCredentials are the static credentials of the IAM user
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// sigV4Signer signs HTTP requests with AWS Signature Version 4.
+// See https://docs.aws.amazon.com/general/latest/gr/signature-version-4.html
+type sigV4Signer struct {
+	credentials awsCredentials
+	region      string
+	service     string
+}
+
+// Sign adds X-Amz-Date and Authorization headers to the request.
+// The payload must be the exact body of the request.
+func (s sigV4Signer) Sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	if s.credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	signedHeaders, signature := s.signature(req, payload, now)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.credentials.AccessKeyID, s.scope(now), signedHeaders, signature))
+}
+
+// signature returns the list of signed headers and the hex encoded signature
+func (s sigV4Signer) signature(req *http.Request, payload []byte, now time.Time) (string, string) {
+	canonical, signedHeaders := canonicalRequest(req, payload)
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(sigV4TimeFormat),
+		s.scope(now),
+		hexSHA256([]byte(canonical)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), now.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s sigV4Signer) scope(now time.Time) string {
+	return strings.Join([]string{now.Format(sigV4DateFormat), s.region, s.service, "aws4_request"}, "/")
+}
+
+// canonicalRequest builds the canonical form of the request. All headers set on
+// the request together with Host are signed.
+func canonicalRequest(req *http.Request, payload []byte) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "authorization" {
+			continue
+		}
+		trimmed := make([]string, 0, len(v))
+		for _, value := range v {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(payload),
+	}, "\n"), signedHeaders
+}
+
+func canonicalQuery(v url.Values) string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), v[k]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape encodes the string according to RFC 3986 as required by AWS
+func awsEscape(s string) string {
+	return strings.Replace(strings.Replace(url.QueryEscape(s), "+", "%20", -1), "%7E", "~", -1)
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
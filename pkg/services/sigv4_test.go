@@ -0,0 +1,84 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The test vectors come from AWS Signature Version 4 test suite
+// https://docs.aws.amazon.com/general/latest/gr/signature-v4-test-suite.html
+var sigV4TestCredentials = awsCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSigV4Signer_Sign(t *testing.T) {
+	// Arrange
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	t.Run("get-vanilla", func(t *testing.T) {
+		// Arrange
+		signer := sigV4Signer{credentials: sigV4TestCredentials, region: "us-east-1", service: "service"}
+		req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		signer.Sign(req, nil, now)
+
+		// Assert
+		assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"), "Date header must be set")
+		assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, "+
+			"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+			req.Header.Get("Authorization"), "Signature must match the test suite")
+	})
+
+	t.Run("get-vanilla-query-order-key-case", func(t *testing.T) {
+		// Arrange
+		signer := sigV4Signer{credentials: sigV4TestCredentials, region: "us-east-1", service: "service"}
+		req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1", nil)
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		signer.Sign(req, nil, now)
+
+		// Assert
+		assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, "+
+			"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+			req.Header.Get("Authorization"), "Signature must match the test suite")
+	})
+
+	t.Run("Session token is signed", func(t *testing.T) {
+		// Arrange
+		creds := sigV4TestCredentials
+		creds.SessionToken = "session-token"
+		signer := sigV4Signer{credentials: creds, region: "us-east-1", service: "ses"}
+		req, err := http.NewRequest(http.MethodPost, "https://email.us-east-1.amazonaws.com/", nil)
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		signer.Sign(req, []byte("Action=SendRawEmail"), now)
+
+		// Assert
+		assert.Equal(t, "session-token", req.Header.Get("X-Amz-Security-Token"), "Token header must be set")
+		assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token",
+			"Token header must be signed")
+	})
+}
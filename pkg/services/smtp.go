@@ -0,0 +1,377 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/dkim"
+	"github.com/RafalKorepta/coding-challenge/pkg/mime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The TLS modes of SMTP provider
+const (
+	// SMTPTLSNone sends emails in plain text
+	SMTPTLSNone = "none"
+	// SMTPStartTLS upgrades the plain text connection and fails if the server does not support it
+	SMTPStartTLS = "starttls"
+	// SMTPImplicitTLS connects with TLS from the beginning, usually on port 465
+	SMTPImplicitTLS = "tls"
+)
+
+// The authentication mechanisms of SMTP provider
+const (
+	SMTPAuthPlain   = "plain"
+	SMTPAuthLogin   = "login"
+	SMTPAuthCRAMMD5 = "cram-md5"
+)
+
+const (
+	smtpDefaultDialTimeout = 10 * time.Second
+	smtpDefaultTimeout     = time.Minute
+)
+
+// SMTPConfig configures the SMTP relay provider
+type SMTPConfig struct {
+	// Name of the provider, default is smtp
+	Name string `mapstructure:"name"`
+	// Host of the SMTP relay
+	Host string `mapstructure:"host"`
+	// Port of the SMTP relay, default is 587 or 465 for implicit TLS
+	Port int `mapstructure:"port"`
+	// TLS is one of none, starttls or tls, default is starttls
+	TLS string `mapstructure:"tls"`
+	// InsecureSkipVerify disables verification of the relay certificate
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+	// Username enables authentication, without it AUTH is not used
+	Username string `mapstructure:"username"`
+	// Password of the user
+	Password string `mapstructure:"password"`
+	// AuthMechanism is one of plain, login or cram-md5. When empty the strongest
+	// mechanism advertised by the relay is used.
+	AuthMechanism string `mapstructure:"auth_mechanism"`
+	// HeloName is sent in EHLO command, default is localhost
+	HeloName string `mapstructure:"helo_name"`
+	// DialTimeout limits establishing the connection, default is 10 seconds
+	DialTimeout time.Duration `mapstructure:"dial_timeout"`
+	// Timeout limits the whole SMTP session, default is 1 minute
+	Timeout time.Duration `mapstructure:"timeout"`
+	// TLSConfig overrides TLS configuration e.g. to trust private CA
+	TLSConfig *tls.Config `mapstructure:"-"`
+	// DKIM signs the messages of the sender domains which have the key
+	DKIM *dkim.Keyring `mapstructure:"-"`
+}
+
+// SMTP delivers emails through the SMTP relay
+type SMTP struct {
+	cfg  SMTPConfig
+	addr string
+	now  func() time.Time
+}
+
+// NewSMTP constructor of SMTP provider
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp host can not be empty")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "smtp"
+	}
+	if cfg.TLS == "" {
+		cfg.TLS = SMTPStartTLS
+	}
+	switch cfg.TLS {
+	case SMTPTLSNone, SMTPStartTLS, SMTPImplicitTLS:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", cfg.TLS)
+	}
+	cfg.AuthMechanism = strings.ToLower(cfg.AuthMechanism)
+	switch cfg.AuthMechanism {
+	case "", SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCRAMMD5:
+	default:
+		return nil, fmt.Errorf("unknown smtp auth mechanism %q", cfg.AuthMechanism)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == SMTPImplicitTLS {
+			cfg.Port = 465
+		}
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = smtpDefaultDialTimeout
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = smtpDefaultTimeout
+	}
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{}
+	}
+	cfg.TLSConfig = cfg.TLSConfig.Clone()
+	if cfg.TLSConfig.ServerName == "" {
+		cfg.TLSConfig.ServerName = cfg.Host
+	}
+	if cfg.InsecureSkipVerify {
+		cfg.TLSConfig.InsecureSkipVerify = true
+	}
+	return &SMTP{
+		cfg:  cfg,
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		now:  time.Now,
+	}, nil
+}
+
+// Name of the provider
+func (s *SMTP) Name() string {
+	return s.cfg.Name
+}
+
+// Send delivers the email in a single SMTP session
+func (s *SMTP) Send(ctx context.Context, req *pb.EmailRequest) (*Receipt, error) {
+	msg, raw, err := buildRawMessage(req, s.now(), s.cfg.DKIM)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to build raw message: %v", err)
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, s.error(ctx, "dial", err)
+	}
+	defer conn.Close()
+
+	deadline := s.now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, s.error(ctx, "dial", err)
+	}
+	// Unblock the session when the caller gives up
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	if err := s.session(conn, msg, raw); err != nil {
+		return nil, s.error(ctx, "session", err)
+	}
+	return &Receipt{
+		Provider:  s.cfg.Name,
+		MessageID: msg.MessageID,
+	}, nil
+}
+
+func (s *SMTP) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.cfg.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.TLS != SMTPImplicitTLS {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, s.cfg.TLSConfig)
+	_ = tlsConn.SetDeadline(time.Now().Add(s.cfg.DialTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (s *SMTP) session(conn net.Conn, msg *mime.Message, raw []byte) error {
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if s.cfg.HeloName != "" {
+		if err := c.Hello(s.cfg.HeloName); err != nil {
+			return err
+		}
+	}
+	if s.cfg.TLS == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errSTARTTLSNotSupported
+		}
+		if err := c.StartTLS(s.cfg.TLSConfig); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		auth, err := s.auth(c)
+		if err != nil {
+			return err
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(msg.From.Email); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	// The relay took the message once it accepted the data, failing the send
+	// now would deliver the email twice
+	if err := c.Quit(); err != nil {
+		zap.L().Warn("SMTP relay did not close the session", zap.String("host", s.cfg.Host), zap.Error(err))
+	}
+	return nil
+}
+
+// auth picks the configured mechanism or the strongest one advertised by the relay
+func (s *SMTP) auth(c *smtp.Client) (smtp.Auth, error) {
+	mech := s.cfg.AuthMechanism
+	if mech == "" {
+		ok, advertised := c.Extension("AUTH")
+		if !ok {
+			return nil, errAuthNotSupported
+		}
+		advertised = strings.ToLower(advertised)
+		for _, m := range []string{SMTPAuthCRAMMD5, SMTPAuthPlain, SMTPAuthLogin} {
+			if containsField(advertised, m) {
+				mech = m
+				break
+			}
+		}
+		if mech == "" {
+			return nil, errAuthNotSupported
+		}
+	}
+
+	switch mech {
+	case SMTPAuthCRAMMD5:
+		return smtp.CRAMMD5Auth(s.cfg.Username, s.cfg.Password), nil
+	case SMTPAuthLogin:
+		return &loginAuth{username: s.cfg.Username, password: s.cfg.Password, host: s.cfg.Host}, nil
+	default:
+		return smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host), nil
+	}
+}
+
+var (
+	errSTARTTLSNotSupported = errors.New("relay does not support STARTTLS")
+	errAuthNotSupported     = errors.New("relay does not support any known AUTH mechanism")
+)
+
+// error translates SMTP reply codes into gRPC status. The 4xx replies are
+// temporary failures and 5xx replies are permanent ones.
+func (s *SMTP) error(ctx context.Context, stage string, err error) error {
+	if ctx.Err() != nil {
+		return transportError(ctx, s.cfg.Name, err)
+	}
+	if tpErr, ok := err.(*textproto.Error); ok {
+		return status.Errorf(smtpReplyToCode(tpErr.Code), "%s replied with %d: %s", s.cfg.Name, tpErr.Code, tpErr.Msg)
+	}
+	switch err {
+	case errSTARTTLSNotSupported, errAuthNotSupported:
+		return status.Errorf(codes.FailedPrecondition, "%s %s failed: %v", s.cfg.Name, stage, err)
+	}
+	return status.Errorf(codes.Unavailable, "%s %s failed: %v", s.cfg.Name, stage, err)
+}
+
+// smtpReplyToCode maps SMTP reply code to the gRPC code.
+// See https://tools.ietf.org/html/rfc5321#section-4.2.3
+func smtpReplyToCode(reply int) codes.Code {
+	switch {
+	case reply == 452:
+		return codes.ResourceExhausted
+	case reply >= 400 && reply < 500:
+		return codes.Unavailable
+	case reply == 530, reply == 534, reply == 535, reply == 538:
+		return codes.PermissionDenied
+	case reply == 501, reply == 550, reply == 552, reply == 553:
+		return codes.InvalidArgument
+	case reply >= 500 && reply < 600:
+		return codes.FailedPrecondition
+	}
+	return codes.Unknown
+}
+
+func containsField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// loginAuth implements the obsolete, but still widely used AUTH LOGIN mechanism
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Same protection as in smtp.PlainAuth, the password is sent in plain text
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
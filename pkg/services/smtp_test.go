@@ -0,0 +1,299 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/dkim"
+	"github.com/RafalKorepta/coding-challenge/pkg/smtptest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	smtpTestUser     = "relay-user"
+	smtpTestPassword = "relay-password"
+)
+
+func newTestSMTPServer(t *testing.T, cfg smtptest.Config) *smtptest.Server {
+	srv, err := smtptest.NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func newTestSMTP(t *testing.T, srv *smtptest.Server, cfg SMTPConfig) *SMTP {
+	host, port, err := net.SplitHostPort(srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Host = host
+	cfg.Port, _ = strconv.Atoi(port)
+	cfg.TLSConfig = &tls.Config{RootCAs: srv.RootCAs()}
+	s, err := NewSMTP(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewSMTP(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  SMTPConfig
+	}{
+		{"Host is required", SMTPConfig{}},
+		{"Unknown TLS mode", SMTPConfig{Host: "localhost", TLS: "ssl"}},
+		{"Unknown auth mechanism", SMTPConfig{Host: "localhost", AuthMechanism: "xoauth2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			s, err := NewSMTP(tt.cfg)
+
+			// Assert
+			assert.Error(t, err, "Error must occur")
+			assert.Nil(t, s, "Provider must not exist")
+		})
+	}
+
+	t.Run("Implicit TLS uses port 465 by default", func(t *testing.T) {
+		// Act
+		s, err := NewSMTP(SMTPConfig{Host: "relay.example.com", TLS: SMTPImplicitTLS})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "relay.example.com:465", s.addr, "Port 465 must be used")
+		assert.Equal(t, "smtp", s.Name(), "Default name must be used")
+	})
+}
+
+func TestSMTP_Send(t *testing.T) {
+	authTests := []struct {
+		name      string
+		serverCfg smtptest.Config
+		cfg       SMTPConfig
+		mechanism string
+	}{
+		{"STARTTLS with AUTH PLAIN",
+			smtptest.Config{StartTLS: true, Username: smtpTestUser, Password: smtpTestPassword},
+			SMTPConfig{TLS: SMTPStartTLS, AuthMechanism: SMTPAuthPlain}, "PLAIN"},
+		{"STARTTLS with AUTH LOGIN",
+			smtptest.Config{StartTLS: true, Username: smtpTestUser, Password: smtpTestPassword},
+			SMTPConfig{TLS: SMTPStartTLS, AuthMechanism: SMTPAuthLogin}, "LOGIN"},
+		{"Implicit TLS with AUTH CRAM-MD5",
+			smtptest.Config{ImplicitTLS: true, Username: smtpTestUser, Password: smtpTestPassword},
+			SMTPConfig{TLS: SMTPImplicitTLS, AuthMechanism: SMTPAuthCRAMMD5}, "CRAM-MD5"},
+		{"Strongest advertised mechanism",
+			smtptest.Config{StartTLS: true, Username: smtpTestUser, Password: smtpTestPassword},
+			SMTPConfig{TLS: SMTPStartTLS}, "CRAM-MD5"},
+	}
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			srv := newTestSMTPServer(t, tt.serverCfg)
+			defer srv.Close()
+			tt.cfg.Username, tt.cfg.Password, tt.cfg.HeloName = smtpTestUser, smtpTestPassword, "mailer.example.com"
+			s := newTestSMTP(t, srv, tt.cfg)
+			req := validRequest()
+			req.Bcc = []*pb.Address{{Email: "hidden@example.com"}}
+
+			// Act
+			receipt, err := s.Send(context.Background(), req)
+
+			// Assert
+			assert.NoError(t, err, "Error should not occur")
+			assert.Equal(t, "smtp", receipt.Provider, "Provider name must be returned")
+			assert.NotEmpty(t, receipt.MessageID, "Message id must be returned")
+			messages := srv.Messages()
+			if assert.Len(t, messages, 1, "Relay must receive the message") {
+				msg := messages[0]
+				assert.True(t, msg.TLS, "Message must be sent over TLS")
+				assert.Equal(t, tt.mechanism, msg.AuthMech, "Expected mechanism must be used")
+				assert.Equal(t, smtpTestUser, msg.AuthUser, "User must be authenticated")
+				assert.Equal(t, "mailer.example.com", msg.Helo, "Configured HELO name must be used")
+				assert.Equal(t, "sender@example.com", msg.From, "Sender must be envelope from")
+				assert.Equal(t, []string{"recipient@example.com", "hidden@example.com"}, msg.To, "Bcc must be envelope recipient")
+				assert.Contains(t, string(msg.Data), "Message-ID: "+receipt.MessageID, "Message id must be in headers")
+				assert.NotContains(t, string(msg.Data), "hidden@example.com", "Bcc must not be part of message")
+			}
+		})
+	}
+
+	t.Run("Plain text relay without AUTH", func(t *testing.T) {
+		// Arrange
+		srv := newTestSMTPServer(t, smtptest.Config{})
+		defer srv.Close()
+		s := newTestSMTP(t, srv, SMTPConfig{TLS: SMTPTLSNone})
+
+		// Act
+		_, err := s.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Len(t, srv.Messages(), 1, "Relay must receive the message")
+	})
+
+	t.Run("Failed QUIT after the accepted message", func(t *testing.T) {
+		// Arrange
+		srv := newTestSMTPServer(t, smtptest.Config{Replies: map[string]string{"QUIT": "421 4.4.2 Timeout"}})
+		defer srv.Close()
+		s := newTestSMTP(t, srv, SMTPConfig{TLS: SMTPTLSNone})
+
+		// Act
+		receipt, err := s.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Accepted message must not be sent again")
+		assert.NotNil(t, receipt, "Receipt must be returned")
+		assert.Len(t, srv.Messages(), 1, "Relay must receive the message")
+	})
+
+	t.Run("Message is signed with DKIM", func(t *testing.T) {
+		// Arrange
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err, "Error should not occur")
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		assert.NoError(t, err, "Error should not occur")
+		keyCfg := dkim.KeyConfig{
+			Domain:     "example.com",
+			Selector:   "mail",
+			PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		}
+		keyring, err := dkim.NewKeyring([]dkim.KeyConfig{keyCfg})
+		assert.NoError(t, err, "Error should not occur")
+		signer, err := dkim.NewSigner(keyCfg)
+		assert.NoError(t, err, "Error should not occur")
+		record, err := signer.PublicKeyRecord()
+		assert.NoError(t, err, "Error should not occur")
+		srv := newTestSMTPServer(t, smtptest.Config{})
+		defer srv.Close()
+		s := newTestSMTP(t, srv, SMTPConfig{TLS: SMTPTLSNone, DKIM: keyring})
+
+		// Act
+		_, err = s.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		if messages := srv.Messages(); assert.Len(t, messages, 1, "Relay must receive the message") {
+			data := strings.Replace(string(messages[0].Data), "\n", "\r\n", -1)
+			err := dkim.Verify([]byte(data), func(name string) ([]string, error) {
+				assert.Equal(t, "mail._domainkey.example.com", name, "Key of sender domain must be used")
+				return []string{record}, nil
+			})
+			assert.NoError(t, err, "Signature must be valid")
+		}
+	})
+
+	t.Run("STARTTLS is required", func(t *testing.T) {
+		// Arrange
+		srv := newTestSMTPServer(t, smtptest.Config{})
+		defer srv.Close()
+		s := newTestSMTP(t, srv, SMTPConfig{TLS: SMTPStartTLS})
+
+		// Act
+		_, err := s.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Missing STARTTLS must be permanent failure")
+		assert.Empty(t, srv.Messages(), "Message must not be sent in plain text")
+	})
+
+	t.Run("Untrusted certificate", func(t *testing.T) {
+		// Arrange
+		srv := newTestSMTPServer(t, smtptest.Config{ImplicitTLS: true})
+		defer srv.Close()
+		s := newTestSMTP(t, srv, SMTPConfig{TLS: SMTPImplicitTLS})
+		s.cfg.TLSConfig.RootCAs = nil
+
+		// Act
+		_, err := s.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Unavailable, status.Code(err), "TLS failure must be reported")
+		assert.Empty(t, srv.Messages(), "Message must not be sent")
+	})
+
+	replyTests := []struct {
+		name      string
+		replies   map[string]string
+		cfg       SMTPConfig
+		code      codes.Code
+		retryable bool
+	}{
+		{"Wrong password", nil,
+			SMTPConfig{Username: smtpTestUser, Password: "wrong"}, codes.PermissionDenied, false},
+		{"Greylisted recipient", map[string]string{"RCPT": "451 4.7.1 Greylisted, try again later"},
+			SMTPConfig{}, codes.Unavailable, true},
+		{"Mailbox full", map[string]string{"RCPT": "452 4.2.2 Mailbox full"},
+			SMTPConfig{}, codes.ResourceExhausted, true},
+		{"Unknown recipient", map[string]string{"RCPT": "550 5.1.1 User unknown"},
+			SMTPConfig{}, codes.InvalidArgument, false},
+		{"Message rejected as spam", map[string]string{"DATA": "554 5.7.1 Rejected as spam"},
+			SMTPConfig{}, codes.FailedPrecondition, false},
+		{"Service shutting down", map[string]string{"MAIL": "421 4.3.2 Service shutting down"},
+			SMTPConfig{}, codes.Unavailable, true},
+	}
+	for _, tt := range replyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			srv := newTestSMTPServer(t, smtptest.Config{
+				StartTLS: true,
+				Username: smtpTestUser,
+				Password: smtpTestPassword,
+				Replies:  tt.replies,
+			})
+			defer srv.Close()
+			if tt.cfg.Username == "" {
+				tt.cfg.Username, tt.cfg.Password = smtpTestUser, smtpTestPassword
+			}
+			s := newTestSMTP(t, srv, tt.cfg)
+
+			// Act
+			receipt, err := s.Send(context.Background(), validRequest())
+
+			// Assert
+			assert.Nil(t, receipt, "Receipt must not exist")
+			assert.Equal(t, tt.code, status.Code(err), "Reply must be translated to gRPC code")
+			assert.Equal(t, tt.retryable, IsRetryable(err), "Reply must be classified as temporary or permanent")
+			assert.Empty(t, srv.Messages(), "Message must not be accepted")
+		})
+	}
+
+	t.Run("Closed relay", func(t *testing.T) {
+		// Arrange
+		srv := newTestSMTPServer(t, smtptest.Config{})
+		s := newTestSMTP(t, srv, SMTPConfig{TLS: SMTPTLSNone})
+		srv.Close()
+
+		// Act
+		_, err := s.Send(context.Background(), validRequest())
+
+		// Assert
+		assert.Equal(t, codes.Unavailable, status.Code(err), "Connection failure must be temporary")
+	})
+}
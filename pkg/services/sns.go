@@ -0,0 +1,237 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// Types of the Amazon SNS messages
+const (
+	snsSubscriptionConfirmation = "SubscriptionConfirmation"
+	snsUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+	snsNotification             = "Notification"
+)
+
+// snsMessage is the message delivered by Amazon SNS to the HTTP endpoint
+type snsMessage struct {
+	Type             string
+	MessageID        string `json:"MessageId"`
+	Token            string
+	TopicArn         string
+	Subject          string
+	Message          string
+	Timestamp        string
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+	SubscribeURL     string
+}
+
+// sesNotification is the SES notification or the event of the configuration set
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce struct {
+		BounceType        string `json:"bounceType"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplainedRecipients []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+		ComplaintFeedbackType string `json:"complaintFeedbackType"`
+	} `json:"complaint"`
+	Delivery struct {
+		Recipients []string `json:"recipients"`
+	} `json:"delivery"`
+}
+
+func (w *Webhooks) handleSNS(r *http.Request, body []byte) error {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return webhookErrorf(http.StatusBadRequest, "invalid SNS message: %v", err)
+	}
+	if len(w.sns.TopicARNs) > 0 && !containsString(w.sns.TopicARNs, msg.TopicArn) {
+		return webhookErrorf(http.StatusForbidden, "SNS topic %q is not allowed", msg.TopicArn)
+	}
+	if !w.verifySNS(&msg) {
+		return webhookErrorf(http.StatusForbidden, "invalid signature of SNS message")
+	}
+	if signed, err := time.Parse(time.RFC3339, msg.Timestamp); err != nil || !w.fresh(signed) {
+		return webhookErrorf(http.StatusForbidden, "SNS message is too old")
+	}
+
+	switch msg.Type {
+	case snsSubscriptionConfirmation:
+		if !w.sns.ConfirmSubscriptions {
+			zap.L().Info("Confirm the subscription of SNS topic by visiting the URL",
+				zap.String("topic", msg.TopicArn), zap.String("url", msg.SubscribeURL))
+			return nil
+		}
+		return w.confirmSubscription(&msg)
+	case snsUnsubscribeConfirmation:
+		zap.L().Info("Unsubscribed from SNS topic", zap.String("topic", msg.TopicArn))
+		return nil
+	case snsNotification:
+		var n sesNotification
+		if err := json.Unmarshal([]byte(msg.Message), &n); err != nil {
+			return webhookErrorf(http.StatusBadRequest, "invalid SES notification: %v", err)
+		}
+		for _, ev := range sesProviderEvents(&n) {
+			if err := w.apply(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return webhookErrorf(http.StatusBadRequest, "unknown type of SNS message %q", msg.Type)
+	}
+}
+
+// sesProviderEvents translates the notification to the event of every recipient
+func sesProviderEvents(n *sesNotification) []providerEvent {
+	kind := n.NotificationType
+	if kind == "" {
+		kind = n.EventType
+	}
+	event := func(state MessageState, recipient, description string, suppress pb.Suppression_Reason) providerEvent {
+		return providerEvent{
+			provider:          SESType,
+			kind:              kind,
+			providerMessageID: n.Mail.MessageID,
+			state:             state,
+			recipient:         recipient,
+			description:       description,
+			suppress:          suppress,
+		}
+	}
+
+	var events []providerEvent
+	switch kind {
+	case "Bounce":
+		suppress := pb.Suppression_REASON_UNSPECIFIED
+		if n.Bounce.BounceType == "Permanent" {
+			suppress = pb.Suppression_BOUNCE
+		}
+		for _, r := range n.Bounce.BouncedRecipients {
+			events = append(events, event(MessageBounced, r.EmailAddress, r.DiagnosticCode, suppress))
+		}
+	case "Complaint":
+		for _, r := range n.Complaint.ComplainedRecipients {
+			events = append(events, event("", r.EmailAddress, n.Complaint.ComplaintFeedbackType, pb.Suppression_COMPLAINT))
+		}
+	case "Delivery":
+		events = append(events, event(MessageDelivered, "", "", pb.Suppression_REASON_UNSPECIFIED))
+	case "Open":
+		events = append(events, event(MessageOpened, "", "", pb.Suppression_REASON_UNSPECIFIED))
+	default:
+		// The kind is the label of the metric, so it is not taken from the unknown events
+		events = append(events, event("", "", "", pb.Suppression_REASON_UNSPECIFIED))
+		events[0].kind = otherEventKind
+	}
+	return events
+}
+
+// verifySNS checks the signature of the message with the configured certificates
+func (w *Webhooks) verifySNS(msg *snsMessage) bool {
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return false
+	}
+	var (
+		hash   crypto.Hash
+		digest []byte
+	)
+	text := snsStringToSign(msg)
+	switch msg.SignatureVersion {
+	case "1":
+		sum := sha1.Sum(text)
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256(text)
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return false
+	}
+	for _, cert := range w.snsCerts {
+		pk, ok := cert.PublicKey.(*rsa.PublicKey)
+		if ok && rsa.VerifyPKCS1v15(pk, hash, digest, sig) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// snsStringToSign builds the text signed by Amazon SNS from the fields of
+// the message in the alphabetical order
+func snsStringToSign(msg *snsMessage) []byte {
+	var buf bytes.Buffer
+	field := func(name, value string) {
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	field("Message", msg.Message)
+	field("MessageId", msg.MessageID)
+	if msg.Type == snsNotification {
+		if msg.Subject != "" {
+			field("Subject", msg.Subject)
+		}
+	} else {
+		field("SubscribeURL", msg.SubscribeURL)
+	}
+	field("Timestamp", msg.Timestamp)
+	if msg.Type != snsNotification {
+		field("Token", msg.Token)
+	}
+	field("TopicArn", msg.TopicArn)
+	field("Type", msg.Type)
+	return buf.Bytes()
+}
+
+// confirmSubscription visits the SubscribeURL of the verified message
+func (w *Webhooks) confirmSubscription(msg *snsMessage) error {
+	resp, err := w.client.Get(msg.SubscribeURL)
+	if err != nil {
+		return webhookErrorf(http.StatusBadGateway, "unable to confirm SNS subscription: %v", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return webhookErrorf(http.StatusBadGateway, "unable to confirm SNS subscription: %s", resp.Status)
+	}
+	zap.L().Info("Confirmed the subscription of SNS topic", zap.String("topic", msg.TopicArn))
+	return nil
+}
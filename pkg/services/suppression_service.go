@@ -0,0 +1,85 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SuppressionService manages the addresses to which SendMail does not send emails
+type SuppressionService struct {
+	pb.SuppressionServiceServer
+	opts *options
+}
+
+// NewSuppressionService constructor of SuppressionService
+func NewSuppressionService(opts ...Option) *SuppressionService {
+	return &SuppressionService{
+		opts: evaluateOptions(opts),
+	}
+}
+
+// AddSuppression stops sending emails to the address
+func (ss *SuppressionService) AddSuppression(ctx context.Context, req *pb.AddSuppressionRequest) (*pb.Suppression, error) {
+	suppressions, err := ss.suppressions()
+	if err != nil {
+		return nil, err
+	}
+	return suppressions.Add(req.GetSuppression())
+}
+
+// GetSuppression returns the suppression of the address
+func (ss *SuppressionService) GetSuppression(ctx context.Context, req *pb.GetSuppressionRequest) (*pb.Suppression, error) {
+	suppressions, err := ss.suppressions()
+	if err != nil {
+		return nil, err
+	}
+	return suppressions.Get(req.GetEmail())
+}
+
+// ListSuppressions returns the page of suppressed addresses
+func (ss *SuppressionService) ListSuppressions(ctx context.Context, req *pb.ListSuppressionsRequest) (*pb.ListSuppressionsResponse, error) {
+	suppressions, err := ss.suppressions()
+	if err != nil {
+		return nil, err
+	}
+	list, next, err := suppressions.List(int(req.GetPageSize()), req.GetPageToken(), req.GetReason())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListSuppressionsResponse{Suppressions: list, NextPageToken: next}, nil
+}
+
+// DeleteSuppression allows sending emails to the address again
+func (ss *SuppressionService) DeleteSuppression(ctx context.Context, req *pb.DeleteSuppressionRequest) (*pb.DeleteSuppressionResponse, error) {
+	suppressions, err := ss.suppressions()
+	if err != nil {
+		return nil, err
+	}
+	if err := suppressions.Delete(req.GetEmail()); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteSuppressionResponse{}, nil
+}
+
+func (ss *SuppressionService) suppressions() (*Suppressions, error) {
+	if ss.opts.suppressions == nil {
+		return nil, status.Error(codes.FailedPrecondition, "suppressions are not configured")
+	}
+	return ss.opts.suppressions, nil
+}
@@ -0,0 +1,242 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// storedSuppression is the suppressed address as it is kept in the store
+type storedSuppression struct {
+	Email       string    `json:"email"`
+	Reason      string    `json:"reason"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Suppressions stores the addresses to which emails are not sent. The
+// addresses are compared case insensitive and with the international domains
+// in punycode, as the emails are sent.
+type Suppressions struct {
+	store *storage.Store
+	now   func() time.Time
+}
+
+// NewSuppressions constructor of Suppressions
+func NewSuppressions(store *storage.Store) *Suppressions {
+	return &Suppressions{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Add suppresses the address, the reason of the address which is already
+// suppressed is replaced. The suppression without the reason is manual.
+func (s *Suppressions) Add(sup *pb.Suppression) (*pb.Suppression, error) {
+	if sup == nil {
+		return nil, status.Error(codes.InvalidArgument, "suppression is required")
+	}
+	if err := validateAddress("suppressed", &pb.Address{Email: sup.GetEmail()}); err != nil {
+		return nil, err
+	}
+	if _, ok := pb.Suppression_Reason_name[int32(sup.GetReason())]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown suppression reason %d", sup.GetReason())
+	}
+	if containsNewLine(sup.GetDescription()) {
+		return nil, status.Error(codes.InvalidArgument, "suppression description can not contain new line characters")
+	}
+	reason := sup.GetReason()
+	if reason == pb.Suppression_REASON_UNSPECIFIED {
+		reason = pb.Suppression_MANUAL
+	}
+	return s.add(sup.GetEmail(), reason, sup.GetDescription())
+}
+
+// add suppresses the address without validation of the request
+func (s *Suppressions) add(email string, reason pb.Suppression_Reason, description string) (*pb.Suppression, error) {
+	ss := &storedSuppression{
+		Email:       suppressionKey(email),
+		Reason:      reason.String(),
+		Description: description,
+		CreatedAt:   s.now(),
+	}
+	value, err := json.Marshal(ss)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not encode suppression %q: %v", ss.Email, err)
+	}
+	if err := s.store.Put(ss.Email, value); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not store suppression %q: %v", ss.Email, err)
+	}
+	return suppressionToProto(ss)
+}
+
+// Get returns the suppression of the address, NOT_FOUND when it is not suppressed
+func (s *Suppressions) Get(email string) (*pb.Suppression, error) {
+	if email == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	ss, ok, err := s.lookup(email)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "address %q is not suppressed", email)
+	}
+	return suppressionToProto(ss)
+}
+
+// List returns the page of suppressions ordered by email, which starts after
+// the page token. Only the suppressions with the reason are returned unless
+// the reason is unspecified. The returned token is empty on the last page.
+func (s *Suppressions) List(pageSize int, pageToken string, reason pb.Suppression_Reason) ([]*pb.Suppression, string, error) {
+	pageSize = normalizePageSize(pageSize)
+	var (
+		suppressions []*pb.Suppression
+		next         string
+		err          error
+	)
+	s.store.Range("", func(key string, value []byte) bool {
+		if key <= pageToken {
+			return true
+		}
+		ss := &storedSuppression{}
+		if err = json.Unmarshal(value, ss); err != nil {
+			err = status.Errorf(codes.Internal, "can not decode suppression %q: %v", key, err)
+			return false
+		}
+		if reason != pb.Suppression_REASON_UNSPECIFIED && ss.Reason != reason.String() {
+			return true
+		}
+		if len(suppressions) == pageSize {
+			next = suppressions[len(suppressions)-1].Email
+			return false
+		}
+		var sup *pb.Suppression
+		if sup, err = suppressionToProto(ss); err != nil {
+			return false
+		}
+		suppressions = append(suppressions, sup)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return suppressions, next, nil
+}
+
+// Delete allows sending emails to the address again
+func (s *Suppressions) Delete(email string) error {
+	if email == "" {
+		return status.Error(codes.InvalidArgument, "email is required")
+	}
+	key := suppressionKey(email)
+	if _, ok := s.store.Get(key); !ok {
+		return status.Errorf(codes.NotFound, "address %q is not suppressed", email)
+	}
+	if err := s.store.Delete(key); err != nil {
+		return status.Errorf(codes.Internal, "can not delete suppression %q: %v", email, err)
+	}
+	return nil
+}
+
+// filter returns the copy of the email without the suppressed recipients
+// together with their suppressions. The email is returned as it is when no
+// recipient is suppressed.
+func (s *Suppressions) filter(email *pb.EmailRequest) (*pb.EmailRequest, []*pb.Suppression, error) {
+	var suppressed []*pb.Suppression
+	keep := func(addresses []*pb.Address) ([]*pb.Address, error) {
+		var kept []*pb.Address
+		for _, a := range addresses {
+			ss, ok, err := s.lookup(a.GetEmail())
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				kept = append(kept, a)
+				continue
+			}
+			sup, err := suppressionToProto(ss)
+			if err != nil {
+				return nil, err
+			}
+			// The response reports the address as the client sent it
+			sup.Email = a.GetEmail()
+			suppressed = append(suppressed, sup)
+			suppressedRecipients.WithLabelValues(strings.ToLower(ss.Reason)).Inc()
+		}
+		return kept, nil
+	}
+
+	to, err := keep(email.GetTo())
+	if err != nil {
+		return nil, nil, err
+	}
+	cc, err := keep(email.GetCc())
+	if err != nil {
+		return nil, nil, err
+	}
+	bcc, err := keep(email.GetBcc())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(suppressed) == 0 {
+		return email, nil, nil
+	}
+	filtered := proto.Clone(email).(*pb.EmailRequest)
+	filtered.To, filtered.Cc, filtered.Bcc = to, cc, bcc
+	return filtered, suppressed, nil
+}
+
+func (s *Suppressions) lookup(email string) (*storedSuppression, bool, error) {
+	key := suppressionKey(email)
+	value, ok := s.store.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	ss := &storedSuppression{}
+	if err := json.Unmarshal(value, ss); err != nil {
+		return nil, false, status.Errorf(codes.Internal, "can not decode suppression %q: %v", key, err)
+	}
+	return ss, true, nil
+}
+
+// suppressionKey normalizes the address, so the address with the unicode
+// domain matches the same address in punycode
+func suppressionKey(email string) string {
+	if addr, err := parseAddress(email); err == nil {
+		email = addr.ascii()
+	}
+	return strings.ToLower(email)
+}
+
+func suppressionToProto(ss *storedSuppression) (*pb.Suppression, error) {
+	out := &pb.Suppression{
+		Email:       ss.Email,
+		Reason:      pb.Suppression_Reason(pb.Suppression_Reason_value[ss.Reason]),
+		Description: ss.Description,
+	}
+	var err error
+	if out.CreatedAt, err = timestampProto(ss.CreatedAt); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
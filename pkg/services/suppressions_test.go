@@ -0,0 +1,219 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestSuppressions(t *testing.T) *Suppressions {
+	return NewSuppressions(openTestStore(t, "suppressions"))
+}
+
+func TestSuppressionService(t *testing.T) {
+	// Arrange
+	suppressions := newTestSuppressions(t)
+	ss := NewSuppressionService(WithSuppressions(suppressions))
+	ctx := context.Background()
+
+	t.Run("Address is suppressed", func(t *testing.T) {
+		// Act
+		sup, err := ss.AddSuppression(ctx, &pb.AddSuppressionRequest{Suppression: &pb.Suppression{
+			Email:       "Bounced@Example.com",
+			Reason:      pb.Suppression_BOUNCE,
+			Description: "550 5.1.1 user unknown",
+		}})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "bounced@example.com", sup.Email, "Address must be normalized")
+		assert.Equal(t, pb.Suppression_BOUNCE, sup.Reason, "Reason must be kept")
+		assert.NotNil(t, sup.CreatedAt, "Creation time must be set")
+	})
+
+	t.Run("Suppression without reason is manual", func(t *testing.T) {
+		// Act
+		sup, err := ss.AddSuppression(ctx, &pb.AddSuppressionRequest{Suppression: &pb.Suppression{Email: "manual@example.com"}})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, pb.Suppression_MANUAL, sup.Reason, "Reason must default to manual")
+	})
+
+	t.Run("Suppression is checked case insensitive", func(t *testing.T) {
+		// Act
+		sup, err := ss.GetSuppression(ctx, &pb.GetSuppressionRequest{Email: "BOUNCED@example.com"})
+		_, notFoundErr := ss.GetSuppression(ctx, &pb.GetSuppressionRequest{Email: "other@example.com"})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "550 5.1.1 user unknown", sup.Description, "Description must be returned")
+		assert.Equal(t, codes.NotFound, status.Code(notFoundErr), "Address which is not suppressed must not be found")
+	})
+
+	t.Run("Suppressions are listed by reason", func(t *testing.T) {
+		// Act
+		all, err := ss.ListSuppressions(ctx, &pb.ListSuppressionsRequest{PageSize: 1})
+		bounces, bouncesErr := ss.ListSuppressions(ctx, &pb.ListSuppressionsRequest{Reason: pb.Suppression_BOUNCE})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Len(t, all.Suppressions, 1, "Page must be limited")
+		assert.Equal(t, "bounced@example.com", all.NextPageToken, "Next page token must be returned")
+		assert.NoError(t, bouncesErr, "Error should not occur")
+		if assert.Len(t, bounces.Suppressions, 1, "Only bounces must be listed") {
+			assert.Equal(t, "bounced@example.com", bounces.Suppressions[0].Email, "Bounce must be listed")
+		}
+		assert.Empty(t, bounces.NextPageToken, "Last page has no token")
+	})
+
+	t.Run("Suppression is deleted", func(t *testing.T) {
+		// Act
+		_, err := ss.DeleteSuppression(ctx, &pb.DeleteSuppressionRequest{Email: "manual@example.com"})
+		_, againErr := ss.DeleteSuppression(ctx, &pb.DeleteSuppressionRequest{Email: "manual@example.com"})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, codes.NotFound, status.Code(againErr), "Deleted suppression must not be found")
+	})
+
+	t.Run("Invalid suppressions are rejected", func(t *testing.T) {
+		for _, sup := range []*pb.Suppression{
+			nil,
+			{Email: "not an address"},
+			{Email: "a@example.com", Reason: pb.Suppression_Reason(42)},
+			{Email: "a@example.com", Description: "a\nb"},
+		} {
+			// Act
+			_, err := ss.AddSuppression(ctx, &pb.AddSuppressionRequest{Suppression: sup})
+
+			// Assert
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Suppression %v must be rejected", sup)
+		}
+	})
+
+	t.Run("Service without suppressions", func(t *testing.T) {
+		// Act
+		_, err := NewSuppressionService().GetSuppression(ctx, &pb.GetSuppressionRequest{Email: "a@example.com"})
+
+		// Assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Failed precondition must be returned")
+	})
+}
+
+func TestEmailService_Suppressions(t *testing.T) {
+	// Arrange
+	suppressions := newTestSuppressions(t)
+	_, err := suppressions.add("bounced@example.com", pb.Suppression_BOUNCE, "")
+	assert.NoError(t, err, "Suppression should be added")
+	_, err = suppressions.add("complained@example.com", pb.Suppression_COMPLAINT, "")
+	assert.NoError(t, err, "Suppression should be added")
+
+	t.Run("Suppressed recipients are dropped and reported", func(t *testing.T) {
+		// Arrange
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithSuppressions(suppressions))
+		req := validRequest()
+		req.Cc = []*pb.Address{{Email: "Bounced@example.com"}}
+		req.Bcc = []*pb.Address{{Email: "complained@example.com"}}
+
+		// Act
+		resp, err := es.SendMail(context.Background(), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "id-fake", resp.MessageId, "Email must be sent to the rest of recipients")
+		if assert.Len(t, resp.Suppressed, 2, "Suppressed recipients must be reported") {
+			assert.Equal(t, "Bounced@example.com", resp.Suppressed[0].Email, "Address must be reported as it was sent")
+			assert.Equal(t, pb.Suppression_BOUNCE, resp.Suppressed[0].Reason, "Reason must be reported")
+			assert.Equal(t, pb.Suppression_COMPLAINT, resp.Suppressed[1].Reason, "Reason must be reported")
+		}
+		if assert.Len(t, provider.requests, 1, "Email must be sent") {
+			assert.Empty(t, provider.requests[0].Cc, "Suppressed cc must be dropped")
+			assert.Empty(t, provider.requests[0].Bcc, "Suppressed bcc must be dropped")
+			assert.Len(t, provider.requests[0].To, 1, "Recipient must be kept")
+		}
+		assert.Len(t, req.Cc, 1, "Request of the client must not be modified")
+	})
+
+	t.Run("Email to suppressed recipients only is not sent", func(t *testing.T) {
+		// Arrange
+		q := newTestQueue(t)
+		es := NewEmailService(WithQueue(q), WithSuppressions(suppressions))
+		req := validRequest()
+		req.To = []*pb.Address{{Email: "bounced@example.com"}}
+
+		// Act
+		resp, err := es.SendMail(context.Background(), req)
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Empty(t, resp.MessageId, "Email must not be queued")
+		assert.Len(t, resp.Suppressed, 1, "Suppressed recipient must be reported")
+		assert.Len(t, q.due, 0, "Queue must be empty")
+	})
+
+	t.Run("Suppression of the international domain matches punycode", func(t *testing.T) {
+		// Arrange
+		_, err := suppressions.add("jan@bücher.example", pb.Suppression_BOUNCE, "")
+		assert.NoError(t, err, "Suppression should be added")
+		provider := &fakeProvider{name: "fake"}
+		v, err := NewAddressValidator(AddressValidation{}, nil)
+		assert.NoError(t, err, "Error should not occur")
+		es := NewEmailService(WithProvider(provider), WithSuppressions(suppressions), WithAddressValidator(v))
+		req := validRequest()
+		req.Cc = []*pb.Address{{Email: "jan@bücher.example"}, {Email: "jan@xn--bcher-kva.example"}}
+
+		// Act
+		resp, err := es.SendMail(context.Background(), req)
+		sup, getErr := suppressions.Get("JAN@xn--bcher-kva.example")
+		deleteErr := suppressions.Delete("jan@xn--bcher-kva.example")
+		_, lookupErr := suppressions.Get("jan@bücher.example")
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Len(t, resp.Suppressed, 2, "Both forms of the address must be suppressed")
+		if assert.Len(t, provider.requests, 1, "Email must be sent") {
+			assert.Empty(t, provider.requests[0].Cc, "Suppressed recipients must be dropped")
+		}
+		assert.NoError(t, getErr, "Suppression must be found by punycode")
+		assert.Equal(t, "jan@xn--bcher-kva.example", sup.Email, "Address must be stored in punycode")
+		assert.NoError(t, deleteErr, "Suppression must be deleted by punycode")
+		assert.Equal(t, codes.NotFound, status.Code(lookupErr), "Suppression must be deleted")
+	})
+
+	t.Run("Suppressed recipient of the batch is reported", func(t *testing.T) {
+		// Arrange
+		provider := &fakeProvider{name: "fake"}
+		es := NewEmailService(WithProvider(provider), WithSuppressions(suppressions))
+
+		// Act
+		resp, err := es.SendMailBatch(context.Background(), batchRequest("first@example.com", "complained@example.com"))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		if assert.Len(t, resp.Results, 2, "Result must be returned per recipient") {
+			assert.Equal(t, "id-fake", resp.Results[0].MessageId, "Email must be sent")
+			assert.Empty(t, resp.Results[1].MessageId, "Email must not be sent to suppressed recipient")
+			assert.Equal(t, pb.Suppression_COMPLAINT, resp.Results[1].Suppressed.GetReason(), "Suppression must be reported")
+		}
+		assert.Len(t, provider.requests, 1, "Only one email must be sent")
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TemplateService stores the templates used by SendMail
+type TemplateService struct {
+	pb.TemplateServiceServer
+	opts *options
+}
+
+// NewTemplateService constructor of TemplateService
+func NewTemplateService(opts ...Option) *TemplateService {
+	return &TemplateService{
+		opts: evaluateOptions(opts),
+	}
+}
+
+// CreateTemplate stores the new template
+func (ts *TemplateService) CreateTemplate(ctx context.Context, req *pb.CreateTemplateRequest) (*pb.Template, error) {
+	templates, err := ts.templates()
+	if err != nil {
+		return nil, err
+	}
+	return templates.Create(req.GetTemplate())
+}
+
+// GetTemplate returns the template
+func (ts *TemplateService) GetTemplate(ctx context.Context, req *pb.GetTemplateRequest) (*pb.Template, error) {
+	templates, err := ts.templates()
+	if err != nil {
+		return nil, err
+	}
+	return templates.Get(req.GetId())
+}
+
+// ListTemplates returns the page of templates
+func (ts *TemplateService) ListTemplates(ctx context.Context, req *pb.ListTemplatesRequest) (*pb.ListTemplatesResponse, error) {
+	templates, err := ts.templates()
+	if err != nil {
+		return nil, err
+	}
+	list, next, err := templates.List(int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListTemplatesResponse{Templates: list, NextPageToken: next}, nil
+}
+
+// UpdateTemplate replaces the content of the template
+func (ts *TemplateService) UpdateTemplate(ctx context.Context, req *pb.UpdateTemplateRequest) (*pb.Template, error) {
+	templates, err := ts.templates()
+	if err != nil {
+		return nil, err
+	}
+	return templates.Update(req.GetTemplate())
+}
+
+// DeleteTemplate removes the template
+func (ts *TemplateService) DeleteTemplate(ctx context.Context, req *pb.DeleteTemplateRequest) (*pb.DeleteTemplateResponse, error) {
+	templates, err := ts.templates()
+	if err != nil {
+		return nil, err
+	}
+	if err := templates.Delete(req.GetId()); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteTemplateResponse{}, nil
+}
+
+func (ts *TemplateService) templates() (*Templates, error) {
+	if ts.opts.templates == nil {
+		return nil, status.Error(codes.FailedPrecondition, "templates are not configured")
+	}
+	return ts.opts.templates, nil
+}
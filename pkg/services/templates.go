@@ -0,0 +1,312 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"regexp"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/RafalKorepta/coding-challenge/pkg/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var templateIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// storedTemplate is the template as it is kept in the store
+type storedTemplate struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"subject"`
+	TextBody  string    `json:"text_body,omitempty"`
+	HTMLBody  string    `json:"html_body,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// parsedTemplate is the template ready to render
+type parsedTemplate struct {
+	subject  *texttemplate.Template
+	textBody *texttemplate.Template
+	htmlBody *htmltemplate.Template
+}
+
+// rendered is the content of the email produced from the template
+type rendered struct {
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Templates stores the email templates. The subject and the text body are
+// rendered with text/template, the HTML body with html/template. Referencing
+// the variable which was not passed is an error.
+type Templates struct {
+	store *storage.Store
+	now   func() time.Time
+
+	// mu serializes writes, so the update does not race with the delete
+	mu sync.Mutex
+}
+
+// NewTemplates constructor of Templates
+func NewTemplates(store *storage.Store) *Templates {
+	return &Templates{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Create stores the new template, the id is generated when empty
+func (t *Templates) Create(tpl *pb.Template) (*pb.Template, error) {
+	if tpl == nil {
+		return nil, status.Error(codes.InvalidArgument, "template is required")
+	}
+	id := tpl.GetId()
+	if id == "" {
+		var err error
+		if id, err = newID(); err != nil {
+			return nil, status.Errorf(codes.Internal, "can not generate template id: %v", err)
+		}
+	}
+	if !templateIDPattern.MatchString(id) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"template id %q must have up to 64 letters, digits, '_', '.' or '-'", id)
+	}
+	now := t.now()
+	st := &storedTemplate{
+		ID:        id,
+		Subject:   tpl.GetSubject(),
+		TextBody:  tpl.GetTextBody(),
+		HTMLBody:  tpl.GetHtmlBody(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := parseTemplate(st); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.store.Get(id); ok {
+		return nil, status.Errorf(codes.AlreadyExists, "template %q already exists", id)
+	}
+	if err := t.save(st); err != nil {
+		return nil, err
+	}
+	return templateToProto(st)
+}
+
+// Get returns the template
+func (t *Templates) Get(id string) (*pb.Template, error) {
+	st, err := t.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return templateToProto(st)
+}
+
+// List returns the page of templates ordered by id, which starts after the
+// page token. The returned token is empty on the last page.
+func (t *Templates) List(pageSize int, pageToken string) ([]*pb.Template, string, error) {
+	pageSize = normalizePageSize(pageSize)
+	var (
+		templates []*pb.Template
+		next      string
+		err       error
+	)
+	t.store.Range("", func(key string, value []byte) bool {
+		if key <= pageToken {
+			return true
+		}
+		if len(templates) == pageSize {
+			next = templates[len(templates)-1].Id
+			return false
+		}
+		st := &storedTemplate{}
+		if err = json.Unmarshal(value, st); err != nil {
+			err = status.Errorf(codes.Internal, "can not decode template %q: %v", key, err)
+			return false
+		}
+		var tpl *pb.Template
+		if tpl, err = templateToProto(st); err != nil {
+			return false
+		}
+		templates = append(templates, tpl)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return templates, next, nil
+}
+
+// Update replaces the content of the existing template
+func (t *Templates) Update(tpl *pb.Template) (*pb.Template, error) {
+	if tpl == nil {
+		return nil, status.Error(codes.InvalidArgument, "template is required")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, err := t.get(tpl.GetId())
+	if err != nil {
+		return nil, err
+	}
+	st.Subject = tpl.GetSubject()
+	st.TextBody = tpl.GetTextBody()
+	st.HTMLBody = tpl.GetHtmlBody()
+	st.UpdatedAt = t.now()
+	if _, err := parseTemplate(st); err != nil {
+		return nil, err
+	}
+	if err := t.save(st); err != nil {
+		return nil, err
+	}
+	return templateToProto(st)
+}
+
+// Delete removes the template
+func (t *Templates) Delete(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.get(id); err != nil {
+		return err
+	}
+	if err := t.store.Delete(id); err != nil {
+		return status.Errorf(codes.Internal, "can not remove template %q: %v", id, err)
+	}
+	return nil
+}
+
+// render produces the content of the email from the template and the variables
+func (t *Templates) render(id string, variables map[string]string) (*rendered, error) {
+	st, err := t.get(id)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, status.Errorf(codes.InvalidArgument, "template %q not found", id)
+		}
+		return nil, err
+	}
+	parsed, err := parseTemplate(st)
+	if err != nil {
+		return nil, err
+	}
+	if variables == nil {
+		variables = map[string]string{}
+	}
+
+	out := &rendered{}
+	var buf bytes.Buffer
+	if err := parsed.subject.Execute(&buf, variables); err != nil {
+		return nil, renderError(id, "subject", err)
+	}
+	out.Subject = buf.String()
+	if parsed.textBody != nil {
+		buf.Reset()
+		if err := parsed.textBody.Execute(&buf, variables); err != nil {
+			return nil, renderError(id, "text body", err)
+		}
+		out.TextBody = buf.String()
+	}
+	if parsed.htmlBody != nil {
+		buf.Reset()
+		if err := parsed.htmlBody.Execute(&buf, variables); err != nil {
+			return nil, renderError(id, "HTML body", err)
+		}
+		out.HTMLBody = buf.String()
+	}
+	return out, nil
+}
+
+func (t *Templates) get(id string) (*storedTemplate, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "template id is required")
+	}
+	value, ok := t.store.Get(id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "template %q not found", id)
+	}
+	st := &storedTemplate{}
+	if err := json.Unmarshal(value, st); err != nil {
+		return nil, status.Errorf(codes.Internal, "can not decode template %q: %v", id, err)
+	}
+	return st, nil
+}
+
+func (t *Templates) save(st *storedTemplate) error {
+	value, err := json.Marshal(st)
+	if err != nil {
+		return status.Errorf(codes.Internal, "can not encode template %q: %v", st.ID, err)
+	}
+	if err := t.store.Put(st.ID, value); err != nil {
+		return status.Errorf(codes.Internal, "can not store template %q: %v", st.ID, err)
+	}
+	return nil
+}
+
+// parseTemplate checks the template and prepares it for rendering
+func parseTemplate(st *storedTemplate) (*parsedTemplate, error) {
+	if st.Subject == "" {
+		return nil, status.Error(codes.InvalidArgument, "template subject is required")
+	}
+	if st.TextBody == "" && st.HTMLBody == "" {
+		return nil, status.Error(codes.InvalidArgument, "template requires text or HTML body")
+	}
+
+	var (
+		parsed parsedTemplate
+		err    error
+	)
+	if parsed.subject, err = texttemplate.New("subject").Option("missingkey=error").Parse(st.Subject); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid template subject: %v", err)
+	}
+	if st.TextBody != "" {
+		if parsed.textBody, err = texttemplate.New("text").Option("missingkey=error").Parse(st.TextBody); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid template text body: %v", err)
+		}
+	}
+	if st.HTMLBody != "" {
+		if parsed.htmlBody, err = htmltemplate.New("html").Option("missingkey=error").Parse(st.HTMLBody); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid template HTML body: %v", err)
+		}
+	}
+	return &parsed, nil
+}
+
+func renderError(id, part string, err error) error {
+	return status.Errorf(codes.InvalidArgument, "can not render %s of template %q: %v", part, id, err)
+}
+
+func templateToProto(st *storedTemplate) (*pb.Template, error) {
+	out := &pb.Template{
+		Id:       st.ID,
+		Subject:  st.Subject,
+		TextBody: st.TextBody,
+		HtmlBody: st.HTMLBody,
+	}
+	var err error
+	if out.CreatedAt, err = timestampProto(st.CreatedAt); err != nil {
+		return nil, err
+	}
+	if out.UpdatedAt, err = timestampProto(st.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
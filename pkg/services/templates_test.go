@@ -0,0 +1,215 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestTemplates(t *testing.T) *Templates {
+	return NewTemplates(openTestStore(t, "templates"))
+}
+
+func welcomeTemplate() *pb.Template {
+	return &pb.Template{
+		Id:       "welcome",
+		Subject:  "Welcome {{.name}}",
+		TextBody: "Hello {{.name}}, your code is {{.code}}",
+		HtmlBody: "<p>Hello {{.name}}</p>",
+	}
+}
+
+func TestTemplateService(t *testing.T) {
+	// Arrange
+	templates := newTestTemplates(t)
+	ts := NewTemplateService(WithTemplates(templates))
+	ctx := context.Background()
+
+	t.Run("Template is created", func(t *testing.T) {
+		// Act
+		tpl, err := ts.CreateTemplate(ctx, &pb.CreateTemplateRequest{Template: welcomeTemplate()})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, "welcome", tpl.Id, "Id must be kept")
+		assert.NotNil(t, tpl.CreatedAt, "Creation time must be set")
+	})
+
+	t.Run("Template id is generated", func(t *testing.T) {
+		// Arrange
+		tpl := welcomeTemplate()
+		tpl.Id = ""
+
+		// Act
+		created, err := ts.CreateTemplate(ctx, &pb.CreateTemplateRequest{Template: tpl})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.NotEmpty(t, created.Id, "Id must be generated")
+		_, err = ts.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Id: created.Id})
+		assert.NoError(t, err, "Template should be deleted")
+	})
+
+	t.Run("Duplicated template", func(t *testing.T) {
+		// Act
+		_, err := ts.CreateTemplate(ctx, &pb.CreateTemplateRequest{Template: welcomeTemplate()})
+
+		// Assert
+		assert.Equal(t, codes.AlreadyExists, status.Code(err), "Already exists must be returned")
+	})
+
+	for name, tpl := range map[string]*pb.Template{
+		"Invalid id":       {Id: "no spaces", Subject: "s", TextBody: "t"},
+		"Missing subject":  {Id: "a", TextBody: "t"},
+		"Missing body":     {Id: "a", Subject: "s"},
+		"Invalid template": {Id: "a", Subject: "{{.name", TextBody: "t"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			_, err := ts.CreateTemplate(ctx, &pb.CreateTemplateRequest{Template: tpl})
+
+			// Assert
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+		})
+	}
+
+	t.Run("Template is updated", func(t *testing.T) {
+		// Arrange
+		tpl := welcomeTemplate()
+		tpl.Subject = "Hi {{.name}}"
+
+		// Act
+		_, err := ts.UpdateTemplate(ctx, &pb.UpdateTemplateRequest{Template: tpl})
+		got, getErr := ts.GetTemplate(ctx, &pb.GetTemplateRequest{Id: "welcome"})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.NoError(t, getErr, "Error should not occur")
+		assert.Equal(t, "Hi {{.name}}", got.Subject, "Subject must be updated")
+	})
+
+	t.Run("Templates are listed", func(t *testing.T) {
+		// Arrange
+		tpl := welcomeTemplate()
+		tpl.Id = "reset"
+		_, err := ts.CreateTemplate(ctx, &pb.CreateTemplateRequest{Template: tpl})
+		assert.NoError(t, err, "Error should not occur")
+
+		// Act
+		first, err := ts.ListTemplates(ctx, &pb.ListTemplatesRequest{PageSize: 1})
+		assert.NoError(t, err, "Error should not occur")
+		second, err := ts.ListTemplates(ctx, &pb.ListTemplatesRequest{PageSize: 1, PageToken: first.NextPageToken})
+		assert.NoError(t, err, "Error should not occur")
+
+		// Assert
+		assert.Equal(t, "reset", first.Templates[0].Id, "Templates must be ordered by id")
+		assert.Equal(t, "welcome", second.Templates[0].Id, "Templates must be ordered by id")
+		assert.Empty(t, second.NextPageToken, "Second page must be the last one")
+	})
+
+	t.Run("Template is deleted", func(t *testing.T) {
+		// Act
+		_, err := ts.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Id: "reset"})
+		_, getErr := ts.GetTemplate(ctx, &pb.GetTemplateRequest{Id: "reset"})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, codes.NotFound, status.Code(getErr), "Not found must be returned")
+	})
+}
+
+func TestEmailService_SendMailTemplate(t *testing.T) {
+	// Arrange
+	templates := newTestTemplates(t)
+	_, err := templates.Create(welcomeTemplate())
+	assert.NoError(t, err, "Template should be created")
+	provider := &fakeProvider{name: "fake"}
+	es := NewEmailService(WithProvider(provider), WithTemplates(templates))
+
+	templated := func(variables map[string]string) *pb.EmailRequest {
+		req := validRequest()
+		req.Subject = ""
+		req.TextBody = ""
+		req.TemplateId = "welcome"
+		req.Variables = variables
+		return req
+	}
+
+	t.Run("Template is rendered", func(t *testing.T) {
+		// Act
+		_, err := es.SendMail(context.Background(), templated(map[string]string{
+			"name": "<Jane>",
+			"code": "42",
+		}))
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		sent := provider.requests[len(provider.requests)-1]
+		assert.Equal(t, "Welcome <Jane>", sent.Subject, "Subject must be rendered")
+		assert.Equal(t, "Hello <Jane>, your code is 42", sent.TextBody, "Text body must be rendered")
+		assert.Equal(t, "<p>Hello &lt;Jane&gt;</p>", sent.HtmlBody, "HTML body must be escaped")
+		assert.Empty(t, sent.TemplateId, "Template must not be passed to provider")
+	})
+
+	t.Run("Missing variable", func(t *testing.T) {
+		// Act
+		_, err := es.SendMail(context.Background(), templated(map[string]string{"name": "Jane"}))
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+		assert.Contains(t, status.Convert(err).Message(), "code", "Missing variable must be reported")
+	})
+
+	t.Run("Unknown template", func(t *testing.T) {
+		// Arrange
+		req := templated(nil)
+		req.TemplateId = "unknown"
+
+		// Act
+		_, err := es.SendMail(context.Background(), req)
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+	})
+
+	t.Run("Template together with content", func(t *testing.T) {
+		// Arrange
+		req := templated(map[string]string{"name": "Jane", "code": "42"})
+		req.Subject = "Subject"
+
+		// Act
+		_, err := es.SendMail(context.Background(), req)
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+	})
+
+	t.Run("Variables without template", func(t *testing.T) {
+		// Arrange
+		req := validRequest()
+		req.Variables = map[string]string{"name": "Jane"}
+
+		// Act
+		_, err := es.SendMail(context.Background(), req)
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid argument must be returned")
+	})
+}
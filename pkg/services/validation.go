@@ -0,0 +1,152 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"net/textproto"
+	"strings"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxTags is the limit of SendGrid categories
+	maxTags      = 10
+	maxTagLength = 64
+)
+
+// reservedHeaders are derived from the envelope, so they can not be set as custom headers
+var reservedHeaders = map[string]struct{}{
+	"From":                      {},
+	"Reply-To":                  {},
+	"To":                        {},
+	"Cc":                        {},
+	"Bcc":                       {},
+	"Subject":                   {},
+	"Date":                      {},
+	"Message-Id":                {},
+	"Mime-Version":              {},
+	"Content-Type":              {},
+	"Content-Transfer-Encoding": {},
+}
+
+// validateRequest checks that the request has all required parts of the email.
+// The returned error is a gRPC status with InvalidArgument code.
+func validateRequest(req *pb.EmailRequest) error {
+	if req == nil {
+		return status.Error(codes.InvalidArgument, "request can not be empty")
+	}
+	if req.GetFrom() == nil {
+		return status.Error(codes.InvalidArgument, "from address is required")
+	}
+	if err := validateAddress("from", req.GetFrom()); err != nil {
+		return err
+	}
+	if req.GetReplyTo() != nil {
+		if err := validateAddress("reply_to", req.GetReplyTo()); err != nil {
+			return err
+		}
+	}
+	if len(req.GetTo())+len(req.GetCc())+len(req.GetBcc()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one recipient in to, cc or bcc is required")
+	}
+	for _, a := range req.GetTo() {
+		if err := validateAddress("to", a); err != nil {
+			return err
+		}
+	}
+	for _, a := range req.GetCc() {
+		if err := validateAddress("cc", a); err != nil {
+			return err
+		}
+	}
+	for _, a := range req.GetBcc() {
+		if err := validateAddress("bcc", a); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(req.GetSubject()) == "" {
+		return status.Error(codes.InvalidArgument, "subject is required")
+	}
+	if containsNewLine(req.GetSubject()) {
+		return status.Error(codes.InvalidArgument, "subject can not contain new line characters")
+	}
+	if req.GetTextBody() == "" && req.GetHtmlBody() == "" {
+		return status.Error(codes.InvalidArgument, "text or HTML body is required")
+	}
+	for name, value := range req.GetHeaders() {
+		if err := validateHeader(name, value); err != nil {
+			return err
+		}
+	}
+	if len(req.GetTags()) > maxTags {
+		return status.Errorf(codes.InvalidArgument, "email can have at most %d tags", maxTags)
+	}
+	for _, tag := range req.GetTags() {
+		if err := validateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTag(tag string) error {
+	if tag == "" || len(tag) > maxTagLength {
+		return status.Errorf(codes.InvalidArgument, "tag %q must have from 1 to %d characters", tag, maxTagLength)
+	}
+	for _, c := range tag {
+		if c < 32 || c > 126 {
+			return status.Errorf(codes.InvalidArgument, "tag %q must contain only printable ASCII characters", tag)
+		}
+	}
+	return nil
+}
+
+func validateAddress(field string, a *pb.Address) error {
+	if a == nil || a.GetEmail() == "" {
+		return status.Errorf(codes.InvalidArgument, "%s address can not be empty", field)
+	}
+	if containsNewLine(a.GetName()) {
+		return status.Errorf(codes.InvalidArgument, "%s name can not contain new line characters", field)
+	}
+	if _, err := parseAddress(a.GetEmail()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s address %q is not valid", field, a.GetEmail())
+	}
+	return nil
+}
+
+func validateHeader(name, value string) error {
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "header name can not be empty")
+	}
+	for _, c := range name {
+		// RFC 5322 section 2.2 - printable US-ASCII characters except colon
+		if c < 33 || c > 126 || c == ':' {
+			return status.Errorf(codes.InvalidArgument, "header name %q is not valid", name)
+		}
+	}
+	if _, ok := reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)]; ok {
+		return status.Errorf(codes.InvalidArgument, "header %q can not be overridden", name)
+	}
+	if containsNewLine(value) {
+		return status.Errorf(codes.InvalidArgument, "header %q value can not contain new line characters", name)
+	}
+	return nil
+}
+
+func containsNewLine(s string) bool {
+	return strings.ContainsAny(s, "\r\n")
+}
@@ -0,0 +1,261 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxWebhookBody limits the size of the batch of events sent by the provider
+	maxWebhookBody = 5 << 20
+	// otherEventKind counts the events of unknown types
+	otherEventKind = "other"
+	// defaultWebhookMaxAge is the age after which the signed events are rejected
+	defaultWebhookMaxAge = time.Hour
+)
+
+// WebhookConfig configures the endpoints which receive the events of providers
+type WebhookConfig struct {
+	SendGrid SendGridWebhookConfig `mapstructure:"sendgrid"`
+	SNS      SNSWebhookConfig      `mapstructure:"sns"`
+	// MaxAge is the difference between the signed timestamp of the events
+	// and the current time, above which they are rejected as replayed. It is
+	// 1 hour by default.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// SendGridWebhookConfig configures the endpoint of the SendGrid Event Webhook
+type SendGridWebhookConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// VerificationKey is the base64 public key of the Signed Event Webhook,
+	// it is required to enable the endpoint
+	VerificationKey string `mapstructure:"verification_key"`
+}
+
+// SNSWebhookConfig configures the endpoint of SES notifications delivered by
+// Amazon SNS, it is enabled when the certificate file is set
+type SNSWebhookConfig struct {
+	// CertificateFile is the PEM file with the certificates which sign the SNS messages
+	CertificateFile string `mapstructure:"certificate_file"`
+	// TopicARNs limits the topics from which the messages are accepted
+	TopicARNs []string `mapstructure:"topic_arns"`
+	// ConfirmSubscriptions visits the SubscribeURL of the subscription
+	// confirmation, otherwise it is only logged
+	ConfirmSubscriptions bool `mapstructure:"confirm_subscriptions"`
+}
+
+// providerEvent is the event of the provider translated to the changes of
+// the message and the suppressions
+type providerEvent struct {
+	provider          string
+	kind              string
+	providerMessageID string
+	// state is empty when the event does not change the state of the message
+	state       MessageState
+	recipient   string
+	description string
+	// suppress is unspecified when the recipient should not be suppressed
+	suppress pb.Suppression_Reason
+}
+
+// Webhooks receives the events of providers, e.g. bounces, updates the state
+// of messages and suppresses the recipients which bounced or complained
+type Webhooks struct {
+	opts       *options
+	sendGrid   SendGridWebhookConfig
+	sendGridPK *ecdsa.PublicKey
+	sns        SNSWebhookConfig
+	snsCerts   []*x509.Certificate
+	client     *http.Client
+	maxAge     time.Duration
+	now        func() time.Time
+}
+
+// NewWebhooks constructor of Webhooks, the queue and the suppressions are
+// taken from the options
+func NewWebhooks(cfg WebhookConfig, opts ...Option) (*Webhooks, error) {
+	w := &Webhooks{
+		opts:     evaluateOptions(opts),
+		sendGrid: cfg.SendGrid,
+		sns:      cfg.SNS,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		maxAge:   cfg.MaxAge,
+		now:      time.Now,
+	}
+	if w.maxAge <= 0 {
+		w.maxAge = defaultWebhookMaxAge
+	}
+	if cfg.SendGrid.Enabled {
+		if cfg.SendGrid.VerificationKey == "" {
+			return nil, errors.New("sendgrid webhook requires the verification key")
+		}
+		pk, err := parseSendGridKey(cfg.SendGrid.VerificationKey)
+		if err != nil {
+			return nil, err
+		}
+		w.sendGridPK = pk
+	}
+	if cfg.SNS.CertificateFile != "" {
+		certs, err := loadCertificates(cfg.SNS.CertificateFile)
+		if err != nil {
+			return nil, err
+		}
+		w.snsCerts = certs
+	}
+	return w, nil
+}
+
+// SendGrid returns the handler of the SendGrid Event Webhook, nil when it is not enabled
+func (w *Webhooks) SendGrid() http.Handler {
+	if !w.sendGrid.Enabled {
+		return nil
+	}
+	return webhookHandler(w.handleSendGrid)
+}
+
+// SNS returns the handler of SES notifications delivered by Amazon SNS, nil
+// when it is not enabled
+func (w *Webhooks) SNS() http.Handler {
+	if len(w.snsCerts) == 0 {
+		return nil
+	}
+	return webhookHandler(w.handleSNS)
+}
+
+// webhookError is returned by the handlers to answer with the HTTP status
+type webhookError struct {
+	code int
+	msg  string
+}
+
+func (e *webhookError) Error() string {
+	return e.msg
+}
+
+func webhookErrorf(code int, format string, args ...interface{}) error {
+	return &webhookError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// webhookHandler reads the body of POST requests and answers with the error
+// of the handler. The provider retries the events which were not accepted.
+func webhookHandler(handle func(r *http.Request, body []byte) error) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.Header().Set("Allow", http.MethodPost)
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(rw, "request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := handle(r, body); err != nil {
+			code := http.StatusInternalServerError
+			if werr, ok := err.(*webhookError); ok {
+				code = werr.code
+			}
+			zap.L().Warn("Rejected provider events", zap.String("path", r.URL.Path), zap.Int("code", code), zap.Error(err))
+			http.Error(rw, err.Error(), code)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	})
+}
+
+// fresh reports whether the signed time is within the max age from now, so
+// the captured events can not be replayed later
+func (w *Webhooks) fresh(signed time.Time) bool {
+	age := w.now().Sub(signed)
+	return age <= w.maxAge && age >= -w.maxAge
+}
+
+// apply updates the message and the suppressions. The event of the message
+// which is unknown, e.g. sent by another service using the same account, is
+// ignored.
+func (w *Webhooks) apply(ev providerEvent) error {
+	providerEvents.WithLabelValues(ev.provider, ev.kind).Inc()
+	if ev.suppress != pb.Suppression_REASON_UNSPECIFIED && ev.recipient != "" && w.opts.suppressions != nil {
+		if _, err := w.opts.suppressions.add(ev.recipient, ev.suppress, ev.description); err != nil {
+			return err
+		}
+	}
+	if ev.state == "" || ev.providerMessageID == "" || w.opts.queue == nil {
+		return nil
+	}
+	_, err := w.opts.queue.Track(ev.providerMessageID, ev.state, ev.description)
+	if status.Code(err) == codes.NotFound {
+		zap.L().Debug("Ignored event of unknown message",
+			zap.String("provider", ev.provider), zap.String("provider_message_id", ev.providerMessageID))
+		return nil
+	}
+	return err
+}
+
+func parseSendGridKey(key string) (*ecdsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SendGrid verification key: %v", err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SendGrid verification key: %v", err)
+	}
+	pk, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("SendGrid verification key must be ECDSA public key")
+	}
+	return pk, nil
+}
+
+func loadCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read SNS certificate: %v", err)
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNS certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+	return certs, nil
+}
@@ -0,0 +1,433 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/RafalKorepta/coding-challenge/pkg/api/email/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTopicARN = "arn:aws:sns:us-east-1:123456789012:ses-events"
+
+// snsSigner signs the SNS messages with the key of the self-signed certificate
+type snsSigner struct {
+	key      *rsa.PrivateKey
+	certFile string
+}
+
+func newSNSSigner(t *testing.T, dir string) *snsSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err, "Key should be generated")
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.us-east-1.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	assert.NoError(t, err, "Certificate should be created")
+	certFile := filepath.Join(dir, "sns.pem")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	assert.NoError(t, err, "Certificate should be written")
+	return &snsSigner{key: key, certFile: certFile}
+}
+
+func (s *snsSigner) sign(t *testing.T, msg *snsMessage) []byte {
+	msg.SignatureVersion = "2"
+	digest := sha256.Sum256(snsStringToSign(msg))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	assert.NoError(t, err, "Message should be signed")
+	msg.Signature = base64.StdEncoding.EncodeToString(sig)
+	body, err := json.Marshal(msg)
+	assert.NoError(t, err, "Message should be encoded")
+	return body
+}
+
+func sesNotificationMessage(notification string) *snsMessage {
+	return &snsMessage{
+		Type:      snsNotification,
+		MessageID: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:  testTopicARN,
+		Message:   notification,
+		Timestamp: "2018-06-01T09:00:00.000Z",
+	}
+}
+
+// sentTestMessage queues the message and marks it as sent by the provider with the id
+func sentTestMessage(t *testing.T, q *Queue, providerMessageID string) *Message {
+	queued, err := q.Enqueue(validRequest())
+	assert.NoError(t, err, "Message should be queued")
+	msg, err := q.Next(context.Background())
+	assert.NoError(t, err, "Message should be taken from the queue")
+	assert.NoError(t, q.Complete(msg, &Receipt{Provider: "fake", MessageID: providerMessageID}), "Complete should succeed")
+	return queued
+}
+
+func postWebhook(h http.Handler, body []byte, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestQueue_Track(t *testing.T) {
+	// Arrange
+	dir := testDir(t, "queue")
+	q := openTestQueue(t, dir)
+	queued := sentTestMessage(t, q, "provider-id")
+
+	t.Run("State reported by the provider is recorded", func(t *testing.T) {
+		// Act
+		msg, err := q.Track("provider-id", MessageOpened, "")
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, queued.ID, msg.ID, "Message must be found by the provider id")
+		assert.Equal(t, MessageOpened, msg.State, "Message must be opened")
+	})
+
+	t.Run("Older state is ignored", func(t *testing.T) {
+		// Act
+		msg, err := q.Track("provider-id", MessageDelivered, "")
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Equal(t, MessageOpened, msg.State, "State must not go back")
+	})
+
+	t.Run("Index is not resumed as the message", func(t *testing.T) {
+		// Arrange
+		assert.NoError(t, q.store.Close(), "Store should be closed")
+		assert.NoError(t, q.deadLetters.store.Close(), "Store should be closed")
+
+		// Act
+		q = openTestQueue(t, dir)
+		_, err := q.Get(providerIndexPrefix + "provider-id")
+
+		// Assert
+		assert.Len(t, q.due, 0, "Nothing must be resumed")
+		assert.Error(t, err, "Index must not be returned as the message")
+	})
+}
+
+func TestWebhooks_SNS(t *testing.T) {
+	// Arrange
+	signer := newSNSSigner(t, testDir(t, "webhooks"))
+	q := newTestQueue(t)
+	suppressions := newTestSuppressions(t)
+	confirmed := make(chan struct{}, 1)
+	sns := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		confirmed <- struct{}{}
+	}))
+	defer sns.Close()
+	webhooks, err := NewWebhooks(WebhookConfig{SNS: SNSWebhookConfig{
+		CertificateFile:      signer.certFile,
+		TopicARNs:            []string{testTopicARN},
+		ConfirmSubscriptions: true,
+	}}, WithQueue(q), WithSuppressions(suppressions))
+	assert.NoError(t, err, "Webhooks should be created")
+	webhooks.now = func() time.Time { return time.Date(2018, 6, 1, 9, 30, 0, 0, time.UTC) }
+	h := webhooks.SNS()
+
+	t.Run("Subscription is confirmed", func(t *testing.T) {
+		// Arrange
+		body := signer.sign(t, &snsMessage{
+			Type:         snsSubscriptionConfirmation,
+			MessageID:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+			Token:        "token",
+			TopicArn:     testTopicARN,
+			Message:      "You have chosen to subscribe to the topic",
+			SubscribeURL: sns.URL + "/?Action=ConfirmSubscription",
+			Timestamp:    "2018-06-01T09:00:00.000Z",
+		})
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Confirmation must be accepted")
+		assert.Len(t, confirmed, 1, "Subscribe URL must be visited")
+	})
+
+	t.Run("Permanent bounce suppresses the recipient", func(t *testing.T) {
+		// Arrange
+		queued := sentTestMessage(t, q, "ses-bounce")
+		body := signer.sign(t, sesNotificationMessage(`{"notificationType":"Bounce",
+			"bounce":{"bounceType":"Permanent","bouncedRecipients":[
+				{"emailAddress":"recipient@example.com","diagnosticCode":"smtp; 550 5.1.1 user unknown"}]},
+			"mail":{"messageId":"ses-bounce"}}`))
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Notification must be accepted")
+		msg, _ := q.Get(queued.ID)
+		assert.Equal(t, MessageBounced, msg.State, "Message must be bounced")
+		assert.Contains(t, msg.LastError, "user unknown", "Diagnostic code must be stored")
+		sup, err := suppressions.Get("recipient@example.com")
+		assert.NoError(t, err, "Recipient must be suppressed")
+		assert.Equal(t, pb.Suppression_BOUNCE, sup.GetReason(), "Reason must be bounce")
+	})
+
+	t.Run("Transient bounce does not suppress the recipient", func(t *testing.T) {
+		// Arrange
+		sentTestMessage(t, q, "ses-transient")
+		body := signer.sign(t, sesNotificationMessage(`{"notificationType":"Bounce",
+			"bounce":{"bounceType":"Transient","bouncedRecipients":[{"emailAddress":"full@example.com"}]},
+			"mail":{"messageId":"ses-transient"}}`))
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Notification must be accepted")
+		_, err := suppressions.Get("full@example.com")
+		assert.Error(t, err, "Recipient must not be suppressed")
+	})
+
+	t.Run("Complaint suppresses the recipient", func(t *testing.T) {
+		// Arrange
+		body := signer.sign(t, sesNotificationMessage(`{"notificationType":"Complaint",
+			"complaint":{"complainedRecipients":[{"emailAddress":"angry@example.com"}],"complaintFeedbackType":"abuse"},
+			"mail":{"messageId":"unknown"}}`))
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Notification of unknown message must be accepted")
+		sup, err := suppressions.Get("angry@example.com")
+		assert.NoError(t, err, "Recipient must be suppressed")
+		assert.Equal(t, pb.Suppression_COMPLAINT, sup.GetReason(), "Reason must be complaint")
+	})
+
+	t.Run("Delivery is recorded", func(t *testing.T) {
+		// Arrange
+		queued := sentTestMessage(t, q, "ses-delivery")
+		body := signer.sign(t, sesNotificationMessage(`{"eventType":"Delivery",
+			"delivery":{"recipients":["recipient@example.com"]},"mail":{"messageId":"ses-delivery"}}`))
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Notification must be accepted")
+		msg, _ := q.Get(queued.ID)
+		assert.Equal(t, MessageDelivered, msg.State, "Message must be delivered")
+	})
+
+	t.Run("Message with invalid signature is rejected", func(t *testing.T) {
+		// Arrange
+		msg := sesNotificationMessage(`{"notificationType":"Complaint",
+			"complaint":{"complainedRecipients":[{"emailAddress":"victim@example.com"}]}}`)
+		signer.sign(t, msg)
+		msg.Message = `{"notificationType":"Complaint",
+			"complaint":{"complainedRecipients":[{"emailAddress":"other@example.com"}]}}`
+		body, _ := json.Marshal(msg)
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code, "Message must be rejected")
+		_, err := suppressions.Get("other@example.com")
+		assert.Error(t, err, "Recipient must not be suppressed")
+	})
+
+	t.Run("Old message is rejected", func(t *testing.T) {
+		// Arrange
+		msg := sesNotificationMessage(`{"notificationType":"Complaint",
+			"complaint":{"complainedRecipients":[{"emailAddress":"replayed@example.com"}]},
+			"mail":{"messageId":"ses-replayed"}}`)
+		msg.Timestamp = "2018-06-01T07:00:00.000Z"
+		body := signer.sign(t, msg)
+
+		// Act
+		rec := postWebhook(h, body, nil)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code, "Message must be rejected")
+		_, err := suppressions.Get("replayed@example.com")
+		assert.Error(t, err, "Recipient must not be suppressed")
+	})
+
+	t.Run("Message of other topic is rejected", func(t *testing.T) {
+		// Arrange
+		msg := sesNotificationMessage(`{}`)
+		msg.TopicArn = "arn:aws:sns:us-east-1:123456789012:other"
+
+		// Act
+		rec := postWebhook(h, signer.sign(t, msg), nil)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code, "Message must be rejected")
+	})
+
+	t.Run("Only POST is allowed", func(t *testing.T) {
+		// Arrange
+		rec := httptest.NewRecorder()
+
+		// Act
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/ses", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, "GET must not be allowed")
+	})
+}
+
+func TestWebhooks_SendGrid(t *testing.T) {
+	// Arrange
+	q := newTestQueue(t)
+	suppressions := newTestSuppressions(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "Key should be generated")
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err, "Key should be encoded")
+	webhooks, err := NewWebhooks(WebhookConfig{SendGrid: SendGridWebhookConfig{
+		Enabled:         true,
+		VerificationKey: base64.StdEncoding.EncodeToString(der),
+	}}, WithQueue(q), WithSuppressions(suppressions))
+	assert.NoError(t, err, "Webhooks should be created")
+	webhooks.now = func() time.Time { return time.Unix(1527843660, 0) }
+	h := webhooks.SendGrid()
+	timestamp := "1527843600"
+	signed := func(body []byte) http.Header {
+		digest := sha256.Sum256(append([]byte(timestamp), body...))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		assert.NoError(t, err, "Events should be signed")
+		return http.Header{
+			sendGridSignatureHeader: {base64.StdEncoding.EncodeToString(sig)},
+			sendGridTimestampHeader: {timestamp},
+		}
+	}
+
+	t.Run("Batch of events is applied", func(t *testing.T) {
+		// Arrange
+		delivered := sentTestMessage(t, q, "delivered-id")
+		bounced := sentTestMessage(t, q, "bounced-id")
+		body := []byte(`[
+			{"email":"recipient@example.com","event":"delivered","sg_message_id":"delivered-id.filter0001.16648.5515E0B88.0"},
+			{"email":"recipient@example.com","event":"open","sg_message_id":"delivered-id.filter0001.16648.5515E0B88.0"},
+			{"email":"gone@example.com","event":"bounce","type":"bounce","reason":"550 5.1.1 user unknown","sg_message_id":"bounced-id.filter0001"},
+			{"email":"blocked@example.com","event":"bounce","type":"blocked","sg_message_id":"other-id.filter0001"},
+			{"email":"spam@example.com","event":"spamreport","sg_message_id":"other-id.filter0001"},
+			{"email":"bye@example.com","event":"unsubscribe","sg_message_id":"other-id.filter0001"}
+		]`)
+
+		// Act
+		rec := postWebhook(h, body, signed(body))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code, "Events must be accepted")
+		msg, _ := q.Get(delivered.ID)
+		assert.Equal(t, MessageOpened, msg.State, "Message must be opened")
+		msg, _ = q.Get(bounced.ID)
+		assert.Equal(t, MessageBounced, msg.State, "Message must be bounced")
+		for email, reason := range map[string]pb.Suppression_Reason{
+			"gone@example.com": pb.Suppression_BOUNCE,
+			"spam@example.com": pb.Suppression_COMPLAINT,
+			"bye@example.com":  pb.Suppression_UNSUBSCRIBE,
+		} {
+			sup, err := suppressions.Get(email)
+			assert.NoError(t, err, "Recipient %s must be suppressed", email)
+			assert.Equal(t, reason, sup.GetReason(), "Reason of %s must be returned", email)
+		}
+		_, err := suppressions.Get("blocked@example.com")
+		assert.Error(t, err, "Blocked recipient must not be suppressed")
+	})
+
+	t.Run("Events with invalid signature are rejected", func(t *testing.T) {
+		// Arrange
+		header := signed([]byte(`[]`))
+		body := []byte(`[{"email":"victim@example.com","event":"spamreport"}]`)
+
+		// Act
+		rec := postWebhook(h, body, header)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code, "Events must be rejected")
+		_, err := suppressions.Get("victim@example.com")
+		assert.Error(t, err, "Recipient must not be suppressed")
+	})
+
+	t.Run("Replayed events are rejected", func(t *testing.T) {
+		// Arrange
+		body := []byte(`[{"email":"replayed@example.com","event":"spamreport"}]`)
+		header := signed(body)
+		webhooks.now = func() time.Time { return time.Unix(1527843600, 0).Add(2 * time.Hour) }
+		defer func() { webhooks.now = func() time.Time { return time.Unix(1527843660, 0) } }()
+
+		// Act
+		rec := postWebhook(h, body, header)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code, "Events must be rejected")
+		_, err := suppressions.Get("replayed@example.com")
+		assert.Error(t, err, "Recipient must not be suppressed")
+	})
+
+	t.Run("Invalid events are rejected", func(t *testing.T) {
+		// Arrange
+		body := []byte(`{"event":"delivered"}`)
+
+		// Act
+		rec := postWebhook(h, body, signed(body))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "Events must be rejected")
+	})
+
+	t.Run("Disabled webhooks are not handled", func(t *testing.T) {
+		// Act
+		disabled, err := NewWebhooks(WebhookConfig{})
+
+		// Assert
+		assert.NoError(t, err, "Error should not occur")
+		assert.Nil(t, disabled.SendGrid(), "SendGrid handler must not exist")
+		assert.Nil(t, disabled.SNS(), "SNS handler must not exist")
+	})
+
+	t.Run("Verification key is required", func(t *testing.T) {
+		// Act
+		_, err := NewWebhooks(WebhookConfig{SendGrid: SendGridWebhookConfig{Enabled: true}})
+
+		// Assert
+		assert.Error(t, err, "SendGrid webhook without the verification key must not be created")
+	})
+}
@@ -0,0 +1,367 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package smtptest provides a local SMTP server for testing providers
+// without network access, similarly to net/http/httptest.
+package smtptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures the behaviour of the test server
+type Config struct {
+	// ImplicitTLS makes the server speak TLS from the first byte like on port 465
+	ImplicitTLS bool
+	// StartTLS advertises and accepts STARTTLS command
+	StartTLS bool
+	// Username and Password require the clients to authenticate before MAIL command
+	Username string
+	Password string
+	// Replies overrides the reply of the command e.g. "RCPT": "550 5.1.1 User unknown".
+	// The reply for DATA command is returned after the message is received.
+	Replies map[string]string
+}
+
+// Message is the email received by the server
+type Message struct {
+	Helo     string
+	From     string
+	To       []string
+	Data     []byte
+	TLS      bool
+	AuthUser string
+	AuthMech string
+}
+
+// Server is the SMTP server listening on the loopback interface
+type Server struct {
+	// Addr is the host:port of the server
+	Addr string
+
+	cfg       Config
+	listener  net.Listener
+	tlsConfig *tls.Config
+	certPool  *x509.CertPool
+
+	mu       sync.Mutex
+	messages []Message
+	wg       sync.WaitGroup
+}
+
+// NewServer starts the server with a fresh self-signed certificate
+func NewServer(cfg Config) (*Server, error) {
+	cert, pool, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:       cfg,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		certPool:  pool,
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen: %v", err)
+	}
+	if cfg.ImplicitTLS {
+		l = tls.NewListener(l, s.tlsConfig)
+	}
+	s.listener = l
+	s.Addr = l.Addr().String()
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// RootCAs returns the pool which trusts the server certificate
+func (s *Server) RootCAs() *x509.CertPool {
+	return s.certPool
+}
+
+// Messages returns all the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the server and waits for the open sessions
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(time.Minute))
+			s.handle(conn)
+		}()
+	}
+}
+
+type session struct {
+	conn     net.Conn
+	text     *textproto.Conn
+	tls      bool
+	authUser string
+	authMech string
+	helo     string
+	msg      *Message
+}
+
+func (s *Server) handle(conn net.Conn) {
+	sess := &session{conn: conn, text: textproto.NewConn(conn), tls: s.cfg.ImplicitTLS}
+	sess.reply("220 smtptest ESMTP ready")
+
+	for {
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		verb = strings.ToUpper(verb)
+
+		if reply, ok := s.cfg.Replies[verb]; ok && verb != "DATA" {
+			sess.reply(reply)
+			continue
+		}
+
+		switch verb {
+		case "EHLO":
+			sess.helo = arg
+			lines := []string{"smtptest", "8BITMIME"}
+			if s.cfg.StartTLS && !sess.tls {
+				lines = append(lines, "STARTTLS")
+			}
+			if s.cfg.Username != "" {
+				lines = append(lines, "AUTH PLAIN LOGIN CRAM-MD5")
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				sess.reply("250" + sep + l)
+			}
+		case "HELO":
+			sess.helo = arg
+			sess.reply("250 smtptest")
+		case "STARTTLS":
+			if !s.cfg.StartTLS || sess.tls {
+				sess.reply("502 5.5.1 STARTTLS not available")
+				continue
+			}
+			sess.reply("220 2.0.0 Ready to start TLS")
+			tlsConn := tls.Server(sess.conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			sess.conn = tlsConn
+			sess.text = textproto.NewConn(tlsConn)
+			sess.tls = true
+			sess.helo = ""
+		case "AUTH":
+			s.auth(sess, arg)
+		case "MAIL":
+			if s.cfg.Username != "" && sess.authUser == "" {
+				sess.reply("530 5.7.0 Authentication required")
+				continue
+			}
+			sess.msg = &Message{
+				Helo:     sess.helo,
+				From:     pathArg(arg, "FROM:"),
+				TLS:      sess.tls,
+				AuthUser: sess.authUser,
+				AuthMech: sess.authMech,
+			}
+			sess.reply("250 2.1.0 Ok")
+		case "RCPT":
+			if sess.msg == nil {
+				sess.reply("503 5.5.1 Bad sequence of commands")
+				continue
+			}
+			sess.msg.To = append(sess.msg.To, pathArg(arg, "TO:"))
+			sess.reply("250 2.1.5 Ok")
+		case "DATA":
+			if sess.msg == nil || len(sess.msg.To) == 0 {
+				sess.reply("503 5.5.1 Bad sequence of commands")
+				continue
+			}
+			sess.reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := sess.text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			if reply, ok := s.cfg.Replies["DATA"]; ok {
+				sess.msg = nil
+				sess.reply(reply)
+				continue
+			}
+			sess.msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, *sess.msg)
+			n := len(s.messages)
+			s.mu.Unlock()
+			sess.msg = nil
+			sess.reply(fmt.Sprintf("250 2.0.0 Ok: queued as %d", n))
+		case "RSET":
+			sess.msg = nil
+			sess.reply("250 2.0.0 Ok")
+		case "NOOP":
+			sess.reply("250 2.0.0 Ok")
+		case "QUIT":
+			sess.reply("221 2.0.0 Bye")
+			return
+		default:
+			sess.reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (s *Server) auth(sess *session, arg string) {
+	if s.cfg.Username == "" {
+		sess.reply("502 5.5.1 AUTH not available")
+		return
+	}
+	parts := strings.SplitN(arg, " ", 2)
+	mech := strings.ToUpper(parts[0])
+	var user, pass string
+	ok := false
+
+	switch mech {
+	case "PLAIN":
+		resp := ""
+		if len(parts) == 2 {
+			resp = parts[1]
+		} else {
+			resp = sess.challenge("")
+		}
+		fields := strings.Split(decode(resp), "\x00")
+		if len(fields) == 3 {
+			user, pass = fields[1], fields[2]
+			ok = user == s.cfg.Username && pass == s.cfg.Password
+		}
+	case "LOGIN":
+		user = decode(sess.challenge("Username:"))
+		pass = decode(sess.challenge("Password:"))
+		ok = user == s.cfg.Username && pass == s.cfg.Password
+	case "CRAM-MD5":
+		challenge := fmt.Sprintf("<%d@smtptest>", time.Now().UnixNano())
+		fields := strings.SplitN(decode(sess.challenge(challenge)), " ", 2)
+		if len(fields) == 2 {
+			user = fields[0]
+			d := hmac.New(md5.New, []byte(s.cfg.Password))
+			_, _ = d.Write([]byte(challenge))
+			ok = user == s.cfg.Username && hmac.Equal([]byte(hex.EncodeToString(d.Sum(nil))), []byte(fields[1]))
+		}
+	default:
+		sess.reply("504 5.5.4 Unrecognized authentication type")
+		return
+	}
+
+	if !ok {
+		sess.reply("535 5.7.8 Authentication credentials invalid")
+		return
+	}
+	sess.authUser, sess.authMech = user, mech
+	sess.reply("235 2.7.0 Authentication successful")
+}
+
+func (sess *session) reply(line string) {
+	_ = sess.text.PrintfLine("%s", line)
+}
+
+func (sess *session) challenge(c string) string {
+	sess.reply("334 " + base64.StdEncoding.EncodeToString([]byte(c)))
+	line, _ := sess.text.ReadLine()
+	return line
+}
+
+func decode(s string) string {
+	b, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	return string(b)
+}
+
+// pathArg extracts the address from "FROM:<a@b> SIZE=1" like arguments
+func pathArg(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	if i := strings.IndexByte(arg, ' '); i >= 0 {
+		arg = arg[:i]
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(arg, "<"), ">")
+}
+
+func selfSignedCertificate() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smtptest"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("unable to create certificate: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: parsed}, pool, nil
+}
@@ -0,0 +1,64 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+var (
+	defaultOptions = &options{
+		maxSegmentSize:    16 << 20,
+		compactionGarbage: 4 << 20,
+		sync:              true,
+	}
+)
+
+type options struct {
+	maxSegmentSize    int64
+	compactionGarbage int64
+	sync              bool
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// Option configures the Store
+type Option func(*options)
+
+// WithMaxSegmentSize setup the size after which the new segment is started
+func WithMaxSegmentSize(size int64) Option {
+	return func(o *options) {
+		o.maxSegmentSize = size
+	}
+}
+
+// WithCompactionGarbage setup how many bytes of overwritten and deleted records
+// are needed to compact the log. The log is compacted only when the garbage
+// outweighs the live records as well.
+func WithCompactionGarbage(size int64) Option {
+	return func(o *options) {
+		o.compactionGarbage = size
+	}
+}
+
+// WithSync if set to false, then writes are not flushed to the disk before
+// returning. It should be used only in tests.
+func WithSync(s bool) Option {
+	return func(o *options) {
+		o.sync = s
+	}
+}
@@ -0,0 +1,556 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage provides the crash-safe key value store backed by the
+// append-only segment log. All live values are kept in memory, the log on the
+// disk is used to recover them after restart.
+//
+// Every record has the following layout:
+//
+//	crc32c uint32 | kind uint8 | key length uint32 | value length uint32 | key | value
+//
+// where the checksum covers everything after it. The record which was torn by
+// the crash at the end of the last segment is truncated during Open.
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentExt   = ".seg"
+	tmpExt       = ".tmp"
+	headerSize   = 13
+	recordPut    = 0
+	recordDelete = 1
+	// maxRecordSize protects against allocating huge buffers for garbage lengths
+	maxRecordSize = 1 << 30
+)
+
+var (
+	// ErrClosed is returned when the store was already closed
+	ErrClosed = errors.New("storage: store is closed")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	// removeFile removes the segments, the tests replace it to fail the compaction
+	removeFile = os.Remove
+	// writeFile writes the records, the tests replace it to tear them
+	writeFile = (*os.File).Write
+)
+
+// CorruptionError is returned from Open when the segment other than the last
+// one can not be read. Such segment was synced before the next one was created,
+// so it could not be damaged by the crash.
+type CorruptionError struct {
+	Segment string
+	Offset  int64
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("storage: segment %s is corrupted at offset %d", e.Segment, e.Offset)
+}
+
+// Store is the key value store persisted in the append-only segment log
+type Store struct {
+	dir  string
+	opts *options
+
+	mu         sync.RWMutex
+	values     map[string][]byte
+	segments   []uint64
+	active     *os.File
+	activeSize int64
+	// live is the size of records holding the current values,
+	// total is the size of all records in all segments
+	live   int64
+	total  int64
+	closed bool
+	// broken is the error which left the segments in the state in which the
+	// writes would be lost after reopening, the store rejects them since then
+	broken error
+}
+
+// Open opens the store in the directory, creating it when needed,
+// and replays all segments
+func Open(dir string, opts ...Option) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		dir:    dir,
+		opts:   evaluateOptions(opts),
+		values: make(map[string][]byte),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if len(s.segments) == 0 {
+		if err := s.openSegment(1); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	last := s.segments[len(s.segments)-1]
+	f, err := os.OpenFile(s.segmentPath(last), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s.active = f
+	s.activeSize = info.Size()
+	return s, nil
+}
+
+// Get returns the copy of the value stored under the key
+func (s *Store) Get(key string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), v...), true
+}
+
+// Len returns the number of keys in the store
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.values)
+}
+
+// Range calls fn for every key with the given prefix in the lexical order
+// until fn returns false. The store must not be modified from fn.
+func (s *Store) Range(prefix string, fn func(key string, value []byte) bool) {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = s.values[k]
+	}
+	s.mu.RUnlock()
+
+	for i, k := range keys {
+		if !fn(k, append([]byte(nil), values[i]...)) {
+			return
+		}
+	}
+}
+
+// Put stores the value under the key. When Put returns the record is on the disk.
+func (s *Store) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	size, err := s.append(recordPut, key, value)
+	if err != nil {
+		return err
+	}
+	if old, ok := s.values[key]; ok {
+		s.live -= recordSize(key, old)
+	}
+	s.values[key] = append([]byte(nil), value...)
+	s.live += size
+	return s.maybeCompact()
+}
+
+// PutAll stores the values under their keys and syncs the disk once for all
+// of them. When PutAll returns the records are on the disk.
+func (s *Store) PutAll(values map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	for key, value := range values {
+		size, err := s.write(recordPut, key, value)
+		if err != nil {
+			return err
+		}
+		if old, ok := s.values[key]; ok {
+			s.live -= recordSize(key, old)
+		}
+		s.values[key] = append([]byte(nil), value...)
+		s.live += size
+	}
+	if err := s.sync(); err != nil {
+		return err
+	}
+	return s.maybeCompact()
+}
+
+// Delete removes the key from the store. Deleting missing key is not an error.
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	old, ok := s.values[key]
+	if !ok {
+		return nil
+	}
+	if _, err := s.append(recordDelete, key, nil); err != nil {
+		return err
+	}
+	delete(s.values, key)
+	s.live -= recordSize(key, old)
+	return s.maybeCompact()
+}
+
+// Compact rewrites the live values into the new segment and removes the old ones
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	return s.compact()
+}
+
+// Close syncs and closes the active segment
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.active.Sync(); err != nil {
+		_ = s.active.Close()
+		return err
+	}
+	return s.active.Close()
+}
+
+// writable returns the error of the store which can not be written anymore
+func (s *Store) writable() error {
+	if s.closed {
+		return ErrClosed
+	}
+	return s.broken
+}
+
+func (s *Store) append(kind byte, key string, value []byte) (int64, error) {
+	size, err := s.write(kind, key, value)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.sync(); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// write adds the record to the active segment without syncing it
+func (s *Store) write(kind byte, key string, value []byte) (int64, error) {
+	if s.activeSize >= s.opts.maxSegmentSize {
+		if err := s.rollover(); err != nil {
+			return 0, err
+		}
+	}
+	rec := encodeRecord(kind, key, value)
+	if _, err := writeFile(s.active, rec); err != nil {
+		// The replay stops at the torn record, so the records written after
+		// it would be lost. It is cut off to keep the segment readable.
+		if terr := s.active.Truncate(s.activeSize); terr != nil {
+			s.broken = fmt.Errorf("storage: torn record can not be truncated after error: %v", err)
+		}
+		return 0, err
+	}
+	s.activeSize += int64(len(rec))
+	s.total += int64(len(rec))
+	return int64(len(rec)), nil
+}
+
+func (s *Store) sync() error {
+	if !s.opts.sync {
+		return nil
+	}
+	return s.active.Sync()
+}
+
+func (s *Store) rollover() error {
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	if err := s.active.Close(); err != nil {
+		return err
+	}
+	return s.openSegment(s.segments[len(s.segments)-1] + 1)
+}
+
+func (s *Store) openSegment(id uint64) error {
+	f, err := os.OpenFile(s.segmentPath(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(s.dir); err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.active = f
+	s.activeSize = 0
+	s.segments = append(s.segments, id)
+	return nil
+}
+
+func (s *Store) maybeCompact() error {
+	garbage := s.total - s.live
+	if garbage < s.opts.compactionGarbage || garbage < s.live {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact writes the live values into the temporary file which is renamed to
+// the segment with the highest id. The old segments are removed from the
+// oldest one, so after the crash the remaining ones are always followed by the
+// compacted segment, which overrides whatever they hold.
+func (s *Store) compact() error {
+	id := s.segments[len(s.segments)-1] + 1
+	tmp := s.segmentPath(id) + tmpExt
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var size int64
+	for k, v := range s.values {
+		rec := encodeRecord(recordPut, k, v)
+		if _, err = w.Write(rec); err != nil {
+			break
+		}
+		size += int64(len(rec))
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		_ = f.Close()
+		_ = removeFile(tmp)
+		return err
+	}
+	if err = f.Close(); err != nil {
+		_ = removeFile(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, s.segmentPath(id)); err != nil {
+		_ = removeFile(tmp)
+		return err
+	}
+	// The compacted segment overrides the records written to the old active
+	// one, so it is removed when it can not become active
+	discard := func(err error) error {
+		if rerr := removeFile(s.segmentPath(id)); rerr != nil && !os.IsNotExist(rerr) {
+			s.broken = fmt.Errorf("storage: compacted segment %d can not be removed after error: %v", id, err)
+		}
+		return err
+	}
+	if err = syncDir(s.dir); err != nil {
+		return discard(err)
+	}
+	active, err := os.OpenFile(s.segmentPath(id), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return discard(err)
+	}
+
+	// The compacted segment is active before the old ones are removed, so the
+	// store can be written when their removal fails
+	_ = s.active.Close()
+	old := s.segments
+	s.segments = append(s.segments, id)
+	s.active = active
+	s.activeSize = size
+	s.total += size
+	for i, seg := range old {
+		if err = removeFile(s.segmentPath(seg)); err != nil && !os.IsNotExist(err) {
+			s.segments = s.segments[i:]
+			return err
+		}
+	}
+	s.segments = []uint64{id}
+	s.total = size
+	s.live = size
+	return syncDir(s.dir)
+}
+
+// load replays all segments in the order of their ids
+func (s *Store) load() error {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		switch {
+		case strings.HasSuffix(name, tmpExt):
+			// Leftover of the compaction interrupted before the rename
+			if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+				return err
+			}
+		case strings.HasSuffix(name, segmentExt):
+			id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+			if err != nil {
+				continue
+			}
+			s.segments = append(s.segments, id)
+		}
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+	for i, id := range s.segments {
+		if err := s.replay(id, i == len(s.segments)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) replay(id uint64, last bool) error {
+	path := s.segmentPath(id)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		kind, key, value, err := decodeRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if !last {
+				return &CorruptionError{Segment: path, Offset: offset}
+			}
+			return truncate(path, offset)
+		}
+		size := recordSize(key, value)
+		if old, ok := s.values[key]; ok {
+			s.live -= recordSize(key, old)
+			delete(s.values, key)
+		}
+		if kind == recordPut {
+			s.values[key] = value
+			s.live += size
+		}
+		s.total += size
+		offset += size
+	}
+}
+
+func (s *Store) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%016d%s", id, segmentExt))
+}
+
+func recordSize(key string, value []byte) int64 {
+	return int64(headerSize + len(key) + len(value))
+}
+
+func encodeRecord(kind byte, key string, value []byte) []byte {
+	rec := make([]byte, recordSize(key, value))
+	rec[4] = kind
+	binary.BigEndian.PutUint32(rec[5:9], uint32(len(key)))
+	binary.BigEndian.PutUint32(rec[9:13], uint32(len(value)))
+	copy(rec[headerSize:], key)
+	copy(rec[headerSize+len(key):], value)
+	binary.BigEndian.PutUint32(rec[0:4], crc32.Checksum(rec[4:], crcTable))
+	return rec
+}
+
+// decodeRecord returns io.EOF only when there are no more records,
+// the partially written record is reported as io.ErrUnexpectedEOF
+func decodeRecord(r io.Reader) (byte, string, []byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, "", nil, err
+	}
+	kind := header[4]
+	keyLen := binary.BigEndian.Uint32(header[5:9])
+	valueLen := binary.BigEndian.Uint32(header[9:13])
+	if (kind != recordPut && kind != recordDelete) || uint64(keyLen)+uint64(valueLen) > maxRecordSize {
+		return 0, "", nil, errors.New("storage: invalid record header")
+	}
+	body := make([]byte, keyLen+valueLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, "", nil, err
+	}
+	crc := crc32.Update(crc32.Checksum(header[4:], crcTable), crcTable, body)
+	if crc != binary.BigEndian.Uint32(header[0:4]) {
+		return 0, "", nil, errors.New("storage: record checksum mismatch")
+	}
+	return kind, string(body[:keyLen]), body[keyLen:], nil
+}
+
+// truncate cuts the torn record at the end of the last segment
+func truncate(path string, offset int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(offset); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
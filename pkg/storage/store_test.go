@@ -0,0 +1,270 @@
+// Copyright [2018] [Rafał Korepta]
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "storage")
+	assert.NoError(t, err, "Temporary directory should be created")
+	return dir
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	assert.NoError(t, err, "Segments should be listed")
+	return files
+}
+
+func TestStore(t *testing.T) {
+	t.Run("Values survive reopening", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir)
+		assert.NoError(t, err, "Store should be opened")
+
+		// Act
+		assert.NoError(t, s.Put("a", []byte("1")), "Put should succeed")
+		assert.NoError(t, s.Put("b", []byte("2")), "Put should succeed")
+		assert.NoError(t, s.Put("a", []byte("3")), "Put should succeed")
+		assert.NoError(t, s.Delete("b"), "Delete should succeed")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		s, err = Open(dir)
+		assert.NoError(t, err, "Store should be reopened")
+
+		// Assert
+		v, ok := s.Get("a")
+		assert.True(t, ok, "Key a must exist")
+		assert.Equal(t, "3", string(v), "Last value must win")
+		_, ok = s.Get("b")
+		assert.False(t, ok, "Deleted key must not exist")
+		assert.Equal(t, 1, s.Len(), "Only one key must be left")
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Values put together survive reopening", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir)
+		assert.NoError(t, err, "Store should be opened")
+		assert.NoError(t, s.Put("a", []byte("1")), "Put should succeed")
+
+		// Act
+		err = s.PutAll(map[string][]byte{"a": []byte("2"), "b": []byte("3")})
+		assert.NoError(t, err, "PutAll should succeed")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		s, err = Open(dir)
+		assert.NoError(t, err, "Store should be reopened")
+
+		// Assert
+		v, _ := s.Get("a")
+		assert.Equal(t, "2", string(v), "Value must be overwritten")
+		v, _ = s.Get("b")
+		assert.Equal(t, "3", string(v), "Value must be stored")
+		assert.Equal(t, 2, s.Len(), "Two keys must be stored")
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Torn record at the end is truncated", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir)
+		assert.NoError(t, err, "Store should be opened")
+		assert.NoError(t, s.Put("a", []byte("1")), "Put should succeed")
+		assert.NoError(t, s.Put("b", []byte("2")), "Put should succeed")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		files := segmentFiles(t, dir)
+		info, err := os.Stat(files[0])
+		assert.NoError(t, err, "Segment should exist")
+		assert.NoError(t, os.Truncate(files[0], info.Size()-1), "Segment should be truncated")
+
+		// Act
+		s, err = Open(dir)
+
+		// Assert
+		assert.NoError(t, err, "Store should be reopened")
+		_, ok := s.Get("a")
+		assert.True(t, ok, "Complete record must be recovered")
+		_, ok = s.Get("b")
+		assert.False(t, ok, "Torn record must be dropped")
+		assert.NoError(t, s.Put("c", []byte("3")), "Put after recovery should succeed")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		s, err = Open(dir)
+		assert.NoError(t, err, "Store should be reopened")
+		v, _ := s.Get("c")
+		assert.Equal(t, "3", string(v), "Record written after truncation must be readable")
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Corrupted sealed segment is reported", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir, WithMaxSegmentSize(1))
+		assert.NoError(t, err, "Store should be opened")
+		assert.NoError(t, s.Put("a", []byte("1")), "Put should succeed")
+		assert.NoError(t, s.Put("b", []byte("2")), "Put should succeed")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		files := segmentFiles(t, dir)
+		assert.Len(t, files, 2, "Every record must be in own segment")
+		assert.NoError(t, ioutil.WriteFile(files[0], []byte("garbage garbage"), 0600), "Segment should be overwritten")
+
+		// Act
+		_, err = Open(dir)
+
+		// Assert
+		_, ok := err.(*CorruptionError)
+		assert.True(t, ok, "Corruption error must be returned")
+	})
+
+	t.Run("Compaction removes garbage", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir, WithMaxSegmentSize(64), WithCompactionGarbage(256), WithSync(false))
+		assert.NoError(t, err, "Store should be opened")
+
+		// Act
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, s.Put(fmt.Sprintf("key-%d", i%3), []byte(fmt.Sprintf("value-%d", i))), "Put should succeed")
+		}
+		assert.NoError(t, s.Close(), "Close should succeed")
+
+		// Assert
+		assert.True(t, len(segmentFiles(t, dir)) < 10, "Old segments must be removed")
+		s, err = Open(dir)
+		assert.NoError(t, err, "Store should be reopened")
+		assert.Equal(t, 3, s.Len(), "All keys must be recovered")
+		v, _ := s.Get("key-0")
+		assert.Equal(t, "value-99", string(v), "Last value must win")
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Store is written after the failed compaction", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir, WithMaxSegmentSize(64), WithSync(false))
+		assert.NoError(t, err, "Store should be opened")
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, s.Put(fmt.Sprintf("key-%d", i%3), []byte(fmt.Sprintf("value-%d", i))), "Put should succeed")
+		}
+		failure := errors.New("remove failed")
+		removeFile = func(string) error { return failure }
+		defer func() { removeFile = os.Remove }()
+
+		// Act
+		err = s.Compact()
+		removeFile = os.Remove
+		putErr := s.Put("key-0", []byte("after"))
+
+		// Assert
+		assert.Equal(t, failure, err, "Error of the compaction must be returned")
+		assert.NoError(t, putErr, "Store must be written after the failed compaction")
+		assert.NoError(t, s.Compact(), "Compaction should succeed again")
+		assert.Len(t, segmentFiles(t, dir), 1, "Old segments must be removed")
+		assert.NoError(t, s.Put("key-1", []byte("last")), "Put should succeed")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		s, err = Open(dir)
+		assert.NoError(t, err, "Store should be reopened")
+		for key, value := range map[string]string{"key-0": "after", "key-1": "last", "key-2": "value-8"} {
+			v, _ := s.Get(key)
+			assert.Equal(t, value, string(v), "Last value of %s must win", key)
+		}
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Store is written after the torn record", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir)
+		assert.NoError(t, err, "Store should be opened")
+		assert.NoError(t, s.Put("a", []byte("1")), "Put should succeed")
+		failure := errors.New("no space left on device")
+		writeFile = func(f *os.File, b []byte) (int, error) {
+			n, _ := f.Write(b[:len(b)/2])
+			return n, failure
+		}
+		defer func() { writeFile = (*os.File).Write }()
+
+		// Act
+		err = s.Put("b", []byte("2"))
+		writeFile = (*os.File).Write
+		putErr := s.Put("c", []byte("3"))
+
+		// Assert
+		assert.Equal(t, failure, err, "Error of the write must be returned")
+		assert.NoError(t, putErr, "Store must be written after the torn record")
+		assert.NoError(t, s.Close(), "Close should succeed")
+		s, err = Open(dir)
+		assert.NoError(t, err, "Store should be reopened")
+		_, ok := s.Get("b")
+		assert.False(t, ok, "Torn value must not be recovered")
+		for key, value := range map[string]string{"a": "1", "c": "3"} {
+			v, _ := s.Get(key)
+			assert.Equal(t, value, string(v), "Value of %s must be recovered", key)
+		}
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Range iterates keys with prefix in order", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir, WithSync(false))
+		assert.NoError(t, err, "Store should be opened")
+		for _, k := range []string{"msg/b", "other", "msg/a", "msg/c"} {
+			assert.NoError(t, s.Put(k, []byte(k)), "Put should succeed")
+		}
+
+		// Act
+		var keys []string
+		s.Range("msg/", func(key string, value []byte) bool {
+			keys = append(keys, key)
+			return len(keys) < 2
+		})
+
+		// Assert
+		assert.Equal(t, []string{"msg/a", "msg/b"}, keys, "Keys must be sorted and iteration must stop")
+		assert.NoError(t, s.Close(), "Close should succeed")
+	})
+
+	t.Run("Closed store rejects writes", func(t *testing.T) {
+		// Arrange
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		s, err := Open(dir)
+		assert.NoError(t, err, "Store should be opened")
+		assert.NoError(t, s.Close(), "Close should succeed")
+
+		// Act
+		err = s.Put("a", nil)
+
+		// Assert
+		assert.Equal(t, ErrClosed, err, "Closed error must be returned")
+	})
+}